@@ -21,9 +21,41 @@ type Config struct {
 	StripeSecretKey      string        `mapstructure:"STRIPE_SECRET_KEY"`
 	StripePublishableKey string        `mapstructure:"STRIPE_PUBLISHABLE_KEY"`
 	StripeWebhookSecret  string        `mapstructure:"STRIPE_WEBHOOK_SECRET"`
+	PaypalClientID       string        `mapstructure:"PAYPAL_CLIENT_ID"`
+	PaypalClientSecret   string        `mapstructure:"PAYPAL_CLIENT_SECRET"`
+	PaypalWebhookID      string        `mapstructure:"PAYPAL_WEBHOOK_ID"`
+	PaypalEnvironment    string        `mapstructure:"PAYPAL_ENVIRONMENT"`
+	PaypalBaseURL        string        `mapstructure:"PAYPAL_BASE_URL"`
 	SmtpUsername         string        `mapstructure:"SMTP_USERNAME"`
 	SmtpPassword         string        `mapstructure:"SMTP_PASSWORD"`
 	SymmetricKey         string        `mapstructure:"SYMMETRIC_KEY"`
+	// PasswordResetTokenTTL is how long the link mailed to a user who forgot their password works
+	PasswordResetTokenTTL time.Duration `mapstructure:"PASSWORD_RESET_TOKEN_TTL"`
+	// PasswordResetURL is the client page the reset token is sent to as the token query parameter
+	PasswordResetURL string `mapstructure:"PASSWORD_RESET_URL"`
+	// OrderLookupTokenTTL is how long the order link mailed to a guest buyer works
+	OrderLookupTokenTTL time.Duration `mapstructure:"ORDER_LOOKUP_TOKEN_TTL"`
+	// InventoryReservationTTL is how long checkout holds stock for an unpaid order
+	InventoryReservationTTL time.Duration `mapstructure:"INVENTORY_RESERVATION_TTL"`
+	// SchedulerTimezone is the IANA time zone periodic tasks are scheduled in
+	SchedulerTimezone string `mapstructure:"SCHEDULER_TIMEZONE"`
+	// GuestCartTTL is how long an untouched guest cart is kept before it is purged
+	GuestCartTTL time.Duration `mapstructure:"GUEST_CART_TTL"`
+	// BankTransferPaymentTTL is how long a bank transfer order waits for the money before it is cancelled
+	BankTransferPaymentTTL time.Duration `mapstructure:"BANK_TRANSFER_PAYMENT_TTL"`
+	// StoreCurrency is the currency product prices are kept in, other currencies are converted from it.
+	// Currencies with more than 2 decimals are refused, the money columns keep only 2.
+	StoreCurrency string `mapstructure:"STORE_CURRENCY"`
+	// PaymentCaptureMethod is "manual" to only authorize gateway payments at checkout and
+	// capture them when the order ships, "automatic" takes the money straight away
+	PaymentCaptureMethod string `mapstructure:"PAYMENT_CAPTURE_METHOD"`
+	// PaymentAuthorizationTTL is how long a gateway holds an authorization when it doesn't say
+	PaymentAuthorizationTTL time.Duration `mapstructure:"PAYMENT_AUTHORIZATION_TTL"`
+	// PaymentReconciliationThreshold is how long a gateway payment waits on its webhook
+	// before the reconciliation job asks the gateway about it
+	PaymentReconciliationThreshold time.Duration `mapstructure:"PAYMENT_RECONCILIATION_THRESHOLD"`
+	// MfaIssuer is the name authenticator apps list two-factor codes of this shop under
+	MfaIssuer string `mapstructure:"MFA_ISSUER"`
 }
 
 func LoadConfig(path string) (cfg Config, err error) {
@@ -33,6 +65,19 @@ func LoadConfig(path string) (cfg Config, err error) {
 	viper.SetConfigName("app")
 
 	viper.AutomaticEnv()
+	viper.SetDefault("PASSWORD_RESET_TOKEN_TTL", "1h")
+	viper.SetDefault("PASSWORD_RESET_URL", "http://localhost:3000/reset-password")
+	viper.SetDefault("ORDER_LOOKUP_TOKEN_TTL", "720h")
+	viper.SetDefault("INVENTORY_RESERVATION_TTL", "30m")
+	viper.SetDefault("GUEST_CART_TTL", "720h")
+	viper.SetDefault("SCHEDULER_TIMEZONE", "UTC")
+	viper.SetDefault("BANK_TRANSFER_PAYMENT_TTL", "72h")
+	viper.SetDefault("PAYPAL_ENVIRONMENT", "sandbox")
+	viper.SetDefault("STORE_CURRENCY", "USD")
+	viper.SetDefault("PAYMENT_CAPTURE_METHOD", "automatic")
+	viper.SetDefault("PAYMENT_AUTHORIZATION_TTL", "168h")
+	viper.SetDefault("PAYMENT_RECONCILIATION_THRESHOLD", "30m")
+	viper.SetDefault("MFA_ISSUER", "go-eshop")
 
 	err = viper.ReadInConfig()
 	if err != nil {
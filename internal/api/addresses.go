@@ -57,6 +57,9 @@ func (s *Server) createAddress(w http.ResponseWriter, r *http.Request) {
 		IsDefault:   req.IsDefault,
 		City:        req.City,
 		District:    req.District,
+		Country:     req.Country,
+		State:       req.State,
+		ZipCode:     req.ZipCode,
 	}
 
 	if req.Ward != nil {
@@ -194,6 +197,18 @@ func (s *Server) updateAddress(w http.ResponseWriter, r *http.Request) {
 		payload.District = req.District
 	}
 
+	if req.Country != nil {
+		payload.Country = req.Country
+	}
+
+	if req.State != nil {
+		payload.State = req.State
+	}
+
+	if req.ZipCode != nil {
+		payload.ZipCode = req.ZipCode
+	}
+
 	if req.IsDefault != nil {
 		if *req.IsDefault {
 			err := s.repo.SetPrimaryAddressTx(c, repository.SetPrimaryAddressTxArgs{
@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/jwtauth/v5"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
@@ -20,114 +19,188 @@ import (
 // Setup admin-related routes
 func (s *Server) addAdminRoutes(r chi.Router) {
 	r.Group(func(r chi.Router) {
-		r.Use(func(h http.Handler) http.Handler {
-			return authorizeMiddleware(h, "admin")
-		})
+		// staff get in, what they can do is checked per route against the permissions of their
+		// role: reading needs read, creating and editing write, deleting and moving money execute
+		r.Use(s.authorizeMiddleware)
 		r.Route("/admin", func(r chi.Router) {
-			// Apply authentication and authorization middleware
 			// User routes
 			r.Route("/users", func(r chi.Router) {
-				r.Get("/", s.adminGetUsers)
-				r.Get("/{id}", s.adminGetUser)
+				r.With(s.RequirePermission("users", Read)).Get("/", s.adminGetUsers)
+				r.With(s.RequirePermission("users", Read)).Get("/{id}", s.adminGetUser)
+				r.With(s.RequirePermission("users", Read)).Get("/{id}/support", s.adminGetUserSupportView)
+				r.With(s.RequirePermission("users", Read)).Get("/{id}/audit", s.adminGetUserAuditLogs)
+				r.With(s.RequirePermission("users", Execute)).Post("/{id}/lock", s.adminLockUser)
+				r.With(s.RequirePermission("users", Execute)).Post("/{id}/unlock", s.adminUnlockUser)
+				r.With(s.RequirePermission("users", Execute)).Post("/{id}/password-reset", s.adminForcePasswordReset)
+				r.With(s.RequirePermission(RolesModule, Write)).Put("/{id}/role", s.adminChangeUserRole)
+			})
+
+			// Role routes
+			r.Route("/roles", func(r chi.Router) {
+				r.With(s.RequirePermission(RolesModule, Read)).Get("/", s.adminGetRoles)
+				r.With(s.RequirePermission(RolesModule, Write)).Post("/", s.adminCreateRole)
+				r.With(s.RequirePermission(RolesModule, Read)).Get("/{id}", s.adminGetRole)
+				r.With(s.RequirePermission(RolesModule, Write)).Put("/{id}", s.adminUpdateRole)
+				r.With(s.RequirePermission(RolesModule, Execute)).Delete("/{id}", s.adminDeleteRole)
+				r.With(s.RequirePermission(RolesModule, Write)).Put("/{id}/permissions/{module}", s.adminSetRolePermission)
+				r.With(s.RequirePermission(RolesModule, Execute)).Delete("/{id}/permissions/{module}", s.adminDeleteRolePermission)
 			})
 
 			// Product routes
 			r.Route("/products", func(r chi.Router) {
-				r.Get("/", s.adminGetProducts)
-				r.Post("/", s.adminAddProduct)
+				r.With(s.RequirePermission("products", Read)).Get("/", s.adminGetProducts)
+				r.With(s.RequirePermission("products", Write)).Post("/", s.adminAddProduct)
 
 				r.Route("/{id}", func(r chi.Router) {
-					r.Put("/", s.adminUpdateProduct)
-					r.Delete("/", s.adminDeleteProduct)
-					r.Post("/images", s.adminUploadProductImage)
+					r.With(s.RequirePermission("products", Write)).Put("/", s.adminUpdateProduct)
+					r.With(s.RequirePermission("products", Execute)).Delete("/", s.adminDeleteProduct)
+					r.With(s.RequirePermission("products", Write)).Post("/images", s.adminUploadProductImage)
 
 					r.Route("/variants", func(r chi.Router) {
-						r.Post("/", s.adminAddVariant)
-						r.Get("/", s.getProductVariants)
-						r.Get("/{variantId}", s.getVariantByProductId)
-						r.Put("/{variantId}", s.adminUpdateVariant)
-						r.Post("/{variantId}/images", s.adminUploadVariantImage)
-						r.Delete("/{variantId}", s.adminDeleteVariant)
+						r.With(s.RequirePermission("products", Write)).Post("/", s.adminAddVariant)
+						r.With(s.RequirePermission("products", Read)).Get("/", s.getProductVariants)
+						r.With(s.RequirePermission("products", Read)).Get("/{variantId}", s.getVariantByProductId)
+						r.With(s.RequirePermission("products", Write)).Put("/{variantId}", s.adminUpdateVariant)
+						r.With(s.RequirePermission("products", Write)).Post("/{variantId}/images", s.adminUploadVariantImage)
+						r.With(s.RequirePermission("products", Execute)).Delete("/{variantId}", s.adminDeleteVariant)
 					})
 				})
 			})
 
 			// Attribute routes
 			r.Route("/attributes", func(r chi.Router) {
-				r.Post("/", s.adminCreateAttribute)
-				r.Get("/", s.adminGetAttributes)
-				r.Get("/{id}", s.adminGetAttributeByID)
-				r.Put("/{id}", s.adminUpdateAttribute)
-				r.Delete("/{id}", s.adminRemoveAttribute)
+				r.With(s.RequirePermission("products", Write)).Post("/", s.adminCreateAttribute)
+				r.With(s.RequirePermission("products", Read)).Get("/", s.adminGetAttributes)
+				r.With(s.RequirePermission("products", Read)).Get("/{id}", s.adminGetAttributeByID)
+				r.With(s.RequirePermission("products", Write)).Put("/{id}", s.adminUpdateAttribute)
+				r.With(s.RequirePermission("products", Execute)).Delete("/{id}", s.adminRemoveAttribute)
 
-				r.Get("/product/{id}", s.adminGetAttributeValuesForProduct)
+				r.With(s.RequirePermission("products", Read)).Get("/product/{id}", s.adminGetAttributeValuesForProduct)
 
 				r.Route("/{id}", func(r chi.Router) {
-					r.Post("/create", s.adminAddAttributeValue)
-					r.Put("/update/{valueId}", s.adminUpdateAttrValue)
-					r.Delete("/remove/{valueId}", s.adminRemoveAttrValue)
+					r.With(s.RequirePermission("products", Write)).Post("/create", s.adminAddAttributeValue)
+					r.With(s.RequirePermission("products", Write)).Put("/update/{valueId}", s.adminUpdateAttrValue)
+					r.With(s.RequirePermission("products", Execute)).Delete("/remove/{valueId}", s.adminRemoveAttrValue)
 				})
 			})
 
 			// Order routes
 			r.Route("/orders", func(r chi.Router) {
-				r.Get("/", s.adminGetOrders)
-				r.Get("/{id}", s.adminGetOrderDetail)
-				r.Put("/{id}/status", s.adminChangeOrderStatus)
-				r.Post("/{id}/cancel", s.adminCancelOrder)
-				r.Post("/{id}/refund", s.adminRefundOrder)
-				r.Delete("/{id}", s.adminDeleteOrder)
+				r.With(s.RequirePermission("orders", Read)).Get("/", s.adminGetOrders)
+				r.With(s.RequirePermission("orders", Read)).Get("/{id}", s.adminGetOrderDetail)
+				r.With(s.RequirePermission("orders", Write)).Put("/{id}/status", s.adminChangeOrderStatus)
+				r.With(s.RequirePermission("orders", Execute)).Post("/{id}/cancel", s.adminCancelOrder)
+				r.With(s.RequirePermission("payments", Execute)).Post("/{id}/refund", s.adminRefundOrder)
+				r.With(s.RequirePermission("payments", Read)).Get("/{id}/refunds", s.adminGetOrderRefunds)
+				r.With(s.RequirePermission("payments", Execute)).Post("/{id}/refunds", s.adminCreateRefund)
+				r.With(s.RequirePermission("payments", Write)).Post("/{id}/payment/received", s.adminRecordOfflinePayment)
+				r.With(s.RequirePermission("payments", Write)).Post("/{id}/payment/expired", s.adminExpireOfflinePayment)
+				r.With(s.RequirePermission("payments", Execute)).Post("/{id}/payment/capture", s.adminCapturePayment)
+				r.With(s.RequirePermission("payments", Execute)).Post("/{id}/payment/void", s.adminVoidPayment)
+				r.With(s.RequirePermission("orders", Execute)).Delete("/{id}", s.adminDeleteOrder)
+
+				r.Route("/{id}/shipments", func(r chi.Router) {
+					r.With(s.RequirePermission("shipping", Read)).Get("/", s.adminGetOrderShipments)
+					r.With(s.RequirePermission("shipping", Write)).Post("/", s.adminCreateShipment)
+					r.With(s.RequirePermission("shipping", Write)).Put("/{shipmentId}", s.adminUpdateShipment)
+				})
+			})
+
+			// Payment routes
+			r.Route("/payments", func(r chi.Router) {
+				r.With(s.RequirePermission("payments", Read)).Get("/authorizations/expiring", s.adminGetExpiringAuthorizations)
+				r.With(s.RequirePermission("payments", Read)).Get("/reconciliations", s.adminGetPaymentReconciliations)
+				r.With(s.RequirePermission("payments", Read)).Get("/reconciliations/{id}/report", s.adminDownloadPaymentReconciliation)
+			})
+
+			// Return request routes
+			r.Route("/returns", func(r chi.Router) {
+				r.With(s.RequirePermission("orders", Read)).Get("/", s.adminGetReturnRequests)
+				r.With(s.RequirePermission("orders", Read)).Get("/{id}", s.getReturnRequest)
+				r.With(s.RequirePermission("orders", Execute)).Post("/{id}/approve", s.adminApproveReturnRequest)
+				r.With(s.RequirePermission("orders", Execute)).Post("/{id}/reject", s.adminRejectReturnRequest)
 			})
 
 			// Category routes
 			r.Route("/categories", func(r chi.Router) {
-				r.Get("/", s.adminGetCategories)
-				r.Get("/{id}", s.adminGetCategoryByID)
-				r.Post("/", s.adminCreateCategory)
-				r.Put("/{id}", s.adminUpdateCategory)
-				r.Delete("/{id}", s.adminDeleteCategory)
+				r.With(s.RequirePermission("categories", Read)).Get("/", s.adminGetCategories)
+				r.With(s.RequirePermission("categories", Read)).Get("/{id}", s.adminGetCategoryByID)
+				r.With(s.RequirePermission("categories", Write)).Post("/", s.adminCreateCategory)
+				r.With(s.RequirePermission("categories", Write)).Put("/{id}", s.adminUpdateCategory)
+				r.With(s.RequirePermission("categories", Execute)).Delete("/{id}", s.adminDeleteCategory)
 			})
 
 			// Brand routes
 			r.Route("/brands", func(r chi.Router) {
-				r.Get("/", s.adminGetBrands)
-				r.Get("/{id}", s.adminGetBrandByID)
-				r.Post("/", s.adminCreateBrand)
-				r.Put("/{id}", s.adminUpdateBrand)
-				r.Delete("/{id}", s.adminDeleteBrand)
+				r.With(s.RequirePermission("brands", Read)).Get("/", s.adminGetBrands)
+				r.With(s.RequirePermission("brands", Read)).Get("/{id}", s.adminGetBrandByID)
+				r.With(s.RequirePermission("brands", Write)).Post("/", s.adminCreateBrand)
+				r.With(s.RequirePermission("brands", Write)).Put("/{id}", s.adminUpdateBrand)
+				r.With(s.RequirePermission("brands", Execute)).Delete("/{id}", s.adminDeleteBrand)
 			})
 
 			// Collection routes
 			r.Route("/collections", func(r chi.Router) {
-				r.Get("/", s.getCollections)
-				r.Get("/{id}", s.adminGetCollectionByID)
-				r.Post("/", s.adminCreateCollection)
-				r.Put("/{id}", s.adminUpdateCollection)
-				r.Delete("/{id}", s.adminDeleteCollection)
+				r.With(s.RequirePermission("collections", Read)).Get("/", s.getCollections)
+				r.With(s.RequirePermission("collections", Read)).Get("/{id}", s.adminGetCollectionByID)
+				r.With(s.RequirePermission("collections", Write)).Post("/", s.adminCreateCollection)
+				r.With(s.RequirePermission("collections", Write)).Put("/{id}", s.adminUpdateCollection)
+				r.With(s.RequirePermission("collections", Execute)).Delete("/{id}", s.adminDeleteCollection)
 			})
 
 			// Rating routes
 			r.Route("/ratings", func(r chi.Router) {
-				r.Get("/", s.adminGetRatings)
-				r.Delete("/{id}", s.adminDeleteRating)
-				r.Put("/{id}/approve", s.adminApproveRating)
-				r.Put("/{id}/ban", s.adminBanUserRating)
+				r.With(s.RequirePermission("ratings", Read)).Get("/", s.adminGetRatings)
+				r.With(s.RequirePermission("ratings", Execute)).Delete("/{id}", s.adminDeleteRating)
+				r.With(s.RequirePermission("ratings", Execute)).Put("/{id}/approve", s.adminApproveRating)
+				r.With(s.RequirePermission("ratings", Execute)).Put("/{id}/ban", s.adminBanUserRating)
 			})
 
 			// Discount routes
 			r.Route("/discounts", func(r chi.Router) {
-				r.Post("/", s.adminCreateDiscount)
-				r.Get("/", s.adminGetDiscounts)
-				r.Get("/{id}", s.getDiscountByID)
-				r.Put("/{id}", s.adminUpdateDiscount)
-				r.Delete("/{id}", s.adminDeleteDiscount)
+				r.With(s.RequirePermission("discounts", Write)).Post("/", s.adminCreateDiscount)
+				r.With(s.RequirePermission("discounts", Read)).Get("/", s.adminGetDiscounts)
+				r.With(s.RequirePermission("discounts", Read)).Get("/{id}", s.getDiscountByID)
+				r.With(s.RequirePermission("discounts", Write)).Put("/{id}", s.adminUpdateDiscount)
+				r.With(s.RequirePermission("discounts", Execute)).Delete("/{id}", s.adminDeleteDiscount)
 
 				r.Route("/{id}/rules", func(r chi.Router) {
-					r.Post("/", s.adminAddDiscountRule)
-					r.Get("/", s.adminGetDiscountRules)
-					r.Get("/{ruleId}", s.adminGetDiscountRuleByID)
-					r.Put("/{ruleId}", s.adminUpdateDiscountRule)
-					r.Delete("/{ruleId}", s.adminDeleteDiscountRule)
+					r.With(s.RequirePermission("discounts", Write)).Post("/", s.adminAddDiscountRule)
+					r.With(s.RequirePermission("discounts", Read)).Get("/", s.adminGetDiscountRules)
+					r.With(s.RequirePermission("discounts", Read)).Get("/{ruleId}", s.adminGetDiscountRuleByID)
+					r.With(s.RequirePermission("discounts", Write)).Put("/{ruleId}", s.adminUpdateDiscountRule)
+					r.With(s.RequirePermission("discounts", Execute)).Delete("/{ruleId}", s.adminDeleteDiscountRule)
+				})
+			})
+
+			r.Route("/currencies", func(r chi.Router) {
+				r.With(s.RequirePermission("payments", Read)).Get("/", s.adminGetCurrencyRates)
+				r.With(s.RequirePermission("payments", Write)).Put("/{code}", s.adminUpsertCurrencyRate)
+				r.With(s.RequirePermission("payments", Execute)).Delete("/{code}", s.adminDeleteCurrencyRate)
+			})
+
+			// Shipping routes
+			r.Route("/shipping", func(r chi.Router) {
+				r.Route("/methods", func(r chi.Router) {
+					r.With(s.RequirePermission("shipping", Read)).Get("/", s.adminGetShippingMethods)
+					r.With(s.RequirePermission("shipping", Read)).Get("/{id}", s.adminGetShippingMethodByID)
+					r.With(s.RequirePermission("shipping", Write)).Post("/", s.adminCreateShippingMethod)
+					r.With(s.RequirePermission("shipping", Write)).Put("/{id}", s.adminUpdateShippingMethod)
+					r.With(s.RequirePermission("shipping", Execute)).Delete("/{id}", s.adminDeleteShippingMethod)
+				})
+				r.Route("/zones", func(r chi.Router) {
+					r.With(s.RequirePermission("shipping", Read)).Get("/", s.adminGetShippingZones)
+					r.With(s.RequirePermission("shipping", Read)).Get("/{id}", s.adminGetShippingZoneByID)
+					r.With(s.RequirePermission("shipping", Write)).Post("/", s.adminCreateShippingZone)
+					r.With(s.RequirePermission("shipping", Write)).Put("/{id}", s.adminUpdateShippingZone)
+					r.With(s.RequirePermission("shipping", Execute)).Delete("/{id}", s.adminDeleteShippingZone)
+				})
+				r.Route("/rates", func(r chi.Router) {
+					r.With(s.RequirePermission("shipping", Read)).Get("/", s.adminGetShippingRates)
+					r.With(s.RequirePermission("shipping", Read)).Get("/{id}", s.adminGetShippingRateByID)
+					r.With(s.RequirePermission("shipping", Write)).Post("/", s.adminCreateShippingRate)
+					r.With(s.RequirePermission("shipping", Write)).Put("/{id}", s.adminUpdateShippingRate)
+					r.With(s.RequirePermission("shipping", Execute)).Delete("/{id}", s.adminDeleteShippingRate)
 				})
 			})
 		})
@@ -149,12 +222,6 @@ func (s *Server) addAdminRoutes(r chi.Router) {
 // @Router /admin/users [get]
 func (s *Server) adminGetUsers(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	_, claims, err := jwtauth.FromContext(c)
-	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, errors.New("authorization payload is not provided"))
-		return
-	}
-
 	// Parse query parameters
 	queries := ParsePaginationQuery(r)
 
@@ -174,10 +241,19 @@ func (s *Server) adminGetUsers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	roles, err := s.repo.GetRoles(c)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	roleCodes := make(map[uuid.UUID]string, len(roles))
+	for _, role := range roles {
+		roleCodes[role.ID] = role.Code
+	}
+
 	userResp := make([]dto.UserDetail, 0)
-	roleCode := claims["role_code"].(string)
 	for _, user := range users {
-		userResp = append(userResp, dto.MapToUserResponse(user, roleCode))
+		userResp = append(userResp, dto.MapToUserResponse(user, roleCodes[user.RoleID]))
 	}
 
 	pagination := dto.CreatePagination(queries.Page, queries.PageSize, total)
@@ -198,12 +274,6 @@ func (s *Server) adminGetUsers(w http.ResponseWriter, r *http.Request) {
 // @Router /admin/users/{id} [get]
 func (s *Server) adminGetUser(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	_, claims, err := jwtauth.FromContext(c)
-	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, errors.New("authorization payload is not provided"))
-		return
-	}
-
 	id, err := GetUrlParam(r, "id")
 	if err != nil {
 		RespondBadRequest(w, InvalidBodyCode, err)
@@ -219,8 +289,12 @@ func (s *Server) adminGetUser(w http.ResponseWriter, r *http.Request) {
 		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
-	roleCode := claims["role_code"].(string)
-	userResp := dto.MapToUserResponse(user, roleCode)
+	role, err := s.repo.GetRoleByID(c, user.RoleID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	userResp := dto.MapToUserResponse(user, role.Code)
 	RespondSuccess(w, userResp)
 }
 
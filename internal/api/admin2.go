@@ -13,7 +13,7 @@ import (
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
 	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
 	"github.com/thanhphuocnguyen/go-eshop/internal/models"
-	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+	"github.com/thanhphuocnguyen/go-eshop/internal/orderstate"
 )
 
 // @Summary Cancel order
@@ -53,15 +53,10 @@ func (s *Server) adminCancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if order.Status == repository.OrderStatusCancelled || order.Status == repository.OrderStatusRefunded {
-		RespondBadRequest(w, InvalidPaymentCode, errors.New("order is already cancelled or refunded"))
-		return
-	}
+	actor := orderActorFromClaims(claims)
+	actor.Reason = &req.Reason
 
-	userRole := claims["role"].(string)
-	userID := uuid.MustParse(claims["userId"].(string))
-
-	if order.UserID != userID && userRole != "admin" {
+	if order.UserID != actor.ID && actor.Actor != orderstate.ActorAdmin {
 		RespondForbidden(w, PermissionDeniedCode, errors.New("you do not have permission to access this order"))
 		return
 	}
@@ -72,28 +67,26 @@ func (s *Server) adminCancelOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// if order status is not pending or user is not admin
-	if order.Status != repository.OrderStatusPending || (!errors.Is(err, repository.ErrRecordNotFound) && paymentRow.Status != repository.PaymentStatusPending) {
+	// a payment that went through has to be refunded instead, one that is only authorized is voided
+	if !errors.Is(err, repository.ErrRecordNotFound) &&
+		paymentRow.Status != repository.PaymentStatusPending &&
+		paymentRow.Status != repository.PaymentStatusAuthorized {
 		RespondBadRequest(w, PermissionDeniedCode, errors.New("order cannot be cancelled"))
 		return
 	}
 
 	// if order
 	cancelOrderTxParams := repository.CancelOrderTxArgs{
-		OrderID: uuid.MustParse(id),
+		OrderID:        uuid.MustParse(id),
+		OrderActorArgs: actor,
 		CancelPaymentFromMethod: func(paymentID string, method string) error {
-			req := payment.RefundRequest{
-				TransactionID: paymentID,
-				Amount:        paymentRow.Amount.Int.Int64(),
-			}
-			_, err = s.paymentSrv.RefundPayment(c, req, *paymentRow.Gateway)
-			return err
+			return s.paymentSrv.CancelPayment(c, paymentID, *paymentRow.Gateway)
 		},
 	}
 	ordId, err := s.repo.CancelOrderTx(c, cancelOrderTxParams)
 
 	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, err)
+		respondOrderStatusError(w, err)
 		return
 	}
 	s.cacheSrv.Delete(c, "order_detail:"+id)
@@ -101,19 +94,25 @@ func (s *Server) adminCancelOrder(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Refund order
-// @Description Refund order by order ID
+// @Description Refund what is left of the order payment and move the order to refunded
 // @Tags orders
 // @Accept json
 // @Produce json
-// @Param id path int true "Order ID"
+// @Param id path string true "Order ID"
 // @Security BearerAuth
-// @Success 200 {object} dto.ApiResponse[repository.GetOrderRow]
+// @Success 200 {object} dto.ApiResponse[repository.RefundTxResult]
 // @Failure 400 {object} dto.ErrorResp
 // @Failure 401 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
 // @Failure 500 {object} dto.ErrorResp
-// @Router /admin/order/{orderId}/refund [put]
+// @Router /admin/orders/{id}/refund [post]
 func (s *Server) adminRefundOrder(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
 	id, err := GetUrlParam(r, "id")
 	if err != nil {
 		RespondBadRequest(w, InvalidBodyCode, err)
@@ -124,40 +123,26 @@ func (s *Server) adminRefundOrder(w http.ResponseWriter, r *http.Request) {
 		RespondBadRequest(w, InvalidBodyCode, err)
 		return
 	}
-	order, err := s.repo.GetOrder(c, uuid.MustParse(id))
-	if err != nil {
-		if err == repository.ErrRecordNotFound {
-			RespondNotFound(w, NotFoundCode, fmt.Errorf("order with ID %s not found", id))
-		}
-		RespondInternalServerError(w, InternalServerErrorCode, err)
-		return
-	}
+	actor := orderActorFromClaims(claims)
+	actor.Reason = &req.Reason
 
-	if order.Status != repository.OrderStatusDelivered {
-		RespondBadRequest(w, InvalidPaymentCode, errors.New("order cannot be refunded"))
-		return
-	}
-
-	err = s.repo.RefundOrderTx(c, repository.RefundOrderTxArgs{
-		OrderID: uuid.MustParse(id),
-		RefundPaymentFromMethod: func(paymentID string, method string) (string, error) {
-			req := payment.RefundRequest{
-				TransactionID: paymentID,
-				Amount:        order.TotalPrice.Int.Int64(),
-				Reason:        req.Reason,
-			}
-			rs, err := s.paymentSrv.RefundPayment(c, req, method)
-			return rs.Reason, err
-		},
+	// refund whatever is left of the payment
+	rs, err := s.repo.RefundTx(c, repository.RefundTxArgs{
+		OrderID:         uuid.MustParse(id),
+		OrderActorArgs:  actor,
+		RefundPaymentFn: s.refundPaymentFn(req.Reason),
 	})
-
 	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, err)
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("order with ID %s not found", id))
+			return
+		}
+		respondOrderStatusError(w, err)
 		return
 	}
 	s.cacheSrv.Delete(c, "order_detail:"+id)
 
-	RespondSuccess(w, order)
+	RespondSuccess(w, rs)
 }
 
 // adminGetCategories retrieves a list of Categories.
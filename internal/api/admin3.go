@@ -174,7 +174,7 @@ func (s *Server) adminGetOrderRatings(w http.ResponseWriter, r *http.Request) {
 	}
 	userID := uuid.MustParse(claims["userId"].(string))
 
-	if orderItems[0].UserID != userID {
+	if orderItems[0].UserID != utils.GetPgTypeUUID(userID) {
 		RespondForbidden(w, PermissionDeniedCode, nil)
 		return
 	}
@@ -3,15 +3,15 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"slices"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog/log"
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
 	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
@@ -711,6 +711,7 @@ func (s *Server) adminGetOrders(w http.ResponseWriter, r *http.Request) {
 		orderResponses = append(orderResponses, dto.OrderListItem{
 			ID:            aggregated.ID,
 			Total:         total.Float64,
+			Currency:      aggregated.Currency,
 			TotalItems:    int32(aggregated.TotalItems),
 			Status:        aggregated.Status,
 			CustomerName:  aggregated.CustomerName,
@@ -757,61 +758,44 @@ func (s *Server) adminGetOrderDetail(w http.ResponseWriter, r *http.Request) {
 // @Router /admin/orders/{orderId}/status [put]
 func (s *Server) adminChangeOrderStatus(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	id, err := GetUrlParam(r, "id")
-	var req models.OrderStatusModel
-	if err := s.GetRequestBody(r, &req); err != nil {
-		RespondBadRequest(w, InvalidBodyCode, err)
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
 		return
 	}
-	order, err := s.repo.GetOrder(c, uuid.MustParse(id))
+	id, err := GetUrlParam(r, "id")
 	if err != nil {
-		if errors.Is(err, repository.ErrRecordNotFound) {
-			RespondNotFound(w, NotFoundCode, err)
-			return
-		}
-		RespondInternalServerError(w, InternalServerErrorCode, err)
+		RespondBadRequest(w, InvalidBodyCode, err)
 		return
 	}
-	if order.Status == repository.OrderStatusDelivered || order.Status == repository.OrderStatusCancelled || order.Status == repository.OrderStatusRefunded {
-		RespondBadRequest(w, InvalidPaymentCode, errors.New("order cannot be changed"))
+	var req models.OrderStatusModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
 		return
 	}
 
-	status := repository.OrderStatus(req.Status)
-
-	updateParams := repository.UpdateOrderParams{
-		ID: order.ID,
-		Status: repository.NullOrderStatus{
-			OrderStatus: status,
-			Valid:       true,
-		},
-	}
-	if status == repository.OrderStatusConfirmed {
-		updateParams.ConfirmedAt = pgtype.Timestamptz{
-			Time:  time.Now(),
-			Valid: true,
-		}
+	actor := orderActorFromClaims(claims)
+	if req.Reason != "" {
+		actor.Reason = &req.Reason
 	}
-	if status == repository.OrderStatusDelivering {
-		updateParams.DeliveredAt = pgtype.Timestamptz{
-			Time:  time.Now(),
-			Valid: true,
-		}
-	}
-
-	rs, err := s.repo.UpdateOrder(c, updateParams)
-
+	err = s.repo.ChangeOrderStatusTx(c, repository.ChangeOrderStatusTxArgs{
+		OrderID:        uuid.MustParse(id),
+		Status:         repository.OrderStatus(req.Status),
+		OrderActorArgs: actor,
+	})
 	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, err)
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("order with ID %s not found", id))
+			return
+		}
+		respondOrderStatusError(w, err)
 		return
 	}
 
 	if err := s.cacheSrv.Delete(c, "order_detail:"+id); err != nil {
 		log.Err(err).Msg("failed to delete order detail cache")
-		RespondInternalServerError(w, InternalServerErrorCode, err)
-		return
 	}
-	s.cacheSrv.Delete(c, "order_detail:"+id)
+	s.captureShippedOrderPayment(c, repository.OrderStatus(req.Status), uuid.MustParse(id))
 
-	RespondSuccess(w, rs)
+	RespondSuccess(w, uuid.MustParse(id))
 }
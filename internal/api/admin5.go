@@ -0,0 +1,1618 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
+	"github.com/thanhphuocnguyen/go-eshop/internal/models"
+	"github.com/thanhphuocnguyen/go-eshop/internal/processors"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/internal/worker"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+// defaultAuthorizationExpiryWindow is how far ahead the expiring authorizations report looks
+const defaultAuthorizationExpiryWindow = 48 * time.Hour
+
+// @Summary Create a shipping method
+// @Description Create a shipping method
+// @Tags admin
+// @ID create-shipping-method
+// @Accept json
+// @Produce json
+// @Param request body models.CreateShippingMethodModel true "Shipping method request"
+// @Success 201 {object} dto.ApiResponse[repository.ShippingMethod]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/methods [post]
+func (s *Server) adminCreateShippingMethod(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	var req models.CreateShippingMethodModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	params := repository.CreateShippingMethodParams{
+		Name:                  req.Name,
+		Description:           req.Description,
+		IsActive:              true,
+		RequiresAddress:       true,
+		EstimatedDeliveryTime: req.EstimatedDeliveryTime,
+		IconUrl:               req.IconUrl,
+	}
+	if req.IsActive != nil {
+		params.IsActive = *req.IsActive
+	}
+	if req.RequiresAddress != nil {
+		params.RequiresAddress = *req.RequiresAddress
+	}
+
+	method, err := s.repo.CreateShippingMethod(c, params)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondCreated(w, method)
+}
+
+// @Summary Get a list of shipping methods
+// @Description Get a list of shipping methods, including inactive ones
+// @Tags admin
+// @ID get-shipping-methods
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Success 200 {object} dto.ApiResponse[[]repository.ShippingMethod]
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/methods [get]
+func (s *Server) adminGetShippingMethods(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	queries := ParsePaginationQuery(r)
+
+	rows, err := s.repo.ListShippingMethods(c, repository.ListShippingMethodsParams{
+		Limit:  queries.PageSize,
+		Offset: (queries.Page - 1) * queries.PageSize,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	cnt, err := s.repo.CountShippingMethods(c)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccessWithPagination(w, rows, dto.CreatePagination(queries.Page, queries.PageSize, cnt))
+}
+
+// @Summary Get a shipping method by ID
+// @Description Get a shipping method by ID
+// @Tags admin
+// @ID get-shipping-method-by-id
+// @Accept json
+// @Produce json
+// @Param id path string true "Shipping method ID"
+// @Success 200 {object} dto.ApiResponse[repository.ShippingMethod]
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/methods/{id} [get]
+func (s *Server) adminGetShippingMethodByID(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	method, err := s.repo.GetShippingMethodByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping method with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, method)
+}
+
+// @Summary Update a shipping method
+// @Description Update a shipping method
+// @Tags admin
+// @ID update-shipping-method
+// @Accept json
+// @Produce json
+// @Param id path string true "Shipping method ID"
+// @Param request body models.UpdateShippingMethodModel true "Shipping method request"
+// @Success 200 {object} dto.ApiResponse[repository.ShippingMethod]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/methods/{id} [put]
+func (s *Server) adminUpdateShippingMethod(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.UpdateShippingMethodModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	method, err := s.repo.GetShippingMethodByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping method with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	updated, err := s.repo.UpdateShippingMethod(c, repository.UpdateShippingMethodParams{
+		ID:                    method.ID,
+		Name:                  req.Name,
+		Description:           req.Description,
+		IsActive:              req.IsActive,
+		RequiresAddress:       req.RequiresAddress,
+		EstimatedDeliveryTime: req.EstimatedDeliveryTime,
+		IconUrl:               req.IconUrl,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, updated)
+}
+
+// @Summary Delete a shipping method
+// @Description Delete a shipping method and its rates
+// @Tags admin
+// @ID delete-shipping-method
+// @Accept json
+// @Produce json
+// @Param id path string true "Shipping method ID"
+// @Success 204 {object} nil
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/methods/{id} [delete]
+func (s *Server) adminDeleteShippingMethod(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	_, err = s.repo.GetShippingMethodByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping method with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	if err := s.repo.DeleteShippingMethod(c, uuid.MustParse(id)); err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondNoContent(w)
+}
+
+// @Summary Create a shipping zone
+// @Description Create a shipping zone. Countries are ISO 3166-1 alpha-2 codes.
+// @Tags admin
+// @ID create-shipping-zone
+// @Accept json
+// @Produce json
+// @Param request body models.CreateShippingZoneModel true "Shipping zone request"
+// @Success 201 {object} dto.ApiResponse[repository.ShippingZone]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/zones [post]
+func (s *Server) adminCreateShippingZone(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	var req models.CreateShippingZoneModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	params := repository.CreateShippingZoneParams{
+		Name:        req.Name,
+		Description: req.Description,
+		Countries:   req.Countries,
+		States:      req.States,
+		ZipCodes:    req.ZipCodes,
+		IsActive:    true,
+	}
+	if req.IsActive != nil {
+		params.IsActive = *req.IsActive
+	}
+
+	zone, err := s.repo.CreateShippingZone(c, params)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondCreated(w, zone)
+}
+
+// @Summary Get a list of shipping zones
+// @Description Get a list of shipping zones, including inactive ones
+// @Tags admin
+// @ID get-shipping-zones
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Success 200 {object} dto.ApiResponse[[]repository.ShippingZone]
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/zones [get]
+func (s *Server) adminGetShippingZones(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	queries := ParsePaginationQuery(r)
+
+	rows, err := s.repo.ListShippingZones(c, repository.ListShippingZonesParams{
+		Limit:  queries.PageSize,
+		Offset: (queries.Page - 1) * queries.PageSize,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	cnt, err := s.repo.CountShippingZones(c)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccessWithPagination(w, rows, dto.CreatePagination(queries.Page, queries.PageSize, cnt))
+}
+
+// @Summary Get a shipping zone by ID
+// @Description Get a shipping zone by ID
+// @Tags admin
+// @ID get-shipping-zone-by-id
+// @Accept json
+// @Produce json
+// @Param id path string true "Shipping zone ID"
+// @Success 200 {object} dto.ApiResponse[repository.ShippingZone]
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/zones/{id} [get]
+func (s *Server) adminGetShippingZoneByID(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	zone, err := s.repo.GetShippingZoneByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping zone with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, zone)
+}
+
+// @Summary Update a shipping zone
+// @Description Update a shipping zone
+// @Tags admin
+// @ID update-shipping-zone
+// @Accept json
+// @Produce json
+// @Param id path string true "Shipping zone ID"
+// @Param request body models.UpdateShippingZoneModel true "Shipping zone request"
+// @Success 200 {object} dto.ApiResponse[repository.ShippingZone]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/zones/{id} [put]
+func (s *Server) adminUpdateShippingZone(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.UpdateShippingZoneModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	zone, err := s.repo.GetShippingZoneByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping zone with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	updated, err := s.repo.UpdateShippingZone(c, repository.UpdateShippingZoneParams{
+		ID:          zone.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Countries:   req.Countries,
+		States:      req.States,
+		ZipCodes:    req.ZipCodes,
+		IsActive:    req.IsActive,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, updated)
+}
+
+// @Summary Delete a shipping zone
+// @Description Delete a shipping zone and its rates
+// @Tags admin
+// @ID delete-shipping-zone
+// @Accept json
+// @Produce json
+// @Param id path string true "Shipping zone ID"
+// @Success 204 {object} nil
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/zones/{id} [delete]
+func (s *Server) adminDeleteShippingZone(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	_, err = s.repo.GetShippingZoneByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping zone with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	if err := s.repo.DeleteShippingZone(c, uuid.MustParse(id)); err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondNoContent(w)
+}
+
+// @Summary Create a shipping rate
+// @Description Create a shipping rate for a method and zone. Order amount ranges of rates sharing a method and zone must not overlap.
+// @Tags admin
+// @ID create-shipping-rate
+// @Accept json
+// @Produce json
+// @Param request body models.CreateShippingRateModel true "Shipping rate request"
+// @Success 201 {object} dto.ApiResponse[repository.ShippingRate]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/rates [post]
+func (s *Server) adminCreateShippingRate(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	var req models.CreateShippingRateModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	method, err := s.repo.GetShippingMethodByID(c, uuid.MustParse(req.ShippingMethodID))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping method with ID %s not found", req.ShippingMethodID))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	zone, err := s.repo.GetShippingZoneByID(c, uuid.MustParse(req.ShippingZoneID))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping zone with ID %s not found", req.ShippingZoneID))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	params := repository.CreateShippingRateParams{
+		ShippingMethodID: method.ID,
+		ShippingZoneID:   zone.ID,
+		Name:             req.Name,
+		BaseRate:         utils.GetPgNumericFromFloat(req.BaseRate),
+		IsActive:         true,
+	}
+	if req.MinOrderAmount != nil {
+		params.MinOrderAmount = utils.GetPgNumericFromMoney(payment.MoneyFromFloat(*req.MinOrderAmount, s.currencyProcessor.StoreCurrency(), payment.RoundHalfUp))
+	}
+	if req.MaxOrderAmount != nil {
+		params.MaxOrderAmount = utils.GetPgNumericFromMoney(payment.MoneyFromFloat(*req.MaxOrderAmount, s.currencyProcessor.StoreCurrency(), payment.RoundHalfUp))
+	}
+	if req.FreeShippingThreshold != nil {
+		params.FreeShippingThreshold = utils.GetPgNumericFromFloat(*req.FreeShippingThreshold)
+	}
+	if req.IsActive != nil {
+		params.IsActive = *req.IsActive
+	}
+
+	if err := s.validateShippingRateRange(r, method.ID, zone.ID, uuid.Nil, params.MinOrderAmount, params.MaxOrderAmount); err != nil {
+		if errors.Is(err, errShippingRateRange) {
+			RespondBadRequest(w, InvalidShippingCode, err)
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	rate, err := s.repo.CreateShippingRate(c, params)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondCreated(w, rate)
+}
+
+// @Summary Get a list of shipping rates
+// @Description Get a list of shipping rates, including inactive ones
+// @Tags admin
+// @ID get-shipping-rates
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Success 200 {object} dto.ApiResponse[[]repository.ListShippingRatesRow]
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/rates [get]
+func (s *Server) adminGetShippingRates(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	queries := ParsePaginationQuery(r)
+
+	rows, err := s.repo.ListShippingRates(c, repository.ListShippingRatesParams{
+		Limit:  queries.PageSize,
+		Offset: (queries.Page - 1) * queries.PageSize,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	cnt, err := s.repo.CountShippingRates(c)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccessWithPagination(w, rows, dto.CreatePagination(queries.Page, queries.PageSize, cnt))
+}
+
+// @Summary Get a shipping rate by ID
+// @Description Get a shipping rate by ID
+// @Tags admin
+// @ID get-shipping-rate-by-id
+// @Accept json
+// @Produce json
+// @Param id path string true "Shipping rate ID"
+// @Success 200 {object} dto.ApiResponse[repository.GetShippingRateByIDRow]
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/rates/{id} [get]
+func (s *Server) adminGetShippingRateByID(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	rate, err := s.repo.GetShippingRateByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping rate with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, rate)
+}
+
+// @Summary Update a shipping rate
+// @Description Update a shipping rate
+// @Tags admin
+// @ID update-shipping-rate
+// @Accept json
+// @Produce json
+// @Param id path string true "Shipping rate ID"
+// @Param request body models.UpdateShippingRateModel true "Shipping rate request"
+// @Success 200 {object} dto.ApiResponse[repository.ShippingRate]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/rates/{id} [put]
+func (s *Server) adminUpdateShippingRate(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.UpdateShippingRateModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	rate, err := s.repo.GetShippingRateByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping rate with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	params := repository.UpdateShippingRateParams{
+		ID:       rate.ID,
+		Name:     req.Name,
+		IsActive: req.IsActive,
+	}
+	if req.BaseRate != nil {
+		params.BaseRate = utils.GetPgNumericFromFloat(*req.BaseRate)
+	}
+	if req.FreeShippingThreshold != nil {
+		params.FreeShippingThreshold = utils.GetPgNumericFromFloat(*req.FreeShippingThreshold)
+	}
+
+	minAmount, maxAmount := rate.MinOrderAmount, rate.MaxOrderAmount
+	if req.MinOrderAmount != nil {
+		params.MinOrderAmount = utils.GetPgNumericFromMoney(payment.MoneyFromFloat(*req.MinOrderAmount, s.currencyProcessor.StoreCurrency(), payment.RoundHalfUp))
+		minAmount = params.MinOrderAmount
+	}
+	if req.MaxOrderAmount != nil {
+		params.MaxOrderAmount = utils.GetPgNumericFromMoney(payment.MoneyFromFloat(*req.MaxOrderAmount, s.currencyProcessor.StoreCurrency(), payment.RoundHalfUp))
+		maxAmount = params.MaxOrderAmount
+	}
+
+	if err := s.validateShippingRateRange(r, rate.ShippingMethodID, rate.ShippingZoneID, rate.ID, minAmount, maxAmount); err != nil {
+		if errors.Is(err, errShippingRateRange) {
+			RespondBadRequest(w, InvalidShippingCode, err)
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	updated, err := s.repo.UpdateShippingRate(c, params)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, updated)
+}
+
+// @Summary Delete a shipping rate
+// @Description Delete a shipping rate
+// @Tags admin
+// @ID delete-shipping-rate
+// @Accept json
+// @Produce json
+// @Param id path string true "Shipping rate ID"
+// @Success 204 {object} nil
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/shipping/rates/{id} [delete]
+func (s *Server) adminDeleteShippingRate(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	_, err = s.repo.GetShippingRateByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipping rate with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	if err := s.repo.DeleteShippingRate(c, uuid.MustParse(id)); err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondNoContent(w)
+}
+
+var errShippingRateRange = errors.New("invalid shipping rate order amount range")
+
+// validateShippingRateRange makes sure the order amount range is well formed and does not
+// overlap another rate of the same method and zone. Ranges are half-open, [min, max), so a
+// tier can end where the next one starts. Missing bounds are open.
+func (s *Server) validateShippingRateRange(r *http.Request, methodID, zoneID, excludeID uuid.UUID, minAmount, maxAmount pgtype.Numeric) error {
+	lower, upper, err := rangeBounds(minAmount, maxAmount)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errShippingRateRange, err)
+	}
+	if upper != nil && lower.Cmp(upper) >= 0 {
+		return fmt.Errorf("%w: max order amount must be greater than min order amount", errShippingRateRange)
+	}
+
+	rates, err := s.repo.GetShippingRatesByMethodAndZone(r.Context(), repository.GetShippingRatesByMethodAndZoneParams{
+		ShippingMethodID: methodID,
+		ShippingZoneID:   zoneID,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, rate := range rates {
+		if rate.ID == excludeID {
+			continue
+		}
+		otherLower, otherUpper, err := rangeBounds(rate.MinOrderAmount, rate.MaxOrderAmount)
+		if err != nil {
+			return fmt.Errorf("shipping rate %s: %w", rate.ID, err)
+		}
+		if (otherUpper == nil || lower.Cmp(otherUpper) < 0) && (upper == nil || otherLower.Cmp(upper) < 0) {
+			return fmt.Errorf("%w: overlaps with rate %s (%s)", errShippingRateRange, rate.Name, rate.ID)
+		}
+	}
+	return nil
+}
+
+// rangeBounds reads the exact bounds of an order amount range, a missing min is 0 and a
+// missing max is returned as nil for no upper bound
+func rangeBounds(minAmount, maxAmount pgtype.Numeric) (lower *big.Rat, upper *big.Rat, err error) {
+	lower = new(big.Rat)
+	if minAmount.Valid {
+		value, ok := utils.GetRatFromPgNumeric(minAmount)
+		if !ok {
+			return nil, nil, errors.New("min order amount is not a number")
+		}
+		lower = value
+	}
+	if maxAmount.Valid {
+		value, ok := utils.GetRatFromPgNumeric(maxAmount)
+		if !ok {
+			return nil, nil, errors.New("max order amount is not a number")
+		}
+		upper = value
+	}
+	return lower, upper, nil
+}
+
+// @Summary Create a shipment
+// @Description Create a shipment for a subset of the order items. Quantities can't exceed what is left to ship.
+// @Tags admin
+// @ID create-shipment
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param request body models.CreateShipmentModel true "Shipment request"
+// @Success 201 {object} dto.ApiResponse[repository.Shipment]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/orders/{id}/shipments [post]
+func (s *Server) adminCreateShipment(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.CreateShipmentModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	items := make([]repository.ShipmentItemTxArgs, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, repository.ShipmentItemTxArgs{
+			OrderItemID: uuid.MustParse(item.OrderItemID),
+			Quantity:    item.Quantity,
+		})
+	}
+
+	shipment, err := s.repo.CreateShipmentTx(c, repository.CreateShipmentTxArgs{
+		OrderID:          uuid.MustParse(id),
+		Items:            items,
+		TrackingNumber:   req.TrackingNumber,
+		TrackingUrl:      req.TrackingUrl,
+		ShippingProvider: req.ShippingProvider,
+		ShippingNotes:    req.ShippingNotes,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("order with ID %s not found", id))
+			return
+		}
+		if errors.Is(err, repository.ErrInvalidShipment) {
+			RespondBadRequest(w, InvalidShipmentCode, err)
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	s.cacheSrv.Delete(c, "order_detail:"+id)
+	RespondCreated(w, shipment)
+}
+
+// @Summary Get order shipments
+// @Description Get the shipments of an order with their items
+// @Tags admin
+// @ID get-order-shipments
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} dto.ApiResponse[[]dto.Shipment]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/orders/{id}/shipments [get]
+func (s *Server) adminGetOrderShipments(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	shipments, err := s.getOrderShipments(c, uuid.MustParse(id))
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, shipments)
+}
+
+// @Summary Update a shipment
+// @Description Update tracking details of a shipment or mark it as shipped or delivered.
+// @Description The order moves to delivering once every item is shipped and to delivered once every item is delivered.
+// @Tags admin
+// @ID update-shipment
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param shipmentId path string true "Shipment ID"
+// @Param request body models.UpdateShipmentModel true "Shipment request"
+// @Success 200 {object} dto.ApiResponse[repository.UpdateShipmentTxResult]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/orders/{id}/shipments/{shipmentId} [put]
+func (s *Server) adminUpdateShipment(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	shipmentID, err := GetUrlParam(r, "shipmentId")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.UpdateShipmentModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	shipment, err := s.repo.GetShipmentByID(c, uuid.MustParse(shipmentID))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("shipment with ID %s not found", shipmentID))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if shipment.OrderID.String() != id {
+		RespondNotFound(w, NotFoundCode, fmt.Errorf("shipment with ID %s not found", shipmentID))
+		return
+	}
+
+	actor := orderActorFromClaims(claims)
+	reason := fmt.Sprintf("shipment %s updated", shipment.ID)
+	actor.Reason = &reason
+
+	result, err := s.repo.UpdateShipmentTx(c, repository.UpdateShipmentTxArgs{
+		ShipmentID:       shipment.ID,
+		Actor:            actor,
+		Status:           req.Status,
+		TrackingNumber:   req.TrackingNumber,
+		TrackingUrl:      req.TrackingUrl,
+		ShippingProvider: req.ShippingProvider,
+		ShippingNotes:    req.ShippingNotes,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidShipment) {
+			RespondBadRequest(w, InvalidShipmentCode, err)
+			return
+		}
+		respondOrderStatusError(w, err)
+		return
+	}
+
+	s.cacheSrv.Delete(c, "order_detail:"+id)
+	s.captureShippedOrderPayment(c, result.OrderStatus, shipment.OrderID)
+	RespondSuccess(w, result)
+}
+
+// @Summary Refund an order
+// @Description Refund order items, an arbitrary amount or, when both are left out, what is left of the payment.
+// @Description Refunded items can be put back in stock. The order moves to refunded once the whole payment is returned.
+// @Tags admin
+// @ID create-refund
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param request body models.CreateRefundModel true "Refund request"
+// @Success 201 {object} dto.ApiResponse[repository.RefundTxResult]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/orders/{id}/refunds [post]
+func (s *Server) adminCreateRefund(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.CreateRefundModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	if len(req.Items) > 0 && req.Amount != nil {
+		RespondBadRequest(w, InvalidBodyCode, errors.New("refund either items or an amount, not both"))
+		return
+	}
+	if req.Restock && len(req.Items) == 0 {
+		RespondBadRequest(w, InvalidBodyCode, errors.New("only refunded items can be restocked"))
+		return
+	}
+
+	items := make([]repository.RefundItemTxArgs, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, repository.RefundItemTxArgs{
+			OrderItemID: uuid.MustParse(item.OrderItemID),
+			Quantity:    item.Quantity,
+		})
+	}
+
+	actor := orderActorFromClaims(claims)
+	actor.Reason = &req.Reason
+
+	rs, err := s.repo.RefundTx(c, repository.RefundTxArgs{
+		OrderID:         uuid.MustParse(id),
+		Items:           items,
+		Amount:          req.Amount,
+		Restock:         req.Restock,
+		OrderActorArgs:  actor,
+		RefundPaymentFn: s.refundPaymentFn(req.Reason),
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("order with ID %s not found", id))
+			return
+		}
+		respondOrderStatusError(w, err)
+		return
+	}
+
+	s.cacheSrv.Delete(c, "order_detail:"+id)
+	RespondCreated(w, rs)
+}
+
+// @Summary Get order refunds
+// @Description Get the refunds of an order with their items
+// @Tags admin
+// @ID get-order-refunds
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Success 200 {object} dto.ApiResponse[[]dto.Refund]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/orders/{id}/refunds [get]
+func (s *Server) adminGetOrderRefunds(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	refunds, err := s.getOrderRefunds(c, uuid.MustParse(id))
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, refunds)
+}
+
+// refundPaymentFn returns money through the gateway the payment was made with
+func (s *Server) refundPaymentFn(reason string) repository.RefundPaymentFn {
+	return func(ctx context.Context, paymentIntentID string, method string, amount payment.Money, idempotencyKey string) (string, bool, error) {
+		rs, err := s.paymentSrv.RefundPayment(ctx, payment.RefundRequest{
+			TransactionID:  paymentIntentID,
+			Amount:         amount.Amount,
+			Reason:         reason,
+			IdempotencyKey: idempotencyKey,
+		}, method)
+		if err != nil {
+			return "", false, err
+		}
+		if rs.Status == payment.StatusFailed || rs.Status == payment.StatusCancelled {
+			return "", false, fmt.Errorf("refund %s was %s by the gateway", rs.RefundID, rs.Status)
+		}
+		return rs.RefundID, rs.Status == payment.StatusCompleted, nil
+	}
+}
+
+// @Summary Get return requests
+// @Description Get return requests of all customers, optionally filtered by status
+// @Tags admin
+// @ID get-return-requests
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Param status query string false "Filter by status"
+// @Success 200 {object} dto.ApiResponse[[]repository.ReturnRequest]
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/returns [get]
+func (s *Server) adminGetReturnRequests(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	query := ParsePaginationQuery(r)
+	params := repository.GetReturnRequestsParams{
+		Limit:  query.PageSize,
+		Offset: (query.Page - 1) * query.PageSize,
+	}
+	countParams := repository.CountReturnRequestsParams{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		params.Status = &status
+		countParams.Status = &status
+	}
+
+	rows, err := s.repo.GetReturnRequests(c, params)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	count, err := s.repo.CountReturnRequests(c, countParams)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccessWithPagination(w, rows, dto.CreatePagination(query.Page, query.PageSize, count))
+}
+
+// @Summary Approve a return request
+// @Description Approve a return request, which refunds the returned items against the original payment.
+// @Description Returned items are put back in stock when restock is set.
+// @Tags admin
+// @ID approve-return-request
+// @Accept json
+// @Produce json
+// @Param id path string true "Return request ID"
+// @Param request body models.ApproveReturnRequestModel true "Approve request"
+// @Success 200 {object} dto.ApiResponse[repository.ReviewReturnRequestTxResult]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/returns/{id}/approve [post]
+func (s *Server) adminApproveReturnRequest(w http.ResponseWriter, r *http.Request) {
+	var req models.ApproveReturnRequestModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	s.reviewReturnRequest(w, r, repository.ReviewReturnRequestTxArgs{
+		Approve: true,
+		Note:    req.Note,
+		Restock: req.Restock,
+	})
+}
+
+// @Summary Reject a return request
+// @Description Reject a return request with a note for the customer
+// @Tags admin
+// @ID reject-return-request
+// @Accept json
+// @Produce json
+// @Param id path string true "Return request ID"
+// @Param request body models.RejectReturnRequestModel true "Reject request"
+// @Success 200 {object} dto.ApiResponse[repository.ReviewReturnRequestTxResult]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/returns/{id}/reject [post]
+func (s *Server) adminRejectReturnRequest(w http.ResponseWriter, r *http.Request) {
+	var req models.RejectReturnRequestModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	s.reviewReturnRequest(w, r, repository.ReviewReturnRequestTxArgs{
+		Note: &req.Note,
+	})
+}
+
+// reviewReturnRequest applies an admin decision on a return request and emails the customer.
+func (s *Server) reviewReturnRequest(w http.ResponseWriter, r *http.Request, args repository.ReviewReturnRequestTxArgs) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	args.ID = uuid.MustParse(id)
+	args.OrderActorArgs = orderActorFromClaims(claims)
+	if args.Approve {
+		reason := fmt.Sprintf("return request %s approved", id)
+		args.OrderActorArgs.Reason = &reason
+		args.RefundPaymentFn = s.refundPaymentFn("requested_by_customer")
+	}
+
+	rs, err := s.repo.ReviewReturnRequestTx(c, args)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("return request with ID %s not found", id))
+			return
+		}
+		if errors.Is(err, repository.ErrInvalidReturn) {
+			RespondBadRequest(w, InvalidReturnCode, err)
+			return
+		}
+		respondOrderStatusError(w, err)
+		return
+	}
+
+	s.cacheSrv.Delete(c, "order_detail:"+rs.ReturnRequest.OrderID.String())
+	s.sendReturnRequestStatusEmail(c, rs.ReturnRequest.ID)
+	RespondSuccess(w, rs)
+}
+
+// @Summary Record an offline payment
+// @Description Record money received for a cash on delivery or bank transfer order. Leaving the amount out settles
+// @Description the outstanding balance, a smaller amount leaves the payment processing. A bank transfer order is
+// @Description confirmed once it is paid in full.
+// @Tags admin
+// @ID record-offline-payment
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param request body models.RecordOfflinePaymentModel true "Received payment"
+// @Success 200 {object} dto.ApiResponse[repository.RecordOfflinePaymentTxResult]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/orders/{id}/payment/received [post]
+func (s *Server) adminRecordOfflinePayment(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.RecordOfflinePaymentModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	actor := orderActorFromClaims(claims)
+	actor.Reason = req.Note
+	rs, err := s.repo.RecordOfflinePaymentTx(c, repository.RecordOfflinePaymentTxArgs{
+		OrderID:        uuid.MustParse(id),
+		Amount:         req.Amount,
+		Reference:      req.Reference,
+		OrderActorArgs: actor,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("payment of order with ID %s not found", id))
+			return
+		}
+		respondOrderStatusError(w, err)
+		return
+	}
+
+	s.cacheSrv.Delete(c, "order_detail:"+id)
+	RespondSuccess(w, rs)
+}
+
+// @Summary Expire an offline payment
+// @Description Give up on a cash on delivery or bank transfer payment that never arrived. The order is cancelled
+// @Description and its stock put back on sale.
+// @Tags admin
+// @ID expire-offline-payment
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param request body models.ExpireOfflinePaymentModel true "Expire request"
+// @Success 200 {object} dto.ApiResponse[repository.Payment]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/orders/{id}/payment/expired [post]
+func (s *Server) adminExpireOfflinePayment(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.ExpireOfflinePaymentModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	actor := orderActorFromClaims(claims)
+	actor.Reason = req.Reason
+	rs, err := s.repo.ExpireOfflinePaymentTx(c, repository.ExpireOfflinePaymentTxArgs{
+		OrderID:        uuid.MustParse(id),
+		OrderActorArgs: actor,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("payment of order with ID %s not found", id))
+			return
+		}
+		respondOrderStatusError(w, err)
+		return
+	}
+
+	s.cacheSrv.Delete(c, "order_detail:"+id)
+	RespondSuccess(w, rs)
+}
+
+// @Summary Capture an authorized payment
+// @Description Take the money held for an order. Leaving the amount out captures everything authorized, a smaller
+// @Description amount releases the rest. Orders are captured on their own when they ship.
+// @Tags admin
+// @ID capture-payment
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param request body models.CapturePaymentModel true "Capture request"
+// @Success 200 {object} dto.ApiResponse[repository.Payment]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/orders/{id}/payment/capture [post]
+func (s *Server) adminCapturePayment(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.CapturePaymentModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	rs, err := s.repo.CapturePaymentTx(c, repository.CapturePaymentTxArgs{
+		OrderID:          uuid.MustParse(id),
+		Amount:           req.Amount,
+		CapturePaymentFn: s.capturePaymentFn,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("payment of order with ID %s not found", id))
+			return
+		}
+		respondOrderStatusError(w, err)
+		return
+	}
+
+	s.cacheSrv.Delete(c, "order_detail:"+id)
+	RespondSuccess(w, rs)
+}
+
+// @Summary Void an authorized payment
+// @Description Release the money held for an order without taking any of it. An order still pending is cancelled
+// @Description and its stock put back on sale.
+// @Tags admin
+// @ID void-payment
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param request body models.VoidPaymentModel true "Void request"
+// @Success 200 {object} dto.ApiResponse[repository.Payment]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/orders/{id}/payment/void [post]
+func (s *Server) adminVoidPayment(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.VoidPaymentModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	actor := orderActorFromClaims(claims)
+	actor.Reason = req.Reason
+	rs, err := s.repo.VoidPaymentTx(c, repository.VoidPaymentTxArgs{
+		OrderID: uuid.MustParse(id),
+		VoidPaymentFn: func(ctx context.Context, paymentIntentID, method string) error {
+			return s.paymentSrv.VoidPayment(ctx, paymentIntentID, method)
+		},
+		OrderActorArgs: actor,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("payment of order with ID %s not found", id))
+			return
+		}
+		respondOrderStatusError(w, err)
+		return
+	}
+
+	s.cacheSrv.Delete(c, "order_detail:"+id)
+	RespondSuccess(w, rs)
+}
+
+// @Summary Get expiring authorizations
+// @Description Get the authorized payments the gateways release within a window unless they're captured first,
+// @Description the ones expiring first first. Already expired ones are included.
+// @Tags admin
+// @ID get-expiring-authorizations
+// @Accept json
+// @Produce json
+// @Param within query string false "Window as a duration, 48h by default"
+// @Success 200 {object} dto.ApiResponse[[]dto.ExpiringAuthorization]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/payments/authorizations/expiring [get]
+func (s *Server) adminGetExpiringAuthorizations(w http.ResponseWriter, r *http.Request) {
+	within := defaultAuthorizationExpiryWindow
+	if q := r.URL.Query().Get("within"); q != "" {
+		d, err := time.ParseDuration(q)
+		if err != nil || d <= 0 {
+			RespondBadRequest(w, InvalidBodyCode, fmt.Errorf("invalid window %q", q))
+			return
+		}
+		within = d
+	}
+
+	rows, err := s.repo.ListExpiringPaymentAuthorizations(r.Context(), time.Now().Add(within))
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	resp := make([]dto.ExpiringAuthorization, len(rows))
+	for i, row := range rows {
+		resp[i] = dto.MapExpiringAuthorization(row)
+	}
+	RespondSuccess(w, resp)
+}
+
+// @Summary Get payment reconciliations
+// @Description Get the runs of the job checking payments that are still waiting on a webhook against the gateways,
+// @Description the latest first
+// @Tags admin
+// @ID get-payment-reconciliations
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Success 200 {object} dto.ApiResponse[[]repository.PaymentReconciliation]
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/payments/reconciliations [get]
+func (s *Server) adminGetPaymentReconciliations(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	query := ParsePaginationQuery(r)
+	rows, err := s.repo.GetPaymentReconciliations(c, repository.GetPaymentReconciliationsParams{
+		Limit:  query.PageSize,
+		Offset: (query.Page - 1) * query.PageSize,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	count, err := s.repo.CountPaymentReconciliations(c)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccessWithPagination(w, rows, dto.CreatePagination(query.Page, query.PageSize, count))
+}
+
+// @Summary Download a payment reconciliation report
+// @Description Download what a reconciliation run found for each payment it checked as CSV
+// @Tags admin
+// @ID download-payment-reconciliation
+// @Produce text/csv
+// @Param id path string true "Reconciliation ID"
+// @Success 200 {file} file
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/payments/reconciliations/{id}/report [get]
+func (s *Server) adminDownloadPaymentReconciliation(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	run, err := s.repo.GetPaymentReconciliation(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("payment reconciliation with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	items, err := s.repo.GetPaymentReconciliationItems(c, run.ID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf("attachment; filename=payment-reconciliation-%s.csv", run.StartedAt.Format("20060102-150405")))
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"checked_at", "payment_id", "order_id", "gateway", "payment_intent_id", "local_status", "gateway_status", "action", "message"})
+	for _, item := range items {
+		writer.Write([]string{
+			item.CreatedAt.Format(time.RFC3339),
+			item.PaymentID.String(),
+			item.OrderID.String(),
+			item.Gateway,
+			utils.StringValue(item.PaymentIntentID),
+			string(item.LocalStatus),
+			utils.StringValue(item.GatewayStatus),
+			item.Action,
+			utils.StringValue(item.Message),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		log.Error().Err(err).Str("reconciliationID", id).Msg("write reconciliation report")
+	}
+}
+
+// capturePaymentFn captures an authorization at the gateway it was made with
+func (s *Server) capturePaymentFn(ctx context.Context, paymentIntentID, method string, amount payment.Money) (string, error) {
+	rs, err := s.paymentSrv.CapturePayment(ctx, payment.CaptureRequest{
+		TransactionID: paymentIntentID,
+		Amount:        amount.Amount,
+	}, method)
+	if err != nil {
+		return "", err
+	}
+	if !rs.Success {
+		return "", fmt.Errorf("capture was %s by the gateway", rs.Status)
+	}
+	return rs.Metadata["charge_id"], nil
+}
+
+// captureShippedOrderPayment takes the money held for an order once it ships. Orders that
+// weren't paid by authorization are skipped by the worker.
+func (s *Server) captureShippedOrderPayment(c context.Context, status repository.OrderStatus, orderID uuid.UUID) {
+	if status != repository.OrderStatusDelivering && status != repository.OrderStatusDelivered {
+		return
+	}
+	err := s.taskDistributor.SendCaptureAuthorizedPayment(c,
+		&worker.PayloadCaptureAuthorizedPayment{OrderID: orderID},
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical))
+	if err != nil {
+		log.Error().Err(err).Str("orderID", orderID.String()).Msg("SendCaptureAuthorizedPayment")
+	}
+}
+
+// @Summary Get currency rates
+// @Description Get the exchange rates of every currency prices can be converted to
+// @Tags admin
+// @ID get-currency-rates
+// @Accept json
+// @Produce json
+// @Success 200 {object} dto.ApiResponse[[]repository.CurrencyRate]
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/currencies [get]
+func (s *Server) adminGetCurrencyRates(w http.ResponseWriter, r *http.Request) {
+	rates, err := s.repo.ListCurrencyRates(r.Context(), nil)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondSuccess(w, rates)
+}
+
+// @Summary Set a currency rate
+// @Description Create or update the exchange rate of a currency. The rate is how many units of the currency
+// @Description one unit of the store currency buys, converted prices are rounded to the rounding increment.
+// @Tags admin
+// @ID upsert-currency-rate
+// @Accept json
+// @Produce json
+// @Param code path string true "ISO 4217 currency code"
+// @Param request body models.UpsertCurrencyRateModel true "Currency rate request"
+// @Success 200 {object} dto.ApiResponse[repository.CurrencyRate]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/currencies/{code} [put]
+func (s *Server) adminUpsertCurrencyRate(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	code, err := GetUrlParam(r, "code")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	currency := payment.NormalizeCurrency(code)
+	if err := s.validator.Var(string(currency), "iso4217"); err != nil {
+		RespondBadRequest(w, InvalidCurrencyCode, fmt.Errorf("invalid currency code %s", code))
+		return
+	}
+	if currency == s.currencyProcessor.StoreCurrency() {
+		RespondBadRequest(w, InvalidCurrencyCode, fmt.Errorf("%s is the store currency, its rate is always 1", currency))
+		return
+	}
+	if err := processors.CheckCurrencyPrecision(currency); err != nil {
+		RespondBadRequest(w, InvalidCurrencyCode, err)
+		return
+	}
+	var req models.UpsertCurrencyRateModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	params := repository.UpsertCurrencyRateParams{
+		Currency:          string(currency),
+		Rate:              utils.GetPgNumericFromDecimal(req.Rate),
+		RoundingIncrement: utils.GetPgNumericFromMoney(payment.NewMoney(1, currency)),
+		IsActive:          true,
+	}
+	if req.RoundingIncrement != nil {
+		params.RoundingIncrement = utils.GetPgNumericFromDecimal(*req.RoundingIncrement)
+	}
+	if req.IsActive != nil {
+		params.IsActive = *req.IsActive
+	}
+
+	rate, err := s.repo.UpsertCurrencyRate(c, params)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondSuccess(w, rate)
+}
+
+// @Summary Delete a currency rate
+// @Description Delete the exchange rate of a currency, it can't be paid in anymore
+// @Tags admin
+// @ID delete-currency-rate
+// @Accept json
+// @Produce json
+// @Param code path string true "ISO 4217 currency code"
+// @Success 204 {object} nil
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/currencies/{code} [delete]
+func (s *Server) adminDeleteCurrencyRate(w http.ResponseWriter, r *http.Request) {
+	code, err := GetUrlParam(r, "code")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	deleted, err := s.repo.DeleteCurrencyRate(r.Context(), string(payment.NormalizeCurrency(code)))
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if deleted == 0 {
+		RespondNotFound(w, NotFoundCode, fmt.Errorf("currency rate of %s not found", code))
+		return
+	}
+	RespondNoContent(w)
+}
@@ -0,0 +1,393 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
+	"github.com/thanhphuocnguyen/go-eshop/internal/models"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/internal/worker"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/auth"
+)
+
+// supportViewLimit is how many of the latest orders and ratings the support view shows
+const supportViewLimit = 20
+
+// @Summary Lock a user
+// @Description Lock an account, the user is signed out everywhere and can't sign in until it is unlocked
+// @Tags admin
+// @ID lock-user
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body models.AdminUserActionModel true "Reason"
+// @Success 200 {object} dto.ApiResponse[dto.UserDetail]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/users/{id}/lock [post]
+func (s *Server) adminLockUser(w http.ResponseWriter, r *http.Request) {
+	s.setUserLocked(w, r, true)
+}
+
+// @Summary Unlock a user
+// @Description Unlock an account so the user can sign in again
+// @Tags admin
+// @ID unlock-user
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body models.AdminUserActionModel true "Reason"
+// @Success 200 {object} dto.ApiResponse[dto.UserDetail]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/users/{id}/unlock [post]
+func (s *Server) adminUnlockUser(w http.ResponseWriter, r *http.Request) {
+	s.setUserLocked(w, r, false)
+}
+
+func (s *Server) setUserLocked(w http.ResponseWriter, r *http.Request, locked bool) {
+	c := r.Context()
+	userID, audit, err := s.getAdminUserAction(r)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.AdminUserActionModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	if userID == audit.ActorID {
+		RespondBadRequest(w, PermissionDeniedCode, errors.New("you can't lock or unlock your own account"))
+		return
+	}
+	audit.Reason = &req.Reason
+
+	user, err := s.repo.SetUserLockedTx(c, repository.SetUserLockedTxArgs{
+		UserID:        userID,
+		Locked:        locked,
+		UserAuditArgs: audit,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("user with ID %s not found", userID))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if locked {
+		s.revokeUserAccess(c, userID)
+	}
+
+	s.respondAdminUser(w, r, user)
+}
+
+// @Summary Change the role of a user
+// @Description Give a user another role. Access tokens the user has stop working, the next refresh carries the new role.
+// @Tags admin
+// @ID change-user-role
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body models.ChangeUserRoleModel true "Role"
+// @Success 200 {object} dto.ApiResponse[dto.UserDetail]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/users/{id}/role [put]
+func (s *Server) adminChangeUserRole(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	userID, audit, err := s.getAdminUserAction(r)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.ChangeUserRoleModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	if userID == audit.ActorID {
+		RespondBadRequest(w, PermissionDeniedCode, errors.New("you can't change your own role"))
+		return
+	}
+	audit.Reason = req.Reason
+
+	user, err := s.repo.ChangeUserRoleTx(c, repository.ChangeUserRoleTxArgs{
+		UserID:        userID,
+		RoleID:        uuid.MustParse(req.RoleID),
+		UserAuditArgs: audit,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidRole) {
+			RespondBadRequest(w, InvalidRoleCode, err)
+			return
+		}
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("user with ID %s not found", userID))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	s.revokeUserAccess(c, userID)
+
+	s.respondAdminUser(w, r, user)
+}
+
+// @Summary Force a password reset
+// @Description Make the current password stop working and mail the user a link to choose a new one. The user is
+// @Description signed out everywhere.
+// @Tags admin
+// @ID force-password-reset
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body models.AdminUserActionModel true "Reason"
+// @Success 204 {object} nil
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/users/{id}/password-reset [post]
+func (s *Server) adminForcePasswordReset(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	userID, audit, err := s.getAdminUserAction(r)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.AdminUserActionModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	audit.Reason = &req.Reason
+
+	// nobody is told the replacement password, so only the mailed link gets the user back in
+	password, _, err := auth.GeneratePasswordResetToken()
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	hashedPassword, err := auth.HashPwd(password)
+	if err != nil {
+		RespondInternalServerError(w, HashPasswordCode, err)
+		return
+	}
+	rs, err := s.repo.ForcePasswordResetTx(c, repository.ForcePasswordResetTxArgs{
+		UserID:         userID,
+		HashedPassword: hashedPassword,
+		UserAuditArgs:  audit,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("user with ID %s not found", userID))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	s.cachePasswordChangedAt(c, rs.UserID, rs.PasswordChangedAt)
+
+	err = s.taskDistributor.SendPasswordResetEmail(c,
+		&worker.PayloadSendPasswordResetEmail{UserID: userID},
+		asynq.MaxRetry(3),
+		asynq.Queue(worker.QueueCritical),
+	)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondNoContent(w)
+}
+
+// @Summary Get the support view of a user
+// @Description Get a user with the latest orders and ratings, the addresses and the signed in devices. Looking is
+// @Description recorded in the audit trail of the user.
+// @Tags admin
+// @ID get-user-support-view
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} dto.ApiResponse[dto.UserSupportView]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/users/{id}/support [get]
+func (s *Server) adminGetUserSupportView(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	userID, audit, err := s.getAdminUserAction(r)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	user, err := s.repo.GetUserByID(c, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("user with ID %s not found", userID))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	role, err := s.repo.GetRoleByID(c, user.RoleID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	orders, err := s.repo.GetOrders(c, repository.GetOrdersParams{
+		Limit:  supportViewLimit,
+		UserID: utils.GetPgTypeUUID(userID),
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	addresses, err := s.repo.GetAddresses(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	ratings, err := s.repo.GetProductRatingsByUserID(c, repository.GetProductRatingsByUserIDParams{
+		UserID: userID,
+		Limit:  supportViewLimit,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	sessions, err := s.repo.ListActiveUserSessions(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	// personal data is only shown once the look is on record
+	if _, err = repository.CreateUserAudit(c, s.repo, userID, repository.UserAuditViewSupport, audit, nil); err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	resp := dto.UserSupportView{
+		User:      dto.MapToUserResponse(user, role.Code),
+		Orders:    make([]dto.OrderListItem, len(orders)),
+		Addresses: make([]dto.AddressDetail, len(addresses)),
+		Ratings:   make([]dto.RatingDetail, len(ratings)),
+		Sessions:  make([]dto.SessionDetail, len(sessions)),
+	}
+	for i, order := range orders {
+		resp.Orders[i] = dto.MapToOrderListItem(order)
+	}
+	for i, address := range addresses {
+		resp.Addresses[i] = dto.MapAddressResponse(address)
+	}
+	for i, rating := range ratings {
+		point, _ := rating.ProductRating.Rating.Float64Value()
+		resp.Ratings[i] = dto.RatingDetail{
+			ID:        rating.ProductRating.ID.String(),
+			Title:     utils.StringValue(rating.ProductRating.ReviewTitle),
+			Content:   utils.StringValue(rating.ProductRating.ReviewContent),
+			Rating:    point.Float64,
+			CreatedAt: rating.ProductRating.CreatedAt,
+		}
+	}
+	for i, session := range sessions {
+		resp.Sessions[i] = dto.MapToSessionDetail(session, uuid.Nil)
+	}
+	RespondSuccess(w, resp)
+}
+
+// @Summary Get the audit trail of a user
+// @Description List what staff did to an account, latest first
+// @Tags admin
+// @ID get-user-audit-logs
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Success 200 {object} dto.ApiResponse[[]dto.UserAuditLog]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/users/{id}/audit [get]
+func (s *Server) adminGetUserAuditLogs(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	queries := ParsePaginationQuery(r)
+
+	logs, err := s.repo.GetUserAuditLogs(c, repository.GetUserAuditLogsParams{
+		UserID: userID,
+		Limit:  queries.PageSize,
+		Offset: (queries.Page - 1) * queries.PageSize,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	total, err := s.repo.CountUserAuditLogs(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	resp := make([]dto.UserAuditLog, len(logs))
+	for i, log := range logs {
+		resp[i] = dto.MapToUserAuditLog(log)
+	}
+	RespondSuccessWithPagination(w, resp, dto.CreatePagination(queries.Page, queries.PageSize, total))
+}
+
+// getAdminUserAction reads the user acted on from the path and the staff member acting from the token
+func (s *Server) getAdminUserAction(r *http.Request) (uuid.UUID, repository.UserAuditArgs, error) {
+	var audit repository.UserAuditArgs
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err != nil {
+		return uuid.Nil, audit, errors.New("authorization payload is not provided")
+	}
+	audit.ActorID = uuid.MustParse(claims["userId"].(string))
+
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		return uuid.Nil, audit, err
+	}
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, audit, err
+	}
+	return userID, audit, nil
+}
+
+func (s *Server) respondAdminUser(w http.ResponseWriter, r *http.Request, user repository.User) {
+	role, err := s.repo.GetRoleByID(r.Context(), user.RoleID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondSuccess(w, dto.MapToUserResponse(user, role.Code))
+}
@@ -5,12 +5,15 @@ import (
 	"fmt"
 	"net/http"
 	"net/netip"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/hibiken/asynq"
 	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/constants"
 	repository "github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
 	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
 	"github.com/thanhphuocnguyen/go-eshop/internal/models"
@@ -96,6 +99,9 @@ func (s *Server) register(w http.ResponseWriter, r *http.Request) {
 			Street:      req.Address.Street,
 			City:        req.Address.City,
 			District:    req.Address.District,
+			Country:     req.Address.Country,
+			State:       req.Address.State,
+			ZipCode:     req.Address.ZipCode,
 			IsDefault:   true,
 		}
 
@@ -165,13 +171,15 @@ func (s *Server) register(w http.ResponseWriter, r *http.Request) {
 
 // login godoc
 // @Summary login to the system
-// @Description login to the system
+// @Description login to the system. Users with two-factor authentication get no tokens yet but an mfaToken
+// @Description to finish the login with at /auth/login/mfa.
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Param input body models.LoginModel true "User info"
 // @Success 200 {object} dto.ApiResponse[dto.LoginResponse]
 // @Failure 401 {object} ErrorResp
+// @Failure 403 {object} ErrorResp
 // @Failure 500 {object} ErrorResp
 // @Router /auth/login [post]
 func (s *Server) login(w http.ResponseWriter, r *http.Request) {
@@ -207,39 +215,120 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 		RespondUnauthorized(w, UnauthorizedCode, err)
 		return
 	}
+	if user.Locked {
+		RespondForbidden(w, AccountLockedCode, errors.New("account is locked"))
+		return
+	}
 
-	role, err := s.repo.GetRoleByID(c, user.RoleID)
+	mfa, err := s.repo.GetUserMfa(c, user.ID)
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if err == nil && mfa.EnabledAt.Valid {
+		token, expiresAt, err := s.startMfaLogin(c, user.ID)
+		if err != nil {
+			RespondInternalServerError(w, InternalServerErrorCode, err)
+			return
+		}
+		RespondSuccess(w, dto.LoginResponse{
+			MfaRequired:       true,
+			MfaToken:          token,
+			MfaTokenExpiresAt: &expiresAt,
+		})
+		return
+	}
+
+	s.completeLogin(w, r, user, false)
+}
+
+// loginMfa godoc
+// @Summary Finish a login with a second factor
+// @Description Finish a login waiting for its second factor with a code of the authenticator app or a recovery
+// @Description code. The mfaToken stops working after a few wrong codes.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param input body models.LoginMfaModel true "MFA token and code"
+// @Success 200 {object} dto.ApiResponse[dto.LoginResponse]
+// @Failure 400 {object} ErrorResp
+// @Failure 401 {object} ErrorResp
+// @Failure 403 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /auth/login/mfa [post]
+func (s *Server) loginMfa(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	var req models.LoginMfaModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	key := mfaLoginCacheKey(auth.HashMfaToken(req.MfaToken))
+	var pending mfaLogin
+	if err := s.cacheSrv.Get(c, key, &pending); err != nil {
+		RespondUnauthorized(w, InvalidTokenCode, errors.New("mfa token is invalid or expired"))
+		return
+	}
+	user, err := s.repo.GetUserByID(c, pending.UserID)
 	if err != nil {
 		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
+	if user.Locked {
+		s.endMfaLogin(c, key)
+		RespondForbidden(w, AccountLockedCode, errors.New("account is locked"))
+		return
+	}
 
-	payload, accessToken, err := s.generateToken(user.ID, user.Username, role, s.config.AccessTokenDuration)
+	ok, err := s.verifyMfaCode(c, user.ID, req.Code)
 	if err != nil {
-		RespondInternalServerError(w, InvalidTokenCode, err)
+		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
+	if !ok {
+		s.failMfaLogin(c, key, pending)
+		RespondUnauthorized(w, InvalidMfaCode, errors.New("code is not valid"))
+		return
+	}
+
+	s.endMfaLogin(c, key)
+	s.completeLogin(w, r, user, true)
+}
 
-	rfPayload, refreshToken, err := s.generateToken(user.ID, user.Username, role, s.config.RefreshTokenDuration)
+// completeLogin signs the user in on a new device, mfa tells whether a second factor was used
+func (s *Server) completeLogin(w http.ResponseWriter, r *http.Request, user repository.User, mfa bool) {
+	c := r.Context()
+	role, err := s.repo.GetRoleByID(c, user.RoleID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	// the family is the device, it keeps its id while the refresh token rotates
+	familyID, _ := uuid.NewRandom()
+	payload, accessToken, err := s.generateToken(user.ID, user.Username, role, familyID, mfa, s.config.AccessTokenDuration)
 	if err != nil {
 		RespondInternalServerError(w, InvalidTokenCode, err)
 		return
 	}
 
-	clientIP, err := netip.ParseAddr(r.RemoteAddr)
+	rfPayload, refreshToken, err := s.generateToken(user.ID, user.Username, role, familyID, mfa, s.config.RefreshTokenDuration)
 	if err != nil {
-		// Fallback to localhost if parsing fails
-		clientIP = netip.MustParseAddr("127.0.0.1")
+		RespondInternalServerError(w, InvalidTokenCode, err)
+		return
 	}
+
 	id, _ := rfPayload.Get("id")
 	session, err := s.repo.InsertSession(c, repository.InsertSessionParams{
 		ID:           id.(uuid.UUID),
 		UserID:       user.ID,
+		FamilyID:     familyID,
 		RefreshToken: refreshToken,
 		UserAgent:    r.Header.Get("User-Agent"),
-		ClientIp:     clientIP,
+		ClientIp:     getClientIP(r),
 		Blocked:      false,
-		ExpiredAt:    utils.GetPgTypeTimestamp(time.Now().Add(s.config.RefreshTokenDuration)),
+		ExpiredAt:    utils.GetPgTypeTimestamp(rfPayload.Expiration()),
 	})
 
 	if err != nil {
@@ -247,8 +336,36 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// carry over what the user put in the cart before signing in
+	if sessionID, ok := s.getCartSessionID(r); ok {
+		err = s.repo.MergeGuestCartTx(c, repository.MergeGuestCartTxArgs{
+			UserID:    user.ID,
+			SessionID: sessionID,
+		})
+		if err != nil {
+			log.Error().Err(err).Str("sessionID", sessionID).Msg("failed to merge guest cart")
+		} else {
+			http.SetCookie(w, &http.Cookie{
+				Name:   constants.CartSessionCookie,
+				Path:   "/",
+				MaxAge: -1,
+			})
+		}
+	}
+
+	// guest orders placed after the email was verified are only claimed here
+	if user.VerifiedEmail {
+		_, err = s.repo.ClaimGuestOrders(c, repository.ClaimGuestOrdersParams{
+			UserID: utils.GetPgTypeUUID(user.ID),
+			Email:  user.Email,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to claim guest orders")
+		}
+	}
+
 	loginResp := dto.LoginResponse{
-		ID:                    session.ID.String(),
+		ID:                    session.FamilyID.String(),
 		AccessToken:           accessToken,
 		AccessTokenExpiresAt:  payload.Expiration(),
 		RefreshToken:          refreshToken,
@@ -259,105 +376,292 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 
 // refreshToken godoc
 // @Summary Refresh token
-// @Description Refresh token
+// @Description Exchange a refresh token for a new access and refresh token. A refresh token works once, using
+// @Description one that was already exchanged signs the device out.
 // @Tags users
 // @Accept  json
 // @Produce  json
 // @Success 200 {object} dto.ApiResponse[dto.RefreshToken]
 // @Failure 401 {object} ErrorResp
+// @Failure 403 {object} ErrorResp
 // @Failure 500 {object} ErrorResp
 // @Router /auth/refresh-token [post]
 func (s *Server) refreshToken(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		RespondUnauthorized(w, UnauthorizedCode, fmt.Errorf("refresh token is required"))
+	refreshToken, sessionID, mfa, err := s.getRefreshToken(r)
+	if err != nil {
+		RespondUnauthorized(w, InvalidTokenCode, err)
 		return
 	}
 
-	refreshToken := authHeader[len("Bearer "):]
-	refreshTokenPayload, err := s.tokenAuth.Decode(refreshToken)
+	// the new refresh token is signed up front so the exchange is a single transaction
+	session, err := s.repo.GetSession(c, sessionID)
 	if err != nil {
-		RespondUnauthorized(w, UnauthorizedCode, err)
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondUnauthorized(w, InvalidSessionCode, fmt.Errorf("session not found"))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
-	id, _ := refreshTokenPayload.Get("id")
-	rfTkUUID := id.(uuid.UUID)
-	session, err := s.repo.GetSession(c, rfTkUUID)
+	user, err := s.repo.GetUserByID(c, session.UserID)
 	if err != nil {
-		if errors.Is(err, repository.ErrRecordNotFound) {
-			RespondNotFound(w, NotFoundCode, fmt.Errorf("session not found"))
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if user.Locked {
+		RespondForbidden(w, AccountLockedCode, errors.New("account is locked"))
+		return
+	}
+	role, err := s.repo.GetRoleByID(c, user.RoleID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	rfPayload, newRefreshToken, err := s.generateToken(user.ID, user.Username, role, session.FamilyID, mfa, s.config.RefreshTokenDuration)
+	if err != nil {
+		RespondInternalServerError(w, InvalidTokenCode, err)
+		return
+	}
+	newID, _ := rfPayload.Get("id")
+
+	rs, err := s.repo.RotateSessionTx(c, repository.RotateSessionTxArgs{
+		SessionID:       sessionID,
+		RefreshToken:    refreshToken,
+		NewSessionID:    newID.(uuid.UUID),
+		NewRefreshToken: newRefreshToken,
+		UserAgent:       r.Header.Get("User-Agent"),
+		ClientIp:        getClientIP(r),
+		ExpiredAt:       rfPayload.Expiration(),
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidSession) {
+			RespondUnauthorized(w, InvalidSessionCode, err)
 			return
 		}
 		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
+	if rs.ReuseDetected {
+		log.Warn().Str("userID", session.UserID.String()).Str("sessionID", session.FamilyID.String()).
+			Msg("refresh token reused, session signed out")
+		s.revokeSessionAccess(c, session.FamilyID)
+		RespondUnauthorized(w, InvalidSessionCode, errors.New("refresh token was already used"))
+		return
+	}
 
-	if session.ID != rfTkUUID {
-		err := errors.New("refresh token is not valid")
+	payload, accessToken, err := s.generateToken(user.ID, user.Username, role, session.FamilyID, mfa, s.config.AccessTokenDuration)
+	if err != nil {
+		RespondInternalServerError(w, InvalidTokenCode, err)
+		return
+	}
+
+	resp := dto.RefreshToken{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  payload.Expiration(),
+		RefreshToken:          newRefreshToken,
+		RefreshTokenExpiresAt: rfPayload.Expiration(),
+	}
+	RespondSuccess(w, resp)
+}
+
+// logout godoc
+// @Summary Logout
+// @Description Sign out the device of the refresh token. Its access tokens stop working as well.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Success 204 {object} nil
+// @Failure 401 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /auth/logout [post]
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	refreshToken, sessionID, _, err := s.getRefreshToken(r)
+	if err != nil {
 		RespondUnauthorized(w, InvalidTokenCode, err)
 		return
 	}
 
+	session, err := s.repo.GetSession(c, sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondUnauthorized(w, InvalidSessionCode, fmt.Errorf("session not found"))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
 	if session.RefreshToken != refreshToken {
-		err := errors.New("refresh token is not valid")
-		RespondUnauthorized(w, InvalidTokenCode, err)
+		RespondUnauthorized(w, InvalidTokenCode, errors.New("refresh token is not valid"))
 		return
 	}
 
-	if session.Blocked {
-		err := errors.New("session is blocked")
-		RespondUnauthorized(w, InvalidSessionCode, err)
+	_, err = s.repo.BlockSessionFamily(c, repository.BlockSessionFamilyParams{
+		FamilyID: session.FamilyID,
+		UserID:   session.UserID,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
+	s.revokeSessionAccess(c, session.FamilyID)
+	RespondNoContent(w)
+}
 
-	if time.Now().After(session.ExpiredAt) {
-		err := errors.New("refresh token was expired")
-		RespondUnauthorized(w, InvalidSessionCode, err)
+// forgotPassword godoc
+// @Summary Request a password reset
+// @Description Mail a single use link to choose a new password. The response is the same whether an account
+// @Description uses the email or not, so it can't be used to find out who has one.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param input body models.ForgotPasswordModel true "Account email"
+// @Success 204 {object} nil
+// @Failure 400 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /auth/forgot-password [post]
+func (s *Server) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	var req models.ForgotPasswordModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
 		return
 	}
-	roleID, _ := refreshTokenPayload.Get("roleID")
-	role, err := s.repo.GetRoleByID(c, roleID.(uuid.UUID))
+
+	user, err := s.repo.GetUserByEmail(c, req.Email)
 	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNoContent(w)
+			return
+		}
 		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
-	userName, _ := refreshTokenPayload.Get("username")
-	_, accessToken, err := s.generateToken(session.UserID, userName.(string), role, s.config.AccessTokenDuration)
+
+	err = s.taskDistributor.SendPasswordResetEmail(c,
+		&worker.PayloadSendPasswordResetEmail{UserID: user.ID},
+		asynq.MaxRetry(3),
+		asynq.Queue(worker.QueueCritical),
+	)
 	if err != nil {
 		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
 
-	resp := dto.RefreshToken{AccessToken: accessToken, AccessTokenExpiresAt: time.Now().Add(s.config.AccessTokenDuration)}
-	RespondSuccess(w, resp)
+	RespondNoContent(w)
+}
+
+// resetPassword godoc
+// @Summary Reset password
+// @Description Choose a new password with the token mailed by forgot-password. The token works once, and
+// @Description every session of the user is signed out.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param input body models.ResetPasswordModel true "Reset token and new password"
+// @Success 204 {object} nil
+// @Failure 400 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /auth/reset-password [post]
+func (s *Server) resetPassword(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	var req models.ResetPasswordModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	hashedPassword, err := auth.HashPwd(req.NewPassword)
+	if err != nil {
+		RespondInternalServerError(w, HashPasswordCode, err)
+		return
+	}
+	rs, err := s.repo.ResetPasswordTx(c, repository.ResetPasswordTxArgs{
+		TokenHash:      auth.HashPasswordResetToken(req.Token),
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidPasswordReset) {
+			RespondBadRequest(w, InvalidTokenCode, err)
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	s.cachePasswordChangedAt(c, rs.UserID, rs.PasswordChangedAt)
+	RespondNoContent(w)
 }
 
 func (s *Server) generateToken(
 	userID uuid.UUID,
 	username string,
 	role repository.UserRole,
+	sessionID uuid.UUID,
+	mfa bool,
 	duration time.Duration,
 
 ) (accessToken jwt.Token, tokenString string, err error) {
 	id, _ := uuid.NewRandom()
 	jwtClaims := map[string]interface{}{
-		"id":       id,
-		"userId":   userID,
-		"username": username,
-		"roleId":   role.ID,
-		"roleCode": role.Code,
-		"exp":      time.Now().Add(duration).Unix(),
+		"id":        id,
+		"userId":    userID,
+		"username":  username,
+		"roleId":    role.ID,
+		"roleCode":  role.Code,
+		"sessionId": sessionID,
+		"mfa":       mfa,
+		"iat":       time.Now().Unix(),
+		"exp":       time.Now().Add(duration).Unix(),
 	}
 	accessToken, tokenString, err = s.tokenAuth.Encode(jwtClaims)
 	return
 }
 
+// getRefreshToken reads the refresh token from the Authorization header and returns it with
+// the id of its session and whether the session was signed in with a second factor
+func (s *Server) getRefreshToken(r *http.Request) (string, uuid.UUID, bool, error) {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", uuid.Nil, false, errors.New("refresh token is required")
+	}
+	refreshToken := strings.TrimPrefix(authHeader, "Bearer ")
+	payload, err := s.tokenAuth.Decode(refreshToken)
+	if err != nil {
+		return "", uuid.Nil, false, err
+	}
+	// claims come back from the token as strings
+	id, _ := payload.Get("id")
+	sessionID, err := uuid.Parse(fmt.Sprint(id))
+	if err != nil {
+		return "", uuid.Nil, false, errors.New("refresh token is not valid")
+	}
+	claim, _ := payload.Get("mfa")
+	mfa, _ := claim.(bool)
+	return refreshToken, sessionID, mfa, nil
+}
+
+// getClientIP returns the address the request came from, localhost when it can't be parsed
+func getClientIP(r *http.Request) netip.Addr {
+	if addrPort, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		return addrPort.Addr()
+	}
+	if addr, err := netip.ParseAddr(r.RemoteAddr); err == nil {
+		return addr
+	}
+	return netip.MustParseAddr("127.0.0.1")
+}
+
 // Setup authentication routes
 func (s *Server) addAuthRoutes(r chi.Router) {
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/register", s.register)
 		r.Post("/login", s.login)
+		r.Post("/login/mfa", s.loginMfa)
 		r.Post("/refresh-token", s.refreshToken)
+		r.Post("/logout", s.logout)
+		r.Post("/forgot-password", s.forgotPassword)
+		r.Post("/reset-password", s.resetPassword)
 	})
 }
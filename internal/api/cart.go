@@ -9,10 +9,13 @@ import (
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/constants"
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
 	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
 	"github.com/thanhphuocnguyen/go-eshop/internal/models"
+	"github.com/thanhphuocnguyen/go-eshop/internal/processors"
 	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/auth"
 )
 
 // @Summary Create a new cart
@@ -30,34 +33,30 @@ import (
 // @Router /carts [post]
 func (s *Server) createCart(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	_, claims, err := jwtauth.FromContext(c)
-
-	userID := uuid.MustParse(claims["userId"].(string))
-
+	owner, err := s.getCartOwner(w, r, true)
 	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, errors.New("user not found"))
+		RespondUnauthorized(w, UnauthorizedCode, err)
 		return
 	}
-	user, err := s.repo.GetUserByID(c, userID)
-	if err != nil {
-		if errors.Is(err, repository.ErrRecordNotFound) {
-			RespondNotFound(w, NotFoundCode, errors.New("user not found"))
+
+	if owner.UserID.Valid {
+		_, err := s.repo.GetUserByID(c, owner.UserID.Bytes)
+		if err != nil {
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				RespondNotFound(w, NotFoundCode, errors.New("user not found"))
+				return
+			}
+			RespondInternalServerError(w, InternalServerErrorCode, err)
 			return
 		}
-		RespondInternalServerError(w, InternalServerErrorCode, err)
-		return
 	}
-	_, err = s.repo.GetCart(c, repository.GetCartParams{
-		UserID: utils.GetPgTypeUUID(userID),
-	})
+	_, err = s.repo.GetCart(c, owner)
 	if err == nil {
 		RespondBadRequest(w, InvalidBodyCode, errors.New("cart already exists"))
 		return
 	}
 
-	newCart, err := s.repo.CreateCart(c, repository.CreateCartParams{
-		UserID: utils.GetPgTypeUUID(user.ID),
-	})
+	newCart, err := s.repo.CreateCart(c, repository.CreateCartParams(owner))
 	if err != nil {
 		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
@@ -87,22 +86,17 @@ func (s *Server) createCart(w http.ResponseWriter, r *http.Request) {
 // @Router /carts [get]
 func (s *Server) getCart(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	_, claims, err := jwtauth.FromContext(c)
+	owner, err := s.getCartOwner(w, r, true)
 	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, errors.New("user not found"))
+		RespondUnauthorized(w, UnauthorizedCode, err)
 		return
 	}
 
-	userID := uuid.MustParse(claims["userId"].(string))
-	cart, err := s.repo.GetCart(c, repository.GetCartParams{
-		UserID: utils.GetPgTypeUUID(userID),
-	})
+	cart, err := s.repo.GetCart(c, owner)
 
 	if err != nil {
 		if errors.Is(err, repository.ErrRecordNotFound) {
-			cart, err := s.repo.CreateCart(c, repository.CreateCartParams{
-				UserID: utils.GetPgTypeUUID(userID),
-			})
+			cart, err := s.repo.CreateCart(c, repository.CreateCartParams(owner))
 			if err != nil {
 				RespondInternalServerError(w, InternalServerErrorCode, err)
 				return
@@ -155,16 +149,13 @@ func (s *Server) getCart(w http.ResponseWriter, r *http.Request) {
 // @Router /carts/available-discounts [get]
 func (s *Server) getCartAvailableDiscounts(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	_, claims, err := jwtauth.FromContext(c)
+	owner, err := s.getCartOwner(w, r, false)
 	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, errors.New("authorization payload is not provided"))
+		RespondUnauthorized(w, UnauthorizedCode, err)
 		return
 	}
-	userID := uuid.MustParse(claims["userId"].(string))
 
-	cart, err := s.repo.GetCart(c, repository.GetCartParams{
-		UserID: utils.GetPgTypeUUID(userID),
-	})
+	cart, err := s.repo.GetCart(c, owner)
 
 	if err != nil {
 		if errors.Is(err, repository.ErrRecordNotFound) {
@@ -179,6 +170,83 @@ func (s *Server) getCartAvailableDiscounts(w http.ResponseWriter, r *http.Reques
 
 }
 
+// @Summary Get shipping quotes for the cart
+// @Schemes http
+// @Description quote the shipping rates available for the cart and its default address. Guests send the address to ship to.
+// @Tags carts
+// @Accept json
+// @Param input body models.CreateAddress false "Shipping address for guests"
+// @Produce json
+// @Success 200 {object} dto.ApiResponse[[]processors.ShippingQuote]
+// @Failure 400 {object} ErrorResp
+// @Failure 404 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /carts/shipping-quotes [post]
+func (s *Server) getShippingQuotes(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	owner, err := s.getCartOwner(w, r, false)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, err)
+		return
+	}
+	if !owner.UserID.Valid && owner.SessionID == nil {
+		RespondNotFound(w, NotFoundCode, errors.New("cart not found"))
+		return
+	}
+
+	cart, err := s.repo.GetCart(c, owner)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, errors.New("cart not found"))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	var address repository.UserAddress
+	if owner.UserID.Valid {
+		address, err = s.repo.GetDefaultAddress(c, owner.UserID.Bytes)
+		if err != nil {
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				RespondNotFound(w, NotFoundCode, errors.New("address not found"))
+				return
+			}
+			RespondInternalServerError(w, InternalServerErrorCode, err)
+			return
+		}
+	} else {
+		var req models.CreateAddress
+		if err := s.GetRequestBody(r, &req); err != nil {
+			RespondBadRequest(w, InvalidBodyCode, err)
+			return
+		}
+		address = addressFromModel(req)
+	}
+
+	itemRows, err := s.repo.GetCartItems(c, cart.ID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if len(itemRows) == 0 {
+		RespondBadRequest(w, InvalidBodyCode, errors.New("cart is empty"))
+		return
+	}
+
+	quotes, err := s.shippingProcessor.QuoteRates(c, processors.ShippingContext{Address: address, CartItems: itemRows, Currency: s.currencyProcessor.StoreCurrency()})
+	if err != nil {
+		if errors.Is(err, processors.ErrShippingZoneNotFound) {
+			RespondBadRequest(w, InvalidShippingCode, err)
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, quotes)
+}
+
 // @Summary update product quantity in the cart
 // @Schemes http
 // @Description add a product to the cart
@@ -192,35 +260,29 @@ func (s *Server) getCartAvailableDiscounts(w http.ResponseWriter, r *http.Reques
 // @Router /carts/items/{variantId} [post]
 func (s *Server) upsertCartItemQty(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	_, claims, err := jwtauth.FromContext(c)
-	if err != nil {
-		RespondUnauthorized(w, UnauthorizedCode, errors.New("user not found"))
-		return
-	}
-
 	variantIDParam, err := GetUrlParam(r, "variantId")
 	if err != nil {
 		RespondBadRequest(w, InvalidBodyCode, err)
 		return
 	}
 
-	userID := uuid.MustParse(claims["userId"].(string))
-
 	var req models.UpdateCartItemQtyModel
 	if err := s.GetRequestBody(r, &req); err != nil {
 		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
 
-	cart, err := s.repo.GetCart(c, repository.GetCartParams{
-		UserID: utils.GetPgTypeUUID(userID),
-	})
+	owner, err := s.getCartOwner(w, r, true)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, err)
+		return
+	}
+
+	cart, err := s.repo.GetCart(c, owner)
 
 	if err != nil {
 		if errors.Is(err, repository.ErrRecordNotFound) {
-			newCart, createCartErr := s.repo.CreateCart(c, repository.CreateCartParams{
-				UserID: utils.GetPgTypeUUID(userID),
-			})
+			newCart, createCartErr := s.repo.CreateCart(c, repository.CreateCartParams(owner))
 			if createCartErr != nil {
 				RespondInternalServerError(w, InternalServerErrorCode, createCartErr)
 				return
@@ -288,12 +350,11 @@ func (s *Server) upsertCartItemQty(w http.ResponseWriter, r *http.Request) {
 // @Router /carts/items/{id} [delete]
 func (s *Server) removeCartItem(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	_, claims, err := jwtauth.FromContext(c)
+	owner, err := s.getCartOwner(w, r, false)
 	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, errors.New("authorization payload is not provided"))
+		RespondUnauthorized(w, UnauthorizedCode, err)
 		return
 	}
-	userID := uuid.MustParse(claims["userId"].(string))
 	id, err := GetUrlParam(r, "id")
 	itemId, err := uuid.Parse(id)
 
@@ -302,9 +363,7 @@ func (s *Server) removeCartItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cart, err := s.repo.GetCart(c, repository.GetCartParams{
-		UserID: utils.GetPgTypeUUID(userID),
-	})
+	cart, err := s.repo.GetCart(c, owner)
 	if err != nil {
 		if errors.Is(err, repository.ErrRecordNotFound) {
 			RespondNotFound(w, NotFoundCode, errors.New("cart not found"))
@@ -314,14 +373,6 @@ func (s *Server) removeCartItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if cart.UserID.Valid {
-		cartUserID, _ := uuid.FromBytes(cart.UserID.Bytes[:])
-		if cartUserID != userID {
-			RespondForbidden(w, UnauthorizedCode, errors.New("forbidden"))
-			return
-		}
-	}
-
 	err = s.repo.RemoveProductFromCart(c, repository.RemoveProductFromCartParams{
 		CartID: cart.ID,
 		ID:     itemId,
@@ -349,16 +400,13 @@ func (s *Server) removeCartItem(w http.ResponseWriter, r *http.Request) {
 // @Router /carts/clear [put]
 func (s *Server) clearCart(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	_, claims, err := jwtauth.FromContext(c)
+	owner, err := s.getCartOwner(w, r, false)
 	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, errors.New("authorization payload is not provided"))
+		RespondUnauthorized(w, UnauthorizedCode, err)
 		return
 	}
-	userID := uuid.MustParse(claims["userId"].(string))
 
-	cart, err := s.repo.GetCart(c, repository.GetCartParams{
-		UserID: utils.GetPgTypeUUID(userID),
-	})
+	cart, err := s.repo.GetCart(c, owner)
 	if err != nil {
 		if errors.Is(err, repository.ErrRecordNotFound) {
 			RespondNotFound(w, NotFoundCode, errors.New("cart not found"))
@@ -368,11 +416,6 @@ func (s *Server) clearCart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if string(cart.UserID.Bytes[:]) != userID.String() {
-		RespondForbidden(w, UnauthorizedCode, errors.New("forbidden"))
-		return
-	}
-
 	err = s.repo.ClearCart(c, cart.ID)
 	if err != nil {
 		RespondInternalServerError(w, InternalServerErrorCode, err)
@@ -381,6 +424,58 @@ func (s *Server) clearCart(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// getCartOwner identifies whose cart the request works on: the authenticated user or,
+// for guests, the signed cart session sent as a header or cookie. When issueSession is
+// set a guest without a valid session gets a new one.
+func (s *Server) getCartOwner(w http.ResponseWriter, r *http.Request, issueSession bool) (repository.GetCartParams, error) {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	if err == nil {
+		return repository.GetCartParams{
+			UserID: utils.GetPgTypeUUID(uuid.MustParse(claims["userId"].(string))),
+		}, nil
+	}
+	if !errors.Is(err, jwtauth.ErrNoTokenFound) {
+		return repository.GetCartParams{}, err
+	}
+
+	if sessionID, ok := s.getCartSessionID(r); ok {
+		return repository.GetCartParams{SessionID: &sessionID}, nil
+	}
+	if !issueSession {
+		return repository.GetCartParams{}, nil
+	}
+
+	sessionID, token := auth.GenerateSessionToken(s.config.SymmetricKey)
+	http.SetCookie(w, &http.Cookie{
+		Name:     constants.CartSessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(s.config.GuestCartTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   s.config.Env != DevEnv,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set(constants.CartSessionHeader, token)
+	return repository.GetCartParams{SessionID: &sessionID}, nil
+}
+
+// getCartSessionID returns the guest cart session of the request if its signature is valid.
+func (s *Server) getCartSessionID(r *http.Request) (string, bool) {
+	token := r.Header.Get(constants.CartSessionHeader)
+	if token == "" {
+		cookie, err := r.Cookie(constants.CartSessionCookie)
+		if err != nil {
+			return "", false
+		}
+		token = cookie.Value
+	}
+	sessionID, err := auth.VerifySessionToken(s.config.SymmetricKey, token)
+	if err != nil {
+		return "", false
+	}
+	return sessionID, true
+}
+
 // ------------------------------ Mappers ------------------------------
 func mapToCartItemsResp(row repository.GetCartItemsRow) dto.CartItemDetail {
 
@@ -418,7 +513,9 @@ func mapToCartItemsResp(row repository.GetCartItemsRow) dto.CartItemDetail {
 func (s *Server) addCartRoutes(r chi.Router) {
 	r.Route("/carts", func(r chi.Router) {
 		r.Post("/", s.createCart)
-		r.Post("/checkout", s.checkout)
+		r.With(jwtauth.Authenticator(s.tokenAuth), s.revokedTokenMiddleware).Post("/checkout", s.checkout)
+		r.Post("/guest-checkout", s.guestCheckout)
+		r.Post("/shipping-quotes", s.getShippingQuotes)
 		r.Get("/", s.getCart)
 		r.Put("/clear", s.clearCart)
 
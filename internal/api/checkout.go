@@ -5,17 +5,39 @@ import (
 	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog/log"
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
 	"github.com/thanhphuocnguyen/go-eshop/internal/models"
 	"github.com/thanhphuocnguyen/go-eshop/internal/processors"
 	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/internal/worker"
 	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
 )
 
+// checkoutArgs holds what differs between a user checkout and a guest checkout
+type checkoutArgs struct {
+	// UserID is left invalid for guests
+	UserID          pgtype.UUID
+	Owner           repository.GetCartParams
+	User            repository.GetUserDetailsByIDRow
+	Customer        repository.CustomerInfoTxArgs
+	Address         repository.UserAddress
+	PaymentMethodID string
+	ShippingRateID  *string
+	DiscountCodes   []string
+	Currency        string
+	// SavedMethod is the card charged for a one-click checkout and PaymentCustomerID the
+	// gateway customer it is saved for
+	SavedMethod       *repository.UserPaymentInfo
+	PaymentCustomerID string
+}
+
 // @Summary Update product items in the cart
 // @Schemes http
 // @Description update product items in the cart
@@ -33,12 +55,12 @@ import (
 func (s *Server) checkout(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
 	_, claims, err := jwtauth.FromContext(c)
-
-	userID := uuid.MustParse(claims["userId"].(string))
 	if err != nil {
 		RespondBadRequest(w, InvalidBodyCode, errors.New("user not found"))
 		return
 	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
 	// verify request body
 	var req models.CheckoutModel
 	if err := s.GetRequestBody(r, &req); err != nil {
@@ -46,12 +68,6 @@ func (s *Server) checkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// validate request
-	if err := s.validator.Struct(req); err != nil {
-		RespondBadRequest(w, InvalidBodyCode, err)
-		return
-	}
-
 	user, err := s.repo.GetUserDetailsByID(c, userID)
 	if err != nil {
 		if errors.Is(err, repository.ErrRecordNotFound) {
@@ -62,20 +78,6 @@ func (s *Server) checkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cart, err := s.repo.GetCart(c, repository.GetCartParams{
-		UserID: utils.GetPgTypeUUID(userID),
-	})
-
-	if err != nil {
-		if errors.Is(err, repository.ErrRecordNotFound) {
-			RespondNotFound(w, InternalServerErrorCode, errors.New("cart not found"))
-			return
-		}
-		RespondInternalServerError(w, InternalServerErrorCode, err)
-		return
-	}
-	var shippingAddr repository.ShippingAddressSnapshot
-
 	address, err := s.repo.GetDefaultAddress(c, userID)
 	if err != nil {
 		if errors.Is(err, repository.ErrRecordNotFound) {
@@ -86,107 +88,289 @@ func (s *Server) checkout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	shippingAddr = repository.ShippingAddressSnapshot{
-		Street:   address.Street,
-		Ward:     *address.Ward,
-		District: address.District,
-		City:     address.City,
-		Phone:    address.PhoneNumber,
+	args := checkoutArgs{
+		UserID: utils.GetPgTypeUUID(userID),
+		Owner:  repository.GetCartParams{UserID: utils.GetPgTypeUUID(userID)},
+		User:   user,
+		Customer: repository.CustomerInfoTxArgs{
+			FullName: user.FirstName + " " + user.LastName,
+			Email:    user.Email,
+			Phone:    user.PhoneNumber,
+		},
+		Address:         address,
+		PaymentMethodID: req.PaymentMethodId,
+		ShippingRateID:  req.ShippingRateId,
+		DiscountCodes:   req.DiscountCodes,
+		Currency:        req.Currency,
 	}
 
-	if cart.UserID.Valid {
-		cartUserId, err := uuid.FromBytes(cart.UserID.Bytes[:])
+	if req.SavedPaymentMethodId != nil {
+		savedMethod, err := s.repo.GetUserPaymentInfo(c, repository.GetUserPaymentInfoParams{
+			ID:     uuid.MustParse(*req.SavedPaymentMethodId),
+			UserID: userID,
+		})
 		if err != nil {
-			log.Error().Err(err).Msg("GetCart")
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				RespondBadRequest(w, InvalidPaymentCode, errors.New("saved payment method not found"))
+				return
+			}
 			RespondInternalServerError(w, InternalServerErrorCode, err)
 			return
 		}
-		if cartUserId != userID {
-			RespondForbidden(w, PermissionDeniedCode, errors.New("you are not allowed to access this cart"))
+		if repository.IsPaymentInfoExpired(savedMethod, time.Now()) {
+			RespondBadRequest(w, InvalidPaymentCode, errors.New("saved card has expired"))
+			return
+		}
+		customer, err := s.repo.GetPaymentCustomer(c, repository.GetPaymentCustomerParams{UserID: userID, Gateway: savedMethod.Gateway})
+		if err != nil {
+			RespondInternalServerError(w, InternalServerErrorCode, err)
 			return
 		}
+		args.SavedMethod = &savedMethod
+		args.PaymentCustomerID = customer.CustomerID
+	}
+
+	rs, ok := s.placeOrder(w, c, args)
+	if !ok {
+		return
+	}
+
+	RespondSuccess(w, rs)
+}
+
+// @Summary Checkout the guest cart
+// @Schemes http
+// @Description place an order for the guest cart without an account. The buyer gets an email with a link to look the order up.
+// @Tags carts
+// @Accept json
+// @Param input body models.GuestCheckoutModel true "Guest checkout input"
+// @Produce json
+// @Success 200 {object} dto.ApiResponse[repository.CreatePaymentResult]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /carts/guest-checkout [post]
+func (s *Server) guestCheckout(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	sessionID, ok := s.getCartSessionID(r)
+	if !ok {
+		RespondNotFound(w, NotFoundCode, errors.New("cart not found"))
+		return
+	}
+
+	var req models.GuestCheckoutModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	rs, ok := s.placeOrder(w, c, checkoutArgs{
+		Owner: repository.GetCartParams{SessionID: &sessionID},
+		Customer: repository.CustomerInfoTxArgs{
+			FullName: req.FullName,
+			Email:    req.Email,
+			Phone:    req.Phone,
+		},
+		Address:         addressFromModel(req.Address),
+		PaymentMethodID: req.PaymentMethodId,
+		ShippingRateID:  req.ShippingRateId,
+		Currency:        req.Currency,
+	})
+	if !ok {
+		return
+	}
+
+	err := s.taskDistributor.SendGuestOrderLookupEmail(c,
+		&worker.PayloadSendGuestOrderLookupEmail{OrderID: rs.OrderID},
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueDefault))
+	if err != nil {
+		log.Error().Err(err).Str("orderID", rs.OrderID.String()).Msg("SendGuestOrderLookupEmail")
+	}
+
+	RespondSuccess(w, rs)
+}
+
+// placeOrder turns the owner's cart into an order and creates its payment. It writes the
+// error response itself and reports false when the order couldn't be placed.
+func (s *Server) placeOrder(w http.ResponseWriter, c context.Context, args checkoutArgs) (repository.CreatePaymentResult, bool) {
+	var rs repository.CreatePaymentResult
+	cart, err := s.repo.GetCart(c, args.Owner)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, errors.New("cart not found"))
+			return rs, false
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return rs, false
 	}
 
 	itemRows, err := s.repo.GetCartItems(c, cart.ID)
 	if err != nil {
 		log.Error().Err(err).Msg("GetCartItems")
-		return
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return rs, false
+	}
+	if len(itemRows) == 0 {
+		RespondBadRequest(w, InvalidBodyCode, errors.New("cart is empty"))
+		return rs, false
+	}
+
+	// prices are kept in the store currency and converted to the one the customer pays in
+	converter, err := s.currencyProcessor.Converter(c, args.Currency)
+	if err != nil {
+		if errors.Is(err, processors.ErrCurrencyNotSupported) {
+			RespondBadRequest(w, InvalidCurrencyCode, err)
+			return rs, false
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return rs, false
 	}
 
 	// Process discounts
-	discountResult, err := s.discountProcessor.ProcessDiscounts(c, processors.DiscountContext{User: user, CartItems: itemRows}, req.DiscountCodes)
+	discountResult, err := s.discountProcessor.ProcessDiscounts(c, processors.DiscountContext{User: args.User, CartItems: itemRows, Currency: s.currencyProcessor.StoreCurrency()}, args.DiscountCodes)
 	if err != nil {
 		log.Error().Err(err).Msg("ProcessDiscounts")
 		RespondBadRequest(w, InvalidBodyCode, err)
-		return
+		return rs, false
+	}
+
+	var shipping *repository.ShippingTxArgs
+	if args.ShippingRateID != nil {
+		quote, err := s.shippingProcessor.QuoteRate(c, processors.ShippingContext{Address: args.Address, CartItems: itemRows, Currency: s.currencyProcessor.StoreCurrency()}, uuid.MustParse(*args.ShippingRateID))
+		if err != nil {
+			if errors.Is(err, processors.ErrShippingZoneNotFound) || errors.Is(err, processors.ErrShippingRateNotFound) {
+				RespondBadRequest(w, InvalidShippingCode, err)
+				return rs, false
+			}
+			RespondInternalServerError(w, InternalServerErrorCode, err)
+			return rs, false
+		}
+		shipping = &repository.ShippingTxArgs{
+			RateID:     quote.RateID,
+			MethodID:   quote.MethodID,
+			MethodName: quote.MethodName,
+			Fee:        converter.Convert(quote.Fee),
+		}
 	}
 
 	// Calculate totals and create order items
-	var totalPrice float64
+	totalPrice := payment.NewMoney(0, converter.Currency)
+	totalDiscount := payment.NewMoney(0, converter.Currency)
 	createOrderItemParams := make([]repository.CreateBulkOrderItemsParams, len(itemRows))
 
 	for i, item := range itemRows {
-		itemPrice, _ := item.VariantPrice.Float64Value()
-		lineTotal := float64(item.CartItem.Quantity) * itemPrice.Float64
-		totalPrice += lineTotal
+		unitPrice := converter.Convert(utils.GetMoneyFromPgNumeric(item.VariantPrice, s.currencyProcessor.StoreCurrency()))
+		lineTotal := unitPrice.Mul(int64(item.CartItem.Quantity))
+		totalPrice = totalPrice.Add(lineTotal)
 
 		// Find discount for this item
-		discountAmount := 0.0
+		discountAmount := payment.NewMoney(0, converter.Currency)
 		if item.ProductDiscountPercentage != nil {
-			discountAmount = lineTotal * (float64(*item.ProductDiscountPercentage) / 100)
+			discountAmount = lineTotal.Percent(float64(*item.ProductDiscountPercentage), payment.RoundHalfUp)
 		}
 
-		// Add applied discounts
+		// Add applied discounts, each converted once so the lines add up to the order discount
 		for _, itemDiscount := range discountResult.ItemDiscounts {
 			if itemDiscount.ItemIndex == i {
-				discountAmount += itemDiscount.DiscountAmount
+				converted := converter.Convert(itemDiscount.DiscountAmount)
+				discountAmount = discountAmount.Add(converted)
+				totalDiscount = totalDiscount.Add(converted)
 			}
 		}
 
 		createOrderItemParams[i] = repository.CreateBulkOrderItemsParams{
 			VariantID:            item.CartItem.VariantID,
 			Quantity:             item.CartItem.Quantity,
-			PricePerUnitSnapshot: item.VariantPrice,
+			PricePerUnitSnapshot: utils.GetPgNumericFromMoney(unitPrice),
 			VariantSkuSnapshot:   item.VariantSku,
 			ProductNameSnapshot:  item.ProductName,
-			LineTotalSnapshot:    utils.GetPgNumericFromFloat(lineTotal),
-			DiscountedPrice:      utils.GetPgNumericFromFloat(discountAmount),
+			LineTotalSnapshot:    utils.GetPgNumericFromMoney(lineTotal),
+			DiscountedPrice:      utils.GetPgNumericFromMoney(discountAmount),
 		}
 
 		err := json.Unmarshal(item.Attributes, &createOrderItemParams[i].AttributesSnapshot)
 		if err != nil {
 			log.Error().Err(err).Msg("Unmarshal attributes")
 			RespondInternalServerError(w, InternalServerErrorCode, err)
-			return
+			return rs, false
 		}
 	}
 
+	paymentAmount := totalPrice.Sub(totalDiscount.Min(totalPrice))
+	if shipping != nil {
+		paymentAmount = paymentAmount.Add(shipping.Fee)
+	}
+	method, err := s.repo.GetPaymentMethodByID(c, uuid.MustParse(args.PaymentMethodID))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondBadRequest(w, InvalidPaymentCode, errors.New("payment method not found"))
+			return rs, false
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return rs, false
+	}
+	if args.SavedMethod != nil && (repository.IsOfflinePaymentMethod(method) || method.Code != args.SavedMethod.Gateway) {
+		RespondBadRequest(w, InvalidPaymentCode, errors.New("saved card can't be used with this payment method"))
+		return rs, false
+	}
+	// limits and fees of payment methods are set in the store currency
+	storeAmount := converter.ToStore(paymentAmount)
+	if paymentAmount.IsPositive() {
+		check := repository.PaymentMethodCheck{
+			Amount:   storeAmount,
+			Currency: string(converter.Currency),
+		}
+		if args.Address.Country != nil {
+			check.Country = *args.Address.Country
+		}
+		if err := repository.CheckPaymentMethod(method, check); err != nil {
+			RespondBadRequest(w, InvalidPaymentCode, err)
+			return rs, false
+		}
+	}
+	reservationTTL := s.config.InventoryReservationTTL
+	if method.Code == repository.PaymentMethodBankTransfer {
+		// the stock is held until the transfer is due
+		reservationTTL = s.config.BankTransferPaymentTTL
+	}
+
 	params := repository.CheckoutCartTxArgs{
-		CartID:          cart.ID,
-		TotalPrice:      totalPrice,
-		ShippingAddress: shippingAddr,
-		UserID:          userID,
-		CustomerInfo: repository.CustomerInfoTxArgs{
-			FullName: user.FirstName + " " + user.LastName,
-			Email:    user.Email,
-			Phone:    user.PhoneNumber,
-		},
+		CartID:                cart.ID,
+		TotalPrice:            totalPrice,
+		ShippingAddress:       shippingAddressSnapshot(args.Address),
+		Shipping:              shipping,
+		UserID:                args.UserID,
+		CustomerInfo:          args.Customer,
 		CreateOrderItemParams: createOrderItemParams,
-		DiscountPrice:         discountResult.TotalDiscount,
+		DiscountPrice:         totalDiscount,
 		DiscountIDs:           discountResult.AppliedDiscounts,
-		PaymentMethodID:       uuid.MustParse(req.PaymentMethodId),
+		PaymentMethodID:       uuid.MustParse(args.PaymentMethodID),
+		ReservationExpiresAt:  time.Now().Add(reservationTTL),
+		ProcessingFee:         converter.Convert(repository.ProcessingFee(method, storeAmount)),
 	}
 
 	params.CreatePaymentFn = func(ctx context.Context, orderID uuid.UUID, method string) (paymentIntentID string, clientSecretID *string, err error) {
 		// create payment intent
-		intent, err := s.paymentSrv.CreatePaymentIntent(ctx, method, payment.PaymentRequest{
-			Amount:   int64((totalPrice - discountResult.TotalDiscount) * 100), // convert to cents
-			Currency: "usd",
-			Email:    user.Email,
+		req := payment.PaymentRequest{
+			Amount:   paymentAmount.Amount,
+			Currency: paymentAmount.Currency,
+			Email:    args.Customer.Email,
 			Metadata: map[string]string{
 				"OrderID": orderID.String(),
 			},
-		})
+			// every checkout creates its own order, a retried request finds the same payment
+			IdempotencyKey: "order-" + orderID.String(),
+		}
+		if s.config.PaymentCaptureMethod == "manual" {
+			// the money is only held here, it's captured when the order ships
+			req.ManualCapture = true
+		}
+		if args.SavedMethod != nil {
+			req.CustomerID = args.PaymentCustomerID
+			req.PaymentMethodID = args.SavedMethod.PaymentMethodToken
+		}
+		intent, err := s.paymentSrv.CreatePaymentIntent(ctx, method, req)
 
 		if err != nil {
 			log.Error().Err(err).Msg("CreatePaymentIntent")
@@ -195,11 +379,63 @@ func (s *Server) checkout(w http.ResponseWriter, r *http.Request) {
 		return intent.ID, &intent.ClientSecret, nil
 	}
 
-	rs, err := s.repo.CheckoutCartTx(c, params)
+	rs, err = s.repo.CheckoutCartTx(c, params)
 	if err != nil {
+		var stockErr *repository.InsufficientStockError
+		if errors.As(err, &stockErr) {
+			RespondBadRequest(w, InsufficientStockCode, stockErr)
+			return rs, false
+		}
 		RespondInternalServerError(w, InternalServerErrorCode, err)
-		return
+		return rs, false
 	}
 
-	RespondSuccess(w, rs)
+	// gateway payments confirm the order from the webhook, offline ones have nothing to wait for
+	if repository.IsOfflinePaymentMethod(method) && rs.Status == repository.PaymentStatusPending {
+		err := s.taskDistributor.SendOrderCreatedEmailTask(c,
+			&worker.PayloadSendOrderCreatedEmailTask{PaymentID: uuid.MustParse(rs.PaymentID)},
+			asynq.MaxRetry(10),
+			asynq.ProcessIn(time.Second*3),
+			asynq.Queue(worker.QueueDefault))
+		if err != nil {
+			log.Error().Err(err).Str("orderID", rs.OrderID.String()).Msg("SendOrderCreatedEmailTask")
+		}
+	}
+	return rs, true
+}
+
+// addressFromModel builds the address a guest ships to. It is never stored as a user address.
+func addressFromModel(req models.CreateAddress) repository.UserAddress {
+	return repository.UserAddress{
+		PhoneNumber: req.Phone,
+		Street:      req.Street,
+		Ward:        req.Ward,
+		District:    req.District,
+		City:        req.City,
+		Country:     req.Country,
+		State:       req.State,
+		ZipCode:     req.ZipCode,
+	}
+}
+
+func shippingAddressSnapshot(address repository.UserAddress) repository.ShippingAddressSnapshot {
+	shippingAddr := repository.ShippingAddressSnapshot{
+		Street:   address.Street,
+		District: address.District,
+		City:     address.City,
+		Phone:    address.PhoneNumber,
+	}
+	if address.Ward != nil {
+		shippingAddr.Ward = *address.Ward
+	}
+	if address.Country != nil {
+		shippingAddr.Country = *address.Country
+	}
+	if address.State != nil {
+		shippingAddr.State = *address.State
+	}
+	if address.ZipCode != nil {
+		shippingAddr.ZipCode = *address.ZipCode
+	}
+	return shippingAddr
 }
@@ -22,6 +22,16 @@ const (
 	InvalidTransactionCode  = "invalid_transaction"
 	InvalidProductCode      = "invalid_product"
 	InvalidOrderCode        = "invalid_order"
+	InsufficientStockCode   = "insufficient_stock"
+	InvalidShippingCode     = "invalid_shipping"
+	InvalidShipmentCode     = "invalid_shipment"
+	InvalidRefundCode       = "invalid_refund"
+	InvalidReturnCode       = "invalid_return"
+	InvalidCurrencyCode     = "invalid_currency"
+	AccountLockedCode       = "account_locked"
+	InvalidRoleCode         = "invalid_role"
+	MfaRequiredCode         = "mfa_required"
+	InvalidMfaCode          = "invalid_mfa"
 )
 
 const (
@@ -103,7 +103,7 @@ func (s *Server) checkDiscountsApplicability(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	discountResult, err := s.discountProcessor.ProcessDiscounts(c, processors.DiscountContext{User: user, CartItems: itemRows}, req.DiscountCodes)
+	discountResult, err := s.discountProcessor.ProcessDiscounts(c, processors.DiscountContext{User: user, CartItems: itemRows, Currency: s.currencyProcessor.StoreCurrency()}, req.DiscountCodes)
 	if err != nil {
 		log.Error().Err(err).Msg("ProcessDiscounts")
 		RespondBadRequest(w, InvalidBodyCode, err)
@@ -0,0 +1,454 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	repository "github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
+	"github.com/thanhphuocnguyen/go-eshop/internal/models"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/auth"
+	cache "github.com/thanhphuocnguyen/go-eshop/pkg/cache"
+)
+
+const (
+	// mfaLoginDuration is how long a login waits for its second factor
+	mfaLoginDuration = 5 * time.Minute
+	// maxMfaLoginAttempts is how many wrong codes a login waiting for its second factor survives
+	maxMfaLoginAttempts = 5
+	recoveryCodeCount   = 10
+)
+
+// mfaLogin is a login waiting for its second factor, cached under the hash of its token
+type mfaLogin struct {
+	UserID    uuid.UUID `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// getMfaStatus godoc
+// @Summary Get two-factor authentication status
+// @Description Tell whether the current user signs in with a second factor, and whether their role requires it
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} dto.ApiResponse[dto.MfaStatus]
+// @Failure 401 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/me/mfa [get]
+// @Security BearerAuth
+func (s *Server) getMfaStatus(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	var resp dto.MfaStatus
+	resp.Required, err = s.isMfaRequired(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	mfa, err := s.repo.GetUserMfa(c, userID)
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if err == nil && mfa.EnabledAt.Valid {
+		resp.Enabled = true
+		resp.EnabledAt = &mfa.EnabledAt.Time
+		resp.RecoveryCodesLeft, err = s.repo.CountUnusedRecoveryCodes(c, userID)
+		if err != nil {
+			RespondInternalServerError(w, InternalServerErrorCode, err)
+			return
+		}
+	}
+	RespondSuccess(w, resp)
+}
+
+// enrollTotp godoc
+// @Summary Start setting up an authenticator app
+// @Description Create the secret of an authenticator app, provisioningUri is meant to be shown as a QR code. Two-factor
+// @Description authentication is only turned on once a code of the app is verified. Starting over replaces the secret.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} dto.ApiResponse[dto.MfaEnrollment]
+// @Failure 401 {object} ErrorResp
+// @Failure 409 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/me/mfa/totp [post]
+// @Security BearerAuth
+func (s *Server) enrollTotp(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+	user, err := s.repo.GetUserByID(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	encrypted, err := auth.EncryptTOTPSecret(s.config.SymmetricKey, secret)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	_, err = s.repo.UpsertUserMfa(c, repository.UpsertUserMfaParams{
+		UserID: userID,
+		Secret: encrypted,
+	})
+	if err != nil {
+		// an enabled secret is kept by the upsert, so nothing comes back
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondError(w, http.StatusConflict, ConflictCode, errors.New("two-factor authentication is already enabled"))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, dto.MfaEnrollment{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(s.config.MfaIssuer, user.Email, secret),
+	})
+}
+
+// verifyTotpEnrollment godoc
+// @Summary Turn on two-factor authentication
+// @Description Verify a code of the authenticator app set up at /users/me/mfa/totp, which turns two-factor authentication on.
+// @Description The recovery codes are only shown in this response. Sign in again for the back office to count the
+// @Description second factor.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param input body models.MfaCodeModel true "Authenticator code"
+// @Success 200 {object} dto.ApiResponse[dto.MfaRecoveryCodes]
+// @Failure 400 {object} ErrorResp
+// @Failure 401 {object} ErrorResp
+// @Failure 409 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/me/mfa/totp/verify [post]
+// @Security BearerAuth
+func (s *Server) verifyTotpEnrollment(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	var req models.MfaCodeModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	mfa, err := s.repo.GetUserMfa(c, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondBadRequest(w, InvalidMfaCode, errors.New("set up an authenticator app first"))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if mfa.EnabledAt.Valid {
+		RespondError(w, http.StatusConflict, ConflictCode, errors.New("two-factor authentication is already enabled"))
+		return
+	}
+	secret, err := auth.DecryptTOTPSecret(s.config.SymmetricKey, mfa.Secret)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	step, ok := auth.ValidateTOTP(secret, strings.TrimSpace(req.Code), time.Now(), mfa.LastUsedStep)
+	if !ok {
+		RespondBadRequest(w, InvalidMfaCode, errors.New("code is not valid"))
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	err = s.repo.EnableMfaTx(c, repository.EnableMfaTxArgs{
+		UserID:             userID,
+		Step:               step,
+		RecoveryCodeHashes: hashes,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidMfa) {
+			RespondBadRequest(w, InvalidMfaCode, err)
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, dto.MfaRecoveryCodes{RecoveryCodes: codes})
+}
+
+// regenerateRecoveryCodes godoc
+// @Summary Replace recovery codes
+// @Description Replace the recovery codes of the current user, the old ones stop working. Takes a code of the
+// @Description authenticator app or a recovery code.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param input body models.MfaCodeModel true "Authenticator or recovery code"
+// @Success 200 {object} dto.ApiResponse[dto.MfaRecoveryCodes]
+// @Failure 400 {object} ErrorResp
+// @Failure 401 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/me/mfa/recovery-codes [post]
+// @Security BearerAuth
+func (s *Server) regenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	var req models.MfaCodeModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	ok, err := s.verifyMfaCode(c, userID, req.Code)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if !ok {
+		RespondBadRequest(w, InvalidMfaCode, errors.New("code is not valid"))
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	err = s.repo.ReplaceRecoveryCodesTx(c, repository.ReplaceRecoveryCodesTxArgs{
+		UserID:             userID,
+		RecoveryCodeHashes: hashes,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccess(w, dto.MfaRecoveryCodes{RecoveryCodes: codes})
+}
+
+// disableMfa godoc
+// @Summary Turn off two-factor authentication
+// @Description Turn off two-factor authentication with the password and a code of the authenticator app or a
+// @Description recovery code. Not allowed when the role of the user requires it.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param input body models.DisableMfaModel true "Password and code"
+// @Success 204 {object} nil
+// @Failure 400 {object} ErrorResp
+// @Failure 401 {object} ErrorResp
+// @Failure 403 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/me/mfa [delete]
+// @Security BearerAuth
+func (s *Server) disableMfa(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	var req models.DisableMfaModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	required, err := s.isMfaRequired(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if required {
+		RespondForbidden(w, MfaRequiredCode, errors.New("your role requires two-factor authentication"))
+		return
+	}
+	user, err := s.repo.GetUserByID(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if err := auth.ComparePwd(req.Password, user.HashedPassword); err != nil {
+		RespondBadRequest(w, InvalidPasswordCode, errors.New("password is incorrect"))
+		return
+	}
+	ok, err := s.verifyMfaCode(c, userID, req.Code)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if !ok {
+		RespondBadRequest(w, InvalidMfaCode, errors.New("code is not valid"))
+		return
+	}
+
+	err = s.repo.DisableMfaTx(c, repository.DisableMfaTxArgs{
+		UserID:        userID,
+		UserAuditArgs: repository.UserAuditArgs{ActorID: userID},
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidMfa) {
+			RespondBadRequest(w, InvalidMfaCode, err)
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondNoContent(w)
+}
+
+// verifyMfaCode checks a code of the authenticator app or a recovery code of a user with two-factor
+// authentication on. Either works once.
+func (s *Server) verifyMfaCode(c context.Context, userID uuid.UUID, code string) (bool, error) {
+	mfa, err := s.repo.GetUserMfa(c, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !mfa.EnabledAt.Valid {
+		return false, nil
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == auth.TOTPDigits {
+		secret, err := auth.DecryptTOTPSecret(s.config.SymmetricKey, mfa.Secret)
+		if err != nil {
+			return false, err
+		}
+		step, ok := auth.ValidateTOTP(secret, code, time.Now(), mfa.LastUsedStep)
+		if !ok {
+			return false, nil
+		}
+		// the step is only taken by one request, so a code can't be replayed meanwhile
+		used, err := s.repo.UseUserMfaStep(c, repository.UseUserMfaStepParams{
+			UserID:       userID,
+			LastUsedStep: step,
+		})
+		return used > 0, err
+	}
+
+	used, err := s.repo.UseRecoveryCode(c, repository.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: auth.HashRecoveryCode(code),
+	})
+	if err == nil && used > 0 {
+		log.Info().Str("userID", userID.String()).Msg("signed in with a recovery code")
+	}
+	return used > 0, err
+}
+
+// isMfaRequired tells whether the role of the user requires two-factor authentication
+func (s *Server) isMfaRequired(c context.Context, userID uuid.UUID) (bool, error) {
+	user, err := s.repo.GetUserByID(c, userID)
+	if err != nil {
+		return false, err
+	}
+	role, err := s.repo.GetRoleByID(c, user.RoleID)
+	if err != nil {
+		return false, err
+	}
+	return role.RequireMfa, nil
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+func mfaLoginCacheKey(tokenHash string) string {
+	return cache.MFA_KEY_PREFIX + "login:" + tokenHash
+}
+
+// mfaLoginAttemptsKey is the counter of wrong codes of the login cached under key
+func mfaLoginAttemptsKey(key string) string {
+	return key + ":attempts"
+}
+
+// startMfaLogin keeps a login waiting for its second factor and returns the token to finish it with
+func (s *Server) startMfaLogin(c context.Context, userID uuid.UUID) (string, time.Time, error) {
+	token, hash, err := auth.GenerateMfaToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	pending := mfaLogin{UserID: userID, ExpiresAt: time.Now().Add(mfaLoginDuration)}
+	ttl := mfaLoginDuration
+	if err := s.cacheSrv.Set(c, mfaLoginCacheKey(hash), pending, &ttl); err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to keep mfa login: %w", err)
+	}
+	return token, pending.ExpiresAt, nil
+}
+
+// failMfaLogin counts a wrong code, the login is dropped after too many. The counter is
+// incremented atomically so wrong codes sent at the same time are all counted.
+func (s *Server) failMfaLogin(c context.Context, key string, pending mfaLogin) {
+	ttl := time.Until(pending.ExpiresAt)
+	if ttl <= 0 {
+		s.endMfaLogin(c, key)
+		return
+	}
+	attempts, err := s.cacheSrv.Incr(c, mfaLoginAttemptsKey(key), &ttl)
+	if err != nil {
+		// a login whose wrong codes can't be counted isn't given more tries
+		log.Error().Err(err).Str("userID", pending.UserID.String()).Msg("failed to count mfa login attempt")
+		s.endMfaLogin(c, key)
+		return
+	}
+	if attempts >= maxMfaLoginAttempts {
+		s.endMfaLogin(c, key)
+	}
+}
+
+func (s *Server) endMfaLogin(c context.Context, key string) {
+	for _, k := range []string{key, mfaLoginAttemptsKey(key)} {
+		if err := s.cacheSrv.Delete(c, k); err != nil {
+			log.Error().Err(err).Msg("failed to end mfa login")
+		}
+	}
+}
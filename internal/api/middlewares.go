@@ -1,46 +1,282 @@
 package api
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 	"github.com/go-chi/jwtauth/v5"
-	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/constants"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	cache "github.com/thanhphuocnguyen/go-eshop/pkg/cache"
 )
 
-func authorizeMiddleware(next http.Handler, roles ...string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		_, claims, err := jwtauth.FromContext(r.Context())
+// Access is a permission flag of the permissions table
+type Access string
+
+const (
+	Read    Access = "read"
+	Write   Access = "write"
+	Execute Access = "execute"
+)
+
+const (
+	// BackOfficeModule is the permission module that lets a role into the admin routes at all
+	BackOfficeModule = "admin"
+	// RolesModule guards editing roles and their permissions
+	RolesModule = "roles"
+)
+
+// rolePermissions is what is cached of a role to check permissions
+type rolePermissions struct {
+	IsActive    bool                    `json:"isActive"`
+	RequireMfa  bool                    `json:"requireMfa"`
+	Permissions []repository.Permission `json:"permissions"`
+}
+
+func (p rolePermissions) allows(module string, access Access) bool {
+	if !p.IsActive {
+		return false
+	}
+	for _, perm := range p.Permissions {
+		if perm.Module != module {
+			continue
+		}
+		switch access {
+		case Read:
+			return perm.R
+		case Write:
+			return perm.W
+		case Execute:
+			return perm.X
+		}
+	}
+	return false
+}
+
+// authorizeMiddleware lets staff into the back office, that is roles allowed to read the admin
+// module, signed in with a second factor when their role requires it. What they can do there is
+// checked per route by RequirePermission.
+func (s *Server) authorizeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, perms, ok := s.getRequestPermissions(w, r)
+		if !ok {
+			return
+		}
+		if !perms.allows(BackOfficeModule, Read) {
+			RespondForbidden(w, PermissionDeniedCode, fmt.Errorf("%s access to %s is required", Read, BackOfficeModule))
+			return
+		}
+		if mfa, _ := claims["mfa"].(bool); perms.RequireMfa && !mfa {
+			RespondForbidden(w, MfaRequiredCode, errors.New("sign in with two-factor authentication to use the back office"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequirePermission rejects requests of users whose role lacks the access to the module
+func (s *Server) RequirePermission(module string, access Access) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, perms, ok := s.getRequestPermissions(w, r)
+			if !ok {
+				return
+			}
+			if !perms.allows(module, access) {
+				RespondForbidden(w, PermissionDeniedCode, fmt.Errorf("%s access to %s is required", access, module))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// getRequestPermissions returns the claims of the request and the permissions of their role,
+// it responds itself when there are none
+func (s *Server) getRequestPermissions(w http.ResponseWriter, r *http.Request) (map[string]interface{}, rolePermissions, bool) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return nil, rolePermissions{}, false
+	}
+	roleID, err := uuid.Parse(fmt.Sprint(claims["roleId"]))
+	if err != nil {
+		RespondUnauthorized(w, InvalidTokenCode, errors.New("token has no role"))
+		return nil, rolePermissions{}, false
+	}
+
+	perms, err := s.getRolePermissions(c, roleID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondForbidden(w, PermissionDeniedCode, errors.New("role no longer exists"))
+			return nil, perms, false
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return nil, perms, false
+	}
+	return claims, perms, true
+}
+
+func rolePermissionsCacheKey(roleID uuid.UUID) string {
+	return cache.ROLE_KEY_PREFIX + roleID.String() + ":permissions"
+}
+
+// getRolePermissions is read on every admin request, so it is cached until the role or its
+// permissions are edited
+func (s *Server) getRolePermissions(c context.Context, roleID uuid.UUID) (rolePermissions, error) {
+	var perms rolePermissions
+	if err := s.cacheSrv.Get(c, rolePermissionsCacheKey(roleID), &perms); err == nil {
+		return perms, nil
+	}
+	role, err := s.repo.GetRoleByID(c, roleID)
+	if err != nil {
+		return perms, err
+	}
+	perms.IsActive = role.IsActive
+	perms.RequireMfa = role.RequireMfa
+	perms.Permissions, err = s.repo.GetRolePermissions(c, roleID)
+	if err != nil {
+		return perms, err
+	}
+	if err := s.cacheSrv.Set(c, rolePermissionsCacheKey(roleID), perms, nil); err != nil {
+		log.Error().Err(err).Str("roleID", roleID.String()).Msg("failed to cache role permissions")
+	}
+	return perms, nil
+}
+
+func (s *Server) invalidateRolePermissions(c context.Context, roleID uuid.UUID) {
+	if err := s.cacheSrv.Delete(c, rolePermissionsCacheKey(roleID)); err != nil {
+		log.Error().Err(err).Str("roleID", roleID.String()).Msg("failed to invalidate role permissions")
+	}
+}
+
+// revokedTokenMiddleware rejects access tokens issued before the user last changed their
+// password or staff revoked them, and those of a session that was signed out, so all of it
+// takes effect on every device at once and not only when the refresh token is next used.
+func (s *Server) revokedTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := r.Context()
+		token, claims, err := jwtauth.FromContext(c)
+		if err != nil || token == nil {
+			RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+			return
+		}
+		userID, err := uuid.Parse(fmt.Sprint(claims["userId"]))
 		if err != nil {
-			err := dto.CreateErr(UnauthorizedCode, fmt.Errorf("unauthorized"))
-			w.WriteHeader(http.StatusUnauthorized)
-			jsoResp, _ := json.Marshal(err)
-			w.Write(jsoResp)
+			RespondUnauthorized(w, InvalidTokenCode, errors.New("token has no user"))
 			return
 		}
 
-		hasRole := false
-		for _, role := range roles {
-			if claims["roleCode"] == role {
-				hasRole = true
-				break
+		changedAt, err := s.getPasswordChangedAt(c, userID)
+		if err != nil {
+			if errors.Is(err, repository.ErrRecordNotFound) {
+				RespondUnauthorized(w, InvalidTokenCode, errors.New("user no longer exists"))
+				return
 			}
+			RespondInternalServerError(w, InternalServerErrorCode, err)
+			return
+		}
+		// iat only keeps seconds
+		if token.IssuedAt().Before(changedAt.Truncate(time.Second)) {
+			RespondUnauthorized(w, InvalidTokenCode, errors.New("token was issued before the password was changed"))
+			return
 		}
-		if !hasRole {
-			err := dto.CreateErr(UnauthorizedCode, fmt.Errorf("forbidden"))
-			w.WriteHeader(http.StatusForbidden)
-			jsoResp, _ := json.Marshal(err)
-			w.Write(jsoResp)
+		if revokedAt, ok := s.getAccessRevokedAt(c, userID); ok && token.IssuedAt().Before(revokedAt.Truncate(time.Second)) {
+			RespondUnauthorized(w, InvalidTokenCode, errors.New("token was revoked"))
+			return
+		}
+		if sessionID, err := uuid.Parse(fmt.Sprint(claims["sessionId"])); err == nil && s.isSessionRevoked(c, sessionID) {
+			RespondUnauthorized(w, InvalidSessionCode, errors.New("session was signed out"))
 			return
 		}
 		next.ServeHTTP(w, r)
+	})
+}
+
+// optionalRevokedTokenMiddleware runs revokedTokenMiddleware on requests that carry a token, guests
+// go through as they are
+func (s *Server) optionalRevokedTokenMiddleware(next http.Handler) http.Handler {
+	checked := s.revokedTokenMiddleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token, _, err := jwtauth.FromContext(r.Context()); err != nil || token == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		checked.ServeHTTP(w, r)
+	})
+}
+
+func passwordChangedAtCacheKey(userID uuid.UUID) string {
+	return cache.USER_KEY_PREFIX + userID.String() + ":password_changed_at"
+}
+
+// getPasswordChangedAt is read on every authenticated request, so it is cached
+func (s *Server) getPasswordChangedAt(c context.Context, userID uuid.UUID) (time.Time, error) {
+	var changedAt time.Time
+	if err := s.cacheSrv.Get(c, passwordChangedAtCacheKey(userID), &changedAt); err == nil {
+		return changedAt, nil
+	}
+	user, err := s.repo.GetUserByID(c, userID)
+	if err != nil {
+		return changedAt, err
+	}
+	s.cachePasswordChangedAt(c, userID, user.PasswordChangedAt)
+	return user.PasswordChangedAt, nil
+}
+
+// cachePasswordChangedAt overwrites rather than deletes the cached time, so a request racing
+// the change can't put the old one back
+func (s *Server) cachePasswordChangedAt(c context.Context, userID uuid.UUID, changedAt time.Time) {
+	if err := s.cacheSrv.Set(c, passwordChangedAtCacheKey(userID), changedAt, nil); err != nil {
+		log.Error().Err(err).Str("userID", userID.String()).Msg("failed to cache password changed at")
+	}
+}
+
+func accessRevokedAtCacheKey(userID uuid.UUID) string {
+	return cache.USER_KEY_PREFIX + userID.String() + ":access_revoked_at"
+}
+
+// revokeUserAccess rejects the access tokens a user has now, for example when the account is
+// locked or the role changes. Tokens issued later work, and no older one outlives the cache.
+func (s *Server) revokeUserAccess(c context.Context, userID uuid.UUID) {
+	if err := s.cacheSrv.Set(c, accessRevokedAtCacheKey(userID), time.Now(), &s.config.AccessTokenDuration); err != nil {
+		log.Error().Err(err).Str("userID", userID.String()).Msg("failed to cache revoked access")
 	}
 }
 
+func (s *Server) getAccessRevokedAt(c context.Context, userID uuid.UUID) (time.Time, bool) {
+	var revokedAt time.Time
+	return revokedAt, s.cacheSrv.Get(c, accessRevokedAtCacheKey(userID), &revokedAt) == nil
+}
+
+func revokedSessionCacheKey(sessionID uuid.UUID) string {
+	return cache.SESSION_KEY_PREFIX + sessionID.String() + ":revoked"
+}
+
+// revokeSessionAccess marks a session family signed out until its access tokens expire, the
+// refresh tokens are blocked in the database
+func (s *Server) revokeSessionAccess(c context.Context, sessionIDs ...uuid.UUID) {
+	for _, id := range sessionIDs {
+		if err := s.cacheSrv.Set(c, revokedSessionCacheKey(id), true, &s.config.AccessTokenDuration); err != nil {
+			log.Error().Err(err).Str("sessionID", id.String()).Msg("failed to cache revoked session")
+		}
+	}
+}
+
+func (s *Server) isSessionRevoked(c context.Context, sessionID uuid.UUID) bool {
+	var revoked bool
+	return s.cacheSrv.Get(c, revokedSessionCacheKey(sessionID), &revoked) == nil && revoked
+}
+
 // Setup environment mode based on configuration
 func (s *Server) registerMiddlewares(r *chi.Mux) {
 	// Add server state validation middleware
@@ -52,8 +288,8 @@ func (s *Server) registerMiddlewares(r *chi.Mux) {
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "PUT", "POST", "DELETE", "HEAD", "OPTION"},
-		AllowedHeaders:   []string{"User-Agent", "Content-Type", "Accept", "Accept-Encoding", "Accept-Language", "Cache-Control", "Connection", "DNT", "Host", "Origin", "Pragma", "Referer"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"User-Agent", "Content-Type", "Accept", "Accept-Encoding", "Accept-Language", "Cache-Control", "Connection", "DNT", "Host", "Origin", "Pragma", "Referer", constants.CartSessionHeader},
+		ExposedHeaders:   []string{"Link", constants.CartSessionHeader},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
@@ -1,19 +1,21 @@
 package api
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
-	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth/v5"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog/log"
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
 	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
 	"github.com/thanhphuocnguyen/go-eshop/internal/models"
+	"github.com/thanhphuocnguyen/go-eshop/internal/orderstate"
 	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/auth"
 )
 
 // Setup order-related routes
@@ -21,8 +23,10 @@ func (s *Server) addOrderRoutes(r chi.Router) {
 	r.Route("/orders", func(r chi.Router) {
 		r.Get("/", s.getOrders)
 		r.Get("/{id}", s.getOrderDetail)
+		r.Get("/{id}/history", s.getOrderStatusHistory)
 		r.Put("/{id}/confirm-received", s.confirmOrderPayment)
 		r.Post("/{id}/cancel", s.adminCancelOrder)
+		r.Post("/{id}/returns", s.createReturnRequest)
 	})
 }
 
@@ -89,24 +93,7 @@ func (s *Server) getOrders(w http.ResponseWriter, r *http.Request) {
 
 	var orderResponses []dto.OrderListItem
 	for _, aggregated := range fetchedOrderRows {
-		// Convert PaymentStatus interface{} to PaymentStatus type
-		paymentStatus := repository.PaymentStatusPending
-		if aggregated.PaymentStatus.Valid {
-			paymentStatus = aggregated.PaymentStatus.PaymentStatus
-		}
-
-		total, _ := aggregated.TotalPrice.Float64Value()
-		orderResponses = append(orderResponses, dto.OrderListItem{
-			ID:            aggregated.ID,
-			Total:         total.Float64,
-			TotalItems:    int32(aggregated.TotalItems),
-			Status:        aggregated.Status,
-			CustomerName:  aggregated.CustomerName,
-			CustomerEmail: aggregated.CustomerEmail,
-			PaymentStatus: paymentStatus,
-			CreatedAt:     aggregated.CreatedAt.UTC(),
-			UpdatedAt:     aggregated.UpdatedAt.UTC(),
-		})
+		orderResponses = append(orderResponses, dto.MapToOrderListItem(aggregated))
 	}
 
 	RespondSuccessWithPagination(w, orderResponses, dto.CreatePagination(orderListQuery.Page, orderListQuery.PageSize, count))
@@ -156,6 +143,7 @@ func (s *Server) getOrderDetail(w http.ResponseWriter, r *http.Request) {
 	resp = &dto.OrderDetail{
 		ID:            order.ID,
 		Total:         total.Float64,
+		Currency:      order.Currency,
 		CustomerName:  order.CustomerName,
 		CustomerEmail: order.CustomerEmail,
 		Status:        order.Status,
@@ -183,6 +171,8 @@ func (s *Server) getOrderDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	resp.PaymentInfo.Reference = paymentInfo.GatewayReference
+
 	var apiErr *dto.ApiError = nil
 
 	if paymentInfo.Status == repository.PaymentStatusPending &&
@@ -255,12 +245,124 @@ func (s *Server) getOrderDetail(w http.ResponseWriter, r *http.Request) {
 
 	resp.LineItems = lineItems
 
+	shipments, err := s.getOrderShipments(c, order.ID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	resp.Shipments = shipments
+
 	response := dto.CreateDataResp(resp, nil, apiErr)
 	RespondJSON(w, http.StatusOK, response)
 }
 
-// @Summary confirm received order payment info
-// @Description confirm received order payment info
+// @Summary Look up an order with a magic link
+// @Description Get order detail with the token mailed to a guest buyer at checkout
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param token query string true "Order lookup token"
+// @Success 200 {object} dto.ApiResponse[dto.OrderDetail]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /order-lookup [get]
+func (s *Server) lookupOrder(w http.ResponseWriter, r *http.Request) {
+	orderID, err := auth.VerifyOrderLookupToken(s.config.SymmetricKey, r.URL.Query().Get("token"))
+	if err != nil {
+		RespondBadRequest(w, InvalidTokenCode, err)
+		return
+	}
+
+	// hand the order ID over to the regular order detail handler
+	chi.RouteContext(r.Context()).URLParams.Add("id", orderID.String())
+	s.getOrderDetail(w, r)
+}
+
+// getOrderShipments loads the shipments of an order together with the items each one carries.
+func (s *Server) getOrderShipments(c context.Context, orderID uuid.UUID) ([]dto.Shipment, error) {
+	rows, err := s.repo.GetShipmentsByOrderID(c, orderID)
+	if err != nil {
+		return nil, err
+	}
+	itemRows, err := s.repo.GetShipmentItemsByOrderID(c, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[uuid.UUID][]dto.ShipmentItem, len(rows))
+	for _, item := range itemRows {
+		items[item.ShipmentID] = append(items[item.ShipmentID], dto.ShipmentItem{
+			OrderItemID: item.OrderItemID.String(),
+			Name:        item.ProductNameSnapshot,
+			Sku:         item.VariantSkuSnapshot,
+			Quantity:    item.Quantity,
+		})
+	}
+
+	shipments := make([]dto.Shipment, 0, len(rows))
+	for _, row := range rows {
+		shipment := dto.Shipment{
+			ID:               row.ID.String(),
+			Status:           row.Status,
+			TrackingNumber:   row.TrackingNumber,
+			TrackingUrl:      row.TrackingUrl,
+			ShippingProvider: row.ShippingProvider,
+			Items:            items[row.ID],
+			CreatedAt:        row.CreatedAt.UTC(),
+		}
+		if row.ShippedAt.Valid {
+			shippedAt := row.ShippedAt.Time.UTC()
+			shipment.ShippedAt = &shippedAt
+		}
+		if row.DeliveredAt.Valid {
+			deliveredAt := row.DeliveredAt.Time.UTC()
+			shipment.DeliveredAt = &deliveredAt
+		}
+		shipments = append(shipments, shipment)
+	}
+	return shipments, nil
+}
+
+func (s *Server) getOrderRefunds(c context.Context, orderID uuid.UUID) ([]dto.Refund, error) {
+	rows, err := s.repo.GetRefundsByOrderID(c, orderID)
+	if err != nil {
+		return nil, err
+	}
+	itemRows, err := s.repo.GetRefundItemsByOrderID(c, orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[uuid.UUID][]dto.RefundItem, len(rows))
+	for _, item := range itemRows {
+		amount, _ := item.Amount.Float64Value()
+		items[item.RefundID] = append(items[item.RefundID], dto.RefundItem{
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+			Amount:      amount.Float64,
+		})
+	}
+
+	refunds := make([]dto.Refund, 0, len(rows))
+	for _, row := range rows {
+		amount, _ := row.Amount.Float64Value()
+		refunds = append(refunds, dto.Refund{
+			ID:              row.ID,
+			Amount:          amount.Float64,
+			Reason:          row.Reason,
+			Status:          row.Status,
+			GatewayRefundID: row.GatewayRefundID,
+			Restocked:       row.Restocked,
+			Items:           items[row.ID],
+			CreatedAt:       row.CreatedAt.UTC(),
+		})
+	}
+	return refunds, nil
+}
+
+// @Summary confirm received order
+// @Description confirm a delivered order was received, which completes it
 // @Tags orders
 // @Accept json
 // @Produce json
@@ -296,29 +398,18 @@ func (s *Server) confirmOrderPayment(w http.ResponseWriter, r *http.Request) {
 	}
 	userID := uuid.MustParse(claims["userId"].(string))
 
-	if order.UserID != userID {
+	if order.UserID != utils.GetPgTypeUUID(userID) {
 		RespondForbidden(w, PermissionDeniedCode, errors.New("you do not have permission to access this order"))
 		return
 	}
-	if order.Status != repository.OrderStatusDelivered {
-		RespondBadRequest(w, InvalidPaymentCode, errors.New("order cannot be confirmed"))
-		return
-	}
 
-	orderUpdateParams := repository.UpdateOrderParams{
-		ID: order.ID,
-		Status: repository.NullOrderStatus{
-			OrderStatus: repository.OrderStatusDelivered,
-			Valid:       true,
-		},
-		ConfirmedAt: pgtype.Timestamptz{
-			Time:  time.Now(),
-			Valid: true,
-		},
-	}
-	_, err = s.repo.UpdateOrder(c, orderUpdateParams)
+	err = s.repo.ChangeOrderStatusTx(c, repository.ChangeOrderStatusTxArgs{
+		OrderID:        order.ID,
+		Status:         repository.OrderStatusCompleted,
+		OrderActorArgs: orderActorFromClaims(claims),
+	})
 	if err != nil {
-		RespondInternalServerError(w, InternalServerErrorCode, err)
+		respondOrderStatusError(w, err)
 		return
 	}
 	var apiErr *dto.ApiError
@@ -334,3 +425,100 @@ func (s *Server) confirmOrderPayment(w http.ResponseWriter, r *http.Request) {
 	response := dto.CreateDataResp(true, nil, apiErr)
 	RespondJSON(w, http.StatusOK, response)
 }
+
+// @Summary Get order status history
+// @Description Get every status change of an order with who made it and why
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.ApiResponse[[]dto.OrderStatusHistory]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /orders/{id}/history [get]
+func (s *Server) getOrderStatusHistory(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	order, err := s.repo.GetOrder(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("order with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+	if claims["roleCode"] != "admin" && order.UserID != utils.GetPgTypeUUID(userID) {
+		RespondForbidden(w, PermissionDeniedCode, errors.New("you do not have permission to access this order"))
+		return
+	}
+
+	rows, err := s.repo.GetOrderStatusHistory(c, order.ID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	history := make([]dto.OrderStatusHistory, len(rows))
+	for i, row := range rows {
+		history[i] = dto.OrderStatusHistory{
+			ID:        row.ID,
+			ToStatus:  row.ToStatus,
+			Actor:     row.Actor,
+			Reason:    row.Reason,
+			CreatedAt: row.CreatedAt.UTC(),
+		}
+		if row.FromStatus.Valid {
+			history[i].FromStatus = &row.FromStatus.OrderStatus
+		}
+		if row.ActorID.Valid {
+			actorID := uuid.UUID(row.ActorID.Bytes)
+			history[i].ActorID = &actorID
+		}
+	}
+
+	RespondSuccess(w, history)
+}
+
+// orderActorFromClaims records the signed in user as the actor of an order status change.
+func orderActorFromClaims(claims map[string]any) repository.OrderActorArgs {
+	actor := repository.OrderActorArgs{
+		Actor: orderstate.ActorCustomer,
+		ID:    utils.GetPgTypeUUID(uuid.MustParse(claims["userId"].(string))),
+	}
+	if claims["roleCode"] == "admin" {
+		actor.Actor = orderstate.ActorAdmin
+	}
+	return actor
+}
+
+// respondOrderStatusError maps errors of the order state machine to responses.
+func respondOrderStatusError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrInvalidRefund):
+		RespondBadRequest(w, InvalidRefundCode, err)
+	case errors.Is(err, repository.ErrInvalidPayment):
+		RespondBadRequest(w, InvalidPaymentCode, err)
+	case errors.Is(err, orderstate.ErrActorNotAllowed):
+		RespondForbidden(w, PermissionDeniedCode, err)
+	case errors.Is(err, orderstate.ErrInvalidTransition):
+		RespondBadRequest(w, InvalidOrderCode, err)
+	default:
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+	}
+}
@@ -0,0 +1,342 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
+	"github.com/thanhphuocnguyen/go-eshop/internal/models"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+const (
+	// defaultSavedMethodGateway keeps the cards when the client doesn't pick a gateway
+	defaultSavedMethodGateway = "stripe"
+	maxSavedPaymentMethods    = 10
+)
+
+// createPaymentSetupIntent godoc
+// @Summary Start saving a card
+// @Description Create a setup intent the client confirms with the card details. The card is saved with the gateway and only its token is kept.
+// @Tags payment-methods
+// @Produce json
+// @Param gateway query string false "Gateway to save the card with, stripe by default"
+// @Success 201 {object} dto.ApiResponse[dto.PaymentSetupIntent]
+// @Failure 400 {object} ErrorResp
+// @Failure 401 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/payment-methods/setup-intent [post]
+func (s *Server) createPaymentSetupIntent(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, fmt.Errorf("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	gateway := r.URL.Query().Get("gateway")
+	if gateway == "" {
+		gateway = defaultSavedMethodGateway
+	}
+
+	customerID, err := s.getPaymentCustomerID(c, userID, gateway)
+	if err != nil {
+		respondSavedMethodError(w, err)
+		return
+	}
+
+	intent, err := s.paymentSrv.CreateSetupIntent(c, customerID, gateway)
+	if err != nil {
+		respondSavedMethodError(w, err)
+		return
+	}
+
+	RespondCreated(w, dto.PaymentSetupIntent{
+		SetupIntentID: intent.ID,
+		ClientSecret:  intent.ClientSecret,
+		Gateway:       gateway,
+	})
+}
+
+// savePaymentMethod godoc
+// @Summary Save a card
+// @Description Keep the card of a confirmed setup intent. The first card saved becomes the default one.
+// @Tags payment-methods
+// @Accept json
+// @Produce json
+// @Param input body models.SavePaymentMethodModel true "Confirmed setup intent"
+// @Success 201 {object} dto.ApiResponse[dto.SavedPaymentMethod]
+// @Failure 400 {object} ErrorResp
+// @Failure 401 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/payment-methods [post]
+func (s *Server) savePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, fmt.Errorf("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	var req models.SavePaymentMethodModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	gateway := req.Gateway
+	if gateway == "" {
+		gateway = defaultSavedMethodGateway
+	}
+
+	customer, err := s.repo.GetPaymentCustomer(c, repository.GetPaymentCustomerParams{UserID: userID, Gateway: gateway})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondBadRequest(w, InvalidPaymentCode, errors.New("no setup intent was created with this gateway"))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	intent, err := s.paymentSrv.GetSetupIntent(c, req.SetupIntentID, gateway)
+	if err != nil {
+		respondSavedMethodError(w, err)
+		return
+	}
+	// the intent must have been created for this user, its ID alone proves nothing
+	if intent.CustomerID != customer.CustomerID {
+		RespondBadRequest(w, InvalidPaymentCode, errors.New("setup intent not found"))
+		return
+	}
+	if intent.Status != payment.StatusCompleted || intent.PaymentMethodID == "" {
+		RespondBadRequest(w, InvalidPaymentCode, errors.New("setup intent is not confirmed"))
+		return
+	}
+
+	infos, err := s.repo.GetUserPaymentInfos(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	saved := false
+	for _, info := range infos {
+		if info.PaymentMethodToken == intent.PaymentMethodID {
+			saved = true
+		}
+	}
+	if !saved && len(infos) >= maxSavedPaymentMethods {
+		RespondBadRequest(w, InvalidPaymentCode, fmt.Errorf("maximum number of saved cards reached"))
+		return
+	}
+
+	method, err := s.paymentSrv.GetSavedMethod(c, intent.PaymentMethodID, gateway)
+	if err != nil {
+		respondSavedMethodError(w, err)
+		return
+	}
+
+	params := repository.UpsertUserPaymentInfoParams{
+		UserID:             userID,
+		Gateway:            gateway,
+		PaymentMethodToken: method.ID,
+		CardLast4:          method.Last4,
+		ExpirationDate:     repository.CardExpirationDate(method.ExpMonth, method.ExpYear),
+		IsDefault:          req.IsDefault,
+	}
+	if method.Brand != "" {
+		params.CardBrand = &method.Brand
+	}
+	if method.BillingAddress != "" {
+		params.BillingAddress = &method.BillingAddress
+	}
+	info, err := s.repo.SaveUserPaymentInfoTx(c, params)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondCreated(w, dto.MapSavedPaymentMethod(info))
+}
+
+// getSavedPaymentMethods godoc
+// @Summary List saved cards
+// @Description List the cards saved by the user, the default one first
+// @Tags payment-methods
+// @Produce json
+// @Success 200 {object} dto.ApiResponse[[]dto.SavedPaymentMethod]
+// @Failure 401 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/payment-methods [get]
+func (s *Server) getSavedPaymentMethods(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, fmt.Errorf("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	infos, err := s.repo.GetUserPaymentInfos(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	resp := make([]dto.SavedPaymentMethod, len(infos))
+	for i, info := range infos {
+		resp[i] = dto.MapSavedPaymentMethod(info)
+	}
+	RespondSuccess(w, resp)
+}
+
+// setDefaultPaymentMethod godoc
+// @Summary Set the default card
+// @Description Make a saved card the one picked by default at checkout
+// @Tags payment-methods
+// @Produce json
+// @Param id path string true "Saved payment method ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} ErrorResp
+// @Failure 401 {object} ErrorResp
+// @Failure 404 {object} ErrorResp
+// @Router /users/payment-methods/{id}/default [patch]
+func (s *Server) setDefaultPaymentMethod(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, fmt.Errorf("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	infoID, err := uuid.Parse(id)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	err = s.repo.SetDefaultUserPaymentInfoTx(c, repository.UserPaymentInfoTxArgs{ID: infoID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, errors.New("saved payment method not found"))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondNoContent(w)
+}
+
+// removePaymentMethod godoc
+// @Summary Remove a saved card
+// @Description Detach a saved card from the gateway customer and forget its token
+// @Tags payment-methods
+// @Produce json
+// @Param id path string true "Saved payment method ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} ErrorResp
+// @Failure 401 {object} ErrorResp
+// @Failure 404 {object} ErrorResp
+// @Router /users/payment-methods/{id} [delete]
+func (s *Server) removePaymentMethod(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, fmt.Errorf("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	infoID, err := uuid.Parse(id)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	info, err := s.repo.GetUserPaymentInfo(c, repository.GetUserPaymentInfoParams{ID: infoID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, errors.New("saved payment method not found"))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	// the token is forgotten either way, a card we don't know of can't be charged by us
+	if err := s.paymentSrv.DetachSavedMethod(c, info.PaymentMethodToken, info.Gateway); err != nil {
+		log.Error().Err(err).Str("paymentInfoID", info.ID.String()).Msg("DetachSavedMethod")
+	}
+
+	err = s.repo.DeleteUserPaymentInfoTx(c, repository.UserPaymentInfoTxArgs{ID: info.ID, UserID: userID})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, errors.New("saved payment method not found"))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondNoContent(w)
+}
+
+// getPaymentCustomerID returns the customer the user is registered as on the gateway,
+// registering the user the first time a card is saved with it.
+func (s *Server) getPaymentCustomerID(c context.Context, userID uuid.UUID, gateway string) (string, error) {
+	customer, err := s.repo.GetPaymentCustomer(c, repository.GetPaymentCustomerParams{UserID: userID, Gateway: gateway})
+	if err == nil {
+		return customer.CustomerID, nil
+	}
+	if !errors.Is(err, repository.ErrRecordNotFound) {
+		return "", err
+	}
+
+	user, err := s.repo.GetUserDetailsByID(c, userID)
+	if err != nil {
+		return "", err
+	}
+	customerID, err := s.paymentSrv.CreateCustomer(c, payment.CustomerRequest{
+		Email:    user.Email,
+		Name:     user.FirstName + " " + user.LastName,
+		Metadata: map[string]string{"UserID": userID.String()},
+	}, gateway)
+	if err != nil {
+		return "", err
+	}
+
+	customer, err = s.repo.CreatePaymentCustomer(c, repository.CreatePaymentCustomerParams{
+		UserID:     userID,
+		Gateway:    gateway,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return customer.CustomerID, nil
+}
+
+func respondSavedMethodError(w http.ResponseWriter, err error) {
+	if errors.Is(err, payment.ErrSavedMethodsNotSupported) || errors.Is(err, payment.ErrGatewayNotFound) {
+		RespondBadRequest(w, InvalidPaymentCode, err)
+		return
+	}
+	RespondInternalServerError(w, InternalServerErrorCode, err)
+}
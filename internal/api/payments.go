@@ -1,9 +1,11 @@
 package api
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"net/http"
-	"time"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/jwtauth/v5"
@@ -11,6 +13,7 @@ import (
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
 	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
 	"github.com/thanhphuocnguyen/go-eshop/internal/models"
+	"github.com/thanhphuocnguyen/go-eshop/internal/processors"
 	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
 	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
 )
@@ -82,7 +85,7 @@ func (s *Server) createPaymentIntent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if ord.UserID != user.ID {
+	if ord.UserID != utils.GetPgTypeUUID(user.ID) {
 		RespondForbidden(w, PermissionDeniedCode, errors.New("permission denied"))
 		return
 	}
@@ -98,13 +101,15 @@ func (s *Server) createPaymentIntent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	total, _ := ord.TotalPrice.Float64Value()
+	total := utils.GetMoneyFromPgNumeric(ord.TotalPrice, payment.NormalizeCurrency(ord.Currency))
 	paymentMethodId := uuid.MustParse(req.PaymentMethodID)
 	// create new payment
 	createPaymentParams := repository.CreatePaymentParams{
 		OrderID:         ord.ID,
-		Amount:          utils.GetPgNumericFromFloat(total.Float64),
+		Amount:          utils.GetPgNumericFromMoney(total),
 		PaymentMethodID: paymentMethodId,
+		Status:          repository.PaymentStatusPending,
+		Currency:        ord.Currency,
 	}
 	var resp dto.PaymentIntentSecret
 	paymentMethod, err := s.repo.GetPaymentMethodByID(c, paymentMethodId)
@@ -116,12 +121,44 @@ func (s *Server) createPaymentIntent(w http.ResponseWriter, r *http.Request) {
 		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
+	if repository.IsOfflinePaymentMethod(paymentMethod) {
+		RespondBadRequest(w, InvalidPaymentCode, fmt.Errorf("%s can't be paid online", paymentMethod.Name))
+		return
+	}
+	converter, err := s.currencyProcessor.Converter(c, ord.Currency)
+	if err != nil {
+		if errors.Is(err, processors.ErrCurrencyNotSupported) {
+			RespondBadRequest(w, InvalidCurrencyCode, err)
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	storeAmount := converter.ToStore(total)
+	err = repository.CheckPaymentMethod(paymentMethod, repository.PaymentMethodCheck{
+		Amount:   storeAmount,
+		Currency: ord.Currency,
+		Country:  ord.ShippingAddress.Country,
+	})
+	if err != nil {
+		RespondBadRequest(w, InvalidPaymentCode, err)
+		return
+	}
+	fee := converter.Convert(repository.ProcessingFee(paymentMethod, storeAmount)).Min(total)
+	createPaymentParams.ProcessingFee = utils.GetPgNumericFromMoney(fee)
+	createPaymentParams.NetAmount = utils.GetPgNumericFromMoney(total.Sub(fee))
+	// a new payment replaces the cancelled one, retrying the request finds the same replacement
+	idempotencyKey := "order-" + ord.ID.String()
+	if pmRow.ID != uuid.Nil {
+		idempotencyKey += "-after-" + pmRow.ID.String()
+	}
 	intent, err := s.paymentSrv.CreatePaymentIntent(c, paymentMethod.Code, payment.PaymentRequest{
-		Amount:      int64(total.Float64 * 100), // convert to smallest currency unit
-		Currency:    payment.USD,
-		Email:       user.Email,
-		Description: "Payment for order " + ord.ID.String(),
-		Metadata:    map[string]string{"order_id": ord.ID.String()},
+		Amount:         total.Amount,
+		Currency:       total.Currency,
+		Email:          user.Email,
+		Description:    "Payment for order " + ord.ID.String(),
+		Metadata:       map[string]string{"order_id": ord.ID.String()},
+		IdempotencyKey: idempotencyKey,
 	})
 	if err != nil {
 		RespondInternalServerError(w, InternalServerErrorCode, err)
@@ -208,6 +245,11 @@ func (s *Server) getPayment(w http.ResponseWriter, r *http.Request) {
 // @Router /payments/{paymentId} [get]
 func (s *Server) changePaymentStatus(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
 	orderId := chi.URLParam(r, "orderId")
 	if orderId == "" {
 		RespondBadRequest(w, InvalidBodyCode, errors.New("missing orderId parameter"))
@@ -256,19 +298,16 @@ func (s *Server) changePaymentStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.Status == repository.PaymentStatusSuccess {
-		_, err := s.repo.UpdateOrder(c, repository.UpdateOrderParams{
-			ID: order.ID,
-			Status: repository.NullOrderStatus{
-				OrderStatus: repository.OrderStatusDelivered,
-				Valid:       true,
-			},
-			DeliveredAt: utils.GetPgTypeTimestamp(time.Now()),
+		// a settled payment confirms the order, which also keeps its reserved stock
+		err := s.repo.ChangeOrderStatusTx(c, repository.ChangeOrderStatusTxArgs{
+			OrderID:        order.ID,
+			Status:         repository.OrderStatusConfirmed,
+			OrderActorArgs: orderActorFromClaims(claims),
 		})
 		if err != nil {
-			RespondInternalServerError(w, InternalServerErrorCode, err)
+			respondOrderStatusError(w, err)
 			return
 		}
-
 	}
 
 	err = s.repo.UpdatePayment(c, repository.UpdatePaymentParams{
@@ -290,10 +329,14 @@ func (s *Server) changePaymentStatus(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary Get payment methods
-// @Description Get payment methods
+// @Description Get the payment methods eligible for a payment. The amount defaults to the total of the user's cart
+// @Description and the country to the user's default address.
 // @Tags payment
 // @Accept json
 // @Produce json
+// @Param amount query number false "Amount to pay, in the currency of the payment"
+// @Param currency query string false "ISO 4217 code of the payment currency, the store currency when empty"
+// @Param country query string false "ISO 3166-1 alpha-2 code of the shipping country"
 // @Security BearerAuth
 // @Success 200 {object} dto.ApiResponse[[]dto.PaymentMethodResponse]
 // @Failure 400 {object} dto.ErrorResp
@@ -303,22 +346,121 @@ func (s *Server) changePaymentStatus(w http.ResponseWriter, r *http.Request) {
 // @Router /payments/methods [get]
 func (s *Server) getPaymentMethods(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, err)
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	queryParams := r.URL.Query()
+	converter, err := s.currencyProcessor.Converter(c, queryParams.Get("currency"))
+	if err != nil {
+		if errors.Is(err, processors.ErrCurrencyNotSupported) {
+			RespondBadRequest(w, InvalidCurrencyCode, err)
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	// limits and fees of payment methods are set in the store currency
+	check := repository.PaymentMethodCheck{
+		Currency: string(converter.Currency),
+		Country:  queryParams.Get("country"),
+	}
+	if amount := queryParams.Get("amount"); amount != "" {
+		value, err := strconv.ParseFloat(amount, 64)
+		if err != nil || value < 0 {
+			RespondBadRequest(w, InvalidBodyCode, fmt.Errorf("invalid amount %s", amount))
+			return
+		}
+		check.Amount = converter.ToStore(payment.MoneyFromFloat(value, converter.Currency, payment.RoundHalfUp))
+	} else {
+		check.Amount, err = s.getCartTotal(c, userID)
+		if err != nil {
+			RespondInternalServerError(w, InternalServerErrorCode, err)
+			return
+		}
+	}
+	if check.Country == "" {
+		address, err := s.repo.GetDefaultAddress(c, userID)
+		if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+			RespondInternalServerError(w, InternalServerErrorCode, err)
+			return
+		}
+		if err == nil && address.Country != nil {
+			check.Country = *address.Country
+		}
+	}
+
 	paymentMethods, err := s.repo.ListPaymentMethods(c)
 	if err != nil {
 		RespondInternalServerError(w, InternalServerErrorCode, err)
 		return
 	}
-	var resp []dto.PaymentMethodResponse
+	resp := make([]dto.PaymentMethodResponse, 0, len(paymentMethods))
 	for _, pm := range paymentMethods {
+		if err := repository.CheckPaymentMethod(pm, check); err != nil {
+			continue
+		}
 		resp = append(resp, dto.PaymentMethodResponse{
-			ID:   pm.ID.String(),
-			Name: pm.Name,
-			Code: pm.Code,
+			ID:                 pm.ID.String(),
+			Name:               pm.Name,
+			Code:               pm.Code,
+			Description:        pm.Description,
+			Gateway:            pm.GatewaySupported,
+			Thumbnail:          pm.IconUrl,
+			IsActive:           pm.IsActive,
+			CurrencySupported:  &pm.CurrencySupported,
+			CountriesSupported: &pm.CountriesSupported,
+			ProcessingFee:      converter.Convert(repository.ProcessingFee(pm, check.Amount)).Float64(),
 		})
 	}
 	RespondSuccess(w, resp)
 }
 
+// @Summary Get currencies
+// @Description Get the currencies customers can pay in, the store currency first
+// @Tags payment
+// @Accept json
+// @Produce json
+// @Success 200 {object} dto.ApiResponse[[]processors.CurrencyConverter]
+// @Failure 500 {object} dto.ErrorResp
+// @Router /currencies [get]
+func (s *Server) getCurrencies(w http.ResponseWriter, r *http.Request) {
+	converters, err := s.currencyProcessor.ListConverters(r.Context())
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondSuccess(w, converters)
+}
+
+// getCartTotal is what the user's cart comes to after product discounts in the store
+// currency, zero without a cart.
+func (s *Server) getCartTotal(c context.Context, userID uuid.UUID) (payment.Money, error) {
+	total := payment.NewMoney(0, s.currencyProcessor.StoreCurrency())
+	cart, err := s.repo.GetCart(c, repository.GetCartParams{UserID: utils.GetPgTypeUUID(userID)})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return total, nil
+		}
+		return total, err
+	}
+	rows, err := s.repo.GetCartItems(c, cart.ID)
+	if err != nil {
+		return total, err
+	}
+	for _, row := range rows {
+		lineTotal := utils.GetMoneyFromPgNumeric(row.VariantPrice, total.Currency).Mul(int64(row.CartItem.Quantity))
+		if row.ProductDiscountPercentage != nil {
+			lineTotal = lineTotal.Sub(lineTotal.Percent(float64(*row.ProductDiscountPercentage), payment.RoundHalfUp))
+		}
+		total = total.Add(lineTotal)
+	}
+	return total.Max(payment.NewMoney(0, total.Currency)), nil
+}
+
 // @Summary Confirm Payment
 // @Description Confirm Payment
 // @Tags payment
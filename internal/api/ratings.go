@@ -45,7 +45,7 @@ func (s *Server) postRating(w http.ResponseWriter, r *http.Request) {
 	}
 	userID := uuid.MustParse(claims["userId"].(string))
 
-	if orderItem.UserID != userID {
+	if orderItem.UserID != utils.GetPgTypeUUID(userID) {
 		RespondUnauthorized(w, UnauthorizedCode, err)
 		return
 	}
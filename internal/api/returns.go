@@ -0,0 +1,340 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
+	"github.com/thanhphuocnguyen/go-eshop/internal/models"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/internal/worker"
+)
+
+// maxReturnRequestImages caps the photos a customer can attach to one return request
+const maxReturnRequestImages = 5
+
+func (s *Server) addReturnRoutes(r chi.Router) {
+	r.Route("/returns", func(r chi.Router) {
+		r.Get("/", s.getReturnRequests)
+		r.Get("/{id}", s.getReturnRequest)
+		r.Post("/{id}/images", s.uploadReturnRequestImage)
+	})
+}
+
+// @Summary Request a return
+// @Description Open a return request for items of a delivered order
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param id path string true "Order ID"
+// @Param input body models.CreateReturnRequestModel true "Return request input"
+// @Security BearerAuth
+// @Success 201 {object} dto.ApiResponse[dto.ReturnRequest]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /orders/{id}/returns [post]
+func (s *Server) createReturnRequest(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.CreateReturnRequestModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	order, err := s.repo.GetOrder(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("order with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if order.UserID != utils.GetPgTypeUUID(userID) {
+		RespondForbidden(w, PermissionDeniedCode, errors.New("you do not have permission to return this order"))
+		return
+	}
+
+	items := make([]repository.ReturnItemTxArgs, 0, len(req.Items))
+	for _, item := range req.Items {
+		items = append(items, repository.ReturnItemTxArgs{
+			OrderItemID: uuid.MustParse(item.OrderItemID),
+			Quantity:    item.Quantity,
+		})
+	}
+
+	returnRequest, err := s.repo.CreateReturnRequestTx(c, repository.CreateReturnRequestTxArgs{
+		OrderID: order.ID,
+		UserID:  userID,
+		Reason:  req.Reason,
+		Items:   items,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidReturn) {
+			RespondBadRequest(w, InvalidReturnCode, err)
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	s.sendReturnRequestStatusEmail(c, returnRequest.ID)
+
+	resp, err := s.getReturnRequestDetail(c, returnRequest)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondCreated(w, resp)
+}
+
+// @Summary List return requests
+// @Description List return requests of the current user
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number"
+// @Param pageSize query int false "Page size"
+// @Security BearerAuth
+// @Success 200 {object} dto.ApiResponse[[]repository.ReturnRequest]
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /returns [get]
+func (s *Server) getReturnRequests(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+	query := ParsePaginationQuery(r)
+
+	rows, err := s.repo.GetReturnRequests(c, repository.GetReturnRequestsParams{
+		Limit:  query.PageSize,
+		Offset: (query.Page - 1) * query.PageSize,
+		UserID: utils.GetPgTypeUUID(userID),
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	count, err := s.repo.CountReturnRequests(c, repository.CountReturnRequestsParams{
+		UserID: utils.GetPgTypeUUID(userID),
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondSuccessWithPagination(w, rows, dto.CreatePagination(query.Page, query.PageSize, count))
+}
+
+// @Summary Get a return request
+// @Description Get a return request with its items and photos
+// @Tags returns
+// @Accept json
+// @Produce json
+// @Param id path string true "Return request ID"
+// @Security BearerAuth
+// @Success 200 {object} dto.ApiResponse[dto.ReturnRequest]
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /returns/{id} [get]
+func (s *Server) getReturnRequest(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	returnRequest, err := s.repo.GetReturnRequestByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("return request with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+	if claims["roleCode"] != "admin" && returnRequest.UserID != userID {
+		RespondForbidden(w, PermissionDeniedCode, errors.New("you do not have permission to access this return request"))
+		return
+	}
+
+	resp, err := s.getReturnRequestDetail(c, returnRequest)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondSuccess(w, resp)
+}
+
+// @Summary Attach a photo to a return request
+// @Description Upload a photo of the returned items while the request waits for review
+// @Tags returns
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Return request ID"
+// @Param image formData file true "Photo"
+// @Security BearerAuth
+// @Success 201 {object} dto.ApiResponse[dto.ReturnRequestImage]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /returns/{id}/images [post]
+func (s *Server) uploadReturnRequestImage(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.ReturnRequestImageModel
+	if err := s.GetFormData(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	returnRequest, err := s.repo.GetReturnRequestByID(c, uuid.MustParse(id))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("return request with ID %s not found", id))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if returnRequest.UserID != uuid.MustParse(claims["userId"].(string)) {
+		RespondForbidden(w, PermissionDeniedCode, errors.New("you do not have permission to access this return request"))
+		return
+	}
+	if returnRequest.Status != repository.ReturnStatusRequested {
+		RespondBadRequest(w, InvalidReturnCode, fmt.Errorf("return request is already %s", returnRequest.Status))
+		return
+	}
+
+	images, err := s.repo.GetReturnRequestImages(c, returnRequest.ID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if len(images) >= maxReturnRequestImages {
+		RespondBadRequest(w, InvalidReturnCode, fmt.Errorf("a return request can have at most %d photos", maxReturnRequestImages))
+		return
+	}
+
+	imageID, imageURL, err := s.uploadService.Upload(c, req.Image)
+	if err != nil {
+		RespondInternalServerError(w, UploadFileCode, err)
+		return
+	}
+
+	image, err := s.repo.CreateReturnRequestImage(c, repository.CreateReturnRequestImageParams{
+		ReturnRequestID: returnRequest.ID,
+		ImageID:         imageID,
+		ImageUrl:        imageURL,
+	})
+	if err != nil {
+		if _, err := s.uploadService.Remove(c, imageID); err != nil {
+			log.Error().Err(err).Str("imageID", imageID).Msg("Remove")
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	RespondCreated(w, dto.ReturnRequestImage{ID: image.ID, Url: image.ImageUrl})
+}
+
+func (s *Server) getReturnRequestDetail(c context.Context, returnRequest repository.ReturnRequest) (dto.ReturnRequest, error) {
+	resp := dto.ReturnRequest{
+		ID:        returnRequest.ID,
+		OrderID:   returnRequest.OrderID,
+		UserID:    returnRequest.UserID,
+		Reason:    returnRequest.Reason,
+		Status:    returnRequest.Status,
+		AdminNote: returnRequest.AdminNote,
+		CreatedAt: returnRequest.CreatedAt.UTC(),
+	}
+	if returnRequest.RefundID.Valid {
+		refundID := uuid.UUID(returnRequest.RefundID.Bytes)
+		resp.RefundID = &refundID
+	}
+	if returnRequest.ReviewedAt.Valid {
+		reviewedAt := returnRequest.ReviewedAt.Time.UTC()
+		resp.ReviewedAt = &reviewedAt
+	}
+
+	items, err := s.repo.GetReturnRequestItems(c, returnRequest.ID)
+	if err != nil {
+		return resp, err
+	}
+	resp.Items = make([]dto.ReturnRequestItem, len(items))
+	for i, item := range items {
+		resp.Items[i] = dto.ReturnRequestItem{
+			OrderItemID: item.OrderItemID,
+			Name:        item.ProductNameSnapshot,
+			Sku:         item.VariantSkuSnapshot,
+			Quantity:    item.Quantity,
+		}
+	}
+
+	images, err := s.repo.GetReturnRequestImages(c, returnRequest.ID)
+	if err != nil {
+		return resp, err
+	}
+	resp.Images = make([]dto.ReturnRequestImage, len(images))
+	for i, image := range images {
+		resp.Images[i] = dto.ReturnRequestImage{ID: image.ID, Url: image.ImageUrl}
+	}
+	return resp, nil
+}
+
+// sendReturnRequestStatusEmail lets the customer know where their return request stands.
+// A failure to enqueue is only logged, the request itself already went through.
+func (s *Server) sendReturnRequestStatusEmail(c context.Context, returnRequestID uuid.UUID) {
+	err := s.taskDistributor.SendReturnRequestStatusEmail(c,
+		&worker.PayloadSendReturnRequestStatusEmail{ReturnRequestID: returnRequestID},
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueDefault))
+	if err != nil {
+		log.Error().Err(err).Str("returnRequestID", returnRequestID.String()).Msg("SendReturnRequestStatusEmail")
+	}
+}
@@ -0,0 +1,344 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/google/uuid"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
+	"github.com/thanhphuocnguyen/go-eshop/internal/models"
+)
+
+// @Summary List roles
+// @Description List the roles users can have
+// @Tags admin
+// @ID list-roles
+// @Accept json
+// @Produce json
+// @Success 200 {object} dto.ApiResponse[[]dto.RoleDetail]
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/roles [get]
+func (s *Server) adminGetRoles(w http.ResponseWriter, r *http.Request) {
+	roles, err := s.repo.GetRoles(r.Context())
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	resp := make([]dto.RoleDetail, len(roles))
+	for i, role := range roles {
+		resp[i] = dto.MapToRoleDetail(role, nil)
+	}
+	RespondSuccess(w, resp)
+}
+
+// @Summary Create a role
+// @Description Create a role without permissions, they are granted one module at a time
+// @Tags admin
+// @ID create-role
+// @Accept json
+// @Produce json
+// @Param request body models.CreateRoleModel true "Role"
+// @Success 201 {object} dto.ApiResponse[dto.RoleDetail]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 409 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/roles [post]
+func (s *Server) adminCreateRole(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateRoleModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	params := repository.CreateRoleParams{
+		Code:        req.Code,
+		Name:        req.Name,
+		Description: req.Description,
+		IsActive:    true,
+	}
+	if req.IsActive != nil {
+		params.IsActive = *req.IsActive
+	}
+	role, err := s.repo.CreateRole(r.Context(), params)
+	if err != nil {
+		if repository.ErrorCode(err) == repository.UniqueViolation {
+			RespondError(w, http.StatusConflict, ConflictCode, fmt.Errorf("role %s already exists", req.Code))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondCreated(w, dto.MapToRoleDetail(role, nil))
+}
+
+// @Summary Get a role
+// @Description Get a role with its permissions
+// @Tags admin
+// @ID get-role
+// @Accept json
+// @Produce json
+// @Param id path string true "Role ID"
+// @Success 200 {object} dto.ApiResponse[dto.RoleDetail]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/roles/{id} [get]
+func (s *Server) adminGetRole(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	roleID, err := getRoleIDParam(r)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	role, err := s.repo.GetRoleByID(c, roleID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("role with ID %s not found", roleID))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	perms, err := s.repo.GetRolePermissions(c, roleID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	RespondSuccess(w, dto.MapToRoleDetail(role, perms))
+}
+
+// @Summary Update a role
+// @Description Rename, describe, or deactivate a role. Users of an inactive role have no permissions. Roles that
+// @Description require MFA only let users into the back office once they sign in with a second factor.
+// @Tags admin
+// @ID update-role
+// @Accept json
+// @Produce json
+// @Param id path string true "Role ID"
+// @Param request body models.UpdateRoleModel true "Role"
+// @Success 200 {object} dto.ApiResponse[dto.RoleDetail]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/roles/{id} [put]
+func (s *Server) adminUpdateRole(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	roleID, err := getRoleIDParam(r)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.UpdateRoleModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	if req.IsActive != nil && !*req.IsActive && isOwnRole(r, roleID) {
+		RespondBadRequest(w, PermissionDeniedCode, errors.New("you can't deactivate your own role"))
+		return
+	}
+
+	role, err := s.repo.UpdateRole(c, repository.UpdateRoleParams{
+		ID:          roleID,
+		Name:        req.Name,
+		Description: req.Description,
+		IsActive:    req.IsActive,
+		RequireMfa:  req.RequireMfa,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("role with ID %s not found", roleID))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	s.invalidateRolePermissions(c, roleID)
+	RespondSuccess(w, dto.MapToRoleDetail(role, nil))
+}
+
+// @Summary Delete a role
+// @Description Delete a role nobody has anymore, its permissions go with it
+// @Tags admin
+// @ID delete-role
+// @Accept json
+// @Produce json
+// @Param id path string true "Role ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 409 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/roles/{id} [delete]
+func (s *Server) adminDeleteRole(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	roleID, err := getRoleIDParam(r)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	deleted, err := s.repo.DeleteRole(c, roleID)
+	if err != nil {
+		if repository.ErrorCode(err) == repository.ForeignKeyViolation {
+			RespondError(w, http.StatusConflict, ConflictCode, errors.New("role is still assigned to users"))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if deleted == 0 {
+		RespondNotFound(w, NotFoundCode, fmt.Errorf("role with ID %s not found", roleID))
+		return
+	}
+
+	s.invalidateRolePermissions(c, roleID)
+	RespondNoContent(w)
+}
+
+// @Summary Set a role's permission on a module
+// @Description Replace the read, write and execute flags a role has on a module. Reading the admin module lets
+// @Description the role into the back office.
+// @Tags admin
+// @ID set-role-permission
+// @Accept json
+// @Produce json
+// @Param id path string true "Role ID"
+// @Param module path string true "Module"
+// @Param request body models.RolePermissionModel true "Permission"
+// @Success 200 {object} dto.ApiResponse[dto.PermissionDetail]
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/roles/{id}/permissions/{module} [put]
+func (s *Server) adminSetRolePermission(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	roleID, module, err := s.getRolePermissionParams(r)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	var req models.RolePermissionModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	if isOwnRole(r, roleID) && locksOutOwnRole(module, req) {
+		RespondBadRequest(w, PermissionDeniedCode, fmt.Errorf("you can't take %s access away from your own role", module))
+		return
+	}
+
+	perm, err := s.repo.UpsertRolePermission(c, repository.UpsertRolePermissionParams{
+		RoleID: roleID,
+		Module: module,
+		R:      req.Read,
+		W:      req.Write,
+		X:      req.Execute,
+	})
+	if err != nil {
+		if repository.ErrorCode(err) == repository.ForeignKeyViolation {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("role with ID %s not found", roleID))
+			return
+		}
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	s.invalidateRolePermissions(c, roleID)
+	RespondSuccess(w, dto.MapToPermissionDetail(perm))
+}
+
+// @Summary Remove a role's permission on a module
+// @Description Take every flag a role has on a module away
+// @Tags admin
+// @ID delete-role-permission
+// @Accept json
+// @Produce json
+// @Param id path string true "Role ID"
+// @Param module path string true "Module"
+// @Success 204 {object} nil
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 403 {object} dto.ErrorResp
+// @Failure 404 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /admin/roles/{id}/permissions/{module} [delete]
+func (s *Server) adminDeleteRolePermission(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	roleID, module, err := s.getRolePermissionParams(r)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	if isOwnRole(r, roleID) && locksOutOwnRole(module, models.RolePermissionModel{}) {
+		RespondBadRequest(w, PermissionDeniedCode, fmt.Errorf("you can't take %s access away from your own role", module))
+		return
+	}
+
+	deleted, err := s.repo.DeleteRolePermission(c, repository.DeleteRolePermissionParams{
+		RoleID: roleID,
+		Module: module,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if deleted == 0 {
+		RespondNotFound(w, NotFoundCode, fmt.Errorf("role has no permission on %s", module))
+		return
+	}
+
+	s.invalidateRolePermissions(c, roleID)
+	RespondNoContent(w)
+}
+
+func getRoleIDParam(r *http.Request) (uuid.UUID, error) {
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(id)
+}
+
+func (s *Server) getRolePermissionParams(r *http.Request) (uuid.UUID, string, error) {
+	roleID, err := getRoleIDParam(r)
+	if err != nil {
+		return roleID, "", err
+	}
+	module, err := GetUrlParam(r, "module")
+	if err != nil {
+		return roleID, "", err
+	}
+	if err := s.validator.Var(module, "max=100,lowercase"); err != nil {
+		return roleID, "", fmt.Errorf("invalid module %s", module)
+	}
+	return roleID, module, nil
+}
+
+// isOwnRole tells whether the role is the one of the signed in user
+func isOwnRole(r *http.Request, roleID uuid.UUID) bool {
+	_, claims, err := jwtauth.FromContext(r.Context())
+	return err == nil && fmt.Sprint(claims["roleId"]) == roleID.String()
+}
+
+// locksOutOwnRole tells whether the permission would take away what is needed to undo it
+func locksOutOwnRole(module string, perm models.RolePermissionModel) bool {
+	switch module {
+	case BackOfficeModule:
+		return !perm.Read
+	case RolesModule:
+		return !perm.Write
+	}
+	return false
+}
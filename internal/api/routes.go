@@ -24,7 +24,7 @@ func (s *Server) addImageRoutes(r chi.Router) {
 // Setup webhook routes
 func (s *Server) addWebhookRoutes(r chi.Router) {
 	r.Route("/webhook/v1", func(r chi.Router) {
-		r.Post("/stripe", s.sendStripeEvent)
+		r.Post("/{gateway}", s.receiveGatewayWebhook)
 	})
 }
 
@@ -67,21 +67,32 @@ func (s *Server) setupMainRoutes() {
 
 		// Public routes
 		r.Get("/homepage", s.getHomePage)
+		r.Get("/order-lookup", s.lookupOrder)
+		r.Get("/currencies", s.getCurrencies)
 		s.addAuthRoutes(r)
 		s.addPublicRoutes(r)
 
+		// Cart routes serve both authenticated users and guests with a cart session
+		r.Group(func(optional chi.Router) {
+			optional.Use(jwtauth.Verifier(s.tokenAuth))
+			optional.Use(s.optionalRevokedTokenMiddleware)
+
+			s.addCartRoutes(optional)
+		})
+
 		// Protected routes (require authentication)
 		r.Group(func(protected chi.Router) {
 			protected.Use(jwtauth.Verifier(s.tokenAuth))
 			protected.Use(jwtauth.Authenticator(s.tokenAuth))
+			protected.Use(s.revokedTokenMiddleware)
 
-			s.addCartRoutes(protected)
 			s.addAdminRoutes(protected)
 			s.addUserRoutes(protected)
 			s.addOrderRoutes(protected)
 			s.addPaymentRoutes(protected)
 			s.addRatingRoutes(protected)
 			s.addDiscountRoutes(protected)
+			s.addReturnRoutes(protected)
 			protected.Delete("/images/remove-external/{id}", s.removeImageByPublicID)
 		})
 	})
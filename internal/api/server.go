@@ -36,6 +36,8 @@ type Server struct {
 	cacheSrv          cache.CacheContainer
 	taskDistributor   worker.TaskDistributor
 	discountProcessor *processors.DiscountProcessor
+	shippingProcessor *processors.ShippingProcessor
+	currencyProcessor *processors.CurrencyProcessor
 	validator         *validator.Validate
 }
 
@@ -68,6 +70,16 @@ func NewAPI(
 		return nil, fmt.Errorf("failed to create discount processor")
 	}
 
+	shippingProcessor := processors.NewShippingProcessor(repo)
+	if shippingProcessor == nil {
+		return nil, fmt.Errorf("failed to create shipping processor")
+	}
+
+	currencyProcessor, err := processors.NewCurrencyProcessor(repo, cfg.StoreCurrency)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create currency processor: %w", err)
+	}
+
 	cacheService := cache.NewRedisCache(cfg)
 	if cacheService == nil {
 		return nil, fmt.Errorf("failed to create cache service")
@@ -87,6 +99,8 @@ func NewAPI(
 		tokenAuth:         tokenAuth,
 		paymentSrv:        paymentSrv,
 		discountProcessor: discountProcessor,
+		shippingProcessor: shippingProcessor,
+		currencyProcessor: currencyProcessor,
 	}
 
 	// Setup validator (consider moving to server initialization if used elsewhere)
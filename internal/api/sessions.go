@@ -0,0 +1,136 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/jwtauth/v5"
+	"github.com/google/uuid"
+	repository "github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
+)
+
+// getSessions godoc
+// @Summary List signed in devices
+// @Description List the devices the current user is signed in on, the one making the request is marked current.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} dto.ApiResponse[[]dto.SessionDetail]
+// @Failure 401 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/me/sessions [get]
+// @Security BearerAuth
+func (s *Server) getSessions(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, fmt.Errorf("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+	// tokens issued before sessions were tracked have no session id and match no device
+	currentID, _ := uuid.Parse(fmt.Sprint(claims["sessionId"]))
+
+	rows, err := s.repo.ListActiveUserSessions(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	resp := make([]dto.SessionDetail, len(rows))
+	for i, row := range rows {
+		resp[i] = dto.MapToSessionDetail(row, currentID)
+	}
+	RespondSuccess(w, resp)
+}
+
+// revokeSession godoc
+// @Summary Sign out a device
+// @Description Sign out one device of the current user, its refresh and access tokens stop working.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Session ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} ErrorResp
+// @Failure 401 {object} ErrorResp
+// @Failure 404 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/me/sessions/{id} [delete]
+// @Security BearerAuth
+func (s *Server) revokeSession(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, fmt.Errorf("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	id, err := GetUrlParam(r, "id")
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+	sessionID, err := uuid.Parse(id)
+	if err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	blocked, err := s.repo.BlockSessionFamily(c, repository.BlockSessionFamilyParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if blocked == 0 {
+		RespondNotFound(w, NotFoundCode, errors.New("session not found"))
+		return
+	}
+
+	s.revokeSessionAccess(c, sessionID)
+	RespondNoContent(w)
+}
+
+// revokeAllSessions godoc
+// @Summary Sign out everywhere
+// @Description Sign out every device of the current user, including this one.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Success 204 {object} nil
+// @Failure 401 {object} ErrorResp
+// @Failure 500 {object} ErrorResp
+// @Router /users/me/sessions [delete]
+// @Security BearerAuth
+func (s *Server) revokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, fmt.Errorf("authorization payload is not provided"))
+		return
+	}
+	userID := uuid.MustParse(claims["userId"].(string))
+
+	rows, err := s.repo.ListActiveUserSessions(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if _, err = s.repo.BlockUserSessions(c, userID); err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	sessionIDs := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		sessionIDs[i] = row.FamilyID
+	}
+	s.revokeSessionAccess(c, sessionIDs...)
+	RespondNoContent(w)
+}
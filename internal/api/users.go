@@ -15,6 +15,7 @@ import (
 	"github.com/thanhphuocnguyen/go-eshop/internal/dto"
 	"github.com/thanhphuocnguyen/go-eshop/internal/models"
 	"github.com/thanhphuocnguyen/go-eshop/internal/worker"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/auth"
 )
 
 // updateUser godoc
@@ -251,11 +252,75 @@ func (s *Server) verifyEmail(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(htmlContent))
 }
 
+// changePassword godoc
+// @Summary Change password
+// @Description Change the password of the current user. Every session is signed out, including this one.
+// @Tags users
+// @Accept  json
+// @Produce  json
+// @Param input body models.ChangePasswordModel true "Current and new password"
+// @Success 204 {object} nil
+// @Failure 400 {object} dto.ErrorResp
+// @Failure 401 {object} dto.ErrorResp
+// @Failure 500 {object} dto.ErrorResp
+// @Router /users/me/password [put]
+// @Security BearerAuth
+func (s *Server) changePassword(w http.ResponseWriter, r *http.Request) {
+	c := r.Context()
+	_, claims, err := jwtauth.FromContext(c)
+	if err != nil {
+		RespondUnauthorized(w, UnauthorizedCode, errors.New("authorization payload is not provided"))
+		return
+	}
+	var req models.ChangePasswordModel
+	if err := s.GetRequestBody(r, &req); err != nil {
+		RespondBadRequest(w, InvalidBodyCode, err)
+		return
+	}
+
+	userID := uuid.MustParse(claims["userId"].(string))
+	user, err := s.repo.GetUserByID(c, userID)
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+	if err := auth.ComparePwd(req.CurrentPassword, user.HashedPassword); err != nil {
+		RespondBadRequest(w, InvalidPasswordCode, errors.New("current password is incorrect"))
+		return
+	}
+
+	hashedPassword, err := auth.HashPwd(req.NewPassword)
+	if err != nil {
+		RespondInternalServerError(w, HashPasswordCode, err)
+		return
+	}
+	rs, err := s.repo.ChangePasswordTx(c, repository.ChangePasswordTxArgs{
+		UserID:         userID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
+	}
+
+	s.cachePasswordChangedAt(c, rs.UserID, rs.PasswordChangedAt)
+	RespondNoContent(w)
+}
+
 // Setup user-related routes
 func (s *Server) addUserRoutes(r chi.Router) {
 	r.Route("/users", func(r chi.Router) {
 		r.Get("/me", s.getCurrentUser)
 		r.Patch("/me", s.updateUser)
+		r.Put("/me/password", s.changePassword)
+		r.Get("/me/sessions", s.getSessions)
+		r.Delete("/me/sessions", s.revokeAllSessions)
+		r.Delete("/me/sessions/{id}", s.revokeSession)
+		r.Get("/me/mfa", s.getMfaStatus)
+		r.Delete("/me/mfa", s.disableMfa)
+		r.Post("/me/mfa/totp", s.enrollTotp)
+		r.Post("/me/mfa/totp/verify", s.verifyTotpEnrollment)
+		r.Post("/me/mfa/recovery-codes", s.regenerateRecoveryCodes)
 		r.Post("/send-verify-email", s.sendVerifyEmail)
 
 		// Address routes
@@ -266,5 +331,14 @@ func (s *Server) addUserRoutes(r chi.Router) {
 			subR.Patch("/{id}", s.updateAddress)
 			subR.Delete("/{id}", s.removeAddress)
 		})
+
+		// Saved card routes
+		r.Route("/payment-methods", func(subR chi.Router) {
+			subR.Get("/", s.getSavedPaymentMethods)
+			subR.Post("/", s.savePaymentMethod)
+			subR.Post("/setup-intent", s.createPaymentSetupIntent)
+			subR.Patch("/{id}/default", s.setDefaultPaymentMethod)
+			subR.Delete("/{id}", s.removePaymentMethod)
+		})
 	})
 }
@@ -69,6 +69,11 @@ func (s *Server) ValidateServerDependencies(ctx context.Context) error {
 	}
 	log.Info().Msg("Discount processor validated")
 
+	if s.shippingProcessor == nil {
+		return fmt.Errorf("shipping processor is nil")
+	}
+	log.Info().Msg("Shipping processor validated")
+
 	if s.router == nil {
 		return fmt.Errorf("router is nil")
 	}
@@ -2,183 +2,98 @@ package api
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
-	"strings"
-	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/hibiken/asynq"
 	"github.com/rs/zerolog/log"
-	"github.com/stripe/stripe-go/v84"
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
-	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
 	"github.com/thanhphuocnguyen/go-eshop/internal/worker"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
 )
 
-// @Summary Stripe webhook
-// @Description Stripe webhook
+// maxWebhookBodySize caps the webhook payloads we are willing to read
+const maxWebhookBodySize = 65536
+
+// @Summary Payment gateway webhook
+// @Description Verify a webhook of a payment gateway, store it in the event inbox and process it in the background.
+// @Description Deliveries of an event that was already received are acknowledged without processing it again.
 // @Tags webhook
 // @Accept json
 // @Produce json
-// @Success 204 {object} nil
+// @Param gateway path string true "Gateway code, e.g. stripe"
+// @Success 200 {object} nil
 // @Failure 400 {object} ErrorResp
+// @Failure 404 {object} ErrorResp
 // @Failure 500 {object} ErrorResp
-// @Router /webhook/stripe [post]
-func (s *Server) sendStripeEvent(w http.ResponseWriter, r *http.Request) {
-	var evt stripe.Event
+// @Router /webhook/v1/{gateway} [post]
+func (s *Server) receiveGatewayWebhook(w http.ResponseWriter, r *http.Request) {
 	c := r.Context()
-	if err := s.GetRequestBody(r, evt); err != nil {
+	gateway := chi.URLParam(r, "gateway")
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
 		RespondBadRequest(w, InvalidBodyCode, err)
 		return
 	}
 
-	// unmarshal the event data
-	evtData := evt.Data.Object
-	var id *string
-	if evtData["id"] != nil {
-		id = utils.StringPtr(evtData["id"].(string))
+	if err := s.paymentSrv.VerifyWebhook(c, body, r.Header, gateway); err != nil {
+		if errors.Is(err, payment.ErrGatewayNotFound) {
+			RespondNotFound(w, NotFoundCode, fmt.Errorf("payment gateway %s not found", gateway))
+			return
+		}
+		log.Warn().Err(err).Str("gateway", gateway).Msg("VerifyWebhook")
+		RespondBadRequest(w, InvalidEventCode, err)
+		return
 	}
-	log.Info().Interface("evt type", evt.Type).Msg("Received stripe event")
 
-	var failureCode *string
-	var failureMsg *string
-	if evtData["failure_code"] != nil {
-		failureCode = utils.StringPtr(evtData["failure_code"].(string))
+	evt, err := s.paymentSrv.ParseWebhook(body, gateway)
+	if err != nil {
+		RespondBadRequest(w, InvalidEventCode, err)
+		return
 	}
-	if evtData["failure_message"] != nil {
-		failureMsg = utils.StringPtr(evtData["failure_message"].(string))
+	if evt.ID == "" {
+		RespondBadRequest(w, InvalidEventCode, errors.New("webhook event has no ID"))
+		return
 	}
+	log.Info().Str("gateway", gateway).Str("evt type", evt.GatewayType).Str("evt id", evt.ID).Msg("Received webhook event")
 
-	if strings.HasPrefix(string(evt.Type), "payment_intent.") {
-		piAmount := evtData["amount"].(float64)
-		payment, err := s.repo.GetPaymentByPaymentIntentID(c, id)
-		if err != nil {
-			if errors.Is(err, repository.ErrRecordNotFound) {
-				// If the payment is not found, we can ignore the event
-				log.Info().Msgf("Payment not found for payment intent ID: %s", *id)
-				RespondSuccess(w, nil)
-				return
-			}
-			RespondBadRequest(w, InternalServerErrorCode, err)
-			return
-		}
-		updateTransactionStatus := repository.UpdatePaymentParams{
-			ID:              payment.ID,
-			ChargeID:        id,
-			Amount:          utils.GetPgNumericFromFloat((float64(piAmount) / 100)),
-			PaymentIntentID: id,
-			ErrorCode:       failureCode,
-			ErrorMessage:    failureMsg,
-		}
-
-		switch evt.Type {
-		case stripe.EventTypePaymentIntentSucceeded:
-			updateTransactionStatus.Status = repository.NullPaymentStatus{
-				PaymentStatus: repository.PaymentStatusSuccess,
-				Valid:         true,
-			}
-			// Safely extract card brand from nested map
-			gateway := evt.GetObjectValue("payment_method_details", "brand")
-			updateTransactionStatus.Gateway = &gateway
-			s.taskDistributor.SendOrderCreatedEmailTask(c,
-				&worker.PayloadSendOrderCreatedEmailTask{
-					PaymentID: payment.ID,
-				},
-				asynq.MaxRetry(10),
-				asynq.ProcessIn(time.Second*3),
-				asynq.Queue(worker.QueueDefault))
-		case stripe.EventTypePaymentIntentCanceled:
-			updateTransactionStatus.Status = repository.NullPaymentStatus{
-				PaymentStatus: repository.PaymentStatusCancelled,
-				Valid:         true,
-			}
-		case stripe.EventTypePaymentIntentPaymentFailed:
-			updateTransactionStatus.Status = repository.NullPaymentStatus{
-				PaymentStatus: repository.PaymentStatusFailed,
-				Valid:         true,
-			}
-
-		default:
-			log.Info().Msgf("Unhandled event type: %s", evt.Type)
-			RespondSuccess(w, nil)
+	event, err := s.repo.CreateWebhookEvent(c, repository.CreateWebhookEventParams{
+		Provider:  gateway,
+		EventID:   evt.ID,
+		EventType: evt.GatewayType,
+		Payload:   body,
+	})
+	if err != nil {
+		if !errors.Is(err, repository.ErrRecordNotFound) {
+			RespondInternalServerError(w, InternalServerErrorCode, err)
 			return
 		}
-		err = s.repo.UpdatePayment(c, updateTransactionStatus)
+		// the event is already in the inbox, only queue it again if it never got processed
+		event, err = s.repo.GetWebhookEventByEventID(c, repository.GetWebhookEventByEventIDParams{
+			Provider: gateway,
+			EventID:  evt.ID,
+		})
 		if err != nil {
-			RespondBadRequest(w, InternalServerErrorCode, err)
+			RespondInternalServerError(w, InternalServerErrorCode, err)
 			return
 		}
-	}
-
-	if strings.HasPrefix(string(evt.Type), "charge.") {
-		piAmount := evtData["amount"].(float64)
-		var paymentIntentID *string
-		if evtData["payment_intent"] != nil {
-			paymentIntentID = utils.StringPtr(evtData["payment_intent"].(string))
-		}
-		payment, err := s.repo.GetPaymentByPaymentIntentID(c, paymentIntentID)
-		if err != nil {
-			if errors.Is(err, repository.ErrRecordNotFound) {
-				// If the payment is not found, we can ignore the event
-				log.Info().Msgf("Payment not found for payment intent ID: %s", *paymentIntentID)
-				RespondSuccess(w, nil)
-				return
-			}
-			RespondBadRequest(w, InternalServerErrorCode, err)
+		if event.Status == "processed" {
+			RespondSuccess(w, nil)
 			return
 		}
-		var createPaymentTransactionArg = repository.CreatePaymentTransactionParams{
-			PaymentID:              payment.ID,
-			Amount:                 utils.GetPgNumericFromFloat((float64(piAmount) / 100)),
-			GatewayTransactionID:   id,
-			Status:                 repository.PaymentStatusPending,
-			GatewayResponseCode:    failureCode,
-			GatewayResponseMessage: failureMsg,
-		}
+	}
 
-		switch evt.Type {
-		case stripe.EventTypeChargeSucceeded:
-			createPaymentTransactionArg.Status = repository.PaymentStatusSuccess
-			updateTransactionStatus := repository.UpdatePaymentParams{
-				ID:       payment.ID,
-				ChargeID: id,
-			}
-			err = s.repo.UpdatePayment(c, updateTransactionStatus)
-			if err != nil {
-				RespondBadRequest(w, InternalServerErrorCode, err)
-				return
-			}
-		case stripe.EventTypeChargeFailed:
-			createPaymentTransactionArg.Status = repository.PaymentStatusFailed
-		case stripe.EventTypeChargeRefunded:
-			createPaymentTransactionArg.Status = repository.PaymentStatusRefunded
-		case stripe.EventTypeChargeCaptured:
-			createPaymentTransactionArg.Status = repository.PaymentStatusProcessing
-		case stripe.EventTypeChargePending:
-			createPaymentTransactionArg.Status = repository.PaymentStatusPending
-		case stripe.EventTypeChargeUpdated:
-			if evtData["status"] != nil {
-				status := evtData["status"].(string)
-				switch status {
-				case "succeeded":
-					createPaymentTransactionArg.Status = repository.PaymentStatusSuccess
-				case "pending":
-					createPaymentTransactionArg.Status = repository.PaymentStatusPending
-				case "failed":
-					createPaymentTransactionArg.Status = repository.PaymentStatusFailed
-				case "canceled":
-					createPaymentTransactionArg.Status = repository.PaymentStatusCancelled
-				case "refunded":
-					createPaymentTransactionArg.Status = repository.PaymentStatusRefunded
-				case "processing":
-					createPaymentTransactionArg.Status = repository.PaymentStatusProcessing
-				}
-			}
-		}
-		_, err = s.repo.CreatePaymentTransaction(c, createPaymentTransactionArg)
-		if err != nil {
-			RespondBadRequest(w, InternalServerErrorCode, err)
-			return
-		}
+	err = s.taskDistributor.SendProcessWebhookEvent(c,
+		&worker.PayloadProcessWebhookEvent{WebhookEventID: event.ID},
+		asynq.TaskID("webhook_event:"+event.ID.String()),
+		asynq.MaxRetry(10),
+		asynq.Queue(worker.QueueCritical))
+	if err != nil && !errors.Is(err, asynq.ErrTaskIDConflict) {
+		// the gateway delivers the event again and it gets queued then
+		RespondInternalServerError(w, InternalServerErrorCode, err)
+		return
 	}
 
 	RespondSuccess(w, nil)
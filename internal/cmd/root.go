@@ -153,11 +153,30 @@ func apiCmd(ctx context.Context, cfg config.Config) *cobra.Command {
 				log.Fatal().Err(err).Msg("failed to add stripe gateway")
 			}
 
-			taskProcessor := worker.NewRedisTaskProcessor(redisCfg, pgRepo, mailer, cfg)
+			if cfg.PaypalClientID != "" {
+				paypalConfig := payment.GatewayConfig{
+					Name:          "paypal",
+					APIKey:        cfg.PaypalClientID,
+					SecretKey:     cfg.PaypalClientSecret,
+					WebhookSecret: cfg.PaypalWebhookID,
+					Environment:   cfg.PaypalEnvironment,
+					Extra:         map[string]string{"base_url": cfg.PaypalBaseURL},
+				}
+				if err := service.AddGateway(paypalConfig); err != nil {
+					log.Fatal().Err(err).Msg("failed to add paypal gateway")
+				}
+			}
+
+			taskProcessor := worker.NewRedisTaskProcessor(redisCfg, pgRepo, taskDistributor, mailer, service, cfg)
 			if taskProcessor == nil {
 				return fmt.Errorf("failed to create task processor")
 			}
 
+			taskScheduler, err := worker.NewRedisTaskScheduler(redisCfg, pgRepo, cfg.SchedulerTimezone)
+			if err != nil {
+				return fmt.Errorf("failed to create task scheduler: %w", err)
+			}
+
 			api, err := api.NewAPI(cfg, pgRepo, taskDistributor, uploadService, service)
 			if err != nil {
 				return fmt.Errorf("failed to create API server: %w", err)
@@ -192,6 +211,13 @@ func apiCmd(ctx context.Context, cfg config.Config) *cobra.Command {
 				}
 			}()
 
+			go func() {
+				log.Info().Msg("Starting task scheduler")
+				if err := taskScheduler.Start(); err != nil {
+					log.Error().Err(err).Msg("task scheduler stopped")
+				}
+			}()
+
 			<-ctx.Done()
 			log.Info().Msg("Shutting down API server")
 			_ = server.Shutdown(ctx)
@@ -202,6 +228,9 @@ func apiCmd(ctx context.Context, cfg config.Config) *cobra.Command {
 			log.Info().Msg("shutting down task processor")
 			taskProcessor.Shutdown()
 
+			log.Info().Msg("shutting down task scheduler")
+			taskScheduler.Shutdown()
+
 			log.Info().Msg("Shutting down pgRepo")
 			pgRepo.Close()
 
@@ -0,0 +1,6 @@
+package constants
+
+const (
+	CartSessionCookie = "cart_session"
+	CartSessionHeader = "X-Cart-Session"
+)
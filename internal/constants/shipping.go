@@ -0,0 +1,9 @@
+package constants
+
+type ShippingCondition string
+
+const (
+	WeightCondition     ShippingCondition = "weight"
+	OrderValueCondition ShippingCondition = "order_value"
+	CategoryCondition   ShippingCondition = "category"
+)
@@ -25,6 +25,15 @@ UPDATE carts SET updated_at = NOW() WHERE id = $1 RETURNING *;
 -- name: CheckoutCart :exec
 UPDATE carts SET order_id = $1 WHERE id = $2 RETURNING *;
 
+-- name: AssignCartToUser :exec
+UPDATE carts SET user_id = $1, session_id = NULL, updated_at = NOW() WHERE id = $2;
+
+-- name: DeleteCart :exec
+DELETE FROM carts WHERE id = $1;
+
+-- name: DeleteStaleGuestCarts :execrows
+DELETE FROM carts WHERE user_id IS NULL AND order_id IS NULL AND updated_at < $1;
+
 -- Cart Item Section
 -- name: AddCartItem :one
 INSERT INTO cart_items (cart_id, variant_id, quantity) VALUES ($1, $2, $3) RETURNING *;
@@ -44,7 +53,7 @@ SELECT * FROM cart_items WHERE variant_id = $1 AND cart_id = $2;
 -- name: GetCartItems :many
 SELECT
     sqlc.embed(ci),
-    pv.price AS variant_price, pv.sku AS variant_sku, pv.stock AS variant_stock, pv.image_url AS variant_image_url,
+    pv.price AS variant_price, pv.sku AS variant_sku, pv.stock AS variant_stock, pv.image_url AS variant_image_url, pv.weight AS variant_weight,
     p.name AS product_name, p.id AS product_id, p.discount_percentage AS product_discount_percentage, p.brand_id AS product_brand_id,
     JSONB_AGG(
     DISTINCT JSONB_BUILD_OBJECT(
@@ -69,5 +78,12 @@ WHERE ci.cart_id = $1
 GROUP BY ci.id, pv.id, p.id
 ORDER BY ci.added_at, ci.id, pv.id DESC;
 
+-- name: GetCartItemsForMerge :many
+SELECT ci.variant_id, ci.quantity, pv.stock
+FROM cart_items AS ci
+JOIN product_variants AS pv ON pv.id = ci.variant_id
+WHERE ci.cart_id = $1
+ORDER BY ci.added_at;
+
 -- name: ClearCart :exec
-DELETE FROM cart_items WHERE id = $1;
+DELETE FROM cart_items WHERE cart_id = $1;
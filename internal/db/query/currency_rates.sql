@@ -0,0 +1,17 @@
+-- name: UpsertCurrencyRate :one
+INSERT INTO currency_rates (currency, rate, rounding_increment, is_active) VALUES ($1, $2, $3, $4)
+ON CONFLICT (currency) DO UPDATE SET
+    rate = EXCLUDED.rate,
+    rounding_increment = EXCLUDED.rounding_increment,
+    is_active = EXCLUDED.is_active,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetCurrencyRate :one
+SELECT * FROM currency_rates WHERE currency = $1 LIMIT 1;
+
+-- name: ListCurrencyRates :many
+SELECT * FROM currency_rates WHERE is_active = COALESCE(sqlc.narg('is_active'), is_active) ORDER BY currency;
+
+-- name: DeleteCurrencyRate :execrows
+DELETE FROM currency_rates WHERE currency = $1;
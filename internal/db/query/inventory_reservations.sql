@@ -0,0 +1,14 @@
+-- name: CreateInventoryReservation :one
+INSERT INTO inventory_reservations (order_id, variant_id, quantity, expires_at) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetExpiredReservationOrderIDs :many
+SELECT DISTINCT order_id FROM inventory_reservations WHERE status = 'active' AND expires_at < NOW() LIMIT $1;
+
+-- name: CommitOrderReservations :exec
+UPDATE inventory_reservations SET status = 'committed', updated_at = NOW() WHERE order_id = $1 AND status = 'active';
+
+-- name: GetOrderReservationExpiry :one
+SELECT MIN(expires_at)::TIMESTAMPTZ AS expires_at FROM inventory_reservations WHERE order_id = $1 AND status = 'active';
+
+-- name: ReleaseOrderReservations :many
+UPDATE inventory_reservations SET status = 'released', updated_at = NOW() WHERE order_id = $1 AND status <> 'released' RETURNING *;
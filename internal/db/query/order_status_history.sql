@@ -0,0 +1,5 @@
+-- name: CreateOrderStatusHistory :one
+INSERT INTO order_status_history (order_id, from_status, to_status, actor, actor_id, reason) VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetOrderStatusHistory :many
+SELECT * FROM order_status_history WHERE order_id = $1 ORDER BY created_at, id;
@@ -1,5 +1,5 @@
 -- name: CreateOrder :one
-INSERT INTO orders (user_id, customer_email, customer_name, customer_phone, total_price, shipping_address) VALUES ($1, $2, $3, $4,  $5, $6) RETURNING *;
+INSERT INTO orders (user_id, customer_email, customer_name, customer_phone, total_price, shipping_address, shipping_method_id, shipping_rate_id, shipping_method, currency) VALUES ($1, $2, $3, $4,  $5, $6, $7, $8, $9, $10) RETURNING *;
 
 -- name: GetOrder :one
 SELECT
@@ -18,6 +18,9 @@ LEFT JOIN payment_methods pmt ON pm.payment_method_id = pmt.id
 WHERE orders.id = $1
 LIMIT 1;
 
+-- name: GetOrderForUpdate :one
+SELECT id, status FROM orders WHERE id = $1 FOR UPDATE;
+
 
 -- name: GetOrderItems :many
 SELECT
@@ -34,7 +37,7 @@ FROM orders ord
 LEFT JOIN order_items oi ON ord.id = oi.order_id
 LEFT JOIN payments pm ON ord.id = pm.order_id
 WHERE
-    ord.user_id = COALESCE(sqlc.narg('user_id'), ord.user_id) AND
+    (sqlc.narg('user_id')::uuid IS NULL OR ord.user_id = sqlc.narg('user_id')) AND
     ord.status = COALESCE(sqlc.narg('status'), ord.status) AND
     ord.created_at >= COALESCE(sqlc.narg('start_date'), ord.created_at) AND
     ord.created_at <= COALESCE(sqlc.narg('end_date'), ord.created_at) AND
@@ -51,6 +54,7 @@ SET
     confirmed_at = coalesce(sqlc.narg('confirmed_at'), confirmed_at),
     cancelled_at = coalesce(sqlc.narg('cancelled_at'), cancelled_at),
     delivered_at = coalesce(sqlc.narg('delivered_at'), delivered_at),
+    refunded_at = coalesce(sqlc.narg('refunded_at'), refunded_at),
     updated_at = now()
 WHERE id = sqlc.arg('id') RETURNING orders.id;
 
@@ -90,7 +94,7 @@ FROM orders ord
 LEFT JOIN payments p ON ord.id = p.order_id
 WHERE
     ord.status = COALESCE(sqlc.narg('status'), ord.status) AND
-    user_id = COALESCE(sqlc.narg('user_id'), user_id) AND
+    (sqlc.narg('user_id')::uuid IS NULL OR ord.user_id = sqlc.narg('user_id')) AND
     ord.created_at >= COALESCE(sqlc.narg('start_date'), ord.created_at) AND
     ord.created_at <= COALESCE(sqlc.narg('end_date'), ord.created_at) AND
     (p.status IS NULL OR p.status = COALESCE(sqlc.narg('payment_status'), p.status));
@@ -99,4 +103,9 @@ WHERE
 SELECT * FROM orders
 WHERE user_id = $1
 ORDER BY created_at DESC
-LIMIT 1;
\ No newline at end of file
+LIMIT 1;
+
+-- name: ClaimGuestOrders :execrows
+UPDATE orders
+SET user_id = sqlc.arg('user_id'), updated_at = NOW()
+WHERE user_id IS NULL AND LOWER(customer_email) = LOWER(sqlc.arg('email'));
\ No newline at end of file
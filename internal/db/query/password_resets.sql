@@ -0,0 +1,9 @@
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING *;
+
+-- name: GetPasswordResetByTokenHashForUpdate :one
+SELECT * FROM password_resets WHERE token_hash = $1 LIMIT 1 FOR UPDATE;
+
+-- name: ExpireUserPasswordResets :exec
+-- ExpireUserPasswordResets uses up every reset token of a user still waiting to be used.
+UPDATE password_resets SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL;
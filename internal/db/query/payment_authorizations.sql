@@ -0,0 +1,31 @@
+-- name: UpsertPaymentAuthorization :one
+INSERT INTO payment_authorizations (payment_id, amount, expires_at) VALUES ($1, $2, $3)
+ON CONFLICT (payment_id) DO UPDATE SET
+    amount = EXCLUDED.amount,
+    expires_at = EXCLUDED.expires_at
+RETURNING *;
+
+-- name: GetPaymentAuthorizationForUpdate :one
+SELECT * FROM payment_authorizations WHERE payment_id = $1 LIMIT 1 FOR UPDATE;
+
+-- name: CapturePaymentAuthorization :exec
+UPDATE payment_authorizations SET captured_amount = $2, captured_at = NOW() WHERE payment_id = $1;
+
+-- name: VoidPaymentAuthorization :exec
+UPDATE payment_authorizations SET voided_at = NOW() WHERE payment_id = $1 AND captured_at IS NULL AND voided_at IS NULL;
+
+-- name: ListExpiringPaymentAuthorizations :many
+-- ListExpiringPaymentAuthorizations returns the authorizations still open before a
+-- deadline, the ones expiring first first.
+SELECT
+    pa.payment_id, pa.amount, pa.authorized_at, pa.expires_at,
+    p.order_id, p.currency, p.payment_intent_id, pm.code AS payment_method_code,
+    o.status AS order_status, o.customer_email
+FROM payment_authorizations pa
+JOIN payments p ON p.id = pa.payment_id
+JOIN payment_methods pm ON pm.id = p.payment_method_id
+JOIN orders o ON o.id = p.order_id
+WHERE pa.captured_at IS NULL AND pa.voided_at IS NULL
+    AND p.status = 'authorized'
+    AND pa.expires_at < $1
+ORDER BY pa.expires_at ASC;
@@ -0,0 +1,8 @@
+-- name: GetPaymentCustomer :one
+SELECT * FROM payment_customers WHERE user_id = $1 AND gateway = $2 LIMIT 1;
+
+-- name: CreatePaymentCustomer :one
+-- A customer registered first by a concurrent request is kept and returned
+INSERT INTO payment_customers (user_id, gateway, customer_id) VALUES ($1, $2, $3)
+ON CONFLICT (user_id, gateway) DO UPDATE SET customer_id = payment_customers.customer_id
+RETURNING *;
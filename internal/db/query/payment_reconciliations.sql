@@ -0,0 +1,46 @@
+-- name: ListPaymentsToReconcile :many
+-- ListPaymentsToReconcile returns the gateway payments left waiting on a webhook since
+-- before a cutoff, the oldest first.
+SELECT
+    p.id, p.order_id, p.status, p.amount, p.currency, p.payment_intent_id,
+    pm.code AS payment_method_code
+FROM payments p
+JOIN payment_methods pm ON pm.id = p.payment_method_id
+WHERE p.status IN ('pending', 'processing')
+    AND p.payment_intent_id IS NOT NULL
+    AND p.updated_at < sqlc.arg('updated_before')::timestamptz
+ORDER BY p.updated_at ASC
+LIMIT sqlc.arg('limit');
+
+-- name: CreatePaymentReconciliation :one
+INSERT INTO payment_reconciliations DEFAULT VALUES RETURNING *;
+
+-- name: FinishPaymentReconciliation :one
+UPDATE payment_reconciliations SET
+    finished_at = NOW(),
+    checked = $2,
+    repaired = $3,
+    failed = $4
+WHERE id = $1
+RETURNING *;
+
+-- name: CreatePaymentReconciliationItem :exec
+INSERT INTO payment_reconciliation_items (
+    reconciliation_id, payment_id, order_id, gateway, payment_intent_id,
+    local_status, gateway_status, action, message
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);
+
+-- name: GetPaymentReconciliation :one
+SELECT * FROM payment_reconciliations WHERE id = $1 LIMIT 1;
+
+-- name: GetPaymentReconciliations :many
+SELECT * FROM payment_reconciliations
+ORDER BY started_at DESC
+LIMIT $1
+OFFSET $2;
+
+-- name: CountPaymentReconciliations :one
+SELECT COUNT(*) FROM payment_reconciliations;
+
+-- name: GetPaymentReconciliationItems :many
+SELECT * FROM payment_reconciliation_items WHERE reconciliation_id = $1 ORDER BY created_at ASC;
@@ -1,5 +1,10 @@
 -- name: CreatePayment :one
-INSERT INTO payments (order_id, amount, payment_method_id, gateway, status, payment_intent_id, charge_id, net_amount) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+INSERT INTO payments (order_id, amount, payment_method_id, gateway, status, payment_intent_id, charge_id, net_amount, gateway_reference, processing_fee, currency) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING *;
+
+-- GetPaymentByChargeIDForUpdate finds a payment by what the gateway calls the money it took,
+-- for events like paypal disputes that only name the capture
+-- name: GetPaymentByChargeIDForUpdate :one
+SELECT * FROM payments WHERE charge_id = $1 LIMIT 1 FOR UPDATE;
 
 -- name: GetPaymentByID :one
 SELECT * FROM payments WHERE id = $1 LIMIT 1;
@@ -7,9 +12,15 @@ SELECT * FROM payments WHERE id = $1 LIMIT 1;
 -- name: GetPaymentByOrderID :one
 SELECT * FROM payments WHERE order_id = $1 LIMIT 1;
 
+-- name: GetPaymentByOrderIDForUpdate :one
+SELECT * FROM payments WHERE order_id = $1 LIMIT 1 FOR UPDATE;
+
 -- name: GetPaymentByPaymentIntentID :one
 SELECT * FROM payments WHERE payment_intent_id = $1 LIMIT 1;
 
+-- name: GetPaymentByPaymentIntentIDForUpdate :one
+SELECT * FROM payments WHERE payment_intent_id = $1 LIMIT 1 FOR UPDATE;
+
 -- name: UpdatePayment :exec
 UPDATE payments
 SET
@@ -37,6 +48,13 @@ SELECT * FROM payment_transactions WHERE id = $1 LIMIT 1;
 -- name: GetPaymentTransactionByPaymentID :one
 SELECT * FROM payment_transactions WHERE payment_id = $1 LIMIT 1;
 
+-- name: GetReceivedAmountByPaymentID :one
+-- GetReceivedAmountByPaymentID sums the money recorded against a payment so far,
+-- offline payments can be settled in several parts.
+SELECT COALESCE(SUM(amount), 0)::DECIMAL AS received_amount
+FROM payment_transactions
+WHERE payment_id = $1 AND status = 'success';
+
 -- name: UpdatePaymentTransaction :exec
 UPDATE payment_transactions
 SET
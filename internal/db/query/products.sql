@@ -153,3 +153,9 @@ INSERT INTO products (brand_id, name, description) VALUES ($1, $2, $3);
 
 -- name: UpdateProductStock :one
 UPDATE product_variants SET stock = stock - $1 WHERE id = $2 RETURNING *;
+
+-- name: RestoreProductStock :one
+UPDATE product_variants SET stock = stock + $1 WHERE id = $2 RETURNING *;
+
+-- name: GetProductVariantsForUpdate :many
+SELECT id, stock, is_active FROM product_variants WHERE id = ANY(sqlc.arg('ids')::uuid[]) ORDER BY id FOR UPDATE;
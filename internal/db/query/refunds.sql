@@ -0,0 +1,70 @@
+-- name: CreateRefund :one
+INSERT INTO refunds (payment_id, order_id, amount, reason, status, gateway_refund_id, restocked, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+
+-- name: CreateRefundItem :one
+INSERT INTO refund_items (refund_id, order_item_id, quantity, amount) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetRefundsByOrderID :many
+SELECT * FROM refunds WHERE order_id = $1 ORDER BY created_at;
+
+-- name: GetRefundItemsByOrderID :many
+SELECT ri.*
+FROM refund_items ri
+JOIN refunds r ON r.id = ri.refund_id
+WHERE r.order_id = $1
+ORDER BY r.created_at;
+
+-- name: GetRefundedAmountByPaymentID :one
+SELECT COALESCE(SUM(amount), 0)::DECIMAL AS refunded_amount
+FROM refunds
+WHERE payment_id = $1 AND status <> 'failed';
+
+-- name: GetRefundedQuantitiesByOrderID :many
+SELECT ri.order_item_id, SUM(ri.quantity)::INT AS refunded_quantity
+FROM refund_items ri
+JOIN refunds r ON r.id = ri.refund_id
+WHERE r.order_id = $1 AND r.status <> 'failed'
+GROUP BY ri.order_item_id;
+
+-- name: GetRefundForUpdate :one
+SELECT * FROM refunds WHERE id = $1 LIMIT 1 FOR UPDATE;
+
+-- name: GetRefundByGatewayRefundIDForUpdate :one
+SELECT * FROM refunds WHERE gateway_refund_id = $1 LIMIT 1 FOR UPDATE;
+
+-- name: GetSettledRefundAmountByPaymentID :one
+SELECT COALESCE(SUM(amount), 0)::DECIMAL AS settled_amount
+FROM refunds
+WHERE payment_id = $1 AND status = 'succeeded';
+
+-- name: GetRestockItemsByRefundID :many
+SELECT ri.order_item_id, ri.quantity, oi.variant_id
+FROM refund_items ri
+JOIN order_items oi ON oi.id = ri.order_item_id
+WHERE ri.refund_id = $1;
+
+-- name: UpdateRefund :one
+UPDATE refunds
+SET
+    status = $2,
+    gateway_refund_id = COALESCE(sqlc.narg('gateway_refund_id'), gateway_refund_id),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListRefundsToReconcile :many
+-- ListRefundsToReconcile returns the gateway refunds that were never handed to the gateway,
+-- or whose answer was lost, since before a cutoff, the oldest first.
+SELECT
+    r.id, r.order_id, r.amount, r.reason, p.currency, p.payment_intent_id,
+    pm.code AS payment_method_code,
+    EXISTS (SELECT 1 FROM return_requests rr WHERE rr.refund_id = r.id)::BOOL AS from_return_request
+FROM refunds r
+JOIN payments p ON p.id = r.payment_id
+JOIN payment_methods pm ON pm.id = p.payment_method_id
+WHERE r.status = 'pending'
+    AND r.gateway_refund_id IS NULL
+    AND p.payment_intent_id IS NOT NULL
+    AND r.updated_at < sqlc.arg('updated_before')::timestamptz
+ORDER BY r.updated_at ASC
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,56 @@
+-- name: CreateReturnRequest :one
+INSERT INTO return_requests (order_id, user_id, reason) VALUES ($1, $2, $3) RETURNING *;
+
+-- name: CreateReturnRequestItem :exec
+INSERT INTO return_request_items (return_request_id, order_item_id, quantity) VALUES ($1, $2, $3);
+
+-- name: CreateReturnRequestImage :one
+INSERT INTO return_request_images (return_request_id, image_id, image_url) VALUES ($1, $2, $3) RETURNING *;
+
+-- name: GetReturnRequestByID :one
+SELECT * FROM return_requests WHERE id = $1 LIMIT 1;
+
+-- name: GetReturnRequestForUpdate :one
+SELECT * FROM return_requests WHERE id = $1 LIMIT 1 FOR UPDATE;
+
+-- name: GetReturnRequests :many
+SELECT * FROM return_requests
+WHERE
+    (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id')) AND
+    status = COALESCE(sqlc.narg('status'), status)
+ORDER BY created_at DESC
+LIMIT $1
+OFFSET $2;
+
+-- name: CountReturnRequests :one
+SELECT COUNT(*) FROM return_requests
+WHERE
+    (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id')) AND
+    status = COALESCE(sqlc.narg('status'), status);
+
+-- name: GetReturnRequestItems :many
+SELECT rri.return_request_id, rri.order_item_id, rri.quantity, oi.variant_id, oi.product_name_snapshot, oi.variant_sku_snapshot
+FROM return_request_items rri
+JOIN order_items oi ON rri.order_item_id = oi.id
+WHERE rri.return_request_id = $1
+ORDER BY oi.product_name_snapshot;
+
+-- name: GetReturnRequestImages :many
+SELECT * FROM return_request_images WHERE return_request_id = $1 ORDER BY created_at;
+
+-- name: GetRequestedReturnQuantitiesByOrderID :many
+SELECT rri.order_item_id, SUM(rri.quantity)::INT AS requested_quantity
+FROM return_request_items rri
+JOIN return_requests rr ON rr.id = rri.return_request_id
+WHERE rr.order_id = $1 AND rr.status = 'requested'
+GROUP BY rri.order_item_id;
+
+-- name: UpdateReturnRequest :one
+UPDATE return_requests SET
+    status = COALESCE(sqlc.narg('status'), status),
+    admin_note = COALESCE(sqlc.narg('admin_note'), admin_note),
+    refund_id = COALESCE(sqlc.narg('refund_id'), refund_id),
+    reviewed_by = COALESCE(sqlc.narg('reviewed_by'), reviewed_by),
+    reviewed_at = COALESCE(sqlc.narg('reviewed_at'), reviewed_at),
+    updated_at = NOW()
+WHERE id = $1 RETURNING *;
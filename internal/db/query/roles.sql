@@ -0,0 +1,33 @@
+-- name: GetRoles :many
+SELECT * FROM user_roles ORDER BY created_at;
+
+-- name: CreateRole :one
+INSERT INTO user_roles (code, name, description, is_active) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: UpdateRole :one
+UPDATE user_roles
+SET
+    name = COALESCE(sqlc.narg('name'), name),
+    description = COALESCE(sqlc.narg('description'), description),
+    is_active = COALESCE(sqlc.narg('is_active'), is_active),
+    require_mfa = COALESCE(sqlc.narg('require_mfa'), require_mfa),
+    updated_at = NOW()
+WHERE id = $1 RETURNING *;
+
+-- name: DeleteRole :execrows
+DELETE FROM user_roles WHERE id = $1;
+
+-- name: GetRolePermissions :many
+SELECT * FROM permissions WHERE role_id = $1 ORDER BY module;
+
+-- name: UpsertRolePermission :one
+INSERT INTO permissions (role_id, module, r, w, x) VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (role_id, module) DO UPDATE SET
+    r = EXCLUDED.r,
+    w = EXCLUDED.w,
+    x = EXCLUDED.x,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteRolePermission :execrows
+DELETE FROM permissions WHERE role_id = $1 AND module = $2;
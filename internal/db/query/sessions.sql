@@ -1,10 +1,13 @@
 -- name: InsertSession :one
-INSERT INTO user_sessions (id,user_id,refresh_token,user_agent,client_ip,blocked,expired_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+INSERT INTO user_sessions (id,user_id,family_id,refresh_token,user_agent,client_ip,blocked,expired_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
 
 
 -- name: GetSession :one
 SELECT * FROM user_sessions WHERE id = $1 LIMIT 1;
 
+-- name: GetSessionForUpdate :one
+SELECT * FROM user_sessions WHERE id = $1 LIMIT 1 FOR UPDATE;
+
 -- name: GetSessionByRefreshToken :one
 SELECT * FROM user_sessions WHERE refresh_token = $1 LIMIT 1;
 -- name: UpdateSession :one
@@ -14,4 +17,30 @@ SET
     client_ip = COALESCE(sqlc.narg('client_ip'), client_ip),
     blocked = COALESCE(sqlc.narg('blocked'), blocked),
     expired_at = COALESCE(sqlc.narg('expired_at'), expired_at)
-WHERE id = $1 RETURNING *;
\ No newline at end of file
+WHERE id = $1 RETURNING *;
+
+-- name: BlockUserSessions :execrows
+UPDATE user_sessions SET blocked = TRUE WHERE user_id = $1 AND blocked = FALSE;
+
+-- name: RotateSession :exec
+UPDATE user_sessions SET rotated_at = NOW() WHERE id = $1;
+
+-- name: BlockSessionFamily :execrows
+UPDATE user_sessions SET blocked = TRUE WHERE family_id = $1 AND user_id = $2 AND blocked = FALSE;
+
+-- name: ListActiveUserSessions :many
+-- ListActiveUserSessions returns the signed in devices of a user, the latest session of each
+-- family with the time the family signed in.
+SELECT s.*, f.signed_in_at::timestamptz AS signed_in_at
+FROM user_sessions s
+JOIN (
+    SELECT family_id, MIN(created_at) AS signed_in_at
+    FROM user_sessions
+    WHERE user_id = $1
+    GROUP BY family_id
+) f ON f.family_id = s.family_id
+WHERE s.user_id = $1
+    AND s.blocked = FALSE
+    AND s.rotated_at IS NULL
+    AND s.expired_at > NOW()
+ORDER BY s.created_at DESC;
@@ -0,0 +1,45 @@
+-- name: CreateShipment :one
+INSERT INTO shipments (order_id, tracking_number, tracking_url, shipping_provider, shipping_notes) VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: CreateShipmentItem :exec
+INSERT INTO shipment_items (shipment_id, order_item_id, quantity) VALUES ($1, $2, $3);
+
+-- name: GetShipmentByID :one
+SELECT * FROM shipments WHERE id = $1 LIMIT 1;
+
+-- name: GetShipmentsByOrderID :many
+SELECT * FROM shipments WHERE order_id = $1 ORDER BY created_at;
+
+-- name: GetShipmentItemsByOrderID :many
+SELECT si.shipment_id, si.order_item_id, si.quantity, oi.product_name_snapshot, oi.variant_sku_snapshot
+FROM shipment_items si
+JOIN shipments s ON si.shipment_id = s.id
+JOIN order_items oi ON si.order_item_id = oi.id
+WHERE s.order_id = $1
+ORDER BY s.created_at, oi.product_name_snapshot;
+
+-- name: GetOrderFulfillment :many
+SELECT
+    oi.id AS order_item_id,
+    oi.quantity,
+    COALESCE(SUM(si.quantity), 0)::INT AS allocated_quantity,
+    COALESCE(SUM(si.quantity) FILTER (WHERE s.status IN ('shipped', 'delivered')), 0)::INT AS shipped_quantity,
+    COALESCE(SUM(si.quantity) FILTER (WHERE s.status = 'delivered'), 0)::INT AS delivered_quantity
+FROM order_items oi
+LEFT JOIN shipment_items si ON si.order_item_id = oi.id
+LEFT JOIN shipments s ON si.shipment_id = s.id
+WHERE oi.order_id = $1
+GROUP BY oi.id, oi.quantity
+ORDER BY oi.id;
+
+-- name: UpdateShipment :one
+UPDATE shipments SET
+    status = COALESCE(sqlc.narg('status'), status),
+    shipped_at = COALESCE(sqlc.narg('shipped_at'), shipped_at),
+    delivered_at = COALESCE(sqlc.narg('delivered_at'), delivered_at),
+    tracking_number = COALESCE(sqlc.narg('tracking_number'), tracking_number),
+    tracking_url = COALESCE(sqlc.narg('tracking_url'), tracking_url),
+    shipping_provider = COALESCE(sqlc.narg('shipping_provider'), shipping_provider),
+    shipping_notes = COALESCE(sqlc.narg('shipping_notes'), shipping_notes),
+    updated_at = NOW()
+WHERE id = $1 RETURNING *;
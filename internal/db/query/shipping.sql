@@ -25,6 +25,9 @@ DELETE FROM shipping_methods WHERE id = $1;
 -- name: CountShippingMethods :one
 SELECT COUNT(*) FROM shipping_methods;
 
+-- name: ListShippingMethods :many
+SELECT * FROM shipping_methods ORDER BY name LIMIT $1 OFFSET $2;
+
 -- name: SeedShippingMethods :copyfrom
 INSERT INTO shipping_methods (name, description, is_active, requires_address, estimated_delivery_time) 
 VALUES ($1, $2, $3, $4, $5);
@@ -57,6 +60,9 @@ DELETE FROM shipping_zones WHERE id = $1;
 -- name: CountShippingZones :one
 SELECT COUNT(*) FROM shipping_zones;
 
+-- name: ListShippingZones :many
+SELECT * FROM shipping_zones ORDER BY name LIMIT $1 OFFSET $2;
+
 -- name: SeedShippingZones :copyfrom
 INSERT INTO shipping_zones (name, description, countries, states, zip_codes, is_active) 
 VALUES ($1, $2, $3, $4, $5, $6);
@@ -103,4 +109,21 @@ WHERE id = $1 RETURNING *;
 DELETE FROM shipping_rates WHERE id = $1;
 
 -- name: CountShippingRates :one
-SELECT COUNT(*) FROM shipping_rates;
\ No newline at end of file
+SELECT COUNT(*) FROM shipping_rates;
+
+-- name: ListShippingRates :many
+SELECT sr.*, sm.name as method_name, sz.name as zone_name
+FROM shipping_rates sr
+JOIN shipping_methods sm ON sr.shipping_method_id = sm.id
+JOIN shipping_zones sz ON sr.shipping_zone_id = sz.id
+ORDER BY sm.name, sz.name, sr.min_order_amount NULLS FIRST
+LIMIT $1 OFFSET $2;
+
+-- name: GetShippingRatesByMethodAndZone :many
+SELECT * FROM shipping_rates
+WHERE shipping_method_id = $1 AND shipping_zone_id = $2
+ORDER BY min_order_amount NULLS FIRST;
+
+-- SHIPPING RATE CONDITIONS
+-- name: GetShippingRateConditionsByRateIDs :many
+SELECT * FROM shipping_rate_conditions WHERE shipping_rate_id = ANY(sqlc.arg('rate_ids')::uuid[]) ORDER BY created_at;
@@ -0,0 +1,8 @@
+-- name: CreateUserAuditLog :one
+INSERT INTO user_audit_logs (user_id, actor_id, action, details) VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetUserAuditLogs :many
+SELECT * FROM user_audit_logs WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3;
+
+-- name: CountUserAuditLogs :one
+SELECT COUNT(*) FROM user_audit_logs WHERE user_id = $1;
@@ -0,0 +1,35 @@
+-- name: GetUserMfa :one
+SELECT * FROM user_mfa WHERE user_id = $1 LIMIT 1;
+
+-- name: UpsertUserMfa :one
+INSERT INTO user_mfa (user_id, secret) VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET
+    secret = EXCLUDED.secret,
+    last_used_step = 0,
+    updated_at = NOW()
+WHERE user_mfa.enabled_at IS NULL
+RETURNING *;
+
+-- name: EnableUserMfa :execrows
+UPDATE user_mfa SET enabled_at = NOW(), last_used_step = $2, updated_at = NOW()
+WHERE user_id = $1 AND enabled_at IS NULL AND last_used_step < $2;
+
+-- name: UseUserMfaStep :execrows
+UPDATE user_mfa SET last_used_step = $2, updated_at = NOW()
+WHERE user_id = $1 AND enabled_at IS NOT NULL AND last_used_step < $2;
+
+-- name: DeleteUserMfa :execrows
+DELETE FROM user_mfa WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM user_recovery_codes WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE user_recovery_codes SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL;
@@ -0,0 +1,29 @@
+-- name: UpsertUserPaymentInfo :one
+INSERT INTO user_payment_infos (user_id, gateway, payment_method_token, card_brand, card_last4, expiration_date, billing_address, is_default)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (user_id, payment_method_token) DO UPDATE SET
+    card_brand = EXCLUDED.card_brand,
+    card_last4 = EXCLUDED.card_last4,
+    expiration_date = EXCLUDED.expiration_date,
+    billing_address = EXCLUDED.billing_address,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetUserPaymentInfo :one
+SELECT * FROM user_payment_infos WHERE id = $1 AND user_id = $2 LIMIT 1;
+
+-- name: GetUserPaymentInfos :many
+SELECT * FROM user_payment_infos WHERE user_id = $1 ORDER BY is_default DESC, created_at DESC;
+
+-- name: DeleteUserPaymentInfo :execrows
+DELETE FROM user_payment_infos WHERE id = $1 AND user_id = $2;
+
+-- name: ResetDefaultUserPaymentInfo :exec
+UPDATE user_payment_infos SET is_default = FALSE, updated_at = NOW() WHERE user_id = $1 AND is_default = TRUE;
+
+-- name: SetDefaultUserPaymentInfo :execrows
+UPDATE user_payment_infos SET is_default = TRUE, updated_at = NOW() WHERE id = $1 AND user_id = $2;
+
+-- name: SetLatestUserPaymentInfoDefault :exec
+UPDATE user_payment_infos SET is_default = TRUE, updated_at = NOW()
+WHERE id = (SELECT id FROM user_payment_infos WHERE user_id = $1 ORDER BY created_at DESC LIMIT 1);
@@ -71,7 +71,7 @@ INSERT INTO users (email, username, phone_number, first_name, last_name, hashed_
 
 -- User Address Queries
 -- name: CreateAddress :one
-INSERT INTO user_addresses (user_id, phone_number, street, ward, district, city, "is_default") VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+INSERT INTO user_addresses (user_id, phone_number, street, ward, district, city, "is_default", country, state, zip_code) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING *;
 
 -- name: GetAddress :one
 SELECT * FROM user_addresses WHERE id = $1 AND user_id = $2 LIMIT 1;
@@ -94,7 +94,10 @@ SET
     ward = coalesce(sqlc.narg('ward'), ward),
     district = coalesce(sqlc.narg('district'), district),
     city = coalesce(sqlc.narg('city'), city),
-    "is_default" = coalesce(sqlc.narg('is_default'), "is_default")
+    "is_default" = coalesce(sqlc.narg('is_default'), "is_default"),
+    country = coalesce(sqlc.narg('country'), country),
+    state = coalesce(sqlc.narg('state'), state),
+    zip_code = coalesce(sqlc.narg('zip_code'), zip_code)
 WHERE
     id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
 RETURNING *;
@@ -143,4 +146,18 @@ LEFT JOIN (
 ) du ON o.id = du.order_id
 WHERE o.user_id = $1 
   AND o.status IN ('completed', 'delivered')
-  AND p.status = 'success';
\ No newline at end of file
+  AND p.status = 'success';
+
+-- name: UpdateUserPassword :one
+UPDATE users SET
+    hashed_password = $2,
+    password_changed_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING password_changed_at;
+
+-- name: SetUserLocked :one
+UPDATE users SET locked = $2, updated_at = NOW() WHERE id = $1 RETURNING *;
+
+-- name: UpdateUserRole :one
+UPDATE users SET role_id = $2, updated_at = NOW() WHERE id = $1 RETURNING *;
@@ -0,0 +1,26 @@
+-- name: CreateWebhookEvent :one
+INSERT INTO webhook_events (provider, event_id, event_type, payload) VALUES ($1, $2, $3, $4)
+ON CONFLICT (provider, event_id) DO NOTHING
+RETURNING *;
+
+-- name: GetWebhookEventByEventID :one
+SELECT * FROM webhook_events WHERE provider = $1 AND event_id = $2 LIMIT 1;
+
+-- name: ClaimWebhookEvent :one
+-- Claims an event for processing. Processed events and events another worker is busy
+-- with are left alone, a stuck claim is taken over after a few minutes.
+UPDATE webhook_events SET
+    status = 'processing',
+    attempts = attempts + 1,
+    updated_at = NOW()
+WHERE id = $1 AND (
+    status IN ('received', 'failed') OR
+    (status = 'processing' AND updated_at < NOW() - INTERVAL '5 minutes')
+)
+RETURNING *;
+
+-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events SET status = 'processed', last_error = NULL, processed_at = NOW(), updated_at = NOW() WHERE id = $1;
+
+-- name: MarkWebhookEventFailed :exec
+UPDATE webhook_events SET status = 'failed', last_error = $2, updated_at = NOW() WHERE id = $1;
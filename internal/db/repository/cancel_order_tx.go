@@ -3,33 +3,47 @@ package repository
 import (
 	"context"
 	"errors"
-	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog/log"
 )
 
 type CancelOrderTxArgs struct {
 	OrderID                 uuid.UUID
 	CancelPaymentFromMethod func(ID string, method string) error
+	OrderActorArgs
 }
 
 func (repo *pgRepo) CancelOrderTx(ctx context.Context, args CancelOrderTxArgs) (uuid.UUID, error) {
 	var orderID = args.OrderID
 	err := repo.execTx(ctx, func(q *Queries) error {
+		// cancel order first so an illegal transition never reaches the gateway,
+		// this also puts the reserved stock back
+		err := changeOrderStatus(ctx, q, ChangeOrderStatusTxArgs{
+			OrderID:        args.OrderID,
+			Status:         OrderStatusCancelled,
+			OrderActorArgs: args.OrderActorArgs,
+		})
+		if err != nil {
+			return err
+		}
+
 		// cancel payment
 		payment, err := q.GetPaymentByOrderID(ctx, args.OrderID)
-		if !errors.Is(err, ErrRecordNotFound) {
-			log.Error().Err(err).Msg("GetPaymentTransactionByOrderID")
+		if err != nil {
+			// if payment is not found, we don't need to cancel it
+			if errors.Is(err, ErrRecordNotFound) {
+				return nil
+			}
+			log.Error().Err(err).Msg("GetPaymentByOrderID")
+			return err
 		}
-		paymentMethod, methodErr := q.GetPaymentMethodByID(ctx, payment.PaymentMethodID)
-		if err == nil && methodErr != nil {
+		paymentMethod, err := q.GetPaymentMethodByID(ctx, payment.PaymentMethodID)
+		if err != nil {
 			log.Error().Err(err).Msg("GetPaymentMethodByID")
 			return err
 		}
-		// if payment is not found, we don't need to cancel it
-		if !errors.Is(err, ErrRecordNotFound) && payment.Gateway != nil {
+		if payment.Gateway != nil {
 			if payment.Status == PaymentStatusSuccess {
 				return errors.New("payment is already successful, need to refund")
 			}
@@ -41,6 +55,13 @@ func (repo *pgRepo) CancelOrderTx(ctx context.Context, args CancelOrderTxArgs) (
 					log.Error().Err(err).Msg("CancelPaymentFromGateway")
 					return err
 				}
+				// cancelling an authorized payment released the money held on the card
+				if payment.Status == PaymentStatusAuthorized {
+					if err := q.VoidPaymentAuthorization(ctx, payment.ID); err != nil {
+						log.Error().Err(err).Msg("VoidPaymentAuthorization")
+						return err
+					}
+				}
 				err := q.UpdatePaymentTransaction(ctx, UpdatePaymentTransactionParams{
 					ID: payment.ID,
 					Status: NullPaymentStatus{
@@ -55,45 +76,6 @@ func (repo *pgRepo) CancelOrderTx(ctx context.Context, args CancelOrderTxArgs) (
 			}
 		}
 
-		// cancel order
-		_, err = q.UpdateOrder(ctx, UpdateOrderParams{
-			ID: args.OrderID,
-			Status: NullOrderStatus{
-				OrderStatus: OrderStatusCancelled,
-				Valid:       true,
-			},
-			CancelledAt: pgtype.Timestamptz{
-				Time:  time.Now(),
-				Valid: true,
-			},
-		})
-
-		if err != nil {
-			log.Error().Err(err).Msg("UpdateOrder")
-			return err
-		}
-
-		// refilling stock
-		orderItems, err := q.ListOrderItems(ctx, ListOrderItemsParams{
-			OrderID: args.OrderID,
-		})
-
-		if err != nil {
-			log.Error().Err(err).Msg("ListOrderItems")
-			return err
-		}
-		// TODO: #1 implement UpdateProductStock
-		for _, item := range orderItems {
-			_, err := q.UpdateProductStock(ctx, UpdateProductStockParams{
-				ID:    item.VariantID,
-				Stock: -int32(item.Quantity),
-			})
-
-			if err != nil {
-				log.Error().Err(err).Msg("UpdateProductStock")
-				return err
-			}
-		}
 		return nil
 	})
 
@@ -37,6 +37,20 @@ func (q *Queries) AddCartItem(ctx context.Context, arg AddCartItemParams) (CartI
 	return i, err
 }
 
+const assignCartToUser = `-- name: AssignCartToUser :exec
+UPDATE carts SET user_id = $1, session_id = NULL, updated_at = NOW() WHERE id = $2
+`
+
+type AssignCartToUserParams struct {
+	UserID pgtype.UUID `json:"userId"`
+	ID     uuid.UUID   `json:"id"`
+}
+
+func (q *Queries) AssignCartToUser(ctx context.Context, arg AssignCartToUserParams) error {
+	_, err := q.db.Exec(ctx, assignCartToUser, arg.UserID, arg.ID)
+	return err
+}
+
 const checkoutCart = `-- name: CheckoutCart :exec
 UPDATE carts SET order_id = $1 WHERE id = $2 RETURNING id, user_id, session_id, order_id, updated_at, created_at
 `
@@ -52,11 +66,11 @@ func (q *Queries) CheckoutCart(ctx context.Context, arg CheckoutCartParams) erro
 }
 
 const clearCart = `-- name: ClearCart :exec
-DELETE FROM cart_items WHERE id = $1
+DELETE FROM cart_items WHERE cart_id = $1
 `
 
-func (q *Queries) ClearCart(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.Exec(ctx, clearCart, id)
+func (q *Queries) ClearCart(ctx context.Context, cartID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, clearCart, cartID)
 	return err
 }
 
@@ -94,6 +108,27 @@ func (q *Queries) CreateCart(ctx context.Context, arg CreateCartParams) (Cart, e
 	return i, err
 }
 
+const deleteCart = `-- name: DeleteCart :exec
+DELETE FROM carts WHERE id = $1
+`
+
+func (q *Queries) DeleteCart(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteCart, id)
+	return err
+}
+
+const deleteStaleGuestCarts = `-- name: DeleteStaleGuestCarts :execrows
+DELETE FROM carts WHERE user_id IS NULL AND order_id IS NULL AND updated_at < $1
+`
+
+func (q *Queries) DeleteStaleGuestCarts(ctx context.Context, updatedAt time.Time) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteStaleGuestCarts, updatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getCart = `-- name: GetCart :one
 SELECT carts.id, carts.user_id, carts.session_id, carts.order_id, carts.updated_at, carts.created_at, COUNT(cart_items.id) AS item_count FROM carts
 LEFT JOIN cart_items ON cart_items.cart_id = carts.id
@@ -212,7 +247,7 @@ func (q *Queries) GetCartItemByProductVariantID(ctx context.Context, arg GetCart
 const getCartItems = `-- name: GetCartItems :many
 SELECT
     ci.id, ci.cart_id, ci.variant_id, ci.quantity, ci.added_at,
-    pv.price AS variant_price, pv.sku AS variant_sku, pv.stock AS variant_stock, pv.image_url AS variant_image_url,
+    pv.price AS variant_price, pv.sku AS variant_sku, pv.stock AS variant_stock, pv.image_url AS variant_image_url, pv.weight AS variant_weight,
     p.name AS product_name, p.id AS product_id, p.discount_percentage AS product_discount_percentage, p.brand_id AS product_brand_id,
     JSONB_AGG(
     DISTINCT JSONB_BUILD_OBJECT(
@@ -244,6 +279,7 @@ type GetCartItemsRow struct {
 	VariantSku                string         `json:"variantSku"`
 	VariantStock              int32          `json:"variantStock"`
 	VariantImageUrl           *string        `json:"variantImageUrl"`
+	VariantWeight             pgtype.Numeric `json:"variantWeight"`
 	ProductName               string         `json:"productName"`
 	ProductID                 uuid.UUID      `json:"productId"`
 	ProductDiscountPercentage *int16         `json:"productDiscountPercentage"`
@@ -272,6 +308,7 @@ func (q *Queries) GetCartItems(ctx context.Context, cartID uuid.UUID) ([]GetCart
 			&i.VariantSku,
 			&i.VariantStock,
 			&i.VariantImageUrl,
+			&i.VariantWeight,
 			&i.ProductName,
 			&i.ProductID,
 			&i.ProductDiscountPercentage,
@@ -290,6 +327,40 @@ func (q *Queries) GetCartItems(ctx context.Context, cartID uuid.UUID) ([]GetCart
 	return items, nil
 }
 
+const getCartItemsForMerge = `-- name: GetCartItemsForMerge :many
+SELECT ci.variant_id, ci.quantity, pv.stock
+FROM cart_items AS ci
+JOIN product_variants AS pv ON pv.id = ci.variant_id
+WHERE ci.cart_id = $1
+ORDER BY ci.added_at
+`
+
+type GetCartItemsForMergeRow struct {
+	VariantID uuid.UUID `json:"variantId"`
+	Quantity  int16     `json:"quantity"`
+	Stock     int32     `json:"stock"`
+}
+
+func (q *Queries) GetCartItemsForMerge(ctx context.Context, cartID uuid.UUID) ([]GetCartItemsForMergeRow, error) {
+	rows, err := q.db.Query(ctx, getCartItemsForMerge, cartID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetCartItemsForMergeRow{}
+	for rows.Next() {
+		var i GetCartItemsForMergeRow
+		if err := rows.Scan(&i.VariantID, &i.Quantity, &i.Stock); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const removeProductFromCart = `-- name: RemoveProductFromCart :exec
 DELETE FROM cart_items WHERE cart_id = $1 AND id = $2
 `
@@ -2,10 +2,14 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/orderstate"
 	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
 )
 
 type CustomerInfoTxArgs struct {
@@ -14,6 +18,13 @@ type CustomerInfoTxArgs struct {
 	Phone    string
 }
 
+type ShippingTxArgs struct {
+	RateID     uuid.UUID
+	MethodID   uuid.UUID
+	MethodName string
+	Fee        payment.Money
+}
+
 type CreatePaymentResult struct {
 	PaymentID       string        `json:"paymentId"`
 	ClientSecret    *string       `json:"clientSecret,omitempty"`
@@ -21,37 +32,97 @@ type CreatePaymentResult struct {
 	TotalPrice      float64       `json:"totalPrice"`
 	OrderID         uuid.UUID     `json:"orderId"`
 	Status          PaymentStatus `json:"status"`
+	// PaymentReference is what the customer quotes on a bank transfer
+	PaymentReference *string `json:"paymentReference,omitempty"`
+	// PaymentDueAt is when an unpaid bank transfer order gets cancelled
+	PaymentDueAt *time.Time `json:"paymentDueAt,omitempty"`
 }
 
 type CreatePaymentArgs struct {
-	Amount  float64
+	Amount  payment.Money
 	Email   string
 	OrderID uuid.UUID
 }
 
 type CheckoutCartTxArgs struct {
-	UserID                uuid.UUID
+	// UserID is left invalid for guest checkouts
+	UserID                pgtype.UUID
 	CartID                uuid.UUID
 	CustomerInfo          CustomerInfoTxArgs
 	CreateOrderItemParams []CreateBulkOrderItemsParams
-	TotalPrice            float64
-	DiscountPrice         float64
+	TotalPrice            payment.Money
+	DiscountPrice         payment.Money
 	DiscountIDs           []uuid.UUID
 	ShippingAddress       ShippingAddressSnapshot
+	Shipping              *ShippingTxArgs
 	PaymentMethodID       uuid.UUID
+	ReservationExpiresAt  time.Time
 	CreatePaymentFn       func(ctx context.Context, orderID uuid.UUID, method string) (paymentIntentID string, clientSecret *string, err error)
+	// ProcessingFee is what the payment method charges, in the currency of the checkout
+	ProcessingFee payment.Money
 }
 
 func (repo *pgRepo) CheckoutCartTx(ctx context.Context, arg CheckoutCartTxArgs) (CreatePaymentResult, error) {
 	var result CreatePaymentResult
 	err := repo.execTx(ctx, func(q *Queries) error {
+		// lock the variants first so concurrent checkouts can't oversell
+		requested := make(map[uuid.UUID]int32, len(arg.CreateOrderItemParams))
+		variantIDs := make([]uuid.UUID, 0, len(arg.CreateOrderItemParams))
+		for _, item := range arg.CreateOrderItemParams {
+			if _, ok := requested[item.VariantID]; !ok {
+				variantIDs = append(variantIDs, item.VariantID)
+			}
+			requested[item.VariantID] += int32(item.Quantity)
+		}
+
+		variants, err := q.GetProductVariantsForUpdate(ctx, variantIDs)
+		if err != nil {
+			log.Error().Err(err).Msg("GetProductVariantsForUpdate")
+			return err
+		}
+
+		available := make(map[uuid.UUID]int32, len(variants))
+		for _, variant := range variants {
+			if variant.IsActive != nil && !*variant.IsActive {
+				continue
+			}
+			available[variant.ID] = variant.Stock
+		}
+
+		stockErr := &InsufficientStockError{}
+		for _, id := range variantIDs {
+			if stock, ok := available[id]; !ok || stock < requested[id] {
+				stockErr.VariantIDs = append(stockErr.VariantIDs, id)
+			}
+		}
+		if len(stockErr.VariantIDs) > 0 {
+			return stockErr
+		}
+
+		for _, id := range variantIDs {
+			_, err := q.UpdateProductStock(ctx, UpdateProductStockParams{
+				ID:    id,
+				Stock: requested[id],
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("UpdateProductStock")
+				return err
+			}
+		}
+
 		params := CreateOrderParams{
 			UserID:          arg.UserID,
 			ShippingAddress: arg.ShippingAddress,
-			TotalPrice:      utils.GetPgNumericFromFloat(arg.TotalPrice),
+			TotalPrice:      utils.GetPgNumericFromMoney(arg.TotalPrice),
 			CustomerEmail:   arg.CustomerInfo.Email,
 			CustomerName:    arg.CustomerInfo.FullName,
 			CustomerPhone:   arg.CustomerInfo.Phone,
+			Currency:        string(arg.TotalPrice.Currency),
+		}
+		if arg.Shipping != nil {
+			params.ShippingRateID = utils.GetPgTypeUUID(arg.Shipping.RateID)
+			params.ShippingMethodID = utils.GetPgTypeUUID(arg.Shipping.MethodID)
+			params.ShippingMethod = &arg.Shipping.MethodName
 		}
 
 		order, err := q.CreateOrder(ctx, params)
@@ -60,19 +131,46 @@ func (repo *pgRepo) CheckoutCartTx(ctx context.Context, arg CheckoutCartTxArgs)
 			return err
 		}
 
-		paymentAmount := arg.TotalPrice - arg.DiscountPrice
-		result.TotalPrice = max(paymentAmount, 0)
+		_, err = q.CreateOrderStatusHistory(ctx, CreateOrderStatusHistoryParams{
+			OrderID:  order.ID,
+			ToStatus: order.Status,
+			Actor:    string(orderstate.ActorCustomer),
+			ActorID:  arg.UserID,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("CreateOrderStatusHistory")
+			return err
+		}
 
-		if arg.DiscountPrice != 0 {
-			if arg.DiscountPrice > arg.TotalPrice {
-				arg.DiscountPrice = arg.TotalPrice
+		// hold the stock until the payment settles or the reservation expires
+		for _, id := range variantIDs {
+			_, err := q.CreateInventoryReservation(ctx, CreateInventoryReservationParams{
+				OrderID:   order.ID,
+				VariantID: id,
+				Quantity:  requested[id],
+				ExpiresAt: arg.ReservationExpiresAt,
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("CreateInventoryReservation")
+				return err
 			}
+		}
+
+		// discounts never take the order below zero
+		arg.DiscountPrice = arg.DiscountPrice.Min(arg.TotalPrice)
+		paymentAmount := arg.TotalPrice.Sub(arg.DiscountPrice)
+		if arg.Shipping != nil {
+			paymentAmount = paymentAmount.Add(arg.Shipping.Fee)
+		}
+		result.TotalPrice = paymentAmount.Float64()
+
+		if !arg.DiscountPrice.IsZero() && arg.UserID.Valid {
 			for _, id := range arg.DiscountIDs {
 				_, err := q.AddDiscountUsage(ctx, AddDiscountUsageParams{
 					OrderID:        order.ID,
 					DiscountID:     id,
-					DiscountAmount: utils.GetPgNumericFromFloat(arg.DiscountPrice),
-					UserID:         arg.UserID,
+					DiscountAmount: utils.GetPgNumericFromMoney(arg.DiscountPrice),
+					UserID:         arg.UserID.Bytes,
 				})
 				if err != nil {
 					log.Error().Err(err).Msg("AddDiscountUsage")
@@ -103,28 +201,45 @@ func (repo *pgRepo) CheckoutCartTx(ctx context.Context, arg CheckoutCartTxArgs)
 			return err
 		}
 
+		method, err := q.GetPaymentMethodByID(ctx, arg.PaymentMethodID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetPaymentMethodByID")
+			return err
+		}
+
 		// create payment transaction
+		// methods without a fee may leave it unset
+		fee := payment.NewMoney(0, paymentAmount.Currency)
+		if !arg.ProcessingFee.IsZero() {
+			fee = arg.ProcessingFee.Min(paymentAmount)
+		}
 		createPaymentArgs := CreatePaymentParams{
 			OrderID:         order.ID,
 			PaymentMethodID: arg.PaymentMethodID,
-			Amount:          utils.GetPgNumericFromFloat(paymentAmount),
+			Amount:          utils.GetPgNumericFromMoney(paymentAmount),
 			Status:          PaymentStatusPending,
-			NetAmount:       utils.GetPgNumericFromFloat(paymentAmount),
+			ProcessingFee:   utils.GetPgNumericFromMoney(fee),
+			NetAmount:       utils.GetPgNumericFromMoney(paymentAmount.Sub(fee)),
+			Currency:        string(paymentAmount.Currency),
 		}
-		gateWay, err := q.GetPaymentMethodByID(ctx, arg.PaymentMethodID)
-		if err != nil {
-			log.Error().Err(err).Msg("GetPaymentMethodByID")
-			return err
-		}
-
-		createPaymentArgs.Gateway = &gateWay.Code
 
-		if paymentAmount > 0 {
-			method, err := q.GetPaymentMethodByID(ctx, arg.PaymentMethodID)
-			if err != nil {
-				log.Error().Err(err).Msg("GetPaymentMethodByID")
-				return err
+		switch {
+		case !paymentAmount.IsPositive():
+			createPaymentArgs.Gateway = &method.Code
+			createPaymentArgs.Status = PaymentStatusSuccess
+		case IsOfflinePaymentMethod(method):
+			// the money is collected by hand, there is no gateway to talk to
+			if method.Code == PaymentMethodBankTransfer {
+				reference, err := newPaymentReference()
+				if err != nil {
+					return err
+				}
+				createPaymentArgs.GatewayReference = &reference
+				result.PaymentReference = &reference
+				result.PaymentDueAt = &arg.ReservationExpiresAt
 			}
+		default:
+			createPaymentArgs.Gateway = &method.Code
 			paymentIntentID, clientSecret, err := arg.CreatePaymentFn(ctx, order.ID, method.Code)
 			if err != nil {
 				log.Error().Err(err).Msg("CreatePaymentFn")
@@ -133,20 +248,24 @@ func (repo *pgRepo) CheckoutCartTx(ctx context.Context, arg CheckoutCartTxArgs)
 			createPaymentArgs.PaymentIntentID = &paymentIntentID
 			result.ClientSecret = clientSecret
 			result.PaymentIntentID = paymentIntentID
-		} else {
-			createPaymentArgs.PaymentIntentID = nil
-			createPaymentArgs.Status = PaymentStatusSuccess
 		}
 
-		payment, err := q.CreatePayment(ctx, createPaymentArgs)
+		pm, err := q.CreatePayment(ctx, createPaymentArgs)
 		if err != nil {
 			log.Error().Err(err).Msg("CreatePayment")
 			return err
 		}
-		result.PaymentID = payment.ID.String()
+		// cash on delivery holds the stock until the order is delivered or cancelled
+		if pm.Status == PaymentStatusSuccess || method.Code == PaymentMethodCOD {
+			if err := q.CommitOrderReservations(ctx, order.ID); err != nil {
+				log.Error().Err(err).Msg("CommitOrderReservations")
+				return err
+			}
+		}
+
+		result.PaymentID = pm.ID.String()
 		result.OrderID = order.ID
 		result.Status = createPaymentArgs.Status
-		result.PaymentIntentID = *payment.PaymentIntentID
 
 		return nil
 	})
@@ -2,7 +2,10 @@ package repository
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -22,6 +25,28 @@ var ErrTimeout = &pgconn.PgError{Code: Timeout}
 var ErrDeadlockDetected = &pgconn.PgError{Code: DeadLock}
 var ErrRecordNotFoundPgx = &pgconn.PgError{Code: RecordNotFound}
 var ErrInvalidPrice = errors.New("invalid price")
+var ErrInvalidShipment = errors.New("invalid shipment")
+var ErrInvalidRefund = errors.New("invalid refund")
+var ErrInvalidReturn = errors.New("invalid return request")
+var ErrInvalidPayment = errors.New("invalid payment")
+var ErrInvalidPasswordReset = errors.New("invalid password reset")
+var ErrInvalidSession = errors.New("invalid session")
+var ErrInvalidRole = errors.New("invalid role")
+var ErrInvalidMfa = errors.New("invalid two-factor authentication")
+
+// InsufficientStockError is returned by CheckoutCartTx when one or more
+// variants can't cover the requested quantity.
+type InsufficientStockError struct {
+	VariantIDs []uuid.UUID `json:"variantIds"`
+}
+
+func (e *InsufficientStockError) Error() string {
+	ids := make([]string, len(e.VariantIDs))
+	for i, id := range e.VariantIDs {
+		ids[i] = id.String()
+	}
+	return fmt.Sprintf("insufficient stock for variants: %s", strings.Join(ids, ", "))
+}
 
 func ErrorCode(err error) string {
 	var pgErr *pgconn.PgError
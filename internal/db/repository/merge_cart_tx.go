@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+)
+
+type MergeGuestCartTxArgs struct {
+	UserID    uuid.UUID
+	SessionID string
+}
+
+// MergeGuestCartTx moves the items of the guest cart identified by SessionID into the
+// user's active cart. Quantities of the same variant are summed and capped at stock.
+// If the user has no active cart the guest cart is simply handed over.
+func (repo *pgRepo) MergeGuestCartTx(ctx context.Context, arg MergeGuestCartTxArgs) error {
+	return repo.execTx(ctx, func(q *Queries) error {
+		guestCart, err := q.GetCart(ctx, GetCartParams{SessionID: &arg.SessionID})
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return nil
+			}
+			log.Error().Err(err).Msg("GetCart")
+			return err
+		}
+
+		userCart, err := q.GetCart(ctx, GetCartParams{UserID: utils.GetPgTypeUUID(arg.UserID)})
+		if err != nil {
+			if !errors.Is(err, ErrRecordNotFound) {
+				log.Error().Err(err).Msg("GetCart")
+				return err
+			}
+			err = q.AssignCartToUser(ctx, AssignCartToUserParams{
+				UserID: utils.GetPgTypeUUID(arg.UserID),
+				ID:     guestCart.ID,
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("AssignCartToUser")
+			}
+			return err
+		}
+
+		guestItems, err := q.GetCartItemsForMerge(ctx, guestCart.ID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetCartItemsForMerge")
+			return err
+		}
+
+		for _, item := range guestItems {
+			existing, err := q.GetCartItemByProductVariantID(ctx, GetCartItemByProductVariantIDParams{
+				VariantID: item.VariantID,
+				CartID:    userCart.ID,
+			})
+			if err != nil && !errors.Is(err, ErrRecordNotFound) {
+				log.Error().Err(err).Msg("GetCartItemByProductVariantID")
+				return err
+			}
+
+			found := err == nil
+			qty := int32(item.Quantity)
+			if found {
+				qty += int32(existing.Quantity)
+			}
+			qty = min(qty, item.Stock)
+			if qty <= 0 || (found && qty == int32(existing.Quantity)) {
+				continue
+			}
+
+			if found {
+				err = q.UpdateCartItemQuantity(ctx, UpdateCartItemQuantityParams{
+					Quantity: int16(qty),
+					ID:       existing.ID,
+				})
+				if err != nil {
+					log.Error().Err(err).Msg("UpdateCartItemQuantity")
+					return err
+				}
+				continue
+			}
+			_, err = q.AddCartItem(ctx, AddCartItemParams{
+				CartID:    userCart.ID,
+				VariantID: item.VariantID,
+				Quantity:  int16(qty),
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("AddCartItem")
+				return err
+			}
+		}
+
+		if err = q.DeleteCart(ctx, guestCart.ID); err != nil {
+			log.Error().Err(err).Msg("DeleteCart")
+			return err
+		}
+		if err = q.UpdateCartTimestamp(ctx, userCart.ID); err != nil {
+			log.Error().Err(err).Msg("UpdateCartTimestamp")
+			return err
+		}
+		return nil
+	})
+}
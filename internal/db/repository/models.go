@@ -157,6 +157,7 @@ const (
 	PaymentStatusCancelled  PaymentStatus = "cancelled"
 	PaymentStatusRefunded   PaymentStatus = "refunded"
 	PaymentStatusProcessing PaymentStatus = "processing"
+	PaymentStatusAuthorized PaymentStatus = "authorized"
 )
 
 func (e *PaymentStatus) Scan(src interface{}) error {
@@ -281,6 +282,15 @@ type CollectionProduct struct {
 	ProductID    uuid.UUID `json:"productId"`
 }
 
+type CurrencyRate struct {
+	Currency          string         `json:"currency"`
+	Rate              pgtype.Numeric `json:"rate"`
+	RoundingIncrement pgtype.Numeric `json:"roundingIncrement"`
+	IsActive          bool           `json:"isActive"`
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+}
+
 type Discount struct {
 	ID                uuid.UUID          `json:"id"`
 	Code              string             `json:"code"`
@@ -347,9 +357,20 @@ type FeaturedSection struct {
 	UpdatedAt   time.Time `json:"updatedAt"`
 }
 
+type InventoryReservation struct {
+	ID        uuid.UUID `json:"id"`
+	OrderID   uuid.UUID `json:"orderId"`
+	VariantID uuid.UUID `json:"variantId"`
+	Quantity  int32     `json:"quantity"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
 type Order struct {
 	ID                    uuid.UUID               `json:"id"`
-	UserID                uuid.UUID               `json:"userId"`
+	UserID                pgtype.UUID             `json:"userId"`
 	CustomerEmail         string                  `json:"customerEmail"`
 	CustomerName          string                  `json:"customerName"`
 	CustomerPhone         string                  `json:"customerPhone"`
@@ -370,6 +391,7 @@ type Order struct {
 	TrackingUrl           *string                 `json:"trackingUrl"`
 	ShippingProvider      *string                 `json:"shippingProvider"`
 	ShippingNotes         *string                 `json:"shippingNotes"`
+	Currency              string                  `json:"currency"`
 }
 
 type OrderItem struct {
@@ -387,6 +409,26 @@ type OrderItem struct {
 	DiscountedPrice      pgtype.Numeric          `json:"discountedPrice"`
 }
 
+type OrderStatusHistory struct {
+	ID         uuid.UUID       `json:"id"`
+	OrderID    uuid.UUID       `json:"orderId"`
+	FromStatus NullOrderStatus `json:"fromStatus"`
+	ToStatus   OrderStatus     `json:"toStatus"`
+	Actor      string          `json:"actor"`
+	ActorID    pgtype.UUID     `json:"actorId"`
+	Reason     *string         `json:"reason"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+type PasswordReset struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"userId"`
+	TokenHash string             `json:"tokenHash"`
+	ExpiresAt time.Time          `json:"expiresAt"`
+	UsedAt    pgtype.Timestamptz `json:"usedAt"`
+	CreatedAt time.Time          `json:"createdAt"`
+}
+
 type Payment struct {
 	ID               uuid.UUID          `json:"id"`
 	OrderID          uuid.UUID          `json:"orderId"`
@@ -405,6 +447,24 @@ type Payment struct {
 	Metadata         []byte             `json:"metadata"`
 	CreatedAt        pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt        pgtype.Timestamptz `json:"updatedAt"`
+	Currency         string             `json:"currency"`
+}
+
+type PaymentAuthorization struct {
+	PaymentID      uuid.UUID          `json:"paymentId"`
+	Amount         pgtype.Numeric     `json:"amount"`
+	CapturedAmount pgtype.Numeric     `json:"capturedAmount"`
+	AuthorizedAt   time.Time          `json:"authorizedAt"`
+	ExpiresAt      time.Time          `json:"expiresAt"`
+	CapturedAt     pgtype.Timestamptz `json:"capturedAt"`
+	VoidedAt       pgtype.Timestamptz `json:"voidedAt"`
+}
+
+type PaymentCustomer struct {
+	UserID     uuid.UUID `json:"userId"`
+	Gateway    string    `json:"gateway"`
+	CustomerID string    `json:"customerId"`
+	CreatedAt  time.Time `json:"createdAt"`
 }
 
 type PaymentMethod struct {
@@ -427,6 +487,29 @@ type PaymentMethod struct {
 	UpdatedAt               time.Time      `json:"updatedAt"`
 }
 
+type PaymentReconciliation struct {
+	ID         uuid.UUID          `json:"id"`
+	StartedAt  time.Time          `json:"startedAt"`
+	FinishedAt pgtype.Timestamptz `json:"finishedAt"`
+	Checked    int32              `json:"checked"`
+	Repaired   int32              `json:"repaired"`
+	Failed     int32              `json:"failed"`
+}
+
+type PaymentReconciliationItem struct {
+	ID               uuid.UUID     `json:"id"`
+	ReconciliationID uuid.UUID     `json:"reconciliationId"`
+	PaymentID        uuid.UUID     `json:"paymentId"`
+	OrderID          uuid.UUID     `json:"orderId"`
+	Gateway          string        `json:"gateway"`
+	PaymentIntentID  *string       `json:"paymentIntentId"`
+	LocalStatus      PaymentStatus `json:"localStatus"`
+	GatewayStatus    *string       `json:"gatewayStatus"`
+	Action           string        `json:"action"`
+	Message          *string       `json:"message"`
+	CreatedAt        time.Time     `json:"createdAt"`
+}
+
 type PaymentTransaction struct {
 	ID                     uuid.UUID          `json:"id"`
 	PaymentID              uuid.UUID          `json:"paymentId"`
@@ -542,6 +625,57 @@ type RatingVote struct {
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+type Refund struct {
+	ID              uuid.UUID      `json:"id"`
+	PaymentID       uuid.UUID      `json:"paymentId"`
+	OrderID         uuid.UUID      `json:"orderId"`
+	Amount          pgtype.Numeric `json:"amount"`
+	Reason          *string        `json:"reason"`
+	Status          string         `json:"status"`
+	GatewayRefundID *string        `json:"gatewayRefundId"`
+	Restocked       bool           `json:"restocked"`
+	CreatedBy       pgtype.UUID    `json:"createdBy"`
+	CreatedAt       time.Time      `json:"createdAt"`
+	UpdatedAt       time.Time      `json:"updatedAt"`
+}
+
+type RefundItem struct {
+	ID          uuid.UUID      `json:"id"`
+	RefundID    uuid.UUID      `json:"refundId"`
+	OrderItemID uuid.UUID      `json:"orderItemId"`
+	Quantity    int32          `json:"quantity"`
+	Amount      pgtype.Numeric `json:"amount"`
+}
+
+type ReturnRequest struct {
+	ID         uuid.UUID          `json:"id"`
+	OrderID    uuid.UUID          `json:"orderId"`
+	UserID     uuid.UUID          `json:"userId"`
+	Reason     string             `json:"reason"`
+	Status     string             `json:"status"`
+	AdminNote  *string            `json:"adminNote"`
+	RefundID   pgtype.UUID        `json:"refundId"`
+	ReviewedBy pgtype.UUID        `json:"reviewedBy"`
+	ReviewedAt pgtype.Timestamptz `json:"reviewedAt"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	UpdatedAt  time.Time          `json:"updatedAt"`
+}
+
+type ReturnRequestImage struct {
+	ID              uuid.UUID `json:"id"`
+	ReturnRequestID uuid.UUID `json:"returnRequestId"`
+	ImageID         string    `json:"imageId"`
+	ImageUrl        string    `json:"imageUrl"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+type ReturnRequestItem struct {
+	ID              uuid.UUID `json:"id"`
+	ReturnRequestID uuid.UUID `json:"returnRequestId"`
+	OrderItemID     uuid.UUID `json:"orderItemId"`
+	Quantity        int32     `json:"quantity"`
+}
+
 type Shipment struct {
 	ID               uuid.UUID          `json:"id"`
 	OrderID          uuid.UUID          `json:"orderId"`
@@ -644,19 +778,49 @@ type UserAddress struct {
 	IsDefault   bool      `json:"isDefault"`
 	CreatedAt   time.Time `json:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt"`
+	Country     *string   `json:"country"`
+	State       *string   `json:"state"`
+	ZipCode     *string   `json:"zipCode"`
+}
+
+type UserAuditLog struct {
+	ID        uuid.UUID   `json:"id"`
+	UserID    uuid.UUID   `json:"userId"`
+	ActorID   pgtype.UUID `json:"actorId"`
+	Action    string      `json:"action"`
+	Details   []byte      `json:"details"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+type UserMfa struct {
+	UserID       uuid.UUID          `json:"userId"`
+	Secret       string             `json:"secret"`
+	EnabledAt    pgtype.Timestamptz `json:"enabledAt"`
+	LastUsedStep int64              `json:"lastUsedStep"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	UpdatedAt    time.Time          `json:"updatedAt"`
 }
 
 type UserPaymentInfo struct {
 	ID                 uuid.UUID          `json:"id"`
 	UserID             uuid.UUID          `json:"userId"`
-	CardNumber         string             `json:"cardNumber"`
 	CardLast4          string             `json:"cardLast4"`
 	PaymentMethodToken string             `json:"paymentMethodToken"`
 	ExpirationDate     pgtype.Date        `json:"expirationDate"`
-	BillingAddress     string             `json:"billingAddress"`
-	IsDefault          *bool              `json:"isDefault"`
+	BillingAddress     *string            `json:"billingAddress"`
+	IsDefault          bool               `json:"isDefault"`
 	CreatedAt          pgtype.Timestamptz `json:"createdAt"`
 	UpdatedAt          pgtype.Timestamptz `json:"updatedAt"`
+	Gateway            string             `json:"gateway"`
+	CardBrand          *string            `json:"cardBrand"`
+}
+
+type UserRecoveryCode struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"userId"`
+	CodeHash  string             `json:"codeHash"`
+	UsedAt    pgtype.Timestamptz `json:"usedAt"`
+	CreatedAt time.Time          `json:"createdAt"`
 }
 
 type UserRole struct {
@@ -667,20 +831,37 @@ type UserRole struct {
 	IsActive    bool      `json:"isActive"`
 	CreatedAt   time.Time `json:"createdAt"`
 	UpdatedAt   time.Time `json:"updatedAt"`
+	RequireMfa  bool      `json:"requireMfa"`
 }
 
 type UserSession struct {
-	ID           uuid.UUID  `json:"id"`
-	UserID       uuid.UUID  `json:"userId"`
-	RefreshToken string     `json:"refreshToken"`
-	UserAgent    string     `json:"userAgent"`
-	ClientIp     netip.Addr `json:"clientIp"`
-	Blocked      bool       `json:"blocked"`
-	ExpiredAt    time.Time  `json:"expiredAt"`
-	CreatedAt    time.Time  `json:"createdAt"`
+	ID           uuid.UUID          `json:"id"`
+	UserID       uuid.UUID          `json:"userId"`
+	RefreshToken string             `json:"refreshToken"`
+	UserAgent    string             `json:"userAgent"`
+	ClientIp     netip.Addr         `json:"clientIp"`
+	Blocked      bool               `json:"blocked"`
+	ExpiredAt    time.Time          `json:"expiredAt"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	FamilyID     uuid.UUID          `json:"familyId"`
+	RotatedAt    pgtype.Timestamptz `json:"rotatedAt"`
 }
 
 type VariantAttributeValue struct {
 	VariantID        uuid.UUID `json:"variantId"`
 	AttributeValueID int64     `json:"attributeValueId"`
 }
+
+type WebhookEvent struct {
+	ID          uuid.UUID          `json:"id"`
+	Provider    string             `json:"provider"`
+	EventID     string             `json:"eventId"`
+	EventType   string             `json:"eventType"`
+	Payload     []byte             `json:"payload"`
+	Status      string             `json:"status"`
+	Attempts    int32              `json:"attempts"`
+	LastError   *string            `json:"lastError"`
+	ProcessedAt pgtype.Timestamptz `json:"processedAt"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+}
@@ -0,0 +1,235 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+type RecordOfflinePaymentTxArgs struct {
+	OrderID uuid.UUID
+	// Amount is what was received, the outstanding balance when nil
+	Amount *float64
+	// Reference identifies the receipt, e.g. the bank statement line
+	Reference *string
+	OrderActorArgs
+}
+
+type RecordOfflinePaymentTxResult struct {
+	Payment     Payment     `json:"payment"`
+	Received    float64     `json:"received"`
+	Outstanding float64     `json:"outstanding"`
+	OrderStatus OrderStatus `json:"orderStatus"`
+}
+
+// RecordOfflinePaymentTx records money received for a cash on delivery or bank transfer
+// payment. The payment stays processing until it is received in full, then it succeeds
+// and an order still waiting for the money is confirmed.
+func (repo *pgRepo) RecordOfflinePaymentTx(ctx context.Context, arg RecordOfflinePaymentTxArgs) (RecordOfflinePaymentTxResult, error) {
+	var result RecordOfflinePaymentTxResult
+	err := repo.execTx(ctx, func(q *Queries) error {
+		payment, err := getOfflinePaymentForUpdate(ctx, q, arg.OrderID)
+		if err != nil {
+			return err
+		}
+
+		result, err = recordOfflinePayment(ctx, q, payment, arg.Amount, arg.Reference, arg.Reason)
+		if err != nil {
+			return err
+		}
+		if result.Outstanding > 0 {
+			return nil
+		}
+
+		order, err := q.GetOrderForUpdate(ctx, arg.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetOrderForUpdate")
+			return err
+		}
+		result.OrderStatus = order.Status
+		if order.Status != OrderStatusPending {
+			return nil
+		}
+		err = changeOrderStatus(ctx, q, ChangeOrderStatusTxArgs{
+			OrderID:        arg.OrderID,
+			Status:         OrderStatusConfirmed,
+			OrderActorArgs: arg.OrderActorArgs,
+		})
+		if err != nil {
+			return err
+		}
+		result.OrderStatus = OrderStatusConfirmed
+		return nil
+	})
+	return result, err
+}
+
+type ExpireOfflinePaymentTxArgs struct {
+	OrderID uuid.UUID
+	OrderActorArgs
+}
+
+// ExpireOfflinePaymentTx gives up on an offline payment that never arrived. The payment
+// is cancelled together with its order, which puts the reserved stock back on sale.
+func (repo *pgRepo) ExpireOfflinePaymentTx(ctx context.Context, arg ExpireOfflinePaymentTxArgs) (Payment, error) {
+	var payment Payment
+	err := repo.execTx(ctx, func(q *Queries) (err error) {
+		payment, err = getOfflinePaymentForUpdate(ctx, q, arg.OrderID)
+		if err != nil {
+			return err
+		}
+
+		err = changeOrderStatus(ctx, q, ChangeOrderStatusTxArgs{
+			OrderID:        arg.OrderID,
+			Status:         OrderStatusCancelled,
+			OrderActorArgs: arg.OrderActorArgs,
+		})
+		if err != nil {
+			return err
+		}
+
+		message := "payment was not received in time"
+		if arg.Reason != nil {
+			message = *arg.Reason
+		}
+		err = q.UpdatePayment(ctx, UpdatePaymentParams{
+			ID:           payment.ID,
+			Status:       NullPaymentStatus{PaymentStatus: PaymentStatusCancelled, Valid: true},
+			ErrorCode:    utils.StringPtr("expired"),
+			ErrorMessage: &message,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("UpdatePayment")
+			return err
+		}
+		payment.Status = PaymentStatusCancelled
+		return nil
+	})
+	return payment, err
+}
+
+// getOfflinePaymentForUpdate locks the payment of an order and checks it is an offline
+// payment still waiting for money.
+func getOfflinePaymentForUpdate(ctx context.Context, q *Queries, orderID uuid.UUID) (Payment, error) {
+	payment, err := q.GetPaymentByOrderIDForUpdate(ctx, orderID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetPaymentByOrderIDForUpdate")
+		return payment, err
+	}
+	method, err := q.GetPaymentMethodByID(ctx, payment.PaymentMethodID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetPaymentMethodByID")
+		return payment, err
+	}
+	if !IsOfflinePaymentMethod(method) {
+		return payment, fmt.Errorf("%w: %s payments are settled by the gateway", ErrInvalidPayment, method.Code)
+	}
+	if payment.Status != PaymentStatusPending && payment.Status != PaymentStatusProcessing {
+		return payment, fmt.Errorf("%w: payment is %s", ErrInvalidPayment, payment.Status)
+	}
+	return payment, nil
+}
+
+// recordOfflinePayment adds a receipt to a locked offline payment and moves the payment
+// to processing or, once nothing is outstanding, to success.
+func recordOfflinePayment(ctx context.Context, q *Queries, pm Payment, amount *float64, reference, note *string) (RecordOfflinePaymentTxResult, error) {
+	result := RecordOfflinePaymentTxResult{Payment: pm}
+	currency := payment.NormalizeCurrency(pm.Currency)
+	total := utils.GetMoneyFromPgNumeric(pm.Amount, currency)
+	receivedNum, err := q.GetReceivedAmountByPaymentID(ctx, pm.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetReceivedAmountByPaymentID")
+		return result, err
+	}
+	received := utils.GetMoneyFromPgNumeric(receivedNum, currency)
+	outstanding := total.Sub(received)
+
+	receipt := outstanding
+	if amount != nil {
+		receipt = payment.MoneyFromFloat(*amount, currency, payment.RoundHalfUp)
+	}
+	if !receipt.IsPositive() {
+		return result, fmt.Errorf("%w: nothing outstanding on the payment", ErrInvalidPayment)
+	}
+	if receipt.Cmp(outstanding) > 0 {
+		return result, fmt.Errorf("%w: only %s outstanding on the payment", ErrInvalidPayment, outstanding)
+	}
+
+	_, err = q.CreatePaymentTransaction(ctx, CreatePaymentTransactionParams{
+		PaymentID:              pm.ID,
+		Amount:                 utils.GetPgNumericFromMoney(receipt),
+		Status:                 PaymentStatusSuccess,
+		GatewayTransactionID:   reference,
+		GatewayResponseCode:    utils.StringPtr("offline_received"),
+		GatewayResponseMessage: note,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("CreatePaymentTransaction")
+		return result, err
+	}
+
+	result.Received = received.Add(receipt).Float64()
+	outstanding = outstanding.Sub(receipt)
+	result.Outstanding = outstanding.Float64()
+	status := PaymentStatusProcessing
+	if !outstanding.IsPositive() {
+		status = PaymentStatusSuccess
+	}
+	err = q.UpdatePayment(ctx, UpdatePaymentParams{
+		ID:     pm.ID,
+		Status: NullPaymentStatus{PaymentStatus: status, Valid: true},
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("UpdatePayment")
+		return result, err
+	}
+	result.Payment.Status = status
+	return result, nil
+}
+
+// settleCashOnDelivery collects what is left of a cash on delivery payment when the order
+// is handed over. Orders paid any other way are left alone.
+func settleCashOnDelivery(ctx context.Context, q *Queries, orderID uuid.UUID) error {
+	payment, err := q.GetPaymentByOrderIDForUpdate(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return nil
+		}
+		log.Error().Err(err).Msg("GetPaymentByOrderIDForUpdate")
+		return err
+	}
+	if payment.Status != PaymentStatusPending && payment.Status != PaymentStatusProcessing {
+		return nil
+	}
+	method, err := q.GetPaymentMethodByID(ctx, payment.PaymentMethodID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetPaymentMethodByID")
+		return err
+	}
+	if method.Code != PaymentMethodCOD {
+		return nil
+	}
+	note := "collected on delivery"
+	_, err = recordOfflinePayment(ctx, q, payment, nil, nil, &note)
+	return err
+}
+
+// newPaymentReference generates the reference a customer quotes on a bank transfer.
+// It leaves out characters that are easily confused when typed by hand.
+func newPaymentReference() (string, error) {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	for i, b := range buf {
+		buf[i] = alphabet[int(b)%len(alphabet)]
+	}
+	return "BT-" + string(buf), nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/orderstate"
+)
+
+// OrderActorArgs identifies who changes the status of an order
+type OrderActorArgs struct {
+	Actor orderstate.Actor
+	// ID is the acting user, left invalid for the system and guests
+	ID     pgtype.UUID
+	Reason *string
+}
+
+type ChangeOrderStatusTxArgs struct {
+	OrderID uuid.UUID
+	Status  OrderStatus
+	OrderActorArgs
+}
+
+// ChangeOrderStatusTx moves an order to a new status through the order state machine.
+func (repo *pgRepo) ChangeOrderStatusTx(ctx context.Context, arg ChangeOrderStatusTxArgs) error {
+	return repo.execTx(ctx, func(q *Queries) error {
+		return changeOrderStatus(ctx, q, arg)
+	})
+}
+
+// changeOrderStatus locks the order, checks the transition is legal for the actor, applies
+// its side effects and records it in the status history. It runs inside the caller's transaction.
+func changeOrderStatus(ctx context.Context, q *Queries, arg ChangeOrderStatusTxArgs) error {
+	order, err := q.GetOrderForUpdate(ctx, arg.OrderID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetOrderForUpdate")
+		return err
+	}
+
+	transition, err := orderstate.Authorize(orderstate.Status(order.Status), orderstate.Status(arg.Status), arg.Actor)
+	if err != nil {
+		return err
+	}
+
+	now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	params := UpdateOrderParams{
+		ID:     order.ID,
+		Status: NullOrderStatus{OrderStatus: arg.Status, Valid: true},
+	}
+	if transition.Effects.StampConfirmedAt {
+		params.ConfirmedAt = now
+	}
+	if transition.Effects.StampDeliveredAt {
+		params.DeliveredAt = now
+	}
+	if transition.Effects.StampCancelledAt {
+		params.CancelledAt = now
+	}
+	if transition.Effects.StampRefundedAt {
+		params.RefundedAt = now
+	}
+	if _, err = q.UpdateOrder(ctx, params); err != nil {
+		log.Error().Err(err).Msg("UpdateOrder")
+		return err
+	}
+
+	if transition.Effects.CommitReservations {
+		if err = q.CommitOrderReservations(ctx, order.ID); err != nil {
+			log.Error().Err(err).Msg("CommitOrderReservations")
+			return err
+		}
+	}
+
+	if transition.Effects.ReleaseReservations {
+		// put back exactly what was reserved, released reservations are skipped
+		// so cancelling twice can't restock twice
+		reservations, err := q.ReleaseOrderReservations(ctx, order.ID)
+		if err != nil {
+			log.Error().Err(err).Msg("ReleaseOrderReservations")
+			return err
+		}
+		for _, reservation := range reservations {
+			_, err := q.RestoreProductStock(ctx, RestoreProductStockParams{
+				ID:    reservation.VariantID,
+				Stock: reservation.Quantity,
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("RestoreProductStock")
+				return err
+			}
+		}
+	}
+
+	if transition.Effects.SettleCashOnDelivery {
+		if err = settleCashOnDelivery(ctx, q, order.ID); err != nil {
+			return err
+		}
+	}
+
+	_, err = q.CreateOrderStatusHistory(ctx, CreateOrderStatusHistoryParams{
+		OrderID:    order.ID,
+		FromStatus: NullOrderStatus{OrderStatus: order.Status, Valid: true},
+		ToStatus:   arg.Status,
+		Actor:      string(arg.Actor),
+		ActorID:    arg.ID,
+		Reason:     arg.Reason,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("CreateOrderStatusHistory")
+		return err
+	}
+	return nil
+}
@@ -13,13 +13,32 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const claimGuestOrders = `-- name: ClaimGuestOrders :execrows
+UPDATE orders
+SET user_id = $1, updated_at = NOW()
+WHERE user_id IS NULL AND LOWER(customer_email) = LOWER($2)
+`
+
+type ClaimGuestOrdersParams struct {
+	UserID pgtype.UUID `json:"userId"`
+	Email  string      `json:"email"`
+}
+
+func (q *Queries) ClaimGuestOrders(ctx context.Context, arg ClaimGuestOrdersParams) (int64, error) {
+	result, err := q.db.Exec(ctx, claimGuestOrders, arg.UserID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const countOrders = `-- name: CountOrders :one
 SELECT COUNT(*)
 FROM orders ord
 LEFT JOIN payments p ON ord.id = p.order_id
 WHERE
     ord.status = COALESCE($1, ord.status) AND
-    user_id = COALESCE($2, user_id) AND
+    ($2::uuid IS NULL OR ord.user_id = $2) AND
     ord.created_at >= COALESCE($3, ord.created_at) AND
     ord.created_at <= COALESCE($4, ord.created_at) AND
     (p.status IS NULL OR p.status = COALESCE($5, p.status))
@@ -59,16 +78,20 @@ type CreateBulkOrderItemsParams struct {
 }
 
 const createOrder = `-- name: CreateOrder :one
-INSERT INTO orders (user_id, customer_email, customer_name, customer_phone, total_price, shipping_address) VALUES ($1, $2, $3, $4,  $5, $6) RETURNING id, user_id, customer_email, customer_name, customer_phone, shipping_address, total_price, status, confirmed_at, delivered_at, cancelled_at, shipping_method, refunded_at, order_date, updated_at, created_at, shipping_method_id, shipping_rate_id, estimated_delivery_date, tracking_url, shipping_provider, shipping_notes
+INSERT INTO orders (user_id, customer_email, customer_name, customer_phone, total_price, shipping_address, shipping_method_id, shipping_rate_id, shipping_method, currency) VALUES ($1, $2, $3, $4,  $5, $6, $7, $8, $9, $10) RETURNING id, user_id, customer_email, customer_name, customer_phone, shipping_address, total_price, status, confirmed_at, delivered_at, cancelled_at, shipping_method, refunded_at, order_date, updated_at, created_at, shipping_method_id, shipping_rate_id, estimated_delivery_date, tracking_url, shipping_provider, shipping_notes, currency
 `
 
 type CreateOrderParams struct {
-	UserID          uuid.UUID               `json:"userId"`
-	CustomerEmail   string                  `json:"customerEmail"`
-	CustomerName    string                  `json:"customerName"`
-	CustomerPhone   string                  `json:"customerPhone"`
-	TotalPrice      pgtype.Numeric          `json:"totalPrice"`
-	ShippingAddress ShippingAddressSnapshot `json:"shippingAddress"`
+	UserID           pgtype.UUID             `json:"userId"`
+	CustomerEmail    string                  `json:"customerEmail"`
+	CustomerName     string                  `json:"customerName"`
+	CustomerPhone    string                  `json:"customerPhone"`
+	TotalPrice       pgtype.Numeric          `json:"totalPrice"`
+	ShippingAddress  ShippingAddressSnapshot `json:"shippingAddress"`
+	ShippingMethodID pgtype.UUID             `json:"shippingMethodId"`
+	ShippingRateID   pgtype.UUID             `json:"shippingRateId"`
+	ShippingMethod   *string                 `json:"shippingMethod"`
+	Currency         string                  `json:"currency"`
 }
 
 func (q *Queries) CreateOrder(ctx context.Context, arg CreateOrderParams) (Order, error) {
@@ -79,6 +102,10 @@ func (q *Queries) CreateOrder(ctx context.Context, arg CreateOrderParams) (Order
 		arg.CustomerPhone,
 		arg.TotalPrice,
 		arg.ShippingAddress,
+		arg.ShippingMethodID,
+		arg.ShippingRateID,
+		arg.ShippingMethod,
+		arg.Currency,
 	)
 	var i Order
 	err := row.Scan(
@@ -104,6 +131,7 @@ func (q *Queries) CreateOrder(ctx context.Context, arg CreateOrderParams) (Order
 		&i.TrackingUrl,
 		&i.ShippingProvider,
 		&i.ShippingNotes,
+		&i.Currency,
 	)
 	return i, err
 }
@@ -165,7 +193,7 @@ func (q *Queries) DeleteOrder(ctx context.Context, id uuid.UUID) error {
 
 const getOrder = `-- name: GetOrder :one
 SELECT
-    orders.id, orders.user_id, orders.customer_email, orders.customer_name, orders.customer_phone, orders.shipping_address, orders.total_price, orders.status, orders.confirmed_at, orders.delivered_at, orders.cancelled_at, orders.shipping_method, orders.refunded_at, orders.order_date, orders.updated_at, orders.created_at, orders.shipping_method_id, orders.shipping_rate_id, orders.estimated_delivery_date, orders.tracking_url, orders.shipping_provider, orders.shipping_notes,
+    orders.id, orders.user_id, orders.customer_email, orders.customer_name, orders.customer_phone, orders.shipping_address, orders.total_price, orders.status, orders.confirmed_at, orders.delivered_at, orders.cancelled_at, orders.shipping_method, orders.refunded_at, orders.order_date, orders.updated_at, orders.created_at, orders.shipping_method_id, orders.shipping_rate_id, orders.estimated_delivery_date, orders.tracking_url, orders.shipping_provider, orders.shipping_notes, orders.currency,
     pm.id as payment_id,
     pm.status as payment_status,
     pm.amount as payment_amount,
@@ -183,7 +211,7 @@ LIMIT 1
 
 type GetOrderRow struct {
 	ID                    uuid.UUID               `json:"id"`
-	UserID                uuid.UUID               `json:"userId"`
+	UserID                pgtype.UUID             `json:"userId"`
 	CustomerEmail         string                  `json:"customerEmail"`
 	CustomerName          string                  `json:"customerName"`
 	CustomerPhone         string                  `json:"customerPhone"`
@@ -204,6 +232,7 @@ type GetOrderRow struct {
 	TrackingUrl           *string                 `json:"trackingUrl"`
 	ShippingProvider      *string                 `json:"shippingProvider"`
 	ShippingNotes         *string                 `json:"shippingNotes"`
+	Currency              string                  `json:"currency"`
 	PaymentID             pgtype.UUID             `json:"paymentId"`
 	PaymentStatus         NullPaymentStatus       `json:"paymentStatus"`
 	PaymentAmount         pgtype.Numeric          `json:"paymentAmount"`
@@ -240,6 +269,7 @@ func (q *Queries) GetOrder(ctx context.Context, id uuid.UUID) (GetOrderRow, erro
 		&i.TrackingUrl,
 		&i.ShippingProvider,
 		&i.ShippingNotes,
+		&i.Currency,
 		&i.PaymentID,
 		&i.PaymentStatus,
 		&i.PaymentAmount,
@@ -252,6 +282,22 @@ func (q *Queries) GetOrder(ctx context.Context, id uuid.UUID) (GetOrderRow, erro
 	return i, err
 }
 
+const getOrderForUpdate = `-- name: GetOrderForUpdate :one
+SELECT id, status FROM orders WHERE id = $1 FOR UPDATE
+`
+
+type GetOrderForUpdateRow struct {
+	ID     uuid.UUID   `json:"id"`
+	Status OrderStatus `json:"status"`
+}
+
+func (q *Queries) GetOrderForUpdate(ctx context.Context, id uuid.UUID) (GetOrderForUpdateRow, error) {
+	row := q.db.QueryRow(ctx, getOrderForUpdate, id)
+	var i GetOrderForUpdateRow
+	err := row.Scan(&i.ID, &i.Status)
+	return i, err
+}
+
 const getOrderItemByID = `-- name: GetOrderItemByID :one
 SELECT oi.id as order_item_id, o.id as order_id, p.id as product_id, pv.id as variant_id, o.user_id
 FROM order_items oi
@@ -263,11 +309,11 @@ LIMIT 1
 `
 
 type GetOrderItemByIDRow struct {
-	OrderItemID uuid.UUID `json:"orderItemId"`
-	OrderID     uuid.UUID `json:"orderId"`
-	ProductID   uuid.UUID `json:"productId"`
-	VariantID   uuid.UUID `json:"variantId"`
-	UserID      uuid.UUID `json:"userId"`
+	OrderItemID uuid.UUID   `json:"orderItemId"`
+	OrderID     uuid.UUID   `json:"orderId"`
+	ProductID   uuid.UUID   `json:"productId"`
+	VariantID   uuid.UUID   `json:"variantId"`
+	UserID      pgtype.UUID `json:"userId"`
 }
 
 func (q *Queries) GetOrderItemByID(ctx context.Context, id uuid.UUID) (GetOrderItemByIDRow, error) {
@@ -358,11 +404,11 @@ ORDER BY oi.id
 `
 
 type GetOrderItemsByOrderIDRow struct {
-	OrderItemID uuid.UUID `json:"orderItemId"`
-	OrderID     uuid.UUID `json:"orderId"`
-	ProductID   uuid.UUID `json:"productId"`
-	VariantID   uuid.UUID `json:"variantId"`
-	UserID      uuid.UUID `json:"userId"`
+	OrderItemID uuid.UUID   `json:"orderItemId"`
+	OrderID     uuid.UUID   `json:"orderId"`
+	ProductID   uuid.UUID   `json:"productId"`
+	VariantID   uuid.UUID   `json:"variantId"`
+	UserID      pgtype.UUID `json:"userId"`
 }
 
 func (q *Queries) GetOrderItemsByOrderID(ctx context.Context, orderID uuid.UUID) ([]GetOrderItemsByOrderIDRow, error) {
@@ -392,12 +438,12 @@ func (q *Queries) GetOrderItemsByOrderID(ctx context.Context, orderID uuid.UUID)
 }
 
 const getOrders = `-- name: GetOrders :many
-SELECT ord.id, ord.user_id, ord.customer_email, ord.customer_name, ord.customer_phone, ord.shipping_address, ord.total_price, ord.status, ord.confirmed_at, ord.delivered_at, ord.cancelled_at, ord.shipping_method, ord.refunded_at, ord.order_date, ord.updated_at, ord.created_at, ord.shipping_method_id, ord.shipping_rate_id, ord.estimated_delivery_date, ord.tracking_url, ord.shipping_provider, ord.shipping_notes, pm.status as payment_status, COUNT(oi.id) as total_items
+SELECT ord.id, ord.user_id, ord.customer_email, ord.customer_name, ord.customer_phone, ord.shipping_address, ord.total_price, ord.status, ord.confirmed_at, ord.delivered_at, ord.cancelled_at, ord.shipping_method, ord.refunded_at, ord.order_date, ord.updated_at, ord.created_at, ord.shipping_method_id, ord.shipping_rate_id, ord.estimated_delivery_date, ord.tracking_url, ord.shipping_provider, ord.shipping_notes, ord.currency, pm.status as payment_status, COUNT(oi.id) as total_items
 FROM orders ord
 LEFT JOIN order_items oi ON ord.id = oi.order_id
 LEFT JOIN payments pm ON ord.id = pm.order_id
 WHERE
-    ord.user_id = COALESCE($3, ord.user_id) AND
+    ($3::uuid IS NULL OR ord.user_id = $3) AND
     ord.status = COALESCE($4, ord.status) AND
     ord.created_at >= COALESCE($5, ord.created_at) AND
     ord.created_at <= COALESCE($6, ord.created_at) AND
@@ -420,7 +466,7 @@ type GetOrdersParams struct {
 
 type GetOrdersRow struct {
 	ID                    uuid.UUID               `json:"id"`
-	UserID                uuid.UUID               `json:"userId"`
+	UserID                pgtype.UUID             `json:"userId"`
 	CustomerEmail         string                  `json:"customerEmail"`
 	CustomerName          string                  `json:"customerName"`
 	CustomerPhone         string                  `json:"customerPhone"`
@@ -441,6 +487,7 @@ type GetOrdersRow struct {
 	TrackingUrl           *string                 `json:"trackingUrl"`
 	ShippingProvider      *string                 `json:"shippingProvider"`
 	ShippingNotes         *string                 `json:"shippingNotes"`
+	Currency              string                  `json:"currency"`
 	PaymentStatus         NullPaymentStatus       `json:"paymentStatus"`
 	TotalItems            int64                   `json:"totalItems"`
 }
@@ -485,6 +532,7 @@ func (q *Queries) GetOrders(ctx context.Context, arg GetOrdersParams) ([]GetOrde
 			&i.TrackingUrl,
 			&i.ShippingProvider,
 			&i.ShippingNotes,
+			&i.Currency,
 			&i.PaymentStatus,
 			&i.TotalItems,
 		); err != nil {
@@ -542,7 +590,7 @@ func (q *Queries) ListOrderItems(ctx context.Context, arg ListOrderItemsParams)
 }
 
 const maxPreviousOrderByUserID = `-- name: MaxPreviousOrderByUserID :one
-SELECT id, user_id, customer_email, customer_name, customer_phone, shipping_address, total_price, status, confirmed_at, delivered_at, cancelled_at, shipping_method, refunded_at, order_date, updated_at, created_at, shipping_method_id, shipping_rate_id, estimated_delivery_date, tracking_url, shipping_provider, shipping_notes FROM orders
+SELECT id, user_id, customer_email, customer_name, customer_phone, shipping_address, total_price, status, confirmed_at, delivered_at, cancelled_at, shipping_method, refunded_at, order_date, updated_at, created_at, shipping_method_id, shipping_rate_id, estimated_delivery_date, tracking_url, shipping_provider, shipping_notes, currency FROM orders
 WHERE user_id = $1
 ORDER BY created_at DESC
 LIMIT 1
@@ -574,6 +622,7 @@ func (q *Queries) MaxPreviousOrderByUserID(ctx context.Context, userID uuid.UUID
 		&i.TrackingUrl,
 		&i.ShippingProvider,
 		&i.ShippingNotes,
+		&i.Currency,
 	)
 	return i, err
 }
@@ -585,8 +634,9 @@ SET
     confirmed_at = coalesce($2, confirmed_at),
     cancelled_at = coalesce($3, cancelled_at),
     delivered_at = coalesce($4, delivered_at),
+    refunded_at = coalesce($5, refunded_at),
     updated_at = now()
-WHERE id = $5 RETURNING orders.id
+WHERE id = $6 RETURNING orders.id
 `
 
 type UpdateOrderParams struct {
@@ -594,6 +644,7 @@ type UpdateOrderParams struct {
 	ConfirmedAt pgtype.Timestamptz `json:"confirmedAt"`
 	CancelledAt pgtype.Timestamptz `json:"cancelledAt"`
 	DeliveredAt pgtype.Timestamptz `json:"deliveredAt"`
+	RefundedAt  pgtype.Timestamptz `json:"refundedAt"`
 	ID          uuid.UUID          `json:"id"`
 }
 
@@ -603,6 +654,7 @@ func (q *Queries) UpdateOrder(ctx context.Context, arg UpdateOrderParams) (uuid.
 		arg.ConfirmedAt,
 		arg.CancelledAt,
 		arg.DeliveredAt,
+		arg.RefundedAt,
 		arg.ID,
 	)
 	var id uuid.UUID
@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type ChangePasswordTxArgs struct {
+	UserID         uuid.UUID
+	HashedPassword string
+}
+
+type ResetPasswordTxArgs struct {
+	// TokenHash is the hash of the token mailed to the user, the token itself is never stored
+	TokenHash      string
+	HashedPassword string
+}
+
+type ChangePasswordTxResult struct {
+	UserID            uuid.UUID `json:"userId"`
+	PasswordChangedAt time.Time `json:"passwordChangedAt"`
+}
+
+// ChangePasswordTx sets a new password for a user. Every session of the user is blocked and
+// reset tokens still waiting to be used are thrown away.
+func (repo *pgRepo) ChangePasswordTx(ctx context.Context, arg ChangePasswordTxArgs) (ChangePasswordTxResult, error) {
+	var result ChangePasswordTxResult
+	err := repo.execTx(ctx, func(q *Queries) (err error) {
+		result, err = changePassword(ctx, q, arg.UserID, arg.HashedPassword)
+		return err
+	})
+	return result, err
+}
+
+// ResetPasswordTx sets a new password with a reset token, which can only be used once and
+// before it expires.
+func (repo *pgRepo) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxArgs) (ChangePasswordTxResult, error) {
+	var result ChangePasswordTxResult
+	err := repo.execTx(ctx, func(q *Queries) error {
+		reset, err := q.GetPasswordResetByTokenHashForUpdate(ctx, arg.TokenHash)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return fmt.Errorf("%w: token is not valid", ErrInvalidPasswordReset)
+			}
+			log.Error().Err(err).Msg("GetPasswordResetByTokenHashForUpdate")
+			return err
+		}
+		if reset.UsedAt.Valid {
+			return fmt.Errorf("%w: token was already used", ErrInvalidPasswordReset)
+		}
+		if time.Now().After(reset.ExpiresAt) {
+			return fmt.Errorf("%w: token has expired", ErrInvalidPasswordReset)
+		}
+
+		result, err = changePassword(ctx, q, reset.UserID, arg.HashedPassword)
+		return err
+	})
+	return result, err
+}
+
+func changePassword(ctx context.Context, q *Queries, userID uuid.UUID, hashedPassword string) (ChangePasswordTxResult, error) {
+	result := ChangePasswordTxResult{UserID: userID}
+	changedAt, err := q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+		ID:             userID,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("UpdateUserPassword")
+		return result, err
+	}
+	result.PasswordChangedAt = changedAt
+
+	// whoever knew the old password is signed out everywhere
+	if _, err = q.BlockUserSessions(ctx, userID); err != nil {
+		log.Error().Err(err).Msg("BlockUserSessions")
+		return result, err
+	}
+	if err = q.ExpireUserPasswordResets(ctx, userID); err != nil {
+		log.Error().Err(err).Msg("ExpireUserPasswordResets")
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,201 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+// CapturePaymentFn takes the money of an authorization at the gateway and returns the charge
+type CapturePaymentFn func(ctx context.Context, paymentIntentID, method string, amount payment.Money) (chargeID string, err error)
+
+type CapturePaymentTxArgs struct {
+	OrderID uuid.UUID
+	// Amount is what is captured, everything authorized when nil. The rest is released.
+	Amount           *float64
+	CapturePaymentFn CapturePaymentFn
+}
+
+// CapturePaymentTx captures an authorized payment, once. The payment succeeds with the
+// captured amount.
+func (repo *pgRepo) CapturePaymentTx(ctx context.Context, arg CapturePaymentTxArgs) (Payment, error) {
+	var pm Payment
+	err := repo.execTx(ctx, func(q *Queries) (err error) {
+		var auth PaymentAuthorization
+		var method PaymentMethod
+		pm, auth, method, err = getAuthorizedPaymentForUpdate(ctx, q, arg.OrderID)
+		if err != nil {
+			return err
+		}
+
+		currency := payment.NormalizeCurrency(pm.Currency)
+		authorized := utils.GetMoneyFromPgNumeric(auth.Amount, currency)
+		amount := authorized
+		if arg.Amount != nil {
+			amount = payment.MoneyFromFloat(*arg.Amount, currency, payment.RoundHalfUp)
+		}
+		if !amount.IsPositive() {
+			return fmt.Errorf("%w: capture amount must be positive", ErrInvalidPayment)
+		}
+		if amount.Cmp(authorized) > 0 {
+			return fmt.Errorf("%w: only %s is authorized", ErrInvalidPayment, authorized)
+		}
+
+		chargeID, err := arg.CapturePaymentFn(ctx, *pm.PaymentIntentID, method.Code, amount)
+		if err != nil {
+			log.Error().Err(err).Msg("CapturePaymentFn")
+			return err
+		}
+
+		var charge *string
+		if chargeID != "" {
+			charge = &chargeID
+		}
+		captured := utils.GetPgNumericFromMoney(amount)
+		err = q.CapturePaymentAuthorization(ctx, CapturePaymentAuthorizationParams{
+			PaymentID:      pm.ID,
+			CapturedAmount: captured,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("CapturePaymentAuthorization")
+			return err
+		}
+		err = q.UpdatePayment(ctx, UpdatePaymentParams{
+			ID:       pm.ID,
+			Amount:   captured,
+			Status:   NullPaymentStatus{PaymentStatus: PaymentStatusSuccess, Valid: true},
+			ChargeID: charge,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("UpdatePayment")
+			return err
+		}
+		_, err = q.CreatePaymentTransaction(ctx, CreatePaymentTransactionParams{
+			PaymentID:            pm.ID,
+			Amount:               captured,
+			Status:               PaymentStatusSuccess,
+			GatewayTransactionID: charge,
+			GatewayResponseCode:  utils.StringPtr("captured"),
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("CreatePaymentTransaction")
+			return err
+		}
+		pm.Amount = captured
+		pm.Status = PaymentStatusSuccess
+		return nil
+	})
+	return pm, err
+}
+
+type VoidPaymentTxArgs struct {
+	OrderID       uuid.UUID
+	VoidPaymentFn func(ctx context.Context, paymentIntentID, method string) error
+	OrderActorArgs
+}
+
+// VoidPaymentTx releases an authorized payment without taking any money. An order still
+// pending is cancelled with it, which puts its stock back on sale.
+func (repo *pgRepo) VoidPaymentTx(ctx context.Context, arg VoidPaymentTxArgs) (Payment, error) {
+	var pm Payment
+	err := repo.execTx(ctx, func(q *Queries) (err error) {
+		var method PaymentMethod
+		pm, _, method, err = getAuthorizedPaymentForUpdate(ctx, q, arg.OrderID)
+		if err != nil {
+			return err
+		}
+
+		order, err := q.GetOrderForUpdate(ctx, arg.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetOrderForUpdate")
+			return err
+		}
+		// cancel first so an illegal transition never reaches the gateway
+		if order.Status == OrderStatusPending {
+			err = changeOrderStatus(ctx, q, ChangeOrderStatusTxArgs{
+				OrderID:        arg.OrderID,
+				Status:         OrderStatusCancelled,
+				OrderActorArgs: arg.OrderActorArgs,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err = arg.VoidPaymentFn(ctx, *pm.PaymentIntentID, method.Code); err != nil {
+			log.Error().Err(err).Msg("VoidPaymentFn")
+			return err
+		}
+
+		if err = q.VoidPaymentAuthorization(ctx, pm.ID); err != nil {
+			log.Error().Err(err).Msg("VoidPaymentAuthorization")
+			return err
+		}
+		message := "authorization voided"
+		if arg.Reason != nil {
+			message = *arg.Reason
+		}
+		err = q.UpdatePayment(ctx, UpdatePaymentParams{
+			ID:           pm.ID,
+			Status:       NullPaymentStatus{PaymentStatus: PaymentStatusCancelled, Valid: true},
+			ErrorCode:    utils.StringPtr("voided"),
+			ErrorMessage: &message,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("UpdatePayment")
+			return err
+		}
+		_, err = q.CreatePaymentTransaction(ctx, CreatePaymentTransactionParams{
+			PaymentID:              pm.ID,
+			Amount:                 pm.Amount,
+			Status:                 PaymentStatusCancelled,
+			GatewayResponseCode:    utils.StringPtr("voided"),
+			GatewayResponseMessage: &message,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("CreatePaymentTransaction")
+			return err
+		}
+		pm.Status = PaymentStatusCancelled
+		return nil
+	})
+	return pm, err
+}
+
+// getAuthorizedPaymentForUpdate locks the payment of an order and its authorization and
+// checks the money is still held.
+func getAuthorizedPaymentForUpdate(ctx context.Context, q *Queries, orderID uuid.UUID) (Payment, PaymentAuthorization, PaymentMethod, error) {
+	var auth PaymentAuthorization
+	var method PaymentMethod
+	pm, err := q.GetPaymentByOrderIDForUpdate(ctx, orderID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetPaymentByOrderIDForUpdate")
+		return pm, auth, method, err
+	}
+	if pm.Status != PaymentStatusAuthorized || pm.PaymentIntentID == nil {
+		return pm, auth, method, fmt.Errorf("%w: payment is %s", ErrInvalidPayment, pm.Status)
+	}
+	auth, err = q.GetPaymentAuthorizationForUpdate(ctx, pm.ID)
+	if err != nil {
+		if errors.Is(err, ErrRecordNotFound) {
+			return pm, auth, method, fmt.Errorf("%w: payment has no authorization", ErrInvalidPayment)
+		}
+		log.Error().Err(err).Msg("GetPaymentAuthorizationForUpdate")
+		return pm, auth, method, err
+	}
+	if auth.CapturedAt.Valid || auth.VoidedAt.Valid {
+		return pm, auth, method, fmt.Errorf("%w: authorization is already settled", ErrInvalidPayment)
+	}
+	// the payment keeps the gateway's name for the method, the method code names the gateway
+	method, err = q.GetPaymentMethodByID(ctx, pm.PaymentMethodID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetPaymentMethodByID")
+		return pm, auth, method, err
+	}
+	return pm, auth, method, nil
+}
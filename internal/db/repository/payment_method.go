@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+// Payment methods settled outside a gateway
+const (
+	PaymentMethodCOD          = "cod"
+	PaymentMethodBankTransfer = "bank_transfer"
+)
+
+var ErrPaymentMethodNotEligible = errors.New("payment method is not eligible")
+
+// euCountries are the member states covered by the "EU" entry of countries_supported
+var euCountries = []string{
+	"AT", "BE", "BG", "HR", "CY", "CZ", "DK", "EE", "FI", "FR", "DE", "GR", "HU", "IE",
+	"IT", "LV", "LT", "LU", "MT", "NL", "PL", "PT", "RO", "SK", "SI", "ES", "SE",
+}
+
+// IsOfflinePaymentMethod reports whether the method is paid outside a payment gateway.
+func IsOfflinePaymentMethod(method PaymentMethod) bool {
+	return method.GatewaySupported == nil
+}
+
+// PaymentMethodCheck describes the payment a method has to accept
+type PaymentMethodCheck struct {
+	// Amount is in the store currency the limits are set in. It is left zero when there is
+	// nothing to pay yet, which skips the limits.
+	Amount payment.Money
+	// Currency is what the customer pays in
+	Currency string
+	// Country is the ISO 3166-1 alpha-2 code shipped to, left empty when unknown
+	Country string
+}
+
+// CheckPaymentMethod returns ErrPaymentMethodNotEligible when the method is disabled or
+// doesn't accept the amount, currency or country of the payment.
+func CheckPaymentMethod(method PaymentMethod, arg PaymentMethodCheck) error {
+	if !method.IsActive {
+		return fmt.Errorf("%w: %s is not available", ErrPaymentMethodNotEligible, method.Name)
+	}
+	if arg.Amount.IsPositive() {
+		if method.MinAmount.Valid {
+			minAmount := utils.GetMoneyFromPgNumeric(method.MinAmount, arg.Amount.Currency)
+			if arg.Amount.Cmp(minAmount) < 0 {
+				return fmt.Errorf("%w: %s requires a payment of at least %s", ErrPaymentMethodNotEligible, method.Name, minAmount)
+			}
+		}
+		if method.MaxAmount.Valid {
+			maxAmount := utils.GetMoneyFromPgNumeric(method.MaxAmount, arg.Amount.Currency)
+			if arg.Amount.Cmp(maxAmount) > 0 {
+				return fmt.Errorf("%w: %s accepts payments of at most %s", ErrPaymentMethodNotEligible, method.Name, maxAmount)
+			}
+		}
+	}
+	if len(method.CurrencySupported) > 0 && arg.Currency != "" &&
+		!slices.ContainsFunc(method.CurrencySupported, func(c string) bool { return strings.EqualFold(c, arg.Currency) }) {
+		return fmt.Errorf("%w: %s doesn't accept %s", ErrPaymentMethodNotEligible, method.Name, strings.ToUpper(arg.Currency))
+	}
+	if len(method.CountriesSupported) > 0 && arg.Country != "" && !supportsCountry(method.CountriesSupported, arg.Country) {
+		return fmt.Errorf("%w: %s is not available in %s", ErrPaymentMethodNotEligible, method.Name, strings.ToUpper(arg.Country))
+	}
+	return nil
+}
+
+// ProcessingFee is what the payment method charges for a payment of the amount, which is
+// in the store currency the fixed part of the fee is set in.
+func ProcessingFee(method PaymentMethod, amount payment.Money) payment.Money {
+	fee := payment.NewMoney(0, amount.Currency)
+	if !amount.IsPositive() {
+		return fee
+	}
+	if percentage, ok := utils.GetRatFromPgNumeric(method.ProcessingFeePercentage); ok {
+		fee = fee.Add(amount.MulRat(percentage, payment.RoundHalfUp))
+	}
+	fee = fee.Add(utils.GetMoneyFromPgNumeric(method.ProcessingFeeFixed, amount.Currency))
+	return fee.Min(amount)
+}
+
+func supportsCountry(supported []string, country string) bool {
+	country = strings.ToUpper(country)
+	for _, code := range supported {
+		code = strings.ToUpper(code)
+		if code == country || (code == "EU" && slices.Contains(euCountries, country)) {
+			return true
+		}
+	}
+	return false
+}
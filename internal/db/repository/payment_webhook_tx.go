@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/orderstate"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+type ApplyPaymentWebhookTxArgs struct {
+	Event *payment.WebhookEvent
+	// Note is kept on the payment transaction when the gateway sent no message of its own
+	Note string
+	// AuthorizationTTL is how long an authorization is held when the gateway doesn't say
+	AuthorizationTTL time.Duration
+}
+
+type ApplyPaymentWebhookTxResult struct {
+	Payment Payment
+	// Confirmed is set when the event first held or took the money of the order, its
+	// reservations are committed with it
+	Confirmed bool
+}
+
+// ApplyPaymentWebhookTx applies a normalized gateway event to the payment it is about, its
+// transactions, authorization and refunds, and commits the stock of an order it confirms. It
+// all happens at once, so a retried event finds either everything or nothing applied.
+// ErrRecordNotFound is returned when no payment has the transaction or charge ID of the event.
+func (repo *pgRepo) ApplyPaymentWebhookTx(ctx context.Context, arg ApplyPaymentWebhookTxArgs) (ApplyPaymentWebhookTxResult, error) {
+	var result ApplyPaymentWebhookTxResult
+	evt := arg.Event
+	err := repo.execTx(ctx, func(q *Queries) error {
+		pm, err := lockWebhookPayment(ctx, q, evt)
+		if err != nil {
+			return err
+		}
+		result.Payment = pm
+
+		currency := evt.Currency
+		if currency == "" {
+			currency = payment.Currency(pm.Currency)
+		}
+		amount := utils.GetPgNumericFromMoney(payment.NewMoney(evt.Amount, currency))
+		transaction := CreatePaymentTransactionParams{
+			PaymentID:              pm.ID,
+			Amount:                 amount,
+			GatewayTransactionID:   optionalString(evt.ChargeID),
+			GatewayResponseCode:    optionalString(evt.FailureCode),
+			GatewayResponseMessage: optionalString(evt.FailureMessage),
+		}
+		if transaction.GatewayResponseMessage == nil {
+			transaction.GatewayResponseMessage = optionalString(arg.Note)
+		}
+		update := UpdatePaymentParams{
+			ID:           pm.ID,
+			ChargeID:     optionalString(evt.ChargeID),
+			ErrorCode:    optionalString(evt.FailureCode),
+			ErrorMessage: optionalString(evt.FailureMessage),
+		}
+
+		switch evt.Type {
+		case payment.WebhookPaymentAuthorized:
+			if pm.Status != PaymentStatusPending {
+				log.Info().Str("payment_id", pm.ID.String()).Msgf("payment already %s, authorization ignored", pm.Status)
+				return nil
+			}
+			// stripe doesn't say how long it holds the money, fall back to the configured window
+			expiresAt := evt.AuthorizationExpiresAt
+			if expiresAt.IsZero() {
+				expiresAt = time.Now().Add(arg.AuthorizationTTL)
+			}
+			_, err := q.UpsertPaymentAuthorization(ctx, UpsertPaymentAuthorizationParams{
+				PaymentID: pm.ID,
+				Amount:    amount,
+				ExpiresAt: expiresAt,
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("UpsertPaymentAuthorization")
+				return err
+			}
+			transaction.Status = PaymentStatusAuthorized
+			transaction.GatewayResponseCode = utils.StringPtr("authorized")
+			update.Status = NullPaymentStatus{PaymentStatus: PaymentStatusAuthorized, Valid: true}
+			update.Amount = amount
+			update.Gateway = optionalString(evt.PaymentMethod)
+			result.Confirmed = true
+		case payment.WebhookPaymentSucceeded:
+			if pm.Status == PaymentStatusSuccess {
+				// manual captures already settle the payment when they're made
+				log.Info().Str("payment_id", pm.ID.String()).Msg("payment already succeeded")
+				return nil
+			}
+			if pm.Status == PaymentStatusAuthorized {
+				// captured at the gateway dashboard rather than through the store
+				err := q.CapturePaymentAuthorization(ctx, CapturePaymentAuthorizationParams{
+					PaymentID:      pm.ID,
+					CapturedAmount: amount,
+				})
+				if err != nil {
+					log.Error().Err(err).Msg("CapturePaymentAuthorization")
+					return err
+				}
+			}
+			transaction.Status = PaymentStatusSuccess
+			update.Status = NullPaymentStatus{PaymentStatus: PaymentStatusSuccess, Valid: true}
+			update.Amount = amount
+			update.Gateway = optionalString(evt.PaymentMethod)
+			// the order was confirmed when its money was held, a later capture doesn't confirm it twice
+			result.Confirmed = pm.Status != PaymentStatusAuthorized
+		case payment.WebhookPaymentFailed:
+			transaction.Status = PaymentStatusFailed
+			update.Status = NullPaymentStatus{PaymentStatus: PaymentStatusFailed, Valid: true}
+		case payment.WebhookPaymentCancelled:
+			transaction.Status = PaymentStatusCancelled
+			update.Status = NullPaymentStatus{PaymentStatus: PaymentStatusCancelled, Valid: true}
+		case payment.WebhookRefundCompleted:
+			transaction.Status = PaymentStatusRefunded
+			transaction.GatewayTransactionID = optionalString(evt.RefundID)
+			// refunds the gateway had still pending when they were created are settled now
+			rf, err := q.GetRefundByGatewayRefundIDForUpdate(ctx, &evt.RefundID)
+			if err != nil && !errors.Is(err, ErrRecordNotFound) {
+				log.Error().Err(err).Msg("GetRefundByGatewayRefundIDForUpdate")
+				return err
+			}
+			if err == nil {
+				_, err := settleRefund(ctx, q, rf, SettleRefundTxArgs{
+					RefundID:       rf.ID,
+					Status:         RefundStatusSucceeded,
+					OrderActorArgs: OrderActorArgs{Actor: orderstate.ActorSystem},
+				})
+				if err != nil {
+					return err
+				}
+			}
+		case payment.WebhookDisputeOpened:
+			// a dispute doesn't change the payment until it's settled, keep a record of it
+			log.Warn().Str("payment_id", pm.ID.String()).Str("dispute_id", evt.DisputeID).Msg("dispute opened")
+			transaction.Status = pm.Status
+			transaction.GatewayTransactionID = optionalString(evt.DisputeID)
+			transaction.GatewayResponseCode = utils.StringPtr("dispute_opened")
+			transaction.GatewayResponseMessage = optionalString(evt.FailureCode)
+		default:
+			log.Info().Msgf("Unhandled event type: %s", evt.Type)
+			return nil
+		}
+
+		if update.Status.Valid {
+			if err := q.UpdatePayment(ctx, update); err != nil {
+				log.Error().Err(err).Msg("UpdatePayment")
+				return err
+			}
+			result.Payment.Status = update.Status.PaymentStatus
+		}
+		if _, err := q.CreatePaymentTransaction(ctx, transaction); err != nil {
+			log.Error().Err(err).Msg("CreatePaymentTransaction")
+			return err
+		}
+		if result.Confirmed {
+			// paid orders keep their stock, stop the reservation from expiring
+			if err := q.CommitOrderReservations(ctx, pm.OrderID); err != nil {
+				log.Error().Err(err).Msg("CommitOrderReservations")
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		result.Confirmed = false
+	}
+	return result, err
+}
+
+// lockWebhookPayment finds the payment of an event by its transaction ID, or by its charge
+// ID when the gateway only named the money it took
+func lockWebhookPayment(ctx context.Context, q *Queries, evt *payment.WebhookEvent) (Payment, error) {
+	if evt.TransactionID == "" && evt.ChargeID != "" {
+		return q.GetPaymentByChargeIDForUpdate(ctx, &evt.ChargeID)
+	}
+	return q.GetPaymentByPaymentIntentIDForUpdate(ctx, &evt.TransactionID)
+}
+
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
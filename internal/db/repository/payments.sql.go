@@ -13,18 +13,21 @@ import (
 )
 
 const createPayment = `-- name: CreatePayment :one
-INSERT INTO payments (order_id, amount, payment_method_id, gateway, status, payment_intent_id, charge_id, net_amount) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at
+INSERT INTO payments (order_id, amount, payment_method_id, gateway, status, payment_intent_id, charge_id, net_amount, gateway_reference, processing_fee, currency) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at, currency
 `
 
 type CreatePaymentParams struct {
-	OrderID         uuid.UUID      `json:"orderId"`
-	Amount          pgtype.Numeric `json:"amount"`
-	PaymentMethodID uuid.UUID      `json:"paymentMethodId"`
-	Gateway         *string        `json:"gateway"`
-	Status          PaymentStatus  `json:"status"`
-	PaymentIntentID *string        `json:"paymentIntentId"`
-	ChargeID        *string        `json:"chargeId"`
-	NetAmount       pgtype.Numeric `json:"netAmount"`
+	OrderID          uuid.UUID      `json:"orderId"`
+	Amount           pgtype.Numeric `json:"amount"`
+	PaymentMethodID  uuid.UUID      `json:"paymentMethodId"`
+	Gateway          *string        `json:"gateway"`
+	Status           PaymentStatus  `json:"status"`
+	PaymentIntentID  *string        `json:"paymentIntentId"`
+	ChargeID         *string        `json:"chargeId"`
+	NetAmount        pgtype.Numeric `json:"netAmount"`
+	GatewayReference *string        `json:"gatewayReference"`
+	ProcessingFee    pgtype.Numeric `json:"processingFee"`
+	Currency         string         `json:"currency"`
 }
 
 func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error) {
@@ -37,6 +40,9 @@ func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (P
 		arg.PaymentIntentID,
 		arg.ChargeID,
 		arg.NetAmount,
+		arg.GatewayReference,
+		arg.ProcessingFee,
+		arg.Currency,
 	)
 	var i Payment
 	err := row.Scan(
@@ -57,6 +63,7 @@ func (q *Queries) CreatePayment(ctx context.Context, arg CreatePaymentParams) (P
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Currency,
 	)
 	return i, err
 }
@@ -117,8 +124,40 @@ func (q *Queries) DeletePaymentTransaction(ctx context.Context, id uuid.UUID) er
 	return err
 }
 
+// GetPaymentByChargeIDForUpdate finds a payment by what the gateway calls the money it took,
+// for events like paypal disputes that only name the capture
+const getPaymentByChargeIDForUpdate = `-- name: GetPaymentByChargeIDForUpdate :one
+SELECT id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at, currency FROM payments WHERE charge_id = $1 LIMIT 1 FOR UPDATE
+`
+
+func (q *Queries) GetPaymentByChargeIDForUpdate(ctx context.Context, chargeID *string) (Payment, error) {
+	row := q.db.QueryRow(ctx, getPaymentByChargeIDForUpdate, chargeID)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.PaymentMethodID,
+		&i.Amount,
+		&i.ProcessingFee,
+		&i.NetAmount,
+		&i.Status,
+		&i.Gateway,
+		&i.GatewayReference,
+		&i.RefundID,
+		&i.PaymentIntentID,
+		&i.ChargeID,
+		&i.ErrorCode,
+		&i.ErrorMessage,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
+	)
+	return i, err
+}
+
 const getPaymentByID = `-- name: GetPaymentByID :one
-SELECT id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at FROM payments WHERE id = $1 LIMIT 1
+SELECT id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at, currency FROM payments WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetPaymentByID(ctx context.Context, id uuid.UUID) (Payment, error) {
@@ -142,12 +181,13 @@ func (q *Queries) GetPaymentByID(ctx context.Context, id uuid.UUID) (Payment, er
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Currency,
 	)
 	return i, err
 }
 
 const getPaymentByOrderID = `-- name: GetPaymentByOrderID :one
-SELECT id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at FROM payments WHERE order_id = $1 LIMIT 1
+SELECT id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at, currency FROM payments WHERE order_id = $1 LIMIT 1
 `
 
 func (q *Queries) GetPaymentByOrderID(ctx context.Context, orderID uuid.UUID) (Payment, error) {
@@ -171,12 +211,43 @@ func (q *Queries) GetPaymentByOrderID(ctx context.Context, orderID uuid.UUID) (P
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Currency,
+	)
+	return i, err
+}
+
+const getPaymentByOrderIDForUpdate = `-- name: GetPaymentByOrderIDForUpdate :one
+SELECT id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at, currency FROM payments WHERE order_id = $1 LIMIT 1 FOR UPDATE
+`
+
+func (q *Queries) GetPaymentByOrderIDForUpdate(ctx context.Context, orderID uuid.UUID) (Payment, error) {
+	row := q.db.QueryRow(ctx, getPaymentByOrderIDForUpdate, orderID)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.PaymentMethodID,
+		&i.Amount,
+		&i.ProcessingFee,
+		&i.NetAmount,
+		&i.Status,
+		&i.Gateway,
+		&i.GatewayReference,
+		&i.RefundID,
+		&i.PaymentIntentID,
+		&i.ChargeID,
+		&i.ErrorCode,
+		&i.ErrorMessage,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
 	)
 	return i, err
 }
 
 const getPaymentByPaymentIntentID = `-- name: GetPaymentByPaymentIntentID :one
-SELECT id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at FROM payments WHERE payment_intent_id = $1 LIMIT 1
+SELECT id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at, currency FROM payments WHERE payment_intent_id = $1 LIMIT 1
 `
 
 func (q *Queries) GetPaymentByPaymentIntentID(ctx context.Context, paymentIntentID *string) (Payment, error) {
@@ -200,6 +271,37 @@ func (q *Queries) GetPaymentByPaymentIntentID(ctx context.Context, paymentIntent
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Currency,
+	)
+	return i, err
+}
+
+const getPaymentByPaymentIntentIDForUpdate = `-- name: GetPaymentByPaymentIntentIDForUpdate :one
+SELECT id, order_id, payment_method_id, amount, processing_fee, net_amount, status, gateway, gateway_reference, refund_id, payment_intent_id, charge_id, error_code, error_message, metadata, created_at, updated_at, currency FROM payments WHERE payment_intent_id = $1 LIMIT 1 FOR UPDATE
+`
+
+func (q *Queries) GetPaymentByPaymentIntentIDForUpdate(ctx context.Context, paymentIntentID *string) (Payment, error) {
+	row := q.db.QueryRow(ctx, getPaymentByPaymentIntentIDForUpdate, paymentIntentID)
+	var i Payment
+	err := row.Scan(
+		&i.ID,
+		&i.OrderID,
+		&i.PaymentMethodID,
+		&i.Amount,
+		&i.ProcessingFee,
+		&i.NetAmount,
+		&i.Status,
+		&i.Gateway,
+		&i.GatewayReference,
+		&i.RefundID,
+		&i.PaymentIntentID,
+		&i.ChargeID,
+		&i.ErrorCode,
+		&i.ErrorMessage,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Currency,
 	)
 	return i, err
 }
@@ -318,6 +420,21 @@ func (q *Queries) GetPaymentTransactionByPaymentID(ctx context.Context, paymentI
 	return i, err
 }
 
+const getReceivedAmountByPaymentID = `-- name: GetReceivedAmountByPaymentID :one
+SELECT COALESCE(SUM(amount), 0)::DECIMAL AS received_amount
+FROM payment_transactions
+WHERE payment_id = $1 AND status = 'success'
+`
+
+// GetReceivedAmountByPaymentID sums the money recorded against a payment so far,
+// offline payments can be settled in several parts.
+func (q *Queries) GetReceivedAmountByPaymentID(ctx context.Context, paymentID uuid.UUID) (pgtype.Numeric, error) {
+	row := q.db.QueryRow(ctx, getReceivedAmountByPaymentID, paymentID)
+	var received_amount pgtype.Numeric
+	err := row.Scan(&received_amount)
+	return received_amount, err
+}
+
 const listPaymentMethods = `-- name: ListPaymentMethods :many
 SELECT id, code, name, description, is_active, gateway_supported, icon_url, requires_account, min_amount, max_amount, processing_fee_percentage, processing_fee_fixed, currency_supported, countries_supported, metadata, created_at, updated_at FROM payment_methods WHERE is_active = TRUE ORDER BY name ASC
 `
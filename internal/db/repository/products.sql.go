@@ -728,6 +728,36 @@ func (q *Queries) GetProductVariantsByProductID(ctx context.Context, arg GetProd
 	return items, nil
 }
 
+const getProductVariantsForUpdate = `-- name: GetProductVariantsForUpdate :many
+SELECT id, stock, is_active FROM product_variants WHERE id = ANY($1::uuid[]) ORDER BY id FOR UPDATE
+`
+
+type GetProductVariantsForUpdateRow struct {
+	ID       uuid.UUID `json:"id"`
+	Stock    int32     `json:"stock"`
+	IsActive *bool     `json:"isActive"`
+}
+
+func (q *Queries) GetProductVariantsForUpdate(ctx context.Context, ids []uuid.UUID) ([]GetProductVariantsForUpdateRow, error) {
+	rows, err := q.db.Query(ctx, getProductVariantsForUpdate, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []GetProductVariantsForUpdateRow{}
+	for rows.Next() {
+		var i GetProductVariantsForUpdateRow
+		if err := rows.Scan(&i.ID, &i.Stock, &i.IsActive); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getVariantDetailByID = `-- name: GetVariantDetailByID :many
 SELECT product_variants.id, product_variants.product_id, product_variants.description, product_variants.sku, product_variants.price, product_variants.stock, product_variants.weight, product_variants.is_active, product_variants.created_at, product_variants.updated_at, product_variants.image_url, product_variants.image_id, attribute_values.id as attribute_value_id, attribute_values.value as attribute_value FROM product_variants
 LEFT JOIN variant_attribute_values ON product_variants.id = variant_attribute_values.variant_id
@@ -794,6 +824,35 @@ func (q *Queries) GetVariantDetailByID(ctx context.Context, arg GetVariantDetail
 	return items, nil
 }
 
+const restoreProductStock = `-- name: RestoreProductStock :one
+UPDATE product_variants SET stock = stock + $1 WHERE id = $2 RETURNING id, product_id, description, sku, price, stock, weight, is_active, created_at, updated_at, image_url, image_id
+`
+
+type RestoreProductStockParams struct {
+	Stock int32     `json:"stock"`
+	ID    uuid.UUID `json:"id"`
+}
+
+func (q *Queries) RestoreProductStock(ctx context.Context, arg RestoreProductStockParams) (ProductVariant, error) {
+	row := q.db.QueryRow(ctx, restoreProductStock, arg.Stock, arg.ID)
+	var i ProductVariant
+	err := row.Scan(
+		&i.ID,
+		&i.ProductID,
+		&i.Description,
+		&i.Sku,
+		&i.Price,
+		&i.Stock,
+		&i.Weight,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ImageUrl,
+		&i.ImageID,
+	)
+	return i, err
+}
+
 const updateProduct = `-- name: UpdateProduct :one
 UPDATE
     products
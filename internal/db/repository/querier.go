@@ -6,6 +6,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -22,8 +23,17 @@ type Querier interface {
 	AddProductsToCollection(ctx context.Context, arg []AddProductsToCollectionParams) (int64, error)
 	ArchiveProduct(ctx context.Context, arg ArchiveProductParams) error
 	ArchiveProductVariant(ctx context.Context, arg ArchiveProductVariantParams) error
+	AssignCartToUser(ctx context.Context, arg AssignCartToUserParams) error
+	BlockSessionFamily(ctx context.Context, arg BlockSessionFamilyParams) (int64, error)
+	BlockUserSessions(ctx context.Context, userID uuid.UUID) (int64, error)
+	CapturePaymentAuthorization(ctx context.Context, arg CapturePaymentAuthorizationParams) error
 	CheckoutCart(ctx context.Context, arg CheckoutCartParams) error
-	ClearCart(ctx context.Context, id uuid.UUID) error
+	ClaimGuestOrders(ctx context.Context, arg ClaimGuestOrdersParams) (int64, error)
+	// Claims an event for processing. Processed events and events another worker is busy
+	// with are left alone, a stuck claim is taken over after a few minutes.
+	ClaimWebhookEvent(ctx context.Context, id uuid.UUID) (WebhookEvent, error)
+	ClearCart(ctx context.Context, cartID uuid.UUID) error
+	CommitOrderReservations(ctx context.Context, orderID uuid.UUID) error
 	CountAddresses(ctx context.Context) (int64, error)
 	CountAttributes(ctx context.Context) (int64, error)
 	CountAvailableDiscountsForUser(ctx context.Context, userID uuid.UUID) (int64, error)
@@ -39,11 +49,15 @@ type Querier interface {
 	CountDiscountsByPriority(ctx context.Context, priority *int32) (int64, error)
 	CountDiscountsByType(ctx context.Context, discountType DiscountType) (int64, error)
 	CountOrders(ctx context.Context, arg CountOrdersParams) (int64, error)
+	CountPaymentReconciliations(ctx context.Context) (int64, error)
 	CountProductRatings(ctx context.Context, productID pgtype.UUID) (int64, error)
 	CountProducts(ctx context.Context, arg CountProductsParams) (int64, error)
+	CountReturnRequests(ctx context.Context, arg CountReturnRequestsParams) (int64, error)
 	CountShippingMethods(ctx context.Context) (int64, error)
 	CountShippingRates(ctx context.Context) (int64, error)
 	CountShippingZones(ctx context.Context) (int64, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountUserAuditLogs(ctx context.Context, userID uuid.UUID) (int64, error)
 	CountUsers(ctx context.Context) (int64, error)
 	CountUsersUsingDiscount(ctx context.Context, discountID uuid.UUID) (int64, error)
 	// User Address Queries
@@ -60,9 +74,16 @@ type Querier interface {
 	CreateCart(ctx context.Context, arg CreateCartParams) (Cart, error)
 	CreateCategory(ctx context.Context, arg CreateCategoryParams) (Category, error)
 	CreateCollection(ctx context.Context, arg CreateCollectionParams) (Collection, error)
+	CreateInventoryReservation(ctx context.Context, arg CreateInventoryReservationParams) (InventoryReservation, error)
 	CreateOrder(ctx context.Context, arg CreateOrderParams) (Order, error)
 	CreateOrderItem(ctx context.Context, arg CreateOrderItemParams) (OrderItem, error)
+	CreateOrderStatusHistory(ctx context.Context, arg CreateOrderStatusHistoryParams) (OrderStatusHistory, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
 	CreatePayment(ctx context.Context, arg CreatePaymentParams) (Payment, error)
+	// A customer registered first by a concurrent request is kept and returned
+	CreatePaymentCustomer(ctx context.Context, arg CreatePaymentCustomerParams) (PaymentCustomer, error)
+	CreatePaymentReconciliation(ctx context.Context) (PaymentReconciliation, error)
+	CreatePaymentReconciliationItem(ctx context.Context, arg CreatePaymentReconciliationItemParams) error
 	// Payment Transactions --
 	CreatePaymentTransaction(ctx context.Context, arg CreatePaymentTransactionParams) (PaymentTransaction, error)
 	CreateProduct(ctx context.Context, arg CreateProductParams) (Product, error)
@@ -72,14 +93,25 @@ type Querier interface {
 	CreateProductVariant(ctx context.Context, arg CreateProductVariantParams) (ProductVariant, error)
 	// Product Variant attributes
 	CreateProductVariantAttribute(ctx context.Context, arg CreateProductVariantAttributeParams) (VariantAttributeValue, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateRefund(ctx context.Context, arg CreateRefundParams) (Refund, error)
+	CreateRefundItem(ctx context.Context, arg CreateRefundItemParams) (RefundItem, error)
+	CreateReturnRequest(ctx context.Context, arg CreateReturnRequestParams) (ReturnRequest, error)
+	CreateReturnRequestImage(ctx context.Context, arg CreateReturnRequestImageParams) (ReturnRequestImage, error)
+	CreateReturnRequestItem(ctx context.Context, arg CreateReturnRequestItemParams) error
+	CreateRole(ctx context.Context, arg CreateRoleParams) (UserRole, error)
+	CreateShipment(ctx context.Context, arg CreateShipmentParams) (Shipment, error)
+	CreateShipmentItem(ctx context.Context, arg CreateShipmentItemParams) error
 	CreateShippingMethod(ctx context.Context, arg CreateShippingMethodParams) (ShippingMethod, error)
 	// SHIPPING RATES
 	CreateShippingRate(ctx context.Context, arg CreateShippingRateParams) (ShippingRate, error)
 	// SHIPPING ZONES
 	CreateShippingZone(ctx context.Context, arg CreateShippingZoneParams) (ShippingZone, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserAuditLog(ctx context.Context, arg CreateUserAuditLogParams) (UserAuditLog, error)
 	// Verification Token Queries
 	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (EmailVerification, error)
+	CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) (WebhookEvent, error)
 	DeactivateDiscount(ctx context.Context, id uuid.UUID) error
 	DecrementDiscountUsage(ctx context.Context, id uuid.UUID) error
 	DeleteAddress(ctx context.Context, arg DeleteAddressParams) error
@@ -87,8 +119,10 @@ type Querier interface {
 	DeleteAttributeValue(ctx context.Context, id int64) error
 	DeleteAttributeValueByValueID(ctx context.Context, arg DeleteAttributeValueByValueIDParams) error
 	DeleteBrand(ctx context.Context, id uuid.UUID) error
+	DeleteCart(ctx context.Context, id uuid.UUID) error
 	DeleteCategory(ctx context.Context, id uuid.UUID) error
 	DeleteCollection(ctx context.Context, id uuid.UUID) error
+	DeleteCurrencyRate(ctx context.Context, currency string) (int64, error)
 	DeleteDiscount(ctx context.Context, id uuid.UUID) error
 	DeleteDiscountRule(ctx context.Context, id uuid.UUID) error
 	DeleteDiscountRules(ctx context.Context, discountID uuid.UUID) error
@@ -105,10 +139,20 @@ type Querier interface {
 	DeleteProductVariantAttributes(ctx context.Context, variantID uuid.UUID) error
 	DeleteRatingReplies(ctx context.Context, id uuid.UUID) error
 	DeleteRatingVotes(ctx context.Context, id uuid.UUID) error
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	DeleteRole(ctx context.Context, id uuid.UUID) (int64, error)
+	DeleteRolePermission(ctx context.Context, arg DeleteRolePermissionParams) (int64, error)
 	DeleteShippingMethod(ctx context.Context, id uuid.UUID) error
 	DeleteShippingRate(ctx context.Context, id uuid.UUID) error
 	DeleteShippingZone(ctx context.Context, id uuid.UUID) error
+	DeleteStaleGuestCarts(ctx context.Context, updatedAt time.Time) (int64, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeleteUserMfa(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteUserPaymentInfo(ctx context.Context, arg DeleteUserPaymentInfoParams) (int64, error)
+	EnableUserMfa(ctx context.Context, arg EnableUserMfaParams) (int64, error)
+	// ExpireUserPasswordResets uses up every reset token of a user still waiting to be used.
+	ExpireUserPasswordResets(ctx context.Context, userID uuid.UUID) error
+	FinishPaymentReconciliation(ctx context.Context, arg FinishPaymentReconciliationParams) (PaymentReconciliation, error)
 	GetActiveDiscountRules(ctx context.Context, arg GetActiveDiscountRulesParams) ([]DiscountRule, error)
 	GetActiveDiscounts(ctx context.Context) ([]Discount, error)
 	GetAddress(ctx context.Context, arg GetAddressParams) (UserAddress, error)
@@ -132,6 +176,7 @@ type Querier interface {
 	GetCartItem(ctx context.Context, arg GetCartItemParams) (CartItem, error)
 	GetCartItemByProductVariantID(ctx context.Context, arg GetCartItemByProductVariantIDParams) (CartItem, error)
 	GetCartItems(ctx context.Context, cartID uuid.UUID) ([]GetCartItemsRow, error)
+	GetCartItemsForMerge(ctx context.Context, cartID uuid.UUID) ([]GetCartItemsForMergeRow, error)
 	GetCategories(ctx context.Context, arg GetCategoriesParams) ([]Category, error)
 	GetCategoryByID(ctx context.Context, id uuid.UUID) (Category, error)
 	GetCategoryBySlug(ctx context.Context, slug string) (Category, error)
@@ -139,6 +184,7 @@ type Querier interface {
 	GetCollectionBySlug(ctx context.Context, slug string) (Collection, error)
 	GetCollections(ctx context.Context, arg GetCollectionsParams) ([]Collection, error)
 	GetCollectionsByIDs(ctx context.Context, arg GetCollectionsByIDsParams) ([]GetCollectionsByIDsRow, error)
+	GetCurrencyRate(ctx context.Context, currency string) (CurrencyRate, error)
 	GetDefaultAddress(ctx context.Context, userID uuid.UUID) (UserAddress, error)
 	GetDiscountByCode(ctx context.Context, code string) (GetDiscountByCodeRow, error)
 	GetDiscountByCodes(ctx context.Context, code []string) ([]Discount, error)
@@ -153,21 +199,37 @@ type Querier interface {
 	GetDiscountsWithRules(ctx context.Context, arg GetDiscountsWithRulesParams) ([]GetDiscountsWithRulesRow, error)
 	GetDiscountsWithUsageStatsByUserId(ctx context.Context, arg GetDiscountsWithUsageStatsByUserIdParams) ([]GetDiscountsWithUsageStatsByUserIdRow, error)
 	GetExpiredDiscounts(ctx context.Context) ([]Discount, error)
+	GetExpiredReservationOrderIDs(ctx context.Context, limit int64) ([]uuid.UUID, error)
 	GetImageByID(ctx context.Context, id int64) (ProductImage, error)
 	GetImageByImageID(ctx context.Context, imageID string) (ProductImage, error)
 	GetImagesByProductID(ctx context.Context, productID uuid.UUID) ([]ProductImage, error)
 	GetOrder(ctx context.Context, id uuid.UUID) (GetOrderRow, error)
 	GetOrderDiscounts(ctx context.Context, orderID uuid.UUID) ([]Discount, error)
+	GetOrderForUpdate(ctx context.Context, id uuid.UUID) (GetOrderForUpdateRow, error)
+	GetOrderFulfillment(ctx context.Context, orderID uuid.UUID) ([]GetOrderFulfillmentRow, error)
 	GetOrderItemByID(ctx context.Context, id uuid.UUID) (GetOrderItemByIDRow, error)
 	GetOrderItems(ctx context.Context, orderID uuid.UUID) ([]GetOrderItemsRow, error)
 	GetOrderItemsByOrderID(ctx context.Context, orderID uuid.UUID) ([]GetOrderItemsByOrderIDRow, error)
+	GetOrderReservationExpiry(ctx context.Context, orderID uuid.UUID) (pgtype.Timestamptz, error)
+	GetOrderStatusHistory(ctx context.Context, orderID uuid.UUID) ([]OrderStatusHistory, error)
 	GetOrders(ctx context.Context, arg GetOrdersParams) ([]GetOrdersRow, error)
+	GetPasswordResetByTokenHashForUpdate(ctx context.Context, tokenHash string) (PasswordReset, error)
+	GetPaymentAuthorizationForUpdate(ctx context.Context, paymentID uuid.UUID) (PaymentAuthorization, error)
+	// GetPaymentByChargeIDForUpdate finds a payment by what the gateway calls the money it took,
+	// for events like paypal disputes that only name the capture
+	GetPaymentByChargeIDForUpdate(ctx context.Context, chargeID *string) (Payment, error)
 	GetPaymentByID(ctx context.Context, id uuid.UUID) (Payment, error)
 	GetPaymentByOrderID(ctx context.Context, orderID uuid.UUID) (Payment, error)
+	GetPaymentByOrderIDForUpdate(ctx context.Context, orderID uuid.UUID) (Payment, error)
 	GetPaymentByPaymentIntentID(ctx context.Context, paymentIntentID *string) (Payment, error)
+	GetPaymentByPaymentIntentIDForUpdate(ctx context.Context, paymentIntentID *string) (Payment, error)
+	GetPaymentCustomer(ctx context.Context, arg GetPaymentCustomerParams) (PaymentCustomer, error)
 	// Payment Methods --
 	GetPaymentMethodByID(ctx context.Context, id uuid.UUID) (PaymentMethod, error)
 	GetPaymentMethods(ctx context.Context) ([]PaymentMethod, error)
+	GetPaymentReconciliation(ctx context.Context, id uuid.UUID) (PaymentReconciliation, error)
+	GetPaymentReconciliationItems(ctx context.Context, reconciliationID uuid.UUID) ([]PaymentReconciliationItem, error)
+	GetPaymentReconciliations(ctx context.Context, arg GetPaymentReconciliationsParams) ([]PaymentReconciliation, error)
 	GetPaymentTransactionByID(ctx context.Context, id uuid.UUID) (PaymentTransaction, error)
 	GetPaymentTransactionByPaymentID(ctx context.Context, paymentID uuid.UUID) (PaymentTransaction, error)
 	GetPrimaryImageByProductID(ctx context.Context, productID uuid.UUID) (ProductImage, error)
@@ -189,6 +251,7 @@ type Querier interface {
 	GetProductVariantByID(ctx context.Context, arg GetProductVariantByIDParams) (ProductVariant, error)
 	GetProductVariantList(ctx context.Context, arg GetProductVariantListParams) ([]GetProductVariantListRow, error)
 	GetProductVariantsByProductID(ctx context.Context, arg GetProductVariantsByProductIDParams) ([]ProductVariant, error)
+	GetProductVariantsForUpdate(ctx context.Context, ids []uuid.UUID) ([]GetProductVariantsForUpdateRow, error)
 	GetRatingReplies(ctx context.Context, id uuid.UUID) (RatingReply, error)
 	GetRatingRepliesByRatingID(ctx context.Context, ratingID uuid.UUID) ([]GetRatingRepliesByRatingIDRow, error)
 	GetRatingRepliesByUserID(ctx context.Context, replyBy uuid.UUID) ([]GetRatingRepliesByUserIDRow, error)
@@ -198,30 +261,61 @@ type Querier interface {
 	GetRatingVotesByUserID(ctx context.Context, userID uuid.UUID) ([]RatingVote, error)
 	GetRatingVotesCount(ctx context.Context, ratingID uuid.UUID) (int64, error)
 	GetRatingVotesCountByUserID(ctx context.Context, userID uuid.UUID) (int64, error)
+	// GetReceivedAmountByPaymentID sums the money recorded against a payment so far,
+	// offline payments can be settled in several parts.
+	GetReceivedAmountByPaymentID(ctx context.Context, paymentID uuid.UUID) (pgtype.Numeric, error)
+	GetRefundByGatewayRefundIDForUpdate(ctx context.Context, gatewayRefundID *string) (Refund, error)
+	GetRefundForUpdate(ctx context.Context, id uuid.UUID) (Refund, error)
+	GetRefundItemsByOrderID(ctx context.Context, orderID uuid.UUID) ([]RefundItem, error)
+	GetRefundedAmountByPaymentID(ctx context.Context, paymentID uuid.UUID) (pgtype.Numeric, error)
+	GetRefundedQuantitiesByOrderID(ctx context.Context, orderID uuid.UUID) ([]GetRefundedQuantitiesByOrderIDRow, error)
+	GetRefundsByOrderID(ctx context.Context, orderID uuid.UUID) ([]Refund, error)
+	GetRequestedReturnQuantitiesByOrderID(ctx context.Context, orderID uuid.UUID) ([]GetRequestedReturnQuantitiesByOrderIDRow, error)
+	GetRestockItemsByRefundID(ctx context.Context, refundID uuid.UUID) ([]GetRestockItemsByRefundIDRow, error)
+	GetReturnRequestByID(ctx context.Context, id uuid.UUID) (ReturnRequest, error)
+	GetReturnRequestForUpdate(ctx context.Context, id uuid.UUID) (ReturnRequest, error)
+	GetReturnRequestImages(ctx context.Context, returnRequestID uuid.UUID) ([]ReturnRequestImage, error)
+	GetReturnRequestItems(ctx context.Context, returnRequestID uuid.UUID) ([]GetReturnRequestItemsRow, error)
+	GetReturnRequests(ctx context.Context, arg GetReturnRequestsParams) ([]ReturnRequest, error)
 	// Roles Queries
 	GetRoleByCode(ctx context.Context, code string) (UserRole, error)
 	GetRoleByID(ctx context.Context, id uuid.UUID) (UserRole, error)
+	GetRolePermissions(ctx context.Context, roleID uuid.UUID) ([]Permission, error)
+	GetRoles(ctx context.Context) ([]UserRole, error)
 	GetSession(ctx context.Context, id uuid.UUID) (UserSession, error)
 	GetSessionByRefreshToken(ctx context.Context, refreshToken string) (UserSession, error)
+	GetSessionForUpdate(ctx context.Context, id uuid.UUID) (UserSession, error)
+	GetSettledRefundAmountByPaymentID(ctx context.Context, paymentID uuid.UUID) (pgtype.Numeric, error)
+	GetShipmentByID(ctx context.Context, id uuid.UUID) (Shipment, error)
+	GetShipmentItemsByOrderID(ctx context.Context, orderID uuid.UUID) ([]GetShipmentItemsByOrderIDRow, error)
+	GetShipmentsByOrderID(ctx context.Context, orderID uuid.UUID) ([]Shipment, error)
 	GetShippingMethodByID(ctx context.Context, id uuid.UUID) (ShippingMethod, error)
 	GetShippingMethods(ctx context.Context, isActive *bool) ([]ShippingMethod, error)
 	GetShippingRateByID(ctx context.Context, id uuid.UUID) (GetShippingRateByIDRow, error)
+	// SHIPPING RATE CONDITIONS
+	GetShippingRateConditionsByRateIDs(ctx context.Context, rateIds []uuid.UUID) ([]ShippingRateCondition, error)
 	GetShippingRates(ctx context.Context, isActive *bool) ([]GetShippingRatesRow, error)
+	GetShippingRatesByMethodAndZone(ctx context.Context, arg GetShippingRatesByMethodAndZoneParams) ([]ShippingRate, error)
 	GetShippingRatesByZone(ctx context.Context, shippingZoneID uuid.UUID) ([]GetShippingRatesByZoneRow, error)
 	GetShippingZoneByID(ctx context.Context, id uuid.UUID) (ShippingZone, error)
 	GetShippingZones(ctx context.Context, isActive *bool) ([]ShippingZone, error)
 	GetTopUsedDiscounts(ctx context.Context, arg GetTopUsedDiscountsParams) ([]Discount, error)
 	GetTotalDiscountGiven(ctx context.Context, discountID uuid.UUID) (pgtype.Numeric, error)
+	GetUserAuditLogs(ctx context.Context, arg GetUserAuditLogsParams) ([]UserAuditLog, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
 	GetUserByUsername(ctx context.Context, username string) (User, error)
 	GetUserDetailsByID(ctx context.Context, id uuid.UUID) (GetUserDetailsByIDRow, error)
+	GetUserMfa(ctx context.Context, userID uuid.UUID) (UserMfa, error)
+	GetUserPaymentInfo(ctx context.Context, arg GetUserPaymentInfoParams) (UserPaymentInfo, error)
+	GetUserPaymentInfos(ctx context.Context, userID uuid.UUID) ([]UserPaymentInfo, error)
 	GetUserTotalSpent(ctx context.Context, userID uuid.UUID) (pgtype.Numeric, error)
 	GetUsers(ctx context.Context, arg GetUsersParams) ([]User, error)
 	GetUsersUsingDiscount(ctx context.Context, arg GetUsersUsingDiscountParams) ([]uuid.UUID, error)
 	GetVariantDetailByID(ctx context.Context, arg GetVariantDetailByIDParams) ([]GetVariantDetailByIDRow, error)
 	GetVerifyEmailByID(ctx context.Context, id uuid.UUID) (EmailVerification, error)
 	GetVerifyEmailByVerifyCode(ctx context.Context, verifyCode string) (EmailVerification, error)
+	GetWebhookEventByEventID(ctx context.Context, arg GetWebhookEventByEventIDParams) (WebhookEvent, error)
 	IncrementDiscountUsage(ctx context.Context, id uuid.UUID) error
 	InsertBulkProductImages(ctx context.Context, arg []InsertBulkProductImagesParams) (int64, error)
 	InsertDiscount(ctx context.Context, arg InsertDiscountParams) (uuid.UUID, error)
@@ -231,15 +325,37 @@ type Querier interface {
 	InsertRatingReply(ctx context.Context, arg InsertRatingReplyParams) (RatingReply, error)
 	InsertRatingVotes(ctx context.Context, arg InsertRatingVotesParams) (RatingVote, error)
 	InsertSession(ctx context.Context, arg InsertSessionParams) (UserSession, error)
+	// ListActiveUserSessions returns the signed in devices of a user, the latest session of each
+	// family with the time the family signed in.
+	ListActiveUserSessions(ctx context.Context, userID uuid.UUID) ([]ListActiveUserSessionsRow, error)
+	ListCurrencyRates(ctx context.Context, isActive *bool) ([]CurrencyRate, error)
+	// ListExpiringPaymentAuthorizations returns the authorizations still open before a
+	// deadline, the ones expiring first first.
+	ListExpiringPaymentAuthorizations(ctx context.Context, expiresAt time.Time) ([]ListExpiringPaymentAuthorizationsRow, error)
 	ListOrderItems(ctx context.Context, arg ListOrderItemsParams) ([]OrderItem, error)
 	ListPaymentMethods(ctx context.Context) ([]PaymentMethod, error)
+	// ListPaymentsToReconcile returns the gateway payments left waiting on a webhook since
+	// before a cutoff, the oldest first.
+	ListPaymentsToReconcile(ctx context.Context, arg ListPaymentsToReconcileParams) ([]ListPaymentsToReconcileRow, error)
+	// ListRefundsToReconcile returns the gateway refunds that were never handed to the gateway,
+	// or whose answer was lost, since before a cutoff, the oldest first.
+	ListRefundsToReconcile(ctx context.Context, arg ListRefundsToReconcileParams) ([]ListRefundsToReconcileRow, error)
+	ListShippingMethods(ctx context.Context, arg ListShippingMethodsParams) ([]ShippingMethod, error)
+	ListShippingRates(ctx context.Context, arg ListShippingRatesParams) ([]ListShippingRatesRow, error)
+	ListShippingZones(ctx context.Context, arg ListShippingZonesParams) ([]ShippingZone, error)
+	MarkWebhookEventFailed(ctx context.Context, arg MarkWebhookEventFailedParams) error
+	MarkWebhookEventProcessed(ctx context.Context, id uuid.UUID) error
 	MaxPreviousOrderByUserID(ctx context.Context, userID uuid.UUID) (Order, error)
 	ReactivateDiscount(ctx context.Context, id uuid.UUID) error
+	ReleaseOrderReservations(ctx context.Context, orderID uuid.UUID) ([]InventoryReservation, error)
 	RemoveDiscountUsage(ctx context.Context, arg RemoveDiscountUsageParams) error
 	RemoveProductFromCart(ctx context.Context, arg RemoveProductFromCartParams) error
 	RemoveProductsFromCategory(ctx context.Context, productID uuid.UUID) error
 	RemoveProductsFromCollection(ctx context.Context, productID uuid.UUID) error
+	ResetDefaultUserPaymentInfo(ctx context.Context, userID uuid.UUID) error
 	ResetPrimaryAddress(ctx context.Context, userID uuid.UUID) error
+	RestoreProductStock(ctx context.Context, arg RestoreProductStockParams) (ProductVariant, error)
+	RotateSession(ctx context.Context, id uuid.UUID) error
 	SeedAddresses(ctx context.Context, arg []SeedAddressesParams) (int64, error)
 	SeedBrands(ctx context.Context, arg []SeedBrandsParams) (int64, error)
 	SeedCategories(ctx context.Context, arg []SeedCategoriesParams) (int64, error)
@@ -248,7 +364,10 @@ type Querier interface {
 	SeedShippingMethods(ctx context.Context, arg []SeedShippingMethodsParams) (int64, error)
 	SeedShippingZones(ctx context.Context, arg []SeedShippingZonesParams) (int64, error)
 	SeedUsers(ctx context.Context, arg []SeedUsersParams) (int64, error)
+	SetDefaultUserPaymentInfo(ctx context.Context, arg SetDefaultUserPaymentInfoParams) (int64, error)
+	SetLatestUserPaymentInfoDefault(ctx context.Context, userID uuid.UUID) error
 	SetPrimaryAddress(ctx context.Context, arg SetPrimaryAddressParams) error
+	SetUserLocked(ctx context.Context, arg SetUserLockedParams) (User, error)
 	UpdateAddress(ctx context.Context, arg UpdateAddressParams) (UserAddress, error)
 	UpdateAttribute(ctx context.Context, arg UpdateAttributeParams) (Attribute, error)
 	UpdateAttributeValue(ctx context.Context, arg UpdateAttributeValueParams) (AttributeValue, error)
@@ -270,12 +389,26 @@ type Querier interface {
 	UpdateProductVariant(ctx context.Context, arg UpdateProductVariantParams) (ProductVariant, error)
 	UpdateRatingReplies(ctx context.Context, arg UpdateRatingRepliesParams) (RatingReply, error)
 	UpdateRatingVote(ctx context.Context, arg UpdateRatingVoteParams) (RatingVote, error)
+	UpdateRefund(ctx context.Context, arg UpdateRefundParams) (Refund, error)
+	UpdateReturnRequest(ctx context.Context, arg UpdateReturnRequestParams) (ReturnRequest, error)
+	UpdateRole(ctx context.Context, arg UpdateRoleParams) (UserRole, error)
 	UpdateSession(ctx context.Context, arg UpdateSessionParams) (UserSession, error)
+	UpdateShipment(ctx context.Context, arg UpdateShipmentParams) (Shipment, error)
 	UpdateShippingMethod(ctx context.Context, arg UpdateShippingMethodParams) (ShippingMethod, error)
 	UpdateShippingRate(ctx context.Context, arg UpdateShippingRateParams) (ShippingRate, error)
 	UpdateShippingZone(ctx context.Context, arg UpdateShippingZoneParams) (ShippingZone, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateUserRow, error)
+	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (time.Time, error)
+	UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error)
 	UpdateVerifyEmail(ctx context.Context, arg UpdateVerifyEmailParams) (EmailVerification, error)
+	UpsertCurrencyRate(ctx context.Context, arg UpsertCurrencyRateParams) (CurrencyRate, error)
+	UpsertPaymentAuthorization(ctx context.Context, arg UpsertPaymentAuthorizationParams) (PaymentAuthorization, error)
+	UpsertRolePermission(ctx context.Context, arg UpsertRolePermissionParams) (Permission, error)
+	UpsertUserMfa(ctx context.Context, arg UpsertUserMfaParams) (UserMfa, error)
+	UpsertUserPaymentInfo(ctx context.Context, arg UpsertUserPaymentInfoParams) (UserPaymentInfo, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseUserMfaStep(ctx context.Context, arg UseUserMfaStepParams) (int64, error)
+	VoidPaymentAuthorization(ctx context.Context, paymentID uuid.UUID) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -2,74 +2,364 @@ package repository
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"math/big"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/orderstate"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
 )
 
-type RefundOrderTxArgs struct {
-	OrderID                 uuid.UUID
-	RefundPaymentFromMethod func(paymentID string, method string) (string, error)
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusSucceeded = "succeeded"
+	RefundStatusFailed    = "failed"
+)
+
+// RefundPaymentFn hands a refund to its gateway, the idempotency key is the ID of the refund
+// so a retried call can't return the money twice
+type RefundPaymentFn func(ctx context.Context, paymentIntentID string, method string, amount payment.Money, idempotencyKey string) (refundID string, completed bool, err error)
+
+type RefundItemTxArgs struct {
+	OrderItemID uuid.UUID
+	Quantity    int32
+}
+
+type RefundTxArgs struct {
+	OrderID uuid.UUID
+	// Items refunds specific order items, Amount an arbitrary decimal amount in the
+	// payment's currency. When both are empty the remaining balance of the payment is refunded.
+	Items   []RefundItemTxArgs
+	Amount  *string
+	Restock bool
+	OrderActorArgs
+	RefundPaymentFn RefundPaymentFn
+}
+
+type RefundTxResult struct {
+	Refund      Refund       `json:"refund"`
+	Items       []RefundItem `json:"items"`
+	OrderStatus OrderStatus  `json:"orderStatus"`
+
+	// gateway is set for a refund recorded as pending that still has to go to its gateway
+	gateway *gatewayRefund
+}
+
+type gatewayRefund struct {
+	paymentIntentID string
+	method          string
+	amount          payment.Money
+}
+
+type SettleRefundTxArgs struct {
+	RefundID uuid.UUID
+	// Status is what the gateway made of the refund, pending leaves it to the webhook
+	Status          string
+	GatewayRefundID *string
+	OrderActorArgs
+}
+
+// RefundTx returns money of a successful payment and records it as a refund. The order
+// only moves to refunded once the whole payment has been returned.
+// A gateway refund is first committed as pending, so the amount is held while the gateway
+// is called outside of any transaction, and then settled in a second transaction.
+func (repo *pgRepo) RefundTx(ctx context.Context, arg RefundTxArgs) (RefundTxResult, error) {
+	var result RefundTxResult
+	err := repo.execTx(ctx, func(q *Queries) (err error) {
+		result, err = refund(ctx, q, arg)
+		return err
+	})
+	if err != nil {
+		return result, err
+	}
+	return repo.submitRefund(ctx, result, arg.RefundPaymentFn, arg.OrderActorArgs)
 }
 
-func (repo *pgRepo) RefundOrderTx(ctx context.Context, args RefundOrderTxArgs) (err error) {
-	repo.execTx(ctx, func(q *Queries) error {
-		// refund payment
-		payment, err := q.GetPaymentByOrderID(ctx, args.OrderID)
+// SettleRefundTx records the outcome of a pending refund. A succeeded refund restocks its
+// items and, once the whole payment is returned, refunds the payment and the order.
+func (repo *pgRepo) SettleRefundTx(ctx context.Context, arg SettleRefundTxArgs) (RefundTxResult, error) {
+	var result RefundTxResult
+	err := repo.execTx(ctx, func(q *Queries) error {
+		rf, err := q.GetRefundForUpdate(ctx, arg.RefundID)
 		if err != nil {
-			log.Error().Err(err).Msg("GetPaymentTransactionByOrderID")
+			log.Error().Err(err).Msg("GetRefundForUpdate")
 			return err
 		}
-		// if payment is not found, we don't need to refund it
-		if payment.Status != PaymentStatusSuccess {
-			return errors.New("payment is not successful, can't refund")
+		result, err = settleRefund(ctx, q, rf, arg)
+		return err
+	})
+	return result, err
+}
+
+// submitRefund calls the gateway for a refund committed as pending and settles it with the
+// answer. A refund the gateway didn't take is marked failed, which frees its amount again.
+func (repo *pgRepo) submitRefund(ctx context.Context, result RefundTxResult, refundPaymentFn RefundPaymentFn, actor OrderActorArgs) (RefundTxResult, error) {
+	if result.gateway == nil {
+		return result, nil
+	}
+	settle := SettleRefundTxArgs{
+		RefundID:       result.Refund.ID,
+		Status:         RefundStatusSucceeded,
+		OrderActorArgs: actor,
+	}
+	gatewayRefundID, completed, gatewayErr := refundPaymentFn(ctx, result.gateway.paymentIntentID, result.gateway.method, result.gateway.amount, result.Refund.ID.String())
+	if gatewayErr != nil {
+		log.Error().Err(gatewayErr).Msg("RefundPaymentFn")
+		settle.Status = RefundStatusFailed
+	} else {
+		if gatewayRefundID != "" {
+			settle.GatewayRefundID = &gatewayRefundID
 		}
+		if !completed {
+			settle.Status = RefundStatusPending
+		}
+	}
+
+	// a refund that can't be settled now stays pending for the webhook or reconciliation
+	settled, err := repo.SettleRefundTx(ctx, settle)
+	if err != nil {
+		return result, err
+	}
+	result.Refund = settled.Refund
+	if settled.OrderStatus != "" {
+		result.OrderStatus = settled.OrderStatus
+	}
+	return result, gatewayErr
+}
 
-		paymentMethod, err := q.GetPaymentMethodByID(ctx, payment.PaymentMethodID)
+// refund validates and records a refund inside the caller's transaction. A refund that goes
+// through a gateway is left pending, the caller hands it to the gateway once it is committed.
+func refund(ctx context.Context, q *Queries, arg RefundTxArgs) (RefundTxResult, error) {
+	var result RefundTxResult
+	// lock the order so concurrent refunds can't return the same money twice
+	order, err := q.GetOrderForUpdate(ctx, arg.OrderID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetOrderForUpdate")
+		return result, err
+	}
+	result.OrderStatus = order.Status
+	if _, err := orderstate.Get(orderstate.Status(order.Status), orderstate.StatusRefunded); err != nil {
+		return result, fmt.Errorf("%w: order with status %s can't be refunded", ErrInvalidRefund, order.Status)
+	}
+
+	pm, err := q.GetPaymentByOrderID(ctx, arg.OrderID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetPaymentByOrderID")
+		return result, err
+	}
+	if pm.Status != PaymentStatusSuccess {
+		return result, fmt.Errorf("%w: payment is %s", ErrInvalidRefund, pm.Status)
+	}
+
+	currency := payment.NormalizeCurrency(pm.Currency)
+	paid := utils.GetMoneyFromPgNumeric(pm.Amount, currency)
+	refundedNum, err := q.GetRefundedAmountByPaymentID(ctx, pm.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetRefundedAmountByPaymentID")
+		return result, err
+	}
+	remaining := paid.Sub(utils.GetMoneyFromPgNumeric(refundedNum, currency))
+
+	amount := payment.NewMoney(0, currency)
+	itemAmounts := make(map[uuid.UUID]payment.Money, len(arg.Items))
+	switch {
+	case len(arg.Items) > 0:
+		orderItems, err := q.GetOrderItems(ctx, arg.OrderID)
 		if err != nil {
-			log.Error().Err(err).Msg("GetPaymentMethodByID")
-			return err
+			log.Error().Err(err).Msg("GetOrderItems")
+			return result, err
+		}
+		refundedQty, err := q.GetRefundedQuantitiesByOrderID(ctx, arg.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetRefundedQuantitiesByOrderID")
+			return result, err
+		}
+		left := make(map[uuid.UUID]int32, len(orderItems))
+		quantities := make(map[uuid.UUID]int64, len(orderItems))
+		netTotals := make(map[uuid.UUID]payment.Money, len(orderItems))
+		for _, item := range orderItems {
+			lineTotal := utils.GetMoneyFromPgNumeric(item.LineTotalSnapshot, currency)
+			discount := utils.GetMoneyFromPgNumeric(item.DiscountedPrice, currency)
+			left[item.ID] = int32(item.Quantity)
+			quantities[item.ID] = int64(item.Quantity)
+			netTotals[item.ID] = lineTotal.Sub(discount.Min(lineTotal))
+		}
+		for _, row := range refundedQty {
+			left[row.OrderItemID] -= row.RefundedQuantity
 		}
 
-		// refund payment from gateway if it's not refunded yet
-		if args.RefundPaymentFromMethod != nil {
-			refundID, err := args.RefundPaymentFromMethod(*payment.PaymentIntentID, paymentMethod.Code)
-			if err != nil {
-				log.Error().Err(err).Msg("RefundPaymentFromGateway")
-				return err
+		for _, item := range arg.Items {
+			// an item's amount is its own share of the line, it can only be listed once
+			if _, ok := itemAmounts[item.OrderItemID]; ok {
+				return result, fmt.Errorf("%w: order item %s is listed more than once", ErrInvalidRefund, item.OrderItemID)
 			}
-			updateParams := UpdatePaymentParams{
-				ID: payment.ID,
-				Status: NullPaymentStatus{
-					PaymentStatus: PaymentStatusRefunded,
-					Valid:         true,
-				},
+			qty, ok := left[item.OrderItemID]
+			if !ok {
+				return result, fmt.Errorf("%w: order item %s doesn't belong to order %s", ErrInvalidRefund, item.OrderItemID, arg.OrderID)
 			}
-			if refundID != "" {
-				updateParams.RefundID = &refundID
-			}
-			err = q.UpdatePayment(ctx, updateParams)
-			if err != nil {
-				log.Error().Err(err).Msg("UpdatePaymentTransaction")
-				return err
+			if item.Quantity > qty {
+				return result, fmt.Errorf("%w: only %d of order item %s left to refund", ErrInvalidRefund, qty, item.OrderItemID)
 			}
+			left[item.OrderItemID] -= item.Quantity
+			share := big.NewRat(int64(item.Quantity), quantities[item.OrderItemID])
+			itemAmount := netTotals[item.OrderItemID].MulRat(share, payment.RoundHalfUp)
+			itemAmounts[item.OrderItemID] = itemAmount
+			amount = amount.Add(itemAmount)
+		}
+		// order level discounts and rounding can leave less than the items are worth
+		amount = amount.Min(remaining)
+	case arg.Amount != nil:
+		amount, err = payment.ParseMoney(*arg.Amount, currency)
+		if err != nil {
+			return result, fmt.Errorf("%w: %w", ErrInvalidRefund, err)
 		}
+		if amount.Cmp(remaining) > 0 {
+			return result, fmt.Errorf("%w: only %s left to refund", ErrInvalidRefund, remaining)
+		}
+	default:
+		amount = remaining
+	}
+	if !amount.IsPositive() {
+		return result, fmt.Errorf("%w: nothing left to refund", ErrInvalidRefund)
+	}
+
+	refundParams := CreateRefundParams{
+		PaymentID: pm.ID,
+		OrderID:   arg.OrderID,
+		Amount:    utils.GetPgNumericFromMoney(amount),
+		Reason:    arg.Reason,
+		Status:    RefundStatusPending,
+		Restocked: arg.Restock && len(arg.Items) > 0,
+		CreatedBy: arg.ID,
+	}
+	result.Refund, err = q.CreateRefund(ctx, refundParams)
+	if err != nil {
+		log.Error().Err(err).Msg("CreateRefund")
+		return result, err
+	}
 
-		// refund order
-		_, err = q.UpdateOrder(ctx, UpdateOrderParams{
-			ID: args.OrderID,
-			Status: NullOrderStatus{
-				OrderStatus: OrderStatusRefunded,
-				Valid:       true,
-			},
+	result.Items = make([]RefundItem, 0, len(arg.Items))
+	for _, item := range arg.Items {
+		refundItem, err := q.CreateRefundItem(ctx, CreateRefundItemParams{
+			RefundID:    result.Refund.ID,
+			OrderItemID: item.OrderItemID,
+			Quantity:    item.Quantity,
+			Amount:      utils.GetPgNumericFromMoney(itemAmounts[item.OrderItemID]),
 		})
+		if err != nil {
+			log.Error().Err(err).Msg("CreateRefundItem")
+			return result, err
+		}
+		result.Items = append(result.Items, refundItem)
+	}
 
+	if pm.PaymentIntentID != nil && arg.RefundPaymentFn != nil {
+		method, err := q.GetPaymentMethodByID(ctx, pm.PaymentMethodID)
 		if err != nil {
-			log.Error().Err(err).Msg("UpdateOrder")
-			return err
+			log.Error().Err(err).Msg("GetPaymentMethodByID")
+			return result, err
+		}
+		result.gateway = &gatewayRefund{
+			paymentIntentID: *pm.PaymentIntentID,
+			method:          method.Code,
+			amount:          amount,
 		}
-		return nil
+		return result, nil
+	}
+
+	// payments settled outside a gateway are refunded by hand
+	settled, err := settleRefund(ctx, q, result.Refund, SettleRefundTxArgs{
+		RefundID:       result.Refund.ID,
+		Status:         RefundStatusSucceeded,
+		OrderActorArgs: arg.OrderActorArgs,
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Refund = settled.Refund
+	if settled.OrderStatus != "" {
+		result.OrderStatus = settled.OrderStatus
+	}
+	return result, nil
+}
+
+// settleRefund records the outcome of a locked pending refund inside the caller's transaction.
+// Refunds that were already settled, e.g. by their webhook, are left as they are.
+func settleRefund(ctx context.Context, q *Queries, rf Refund, arg SettleRefundTxArgs) (RefundTxResult, error) {
+	result := RefundTxResult{Refund: rf}
+	if rf.Status != RefundStatusPending {
+		return result, nil
+	}
+	var err error
+	result.Refund, err = q.UpdateRefund(ctx, UpdateRefundParams{
+		ID:              rf.ID,
+		Status:          arg.Status,
+		GatewayRefundID: arg.GatewayRefundID,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("UpdateRefund")
+		return result, err
+	}
+	if arg.Status != RefundStatusSucceeded {
+		return result, nil
+	}
+
+	if rf.Restocked {
+		items, err := q.GetRestockItemsByRefundID(ctx, rf.ID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetRestockItemsByRefundID")
+			return result, err
+		}
+		for _, item := range items {
+			_, err := q.RestoreProductStock(ctx, RestoreProductStockParams{
+				ID:    item.VariantID,
+				Stock: item.Quantity,
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("RestoreProductStock")
+				return result, err
+			}
+		}
+	}
+
+	// lock the payment so refunds settling at the same time see each other
+	pm, err := q.GetPaymentByOrderIDForUpdate(ctx, rf.OrderID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetPaymentByOrderIDForUpdate")
+		return result, err
+	}
+	if pm.Status != PaymentStatusSuccess {
+		return result, nil
+	}
+	currency := payment.NormalizeCurrency(pm.Currency)
+	settledNum, err := q.GetSettledRefundAmountByPaymentID(ctx, pm.ID)
+	if err != nil {
+		log.Error().Err(err).Msg("GetSettledRefundAmountByPaymentID")
+		return result, err
+	}
+	if utils.GetMoneyFromPgNumeric(settledNum, currency).Cmp(utils.GetMoneyFromPgNumeric(pm.Amount, currency)) < 0 {
+		return result, nil
+	}
+
+	err = q.UpdatePayment(ctx, UpdatePaymentParams{
+		ID:       pm.ID,
+		Status:   NullPaymentStatus{PaymentStatus: PaymentStatusRefunded, Valid: true},
+		RefundID: result.Refund.GatewayRefundID,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("UpdatePayment")
+		return result, err
+	}
+	err = changeOrderStatus(ctx, q, ChangeOrderStatusTxArgs{
+		OrderID:        rf.OrderID,
+		Status:         OrderStatusRefunded,
+		OrderActorArgs: arg.OrderActorArgs,
 	})
-	return
+	if err != nil {
+		return result, err
+	}
+	result.OrderStatus = OrderStatusRefunded
+	return result, nil
 }
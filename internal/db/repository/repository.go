@@ -18,13 +18,37 @@ type Store interface {
 	CheckoutCartTx(ctx context.Context, arg CheckoutCartTxArgs) (CreatePaymentResult, error)
 	SetPrimaryAddressTx(ctx context.Context, arg SetPrimaryAddressTxArgs) error
 	CancelOrderTx(ctx context.Context, params CancelOrderTxArgs) (uuid.UUID, error)
-	RefundOrderTx(ctx context.Context, params RefundOrderTxArgs) error
+	RefundTx(ctx context.Context, arg RefundTxArgs) (RefundTxResult, error)
+	SettleRefundTx(ctx context.Context, arg SettleRefundTxArgs) (RefundTxResult, error)
 	VerifyEmailTx(ctx context.Context, arg VerifyEmailTxArgs) error
 	CreateProductTx(ctx context.Context, arg CreateProductTxArgs) (Product, error)
 	UpdateProductTx(ctx context.Context, arg UpdateProductTxArgs) (Product, error)
 	QueryRaw(ctx context.Context, query string, args ...interface{}) (pgx.Rows, error)
 	VoteHelpfulRatingTx(ctx context.Context, arg VoteHelpfulRatingTxArgs) (uuid.UUID, error)
 	UpdateDiscountTx(ctx context.Context, id uuid.UUID, arg UpdateDiscountTxArgs) error
+	CreateShipmentTx(ctx context.Context, arg CreateShipmentTxArgs) (Shipment, error)
+	UpdateShipmentTx(ctx context.Context, arg UpdateShipmentTxArgs) (UpdateShipmentTxResult, error)
+	MergeGuestCartTx(ctx context.Context, arg MergeGuestCartTxArgs) error
+	ChangeOrderStatusTx(ctx context.Context, arg ChangeOrderStatusTxArgs) error
+	CreateReturnRequestTx(ctx context.Context, arg CreateReturnRequestTxArgs) (ReturnRequest, error)
+	ReviewReturnRequestTx(ctx context.Context, arg ReviewReturnRequestTxArgs) (ReviewReturnRequestTxResult, error)
+	RecordOfflinePaymentTx(ctx context.Context, arg RecordOfflinePaymentTxArgs) (RecordOfflinePaymentTxResult, error)
+	ExpireOfflinePaymentTx(ctx context.Context, arg ExpireOfflinePaymentTxArgs) (Payment, error)
+	SaveUserPaymentInfoTx(ctx context.Context, arg UpsertUserPaymentInfoParams) (UserPaymentInfo, error)
+	SetDefaultUserPaymentInfoTx(ctx context.Context, arg UserPaymentInfoTxArgs) error
+	DeleteUserPaymentInfoTx(ctx context.Context, arg UserPaymentInfoTxArgs) error
+	CapturePaymentTx(ctx context.Context, arg CapturePaymentTxArgs) (Payment, error)
+	VoidPaymentTx(ctx context.Context, arg VoidPaymentTxArgs) (Payment, error)
+	ApplyPaymentWebhookTx(ctx context.Context, arg ApplyPaymentWebhookTxArgs) (ApplyPaymentWebhookTxResult, error)
+	ChangePasswordTx(ctx context.Context, arg ChangePasswordTxArgs) (ChangePasswordTxResult, error)
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxArgs) (ChangePasswordTxResult, error)
+	RotateSessionTx(ctx context.Context, arg RotateSessionTxArgs) (RotateSessionTxResult, error)
+	SetUserLockedTx(ctx context.Context, arg SetUserLockedTxArgs) (User, error)
+	ChangeUserRoleTx(ctx context.Context, arg ChangeUserRoleTxArgs) (User, error)
+	ForcePasswordResetTx(ctx context.Context, arg ForcePasswordResetTxArgs) (ChangePasswordTxResult, error)
+	EnableMfaTx(ctx context.Context, arg EnableMfaTxArgs) error
+	ReplaceRecoveryCodesTx(ctx context.Context, arg ReplaceRecoveryCodesTxArgs) error
+	DisableMfaTx(ctx context.Context, arg DisableMfaTxArgs) error
 	Close()
 }
 
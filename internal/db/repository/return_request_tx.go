@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+)
+
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusRejected  = "rejected"
+)
+
+type ReturnItemTxArgs struct {
+	OrderItemID uuid.UUID
+	Quantity    int32
+}
+
+type CreateReturnRequestTxArgs struct {
+	OrderID uuid.UUID
+	UserID  uuid.UUID
+	Reason  string
+	Items   []ReturnItemTxArgs
+}
+
+// CreateReturnRequestTx opens a return request for items of a delivered order. Items
+// already refunded or waiting on another request can't be requested again.
+func (repo *pgRepo) CreateReturnRequestTx(ctx context.Context, arg CreateReturnRequestTxArgs) (ReturnRequest, error) {
+	var returnRequest ReturnRequest
+	err := repo.execTx(ctx, func(q *Queries) error {
+		// lock the order so two requests can't claim the same items
+		order, err := q.GetOrderForUpdate(ctx, arg.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetOrderForUpdate")
+			return err
+		}
+		if order.Status != OrderStatusDelivered && order.Status != OrderStatusCompleted {
+			return fmt.Errorf("%w: order with status %s can't be returned", ErrInvalidReturn, order.Status)
+		}
+
+		orderItems, err := q.GetOrderItems(ctx, arg.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetOrderItems")
+			return err
+		}
+		refunded, err := q.GetRefundedQuantitiesByOrderID(ctx, arg.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetRefundedQuantitiesByOrderID")
+			return err
+		}
+		requested, err := q.GetRequestedReturnQuantitiesByOrderID(ctx, arg.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetRequestedReturnQuantitiesByOrderID")
+			return err
+		}
+
+		left := make(map[uuid.UUID]int32, len(orderItems))
+		for _, item := range orderItems {
+			left[item.ID] = int32(item.Quantity)
+		}
+		for _, row := range refunded {
+			left[row.OrderItemID] -= row.RefundedQuantity
+		}
+		for _, row := range requested {
+			left[row.OrderItemID] -= row.RequestedQuantity
+		}
+		for _, item := range arg.Items {
+			qty, ok := left[item.OrderItemID]
+			if !ok {
+				return fmt.Errorf("%w: order item %s doesn't belong to order %s", ErrInvalidReturn, item.OrderItemID, arg.OrderID)
+			}
+			if item.Quantity > qty {
+				return fmt.Errorf("%w: only %d of order item %s left to return", ErrInvalidReturn, qty, item.OrderItemID)
+			}
+			left[item.OrderItemID] -= item.Quantity
+		}
+
+		returnRequest, err = q.CreateReturnRequest(ctx, CreateReturnRequestParams{
+			OrderID: arg.OrderID,
+			UserID:  arg.UserID,
+			Reason:  arg.Reason,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("CreateReturnRequest")
+			return err
+		}
+
+		for _, item := range arg.Items {
+			err = q.CreateReturnRequestItem(ctx, CreateReturnRequestItemParams{
+				ReturnRequestID: returnRequest.ID,
+				OrderItemID:     item.OrderItemID,
+				Quantity:        item.Quantity,
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("CreateReturnRequestItem")
+				return err
+			}
+		}
+		return nil
+	})
+	return returnRequest, err
+}
+
+type ReviewReturnRequestTxArgs struct {
+	ID      uuid.UUID
+	Approve bool
+	Note    *string
+	// Restock puts the returned items back on sale when the request is approved
+	Restock bool
+	OrderActorArgs
+	RefundPaymentFn RefundPaymentFn
+}
+
+type ReviewReturnRequestTxResult struct {
+	ReturnRequest ReturnRequest   `json:"returnRequest"`
+	Refund        *RefundTxResult `json:"refund,omitempty"`
+}
+
+// ReviewReturnRequestTx approves or rejects a return request. Approving it refunds the
+// returned items against the original payment, through the gateway once the approval is committed.
+func (repo *pgRepo) ReviewReturnRequestTx(ctx context.Context, arg ReviewReturnRequestTxArgs) (ReviewReturnRequestTxResult, error) {
+	var result ReviewReturnRequestTxResult
+	err := repo.execTx(ctx, func(q *Queries) error {
+		returnRequest, err := q.GetReturnRequestForUpdate(ctx, arg.ID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetReturnRequestForUpdate")
+			return err
+		}
+		if returnRequest.Status != ReturnStatusRequested {
+			return fmt.Errorf("%w: return request is already %s", ErrInvalidReturn, returnRequest.Status)
+		}
+
+		status := ReturnStatusRejected
+		params := UpdateReturnRequestParams{
+			ID:         returnRequest.ID,
+			AdminNote:  arg.Note,
+			ReviewedBy: arg.OrderActorArgs.ID,
+			ReviewedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		}
+
+		if arg.Approve {
+			status = ReturnStatusApproved
+			items, err := q.GetReturnRequestItems(ctx, returnRequest.ID)
+			if err != nil {
+				log.Error().Err(err).Msg("GetReturnRequestItems")
+				return err
+			}
+			refundItems := make([]RefundItemTxArgs, len(items))
+			for i, item := range items {
+				refundItems[i] = RefundItemTxArgs{
+					OrderItemID: item.OrderItemID,
+					Quantity:    item.Quantity,
+				}
+			}
+
+			refundResult, err := refund(ctx, q, RefundTxArgs{
+				OrderID:         returnRequest.OrderID,
+				Items:           refundItems,
+				Restock:         arg.Restock,
+				OrderActorArgs:  arg.OrderActorArgs,
+				RefundPaymentFn: arg.RefundPaymentFn,
+			})
+			if err != nil {
+				return err
+			}
+			result.Refund = &refundResult
+			params.RefundID = utils.GetPgTypeUUID(refundResult.Refund.ID)
+		}
+		params.Status = &status
+
+		result.ReturnRequest, err = q.UpdateReturnRequest(ctx, params)
+		if err != nil {
+			log.Error().Err(err).Msg("UpdateReturnRequest")
+			return err
+		}
+		return nil
+	})
+	if err != nil || result.Refund == nil {
+		return result, err
+	}
+
+	// the return stays approved when the gateway turns the refund down, it can be refunded again
+	refundResult, err := repo.submitRefund(ctx, *result.Refund, arg.RefundPaymentFn, arg.OrderActorArgs)
+	result.Refund = &refundResult
+	return result, err
+}
@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+type RotateSessionTxArgs struct {
+	// SessionID and RefreshToken are what the client presented
+	SessionID       uuid.UUID
+	RefreshToken    string
+	NewSessionID    uuid.UUID
+	NewRefreshToken string
+	UserAgent       string
+	ClientIp        netip.Addr
+	ExpiredAt       time.Time
+}
+
+type RotateSessionTxResult struct {
+	Session UserSession `json:"session"`
+	// ReuseDetected is set when a refresh token that was already rotated is used again, the
+	// whole family is blocked then since the token must have leaked.
+	ReuseDetected bool `json:"reuseDetected"`
+}
+
+// RotateSessionTx exchanges a refresh token for a new session of the same family. A refresh
+// token works only once.
+func (repo *pgRepo) RotateSessionTx(ctx context.Context, arg RotateSessionTxArgs) (RotateSessionTxResult, error) {
+	var result RotateSessionTxResult
+	err := repo.execTx(ctx, func(q *Queries) error {
+		session, err := q.GetSessionForUpdate(ctx, arg.SessionID)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return fmt.Errorf("%w: session not found", ErrInvalidSession)
+			}
+			log.Error().Err(err).Msg("GetSessionForUpdate")
+			return err
+		}
+		if session.RefreshToken != arg.RefreshToken {
+			return fmt.Errorf("%w: refresh token does not match", ErrInvalidSession)
+		}
+		if session.Blocked {
+			return fmt.Errorf("%w: session is blocked", ErrInvalidSession)
+		}
+		if session.RotatedAt.Valid {
+			// the block has to be committed, so it is not returned as an error
+			if _, err = q.BlockSessionFamily(ctx, BlockSessionFamilyParams{
+				FamilyID: session.FamilyID,
+				UserID:   session.UserID,
+			}); err != nil {
+				log.Error().Err(err).Msg("BlockSessionFamily")
+				return err
+			}
+			result.Session = session
+			result.ReuseDetected = true
+			return nil
+		}
+		if time.Now().After(session.ExpiredAt) {
+			return fmt.Errorf("%w: session has expired", ErrInvalidSession)
+		}
+
+		if err = q.RotateSession(ctx, session.ID); err != nil {
+			log.Error().Err(err).Msg("RotateSession")
+			return err
+		}
+		result.Session, err = q.InsertSession(ctx, InsertSessionParams{
+			ID:           arg.NewSessionID,
+			UserID:       session.UserID,
+			FamilyID:     session.FamilyID,
+			RefreshToken: arg.NewRefreshToken,
+			UserAgent:    arg.UserAgent,
+			ClientIp:     arg.ClientIp,
+			ExpiredAt:    pgtype.Timestamptz{Time: arg.ExpiredAt, Valid: true},
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("InsertSession")
+			return err
+		}
+		return nil
+	})
+	return result, err
+}
@@ -8,13 +8,43 @@ package repository
 import (
 	"context"
 	"net/netip"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const blockSessionFamily = `-- name: BlockSessionFamily :execrows
+UPDATE user_sessions SET blocked = TRUE WHERE family_id = $1 AND user_id = $2 AND blocked = FALSE
+`
+
+type BlockSessionFamilyParams struct {
+	FamilyID uuid.UUID `json:"familyId"`
+	UserID   uuid.UUID `json:"userId"`
+}
+
+func (q *Queries) BlockSessionFamily(ctx context.Context, arg BlockSessionFamilyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, blockSessionFamily, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const blockUserSessions = `-- name: BlockUserSessions :execrows
+UPDATE user_sessions SET blocked = TRUE WHERE user_id = $1 AND blocked = FALSE
+`
+
+func (q *Queries) BlockUserSessions(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, blockUserSessions, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const getSession = `-- name: GetSession :one
-SELECT id, user_id, refresh_token, user_agent, client_ip, blocked, expired_at, created_at FROM user_sessions WHERE id = $1 LIMIT 1
+SELECT id, user_id, refresh_token, user_agent, client_ip, blocked, expired_at, created_at, family_id, rotated_at FROM user_sessions WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetSession(ctx context.Context, id uuid.UUID) (UserSession, error) {
@@ -29,12 +59,14 @@ func (q *Queries) GetSession(ctx context.Context, id uuid.UUID) (UserSession, er
 		&i.Blocked,
 		&i.ExpiredAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
 	)
 	return i, err
 }
 
 const getSessionByRefreshToken = `-- name: GetSessionByRefreshToken :one
-SELECT id, user_id, refresh_token, user_agent, client_ip, blocked, expired_at, created_at FROM user_sessions WHERE refresh_token = $1 LIMIT 1
+SELECT id, user_id, refresh_token, user_agent, client_ip, blocked, expired_at, created_at, family_id, rotated_at FROM user_sessions WHERE refresh_token = $1 LIMIT 1
 `
 
 func (q *Queries) GetSessionByRefreshToken(ctx context.Context, refreshToken string) (UserSession, error) {
@@ -49,17 +81,42 @@ func (q *Queries) GetSessionByRefreshToken(ctx context.Context, refreshToken str
 		&i.Blocked,
 		&i.ExpiredAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+	)
+	return i, err
+}
+
+const getSessionForUpdate = `-- name: GetSessionForUpdate :one
+SELECT id, user_id, refresh_token, user_agent, client_ip, blocked, expired_at, created_at, family_id, rotated_at FROM user_sessions WHERE id = $1 LIMIT 1 FOR UPDATE
+`
+
+func (q *Queries) GetSessionForUpdate(ctx context.Context, id uuid.UUID) (UserSession, error) {
+	row := q.db.QueryRow(ctx, getSessionForUpdate, id)
+	var i UserSession
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.RefreshToken,
+		&i.UserAgent,
+		&i.ClientIp,
+		&i.Blocked,
+		&i.ExpiredAt,
+		&i.CreatedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
 	)
 	return i, err
 }
 
 const insertSession = `-- name: InsertSession :one
-INSERT INTO user_sessions (id,user_id,refresh_token,user_agent,client_ip,blocked,expired_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, user_id, refresh_token, user_agent, client_ip, blocked, expired_at, created_at
+INSERT INTO user_sessions (id,user_id,family_id,refresh_token,user_agent,client_ip,blocked,expired_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, user_id, refresh_token, user_agent, client_ip, blocked, expired_at, created_at, family_id, rotated_at
 `
 
 type InsertSessionParams struct {
 	ID           uuid.UUID          `json:"id"`
 	UserID       uuid.UUID          `json:"userId"`
+	FamilyID     uuid.UUID          `json:"familyId"`
 	RefreshToken string             `json:"refreshToken"`
 	UserAgent    string             `json:"userAgent"`
 	ClientIp     netip.Addr         `json:"clientIp"`
@@ -71,6 +128,7 @@ func (q *Queries) InsertSession(ctx context.Context, arg InsertSessionParams) (U
 	row := q.db.QueryRow(ctx, insertSession,
 		arg.ID,
 		arg.UserID,
+		arg.FamilyID,
 		arg.RefreshToken,
 		arg.UserAgent,
 		arg.ClientIp,
@@ -87,10 +145,85 @@ func (q *Queries) InsertSession(ctx context.Context, arg InsertSessionParams) (U
 		&i.Blocked,
 		&i.ExpiredAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
 	)
 	return i, err
 }
 
+const listActiveUserSessions = `-- name: ListActiveUserSessions :many
+SELECT s.id, s.user_id, s.refresh_token, s.user_agent, s.client_ip, s.blocked, s.expired_at, s.created_at, s.family_id, s.rotated_at, f.signed_in_at::timestamptz AS signed_in_at
+FROM user_sessions s
+JOIN (
+    SELECT family_id, MIN(created_at) AS signed_in_at
+    FROM user_sessions
+    WHERE user_id = $1
+    GROUP BY family_id
+) f ON f.family_id = s.family_id
+WHERE s.user_id = $1
+    AND s.blocked = FALSE
+    AND s.rotated_at IS NULL
+    AND s.expired_at > NOW()
+ORDER BY s.created_at DESC
+`
+
+type ListActiveUserSessionsRow struct {
+	ID           uuid.UUID          `json:"id"`
+	UserID       uuid.UUID          `json:"userId"`
+	RefreshToken string             `json:"refreshToken"`
+	UserAgent    string             `json:"userAgent"`
+	ClientIp     netip.Addr         `json:"clientIp"`
+	Blocked      bool               `json:"blocked"`
+	ExpiredAt    time.Time          `json:"expiredAt"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	FamilyID     uuid.UUID          `json:"familyId"`
+	RotatedAt    pgtype.Timestamptz `json:"rotatedAt"`
+	SignedInAt   time.Time          `json:"signedInAt"`
+}
+
+// ListActiveUserSessions returns the signed in devices of a user, the latest session of each
+// family with the time the family signed in.
+func (q *Queries) ListActiveUserSessions(ctx context.Context, userID uuid.UUID) ([]ListActiveUserSessionsRow, error) {
+	rows, err := q.db.Query(ctx, listActiveUserSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListActiveUserSessionsRow{}
+	for rows.Next() {
+		var i ListActiveUserSessionsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.RefreshToken,
+			&i.UserAgent,
+			&i.ClientIp,
+			&i.Blocked,
+			&i.ExpiredAt,
+			&i.CreatedAt,
+			&i.FamilyID,
+			&i.RotatedAt,
+			&i.SignedInAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const rotateSession = `-- name: RotateSession :exec
+UPDATE user_sessions SET rotated_at = NOW() WHERE id = $1
+`
+
+func (q *Queries) RotateSession(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, rotateSession, id)
+	return err
+}
+
 const updateSession = `-- name: UpdateSession :one
 UPDATE user_sessions
 SET
@@ -98,7 +231,7 @@ SET
     client_ip = COALESCE($3, client_ip),
     blocked = COALESCE($4, blocked),
     expired_at = COALESCE($5, expired_at)
-WHERE id = $1 RETURNING id, user_id, refresh_token, user_agent, client_ip, blocked, expired_at, created_at
+WHERE id = $1 RETURNING id, user_id, refresh_token, user_agent, client_ip, blocked, expired_at, created_at, family_id, rotated_at
 `
 
 type UpdateSessionParams struct {
@@ -127,6 +260,8 @@ func (q *Queries) UpdateSession(ctx context.Context, arg UpdateSessionParams) (U
 		&i.Blocked,
 		&i.ExpiredAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
 	)
 	return i, err
 }
@@ -0,0 +1,212 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	ShipmentStatusPending   = "pending"
+	ShipmentStatusShipped   = "shipped"
+	ShipmentStatusDelivered = "delivered"
+)
+
+// shipmentTransitions lists the statuses a shipment can move to from its current one.
+var shipmentTransitions = map[string][]string{
+	ShipmentStatusPending: {ShipmentStatusShipped, ShipmentStatusDelivered},
+	ShipmentStatusShipped: {ShipmentStatusDelivered},
+}
+
+type ShipmentItemTxArgs struct {
+	OrderItemID uuid.UUID
+	Quantity    int32
+}
+
+type CreateShipmentTxArgs struct {
+	OrderID          uuid.UUID
+	Items            []ShipmentItemTxArgs
+	TrackingNumber   *string
+	TrackingUrl      *string
+	ShippingProvider *string
+	ShippingNotes    *string
+}
+
+type UpdateShipmentTxArgs struct {
+	ShipmentID uuid.UUID
+	// Actor is recorded when the shipment moves the order along
+	Actor            OrderActorArgs
+	Status           *string
+	TrackingNumber   *string
+	TrackingUrl      *string
+	ShippingProvider *string
+	ShippingNotes    *string
+}
+
+type UpdateShipmentTxResult struct {
+	Shipment    Shipment    `json:"shipment"`
+	OrderStatus OrderStatus `json:"orderStatus"`
+}
+
+func (repo *pgRepo) CreateShipmentTx(ctx context.Context, arg CreateShipmentTxArgs) (Shipment, error) {
+	var shipment Shipment
+	err := repo.execTx(ctx, func(q *Queries) error {
+		// lock the order so concurrent shipments can't allocate the same items twice
+		order, err := q.GetOrderForUpdate(ctx, arg.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetOrderForUpdate")
+			return err
+		}
+		if order.Status != OrderStatusConfirmed && order.Status != OrderStatusDelivering {
+			return fmt.Errorf("%w: order with status %s can't be shipped", ErrInvalidShipment, order.Status)
+		}
+
+		fulfillment, err := q.GetOrderFulfillment(ctx, arg.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetOrderFulfillment")
+			return err
+		}
+		remaining := make(map[uuid.UUID]int32, len(fulfillment))
+		for _, row := range fulfillment {
+			remaining[row.OrderItemID] = int32(row.Quantity) - row.AllocatedQuantity
+		}
+
+		requested := make(map[uuid.UUID]int32, len(arg.Items))
+		orderItemIDs := make([]uuid.UUID, 0, len(arg.Items))
+		for _, item := range arg.Items {
+			if _, ok := requested[item.OrderItemID]; !ok {
+				orderItemIDs = append(orderItemIDs, item.OrderItemID)
+			}
+			requested[item.OrderItemID] += item.Quantity
+		}
+		for _, id := range orderItemIDs {
+			left, ok := remaining[id]
+			if !ok {
+				return fmt.Errorf("%w: order item %s doesn't belong to order %s", ErrInvalidShipment, id, arg.OrderID)
+			}
+			if requested[id] > left {
+				return fmt.Errorf("%w: only %d of order item %s left to ship", ErrInvalidShipment, left, id)
+			}
+		}
+
+		shipment, err = q.CreateShipment(ctx, CreateShipmentParams{
+			OrderID:          arg.OrderID,
+			TrackingNumber:   arg.TrackingNumber,
+			TrackingUrl:      arg.TrackingUrl,
+			ShippingProvider: arg.ShippingProvider,
+			ShippingNotes:    arg.ShippingNotes,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("CreateShipment")
+			return err
+		}
+
+		for _, id := range orderItemIDs {
+			err = q.CreateShipmentItem(ctx, CreateShipmentItemParams{
+				ShipmentID:  shipment.ID,
+				OrderItemID: id,
+				Quantity:    requested[id],
+			})
+			if err != nil {
+				log.Error().Err(err).Msg("CreateShipmentItem")
+				return err
+			}
+		}
+		return nil
+	})
+	return shipment, err
+}
+
+// UpdateShipmentTx updates tracking details and status of a shipment. Once every
+// order item is covered by shipped (or delivered) shipments the order moves to
+// delivering (or delivered).
+func (repo *pgRepo) UpdateShipmentTx(ctx context.Context, arg UpdateShipmentTxArgs) (UpdateShipmentTxResult, error) {
+	var result UpdateShipmentTxResult
+	err := repo.execTx(ctx, func(q *Queries) error {
+		shipment, err := q.GetShipmentByID(ctx, arg.ShipmentID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetShipmentByID")
+			return err
+		}
+		order, err := q.GetOrderForUpdate(ctx, shipment.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetOrderForUpdate")
+			return err
+		}
+		result.OrderStatus = order.Status
+
+		params := UpdateShipmentParams{
+			ID:               shipment.ID,
+			TrackingNumber:   arg.TrackingNumber,
+			TrackingUrl:      arg.TrackingUrl,
+			ShippingProvider: arg.ShippingProvider,
+			ShippingNotes:    arg.ShippingNotes,
+		}
+
+		statusChanged := arg.Status != nil && *arg.Status != shipment.Status
+		if statusChanged {
+			if !slices.Contains(shipmentTransitions[shipment.Status], *arg.Status) {
+				return fmt.Errorf("%w: shipment can't move from %s to %s", ErrInvalidShipment, shipment.Status, *arg.Status)
+			}
+			now := pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			params.Status = arg.Status
+			if !shipment.ShippedAt.Valid {
+				params.ShippedAt = now
+			}
+			if *arg.Status == ShipmentStatusDelivered {
+				params.DeliveredAt = now
+			}
+		}
+
+		result.Shipment, err = q.UpdateShipment(ctx, params)
+		if err != nil {
+			log.Error().Err(err).Msg("UpdateShipment")
+			return err
+		}
+		if !statusChanged {
+			return nil
+		}
+
+		fulfillment, err := q.GetOrderFulfillment(ctx, shipment.OrderID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetOrderFulfillment")
+			return err
+		}
+		allShipped, allDelivered := len(fulfillment) > 0, len(fulfillment) > 0
+		for _, row := range fulfillment {
+			if row.ShippedQuantity < int32(row.Quantity) {
+				allShipped = false
+			}
+			if row.DeliveredQuantity < int32(row.Quantity) {
+				allDelivered = false
+			}
+		}
+
+		var next OrderStatus
+		switch {
+		case allDelivered && (order.Status == OrderStatusConfirmed || order.Status == OrderStatusDelivering):
+			next = OrderStatusDelivered
+		case allShipped && order.Status == OrderStatusConfirmed:
+			next = OrderStatusDelivering
+		default:
+			return nil
+		}
+
+		err = changeOrderStatus(ctx, q, ChangeOrderStatusTxArgs{
+			OrderID:        order.ID,
+			Status:         next,
+			OrderActorArgs: arg.Actor,
+		})
+		if err != nil {
+			return err
+		}
+		result.OrderStatus = next
+		return nil
+	})
+	return result, err
+}
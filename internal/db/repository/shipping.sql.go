@@ -295,6 +295,41 @@ func (q *Queries) GetShippingRateByID(ctx context.Context, id uuid.UUID) (GetShi
 	return i, err
 }
 
+const getShippingRateConditionsByRateIDs = `-- name: GetShippingRateConditionsByRateIDs :many
+SELECT id, shipping_rate_id, condition_type, min_value, max_value, additional_fee, category_ids, created_at, updated_at FROM shipping_rate_conditions WHERE shipping_rate_id = ANY($1::uuid[]) ORDER BY created_at
+`
+
+// SHIPPING RATE CONDITIONS
+func (q *Queries) GetShippingRateConditionsByRateIDs(ctx context.Context, rateIds []uuid.UUID) ([]ShippingRateCondition, error) {
+	rows, err := q.db.Query(ctx, getShippingRateConditionsByRateIDs, rateIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ShippingRateCondition{}
+	for rows.Next() {
+		var i ShippingRateCondition
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShippingRateID,
+			&i.ConditionType,
+			&i.MinValue,
+			&i.MaxValue,
+			&i.AdditionalFee,
+			&i.CategoryIds,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getShippingRates = `-- name: GetShippingRates :many
 SELECT sr.id, sr.shipping_method_id, sr.shipping_zone_id, sr.name, sr.base_rate, sr.min_order_amount, sr.max_order_amount, sr.free_shipping_threshold, sr.is_active, sr.created_at, sr.updated_at, sm.name as method_name, sz.name as zone_name
 FROM shipping_rates sr
@@ -354,6 +389,49 @@ func (q *Queries) GetShippingRates(ctx context.Context, isActive *bool) ([]GetSh
 	return items, nil
 }
 
+const getShippingRatesByMethodAndZone = `-- name: GetShippingRatesByMethodAndZone :many
+SELECT id, shipping_method_id, shipping_zone_id, name, base_rate, min_order_amount, max_order_amount, free_shipping_threshold, is_active, created_at, updated_at FROM shipping_rates
+WHERE shipping_method_id = $1 AND shipping_zone_id = $2
+ORDER BY min_order_amount NULLS FIRST
+`
+
+type GetShippingRatesByMethodAndZoneParams struct {
+	ShippingMethodID uuid.UUID `json:"shippingMethodId"`
+	ShippingZoneID   uuid.UUID `json:"shippingZoneId"`
+}
+
+func (q *Queries) GetShippingRatesByMethodAndZone(ctx context.Context, arg GetShippingRatesByMethodAndZoneParams) ([]ShippingRate, error) {
+	rows, err := q.db.Query(ctx, getShippingRatesByMethodAndZone, arg.ShippingMethodID, arg.ShippingZoneID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ShippingRate{}
+	for rows.Next() {
+		var i ShippingRate
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShippingMethodID,
+			&i.ShippingZoneID,
+			&i.Name,
+			&i.BaseRate,
+			&i.MinOrderAmount,
+			&i.MaxOrderAmount,
+			&i.FreeShippingThreshold,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getShippingRatesByZone = `-- name: GetShippingRatesByZone :many
 SELECT sr.id, sr.shipping_method_id, sr.shipping_zone_id, sr.name, sr.base_rate, sr.min_order_amount, sr.max_order_amount, sr.free_shipping_threshold, sr.is_active, sr.created_at, sr.updated_at, sm.name as method_name
 FROM shipping_rates sr
@@ -465,6 +543,148 @@ func (q *Queries) GetShippingZones(ctx context.Context, isActive *bool) ([]Shipp
 	return items, nil
 }
 
+const listShippingMethods = `-- name: ListShippingMethods :many
+SELECT id, name, description, is_active, requires_address, estimated_delivery_time, icon_url, created_at, updated_at FROM shipping_methods ORDER BY name LIMIT $1 OFFSET $2
+`
+
+type ListShippingMethodsParams struct {
+	Limit  int64 `json:"limit"`
+	Offset int64 `json:"offset"`
+}
+
+func (q *Queries) ListShippingMethods(ctx context.Context, arg ListShippingMethodsParams) ([]ShippingMethod, error) {
+	rows, err := q.db.Query(ctx, listShippingMethods, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ShippingMethod{}
+	for rows.Next() {
+		var i ShippingMethod
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.IsActive,
+			&i.RequiresAddress,
+			&i.EstimatedDeliveryTime,
+			&i.IconUrl,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listShippingRates = `-- name: ListShippingRates :many
+SELECT sr.id, sr.shipping_method_id, sr.shipping_zone_id, sr.name, sr.base_rate, sr.min_order_amount, sr.max_order_amount, sr.free_shipping_threshold, sr.is_active, sr.created_at, sr.updated_at, sm.name as method_name, sz.name as zone_name
+FROM shipping_rates sr
+JOIN shipping_methods sm ON sr.shipping_method_id = sm.id
+JOIN shipping_zones sz ON sr.shipping_zone_id = sz.id
+ORDER BY sm.name, sz.name, sr.min_order_amount NULLS FIRST
+LIMIT $1 OFFSET $2
+`
+
+type ListShippingRatesParams struct {
+	Limit  int64 `json:"limit"`
+	Offset int64 `json:"offset"`
+}
+
+type ListShippingRatesRow struct {
+	ID                    uuid.UUID      `json:"id"`
+	ShippingMethodID      uuid.UUID      `json:"shippingMethodId"`
+	ShippingZoneID        uuid.UUID      `json:"shippingZoneId"`
+	Name                  string         `json:"name"`
+	BaseRate              pgtype.Numeric `json:"baseRate"`
+	MinOrderAmount        pgtype.Numeric `json:"minOrderAmount"`
+	MaxOrderAmount        pgtype.Numeric `json:"maxOrderAmount"`
+	FreeShippingThreshold pgtype.Numeric `json:"freeShippingThreshold"`
+	IsActive              bool           `json:"isActive"`
+	CreatedAt             time.Time      `json:"createdAt"`
+	UpdatedAt             time.Time      `json:"updatedAt"`
+	MethodName            string         `json:"methodName"`
+	ZoneName              string         `json:"zoneName"`
+}
+
+func (q *Queries) ListShippingRates(ctx context.Context, arg ListShippingRatesParams) ([]ListShippingRatesRow, error) {
+	rows, err := q.db.Query(ctx, listShippingRates, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListShippingRatesRow{}
+	for rows.Next() {
+		var i ListShippingRatesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ShippingMethodID,
+			&i.ShippingZoneID,
+			&i.Name,
+			&i.BaseRate,
+			&i.MinOrderAmount,
+			&i.MaxOrderAmount,
+			&i.FreeShippingThreshold,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.MethodName,
+			&i.ZoneName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listShippingZones = `-- name: ListShippingZones :many
+SELECT id, name, description, countries, states, zip_codes, is_active, created_at, updated_at FROM shipping_zones ORDER BY name LIMIT $1 OFFSET $2
+`
+
+type ListShippingZonesParams struct {
+	Limit  int64 `json:"limit"`
+	Offset int64 `json:"offset"`
+}
+
+func (q *Queries) ListShippingZones(ctx context.Context, arg ListShippingZonesParams) ([]ShippingZone, error) {
+	rows, err := q.db.Query(ctx, listShippingZones, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ShippingZone{}
+	for rows.Next() {
+		var i ShippingZone
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Description,
+			&i.Countries,
+			&i.States,
+			&i.ZipCodes,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 type SeedShippingMethodsParams struct {
 	Name                  string  `json:"name"`
 	Description           *string `json:"description"`
@@ -53,4 +53,7 @@ type ShippingAddressSnapshot struct {
 	District string `json:"district" validate:"required"`
 	City     string `json:"city" validate:"required"`
 	Phone    string `json:"phone" validate:"required"`
+	Country  string `json:"country,omitempty"`
+	State    string `json:"state,omitempty"`
+	ZipCode  string `json:"zipCode,omitempty"`
 }
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+)
+
+// Actions recorded in the audit trail of a user
+const (
+	UserAuditLock               = "lock"
+	UserAuditUnlock             = "unlock"
+	UserAuditChangeRole         = "change_role"
+	UserAuditForcePasswordReset = "force_password_reset"
+	UserAuditViewSupport        = "view_support"
+	UserAuditEnableMfa          = "enable_mfa"
+	UserAuditDisableMfa         = "disable_mfa"
+	UserAuditRecoveryCodes      = "regenerate_recovery_codes"
+)
+
+// UserAuditArgs is who acted on a user and why, for the audit trail
+type UserAuditArgs struct {
+	ActorID uuid.UUID
+	Reason  *string
+}
+
+type SetUserLockedTxArgs struct {
+	UserID uuid.UUID
+	Locked bool
+	UserAuditArgs
+}
+
+type ChangeUserRoleTxArgs struct {
+	UserID uuid.UUID
+	RoleID uuid.UUID
+	UserAuditArgs
+}
+
+type ForcePasswordResetTxArgs struct {
+	UserID uuid.UUID
+	// HashedPassword replaces the current password so it stops working, nobody knows it
+	HashedPassword string
+	UserAuditArgs
+}
+
+// SetUserLockedTx locks or unlocks an account. Locking blocks every session of the user.
+func (repo *pgRepo) SetUserLockedTx(ctx context.Context, arg SetUserLockedTxArgs) (User, error) {
+	var user User
+	err := repo.execTx(ctx, func(q *Queries) (err error) {
+		user, err = q.SetUserLocked(ctx, SetUserLockedParams{ID: arg.UserID, Locked: arg.Locked})
+		if err != nil {
+			log.Error().Err(err).Msg("SetUserLocked")
+			return err
+		}
+
+		action := UserAuditUnlock
+		if arg.Locked {
+			action = UserAuditLock
+			if _, err = q.BlockUserSessions(ctx, arg.UserID); err != nil {
+				log.Error().Err(err).Msg("BlockUserSessions")
+				return err
+			}
+		}
+		_, err = CreateUserAudit(ctx, q, arg.UserID, action, arg.UserAuditArgs, nil)
+		return err
+	})
+	return user, err
+}
+
+// ChangeUserRoleTx gives a user another role, which has to be active
+func (repo *pgRepo) ChangeUserRoleTx(ctx context.Context, arg ChangeUserRoleTxArgs) (User, error) {
+	var user User
+	err := repo.execTx(ctx, func(q *Queries) (err error) {
+		role, err := q.GetRoleByID(ctx, arg.RoleID)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				return fmt.Errorf("%w: role not found", ErrInvalidRole)
+			}
+			log.Error().Err(err).Msg("GetRoleByID")
+			return err
+		}
+		if !role.IsActive {
+			return fmt.Errorf("%w: role %s is not active", ErrInvalidRole, role.Code)
+		}
+		previous, err := q.GetUserByID(ctx, arg.UserID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetUserByID")
+			return err
+		}
+
+		user, err = q.UpdateUserRole(ctx, UpdateUserRoleParams{ID: arg.UserID, RoleID: arg.RoleID})
+		if err != nil {
+			log.Error().Err(err).Msg("UpdateUserRole")
+			return err
+		}
+		_, err = CreateUserAudit(ctx, q, arg.UserID, UserAuditChangeRole, arg.UserAuditArgs, map[string]any{
+			"fromRoleId": previous.RoleID,
+			"toRoleId":   role.ID,
+			"toRoleCode": role.Code,
+		})
+		return err
+	})
+	return user, err
+}
+
+// ForcePasswordResetTx replaces the password of a user so it has to be reset, which signs the
+// user out everywhere like any password change.
+func (repo *pgRepo) ForcePasswordResetTx(ctx context.Context, arg ForcePasswordResetTxArgs) (ChangePasswordTxResult, error) {
+	var result ChangePasswordTxResult
+	err := repo.execTx(ctx, func(q *Queries) (err error) {
+		result, err = changePassword(ctx, q, arg.UserID, arg.HashedPassword)
+		if err != nil {
+			return err
+		}
+		_, err = CreateUserAudit(ctx, q, arg.UserID, UserAuditForcePasswordReset, arg.UserAuditArgs, nil)
+		return err
+	})
+	return result, err
+}
+
+// CreateUserAudit records an action on a user in its audit trail, the reason goes with the
+// details.
+func CreateUserAudit(ctx context.Context, q Querier, userID uuid.UUID, action string, arg UserAuditArgs, details map[string]any) (UserAuditLog, error) {
+	if arg.Reason != nil {
+		if details == nil {
+			details = map[string]any{}
+		}
+		details["reason"] = *arg.Reason
+	}
+	var raw []byte
+	if details != nil {
+		var err error
+		if raw, err = json.Marshal(details); err != nil {
+			return UserAuditLog{}, err
+		}
+	}
+	audit, err := q.CreateUserAuditLog(ctx, CreateUserAuditLogParams{
+		UserID:  userID,
+		ActorID: utils.GetPgTypeUUID(arg.ActorID),
+		Action:  action,
+		Details: raw,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("CreateUserAuditLog")
+	}
+	return audit, err
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+type EnableMfaTxArgs struct {
+	UserID uuid.UUID
+	// Step is the time step of the code that proved the authenticator is set up
+	Step               int64
+	RecoveryCodeHashes []string
+}
+
+type ReplaceRecoveryCodesTxArgs struct {
+	UserID             uuid.UUID
+	RecoveryCodeHashes []string
+}
+
+type DisableMfaTxArgs struct {
+	UserID uuid.UUID
+	UserAuditArgs
+}
+
+// EnableMfaTx turns on the second factor of a user once the first code is verified. The
+// recovery codes replace any left from before.
+func (repo *pgRepo) EnableMfaTx(ctx context.Context, arg EnableMfaTxArgs) error {
+	return repo.execTx(ctx, func(q *Queries) error {
+		enabled, err := q.EnableUserMfa(ctx, EnableUserMfaParams{
+			UserID:       arg.UserID,
+			LastUsedStep: arg.Step,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("EnableUserMfa")
+			return err
+		}
+		if enabled == 0 {
+			return fmt.Errorf("%w: already enabled or the code was used", ErrInvalidMfa)
+		}
+		if err = replaceRecoveryCodes(ctx, q, arg.UserID, arg.RecoveryCodeHashes); err != nil {
+			return err
+		}
+		_, err = CreateUserAudit(ctx, q, arg.UserID, UserAuditEnableMfa, UserAuditArgs{ActorID: arg.UserID}, nil)
+		return err
+	})
+}
+
+// ReplaceRecoveryCodesTx gives a user new recovery codes, the old ones stop working
+func (repo *pgRepo) ReplaceRecoveryCodesTx(ctx context.Context, arg ReplaceRecoveryCodesTxArgs) error {
+	return repo.execTx(ctx, func(q *Queries) error {
+		if err := replaceRecoveryCodes(ctx, q, arg.UserID, arg.RecoveryCodeHashes); err != nil {
+			return err
+		}
+		_, err := CreateUserAudit(ctx, q, arg.UserID, UserAuditRecoveryCodes, UserAuditArgs{ActorID: arg.UserID}, nil)
+		return err
+	})
+}
+
+// DisableMfaTx turns off the second factor of a user with its recovery codes
+func (repo *pgRepo) DisableMfaTx(ctx context.Context, arg DisableMfaTxArgs) error {
+	return repo.execTx(ctx, func(q *Queries) error {
+		deleted, err := q.DeleteUserMfa(ctx, arg.UserID)
+		if err != nil {
+			log.Error().Err(err).Msg("DeleteUserMfa")
+			return err
+		}
+		if deleted == 0 {
+			return fmt.Errorf("%w: not enabled", ErrInvalidMfa)
+		}
+		if err = q.DeleteRecoveryCodes(ctx, arg.UserID); err != nil {
+			log.Error().Err(err).Msg("DeleteRecoveryCodes")
+			return err
+		}
+		_, err = CreateUserAudit(ctx, q, arg.UserID, UserAuditDisableMfa, arg.UserAuditArgs, nil)
+		return err
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, q *Queries, userID uuid.UUID, hashes []string) error {
+	if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		log.Error().Err(err).Msg("DeleteRecoveryCodes")
+		return err
+	}
+	for _, hash := range hashes {
+		err := q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{UserID: userID, CodeHash: hash})
+		if err != nil {
+			log.Error().Err(err).Msg("CreateRecoveryCode")
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/rs/zerolog/log"
+)
+
+// SaveUserPaymentInfoTx stores a card saved with a gateway. The first card of a user, or
+// one saved as default, becomes the default card.
+func (repo *pgRepo) SaveUserPaymentInfoTx(ctx context.Context, arg UpsertUserPaymentInfoParams) (UserPaymentInfo, error) {
+	var info UserPaymentInfo
+	err := repo.execTx(ctx, func(q *Queries) error {
+		infos, err := q.GetUserPaymentInfos(ctx, arg.UserID)
+		if err != nil {
+			log.Error().Err(err).Msg("GetUserPaymentInfos")
+			return err
+		}
+		if len(infos) == 0 {
+			arg.IsDefault = true
+		}
+		if arg.IsDefault {
+			if err := q.ResetDefaultUserPaymentInfo(ctx, arg.UserID); err != nil {
+				log.Error().Err(err).Msg("ResetDefaultUserPaymentInfo")
+				return err
+			}
+		}
+
+		info, err = q.UpsertUserPaymentInfo(ctx, arg)
+		if err != nil {
+			log.Error().Err(err).Msg("UpsertUserPaymentInfo")
+			return err
+		}
+		// a card saved again keeps its flag on conflict
+		if arg.IsDefault && !info.IsDefault {
+			_, err = q.SetDefaultUserPaymentInfo(ctx, SetDefaultUserPaymentInfoParams{ID: info.ID, UserID: arg.UserID})
+			if err != nil {
+				log.Error().Err(err).Msg("SetDefaultUserPaymentInfo")
+				return err
+			}
+			info.IsDefault = true
+		}
+		return nil
+	})
+	return info, err
+}
+
+type UserPaymentInfoTxArgs struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+}
+
+// SetDefaultUserPaymentInfoTx makes a card the one used when none is picked
+func (repo *pgRepo) SetDefaultUserPaymentInfoTx(ctx context.Context, arg UserPaymentInfoTxArgs) error {
+	return repo.execTx(ctx, func(q *Queries) error {
+		if err := q.ResetDefaultUserPaymentInfo(ctx, arg.UserID); err != nil {
+			log.Error().Err(err).Msg("ResetDefaultUserPaymentInfo")
+			return err
+		}
+		updated, err := q.SetDefaultUserPaymentInfo(ctx, SetDefaultUserPaymentInfoParams(arg))
+		if err != nil {
+			log.Error().Err(err).Msg("SetDefaultUserPaymentInfo")
+			return err
+		}
+		if updated == 0 {
+			return ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+// DeleteUserPaymentInfoTx removes a saved card. When it was the default card the most
+// recently saved one left takes its place.
+func (repo *pgRepo) DeleteUserPaymentInfoTx(ctx context.Context, arg UserPaymentInfoTxArgs) error {
+	return repo.execTx(ctx, func(q *Queries) error {
+		info, err := q.GetUserPaymentInfo(ctx, GetUserPaymentInfoParams(arg))
+		if err != nil {
+			log.Error().Err(err).Msg("GetUserPaymentInfo")
+			return err
+		}
+		if _, err := q.DeleteUserPaymentInfo(ctx, DeleteUserPaymentInfoParams(arg)); err != nil {
+			log.Error().Err(err).Msg("DeleteUserPaymentInfo")
+			return err
+		}
+		if !info.IsDefault {
+			return nil
+		}
+		if err := q.SetLatestUserPaymentInfoDefault(ctx, arg.UserID); err != nil {
+			log.Error().Err(err).Msg("SetLatestUserPaymentInfoDefault")
+			return err
+		}
+		return nil
+	})
+}
+
+// CardExpirationDate is the last day a card expiring in the month can be charged
+func CardExpirationDate(month, year int) pgtype.Date {
+	return pgtype.Date{Time: time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC), Valid: true}
+}
+
+// IsPaymentInfoExpired reports whether a saved card can no longer be charged
+func IsPaymentInfoExpired(info UserPaymentInfo, now time.Time) bool {
+	if !info.ExpirationDate.Valid {
+		return false
+	}
+	y, m, d := now.UTC().Date()
+	return info.ExpirationDate.Time.Before(time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+}
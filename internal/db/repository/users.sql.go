@@ -36,7 +36,7 @@ func (q *Queries) CountUsers(ctx context.Context) (int64, error) {
 }
 
 const createAddress = `-- name: CreateAddress :one
-INSERT INTO user_addresses (user_id, phone_number, street, ward, district, city, "is_default") VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, user_id, phone_number, street, ward, district, city, is_default, created_at, updated_at
+INSERT INTO user_addresses (user_id, phone_number, street, ward, district, city, "is_default", country, state, zip_code) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, user_id, phone_number, street, ward, district, city, is_default, created_at, updated_at, country, state, zip_code
 `
 
 type CreateAddressParams struct {
@@ -47,6 +47,9 @@ type CreateAddressParams struct {
 	District    string    `json:"district"`
 	City        string    `json:"city"`
 	IsDefault   bool      `json:"isDefault"`
+	Country     *string   `json:"country"`
+	State       *string   `json:"state"`
+	ZipCode     *string   `json:"zipCode"`
 }
 
 // User Address Queries
@@ -59,6 +62,9 @@ func (q *Queries) CreateAddress(ctx context.Context, arg CreateAddressParams) (U
 		arg.District,
 		arg.City,
 		arg.IsDefault,
+		arg.Country,
+		arg.State,
+		arg.ZipCode,
 	)
 	var i UserAddress
 	err := row.Scan(
@@ -72,6 +78,9 @@ func (q *Queries) CreateAddress(ctx context.Context, arg CreateAddressParams) (U
 		&i.IsDefault,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Country,
+		&i.State,
+		&i.ZipCode,
 	)
 	return i, err
 }
@@ -172,7 +181,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
 }
 
 const getAddress = `-- name: GetAddress :one
-SELECT id, user_id, phone_number, street, ward, district, city, is_default, created_at, updated_at FROM user_addresses WHERE id = $1 AND user_id = $2 LIMIT 1
+SELECT id, user_id, phone_number, street, ward, district, city, is_default, created_at, updated_at, country, state, zip_code FROM user_addresses WHERE id = $1 AND user_id = $2 LIMIT 1
 `
 
 type GetAddressParams struct {
@@ -194,12 +203,15 @@ func (q *Queries) GetAddress(ctx context.Context, arg GetAddressParams) (UserAdd
 		&i.IsDefault,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Country,
+		&i.State,
+		&i.ZipCode,
 	)
 	return i, err
 }
 
 const getAddresses = `-- name: GetAddresses :many
-SELECT id, user_id, phone_number, street, ward, district, city, is_default, created_at, updated_at FROM user_addresses WHERE user_id = $1 ORDER BY "is_default" DESC, id ASC
+SELECT id, user_id, phone_number, street, ward, district, city, is_default, created_at, updated_at, country, state, zip_code FROM user_addresses WHERE user_id = $1 ORDER BY "is_default" DESC, id ASC
 `
 
 func (q *Queries) GetAddresses(ctx context.Context, userID uuid.UUID) ([]UserAddress, error) {
@@ -222,6 +234,9 @@ func (q *Queries) GetAddresses(ctx context.Context, userID uuid.UUID) ([]UserAdd
 			&i.IsDefault,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Country,
+			&i.State,
+			&i.ZipCode,
 		); err != nil {
 			return nil, err
 		}
@@ -234,7 +249,7 @@ func (q *Queries) GetAddresses(ctx context.Context, userID uuid.UUID) ([]UserAdd
 }
 
 const getDefaultAddress = `-- name: GetDefaultAddress :one
-SELECT id, user_id, phone_number, street, ward, district, city, is_default, created_at, updated_at FROM user_addresses WHERE user_id = $1 AND "is_default" = TRUE LIMIT 1
+SELECT id, user_id, phone_number, street, ward, district, city, is_default, created_at, updated_at, country, state, zip_code FROM user_addresses WHERE user_id = $1 AND "is_default" = TRUE LIMIT 1
 `
 
 func (q *Queries) GetDefaultAddress(ctx context.Context, userID uuid.UUID) (UserAddress, error) {
@@ -251,12 +266,15 @@ func (q *Queries) GetDefaultAddress(ctx context.Context, userID uuid.UUID) (User
 		&i.IsDefault,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Country,
+		&i.State,
+		&i.ZipCode,
 	)
 	return i, err
 }
 
 const getRoleByCode = `-- name: GetRoleByCode :one
-SELECT id, code, name, description, is_active, created_at, updated_at FROM user_roles WHERE code = $1 LIMIT 1
+SELECT id, code, name, description, is_active, created_at, updated_at, require_mfa FROM user_roles WHERE code = $1 LIMIT 1
 `
 
 // Roles Queries
@@ -271,12 +289,13 @@ func (q *Queries) GetRoleByCode(ctx context.Context, code string) (UserRole, err
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RequireMfa,
 	)
 	return i, err
 }
 
 const getRoleByID = `-- name: GetRoleByID :one
-SELECT id, code, name, description, is_active, created_at, updated_at FROM user_roles WHERE id = $1 LIMIT 1
+SELECT id, code, name, description, is_active, created_at, updated_at, require_mfa FROM user_roles WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetRoleByID(ctx context.Context, id uuid.UUID) (UserRole, error) {
@@ -290,6 +309,7 @@ func (q *Queries) GetRoleByID(ctx context.Context, id uuid.UUID) (UserRole, erro
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.RequireMfa,
 	)
 	return i, err
 }
@@ -598,6 +618,39 @@ func (q *Queries) SetPrimaryAddress(ctx context.Context, arg SetPrimaryAddressPa
 	return err
 }
 
+const setUserLocked = `-- name: SetUserLocked :one
+UPDATE users SET locked = $2, updated_at = NOW() WHERE id = $1 RETURNING id, role_id, username, email, phone_number, first_name, last_name, avatar_url, avatar_image_id, hashed_password, verified_email, verified_phone, locked, password_changed_at, updated_at, created_at
+`
+
+type SetUserLockedParams struct {
+	ID     uuid.UUID `json:"id"`
+	Locked bool      `json:"locked"`
+}
+
+func (q *Queries) SetUserLocked(ctx context.Context, arg SetUserLockedParams) (User, error) {
+	row := q.db.QueryRow(ctx, setUserLocked, arg.ID, arg.Locked)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.RoleID,
+		&i.Username,
+		&i.Email,
+		&i.PhoneNumber,
+		&i.FirstName,
+		&i.LastName,
+		&i.AvatarUrl,
+		&i.AvatarImageID,
+		&i.HashedPassword,
+		&i.VerifiedEmail,
+		&i.VerifiedPhone,
+		&i.Locked,
+		&i.PasswordChangedAt,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const updateAddress = `-- name: UpdateAddress :one
 UPDATE
     user_addresses
@@ -607,10 +660,13 @@ SET
     ward = coalesce($3, ward),
     district = coalesce($4, district),
     city = coalesce($5, city),
-    "is_default" = coalesce($6, "is_default")
+    "is_default" = coalesce($6, "is_default"),
+    country = coalesce($7, country),
+    state = coalesce($8, state),
+    zip_code = coalesce($9, zip_code)
 WHERE
-    id = $7 AND user_id = $8
-RETURNING id, user_id, phone_number, street, ward, district, city, is_default, created_at, updated_at
+    id = $10 AND user_id = $11
+RETURNING id, user_id, phone_number, street, ward, district, city, is_default, created_at, updated_at, country, state, zip_code
 `
 
 type UpdateAddressParams struct {
@@ -620,6 +676,9 @@ type UpdateAddressParams struct {
 	District    *string   `json:"district"`
 	City        *string   `json:"city"`
 	IsDefault   *bool     `json:"isDefault"`
+	Country     *string   `json:"country"`
+	State       *string   `json:"state"`
+	ZipCode     *string   `json:"zipCode"`
 	ID          uuid.UUID `json:"id"`
 	UserID      uuid.UUID `json:"userId"`
 }
@@ -632,6 +691,9 @@ func (q *Queries) UpdateAddress(ctx context.Context, arg UpdateAddressParams) (U
 		arg.District,
 		arg.City,
 		arg.IsDefault,
+		arg.Country,
+		arg.State,
+		arg.ZipCode,
 		arg.ID,
 		arg.UserID,
 	)
@@ -647,6 +709,9 @@ func (q *Queries) UpdateAddress(ctx context.Context, arg UpdateAddressParams) (U
 		&i.IsDefault,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Country,
+		&i.State,
+		&i.ZipCode,
 	)
 	return i, err
 }
@@ -727,6 +792,60 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (UpdateU
 	return i, err
 }
 
+const updateUserPassword = `-- name: UpdateUserPassword :one
+UPDATE users SET
+    hashed_password = $2,
+    password_changed_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1
+RETURNING password_changed_at
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID `json:"id"`
+	HashedPassword string    `json:"hashedPassword"`
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) (time.Time, error) {
+	row := q.db.QueryRow(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	var password_changed_at time.Time
+	err := row.Scan(&password_changed_at)
+	return password_changed_at, err
+}
+
+const updateUserRole = `-- name: UpdateUserRole :one
+UPDATE users SET role_id = $2, updated_at = NOW() WHERE id = $1 RETURNING id, role_id, username, email, phone_number, first_name, last_name, avatar_url, avatar_image_id, hashed_password, verified_email, verified_phone, locked, password_changed_at, updated_at, created_at
+`
+
+type UpdateUserRoleParams struct {
+	ID     uuid.UUID `json:"id"`
+	RoleID uuid.UUID `json:"roleId"`
+}
+
+func (q *Queries) UpdateUserRole(ctx context.Context, arg UpdateUserRoleParams) (User, error) {
+	row := q.db.QueryRow(ctx, updateUserRole, arg.ID, arg.RoleID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.RoleID,
+		&i.Username,
+		&i.Email,
+		&i.PhoneNumber,
+		&i.FirstName,
+		&i.LastName,
+		&i.AvatarUrl,
+		&i.AvatarImageID,
+		&i.HashedPassword,
+		&i.VerifiedEmail,
+		&i.VerifiedPhone,
+		&i.Locked,
+		&i.PasswordChangedAt,
+		&i.UpdatedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const updateVerifyEmail = `-- name: UpdateVerifyEmail :one
 UPDATE email_verifications SET is_used = TRUE WHERE id = $1 AND verify_code = $2 AND expired_at > now() RETURNING id, user_id, email, verify_code, is_used, created_at, expired_at
 `
@@ -15,7 +15,8 @@ type VerifyEmailTxArgs struct {
 }
 
 // VerifyEmailTx updates both the verify email record and user's verification status
-// within a single database transaction to ensure consistency
+// within a single database transaction to ensure consistency. Guest orders placed with
+// the verified email are claimed by the user at the same time.
 func (repo *pgRepo) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxArgs) error {
 	err := repo.execTx(ctx, func(q *Queries) error {
 		// 1. Update the verify email record to mark it as used
@@ -40,6 +41,16 @@ func (repo *pgRepo) VerifyEmailTx(ctx context.Context, arg VerifyEmailTxArgs) er
 			return err
 		}
 
+		// 3. Attach the guest orders placed with the now verified email
+		_, err = q.ClaimGuestOrders(ctx, ClaimGuestOrdersParams{
+			UserID: utils.GetPgTypeUUID(arg.VerifyEmail.UserID),
+			Email:  arg.VerifyEmail.Email,
+		})
+		if err != nil {
+			log.Error().Err(err).Msg("failed to claim guest orders")
+			return err
+		}
+
 		return nil
 	})
 
@@ -15,6 +15,9 @@ type AddressDetail struct {
 	Ward      *string   `json:"ward,omitempty"`
 	District  string    `json:"district"`
 	City      string    `json:"city"`
+	Country   *string   `json:"country,omitempty"`
+	State     *string   `json:"state,omitempty"`
+	ZipCode   *string   `json:"zipCode,omitempty"`
 }
 
 func MapAddressResponse(address repository.UserAddress) AddressDetail {
@@ -27,5 +30,8 @@ func MapAddressResponse(address repository.UserAddress) AddressDetail {
 		Ward:      address.Ward,
 		District:  address.District,
 		City:      address.City,
+		Country:   address.Country,
+		State:     address.State,
+		ZipCode:   address.ZipCode,
 	}
 }
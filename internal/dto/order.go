@@ -32,6 +32,7 @@ type OrderDiscount struct {
 type OrderDetail struct {
 	ID            uuid.UUID                          `json:"id"`
 	Total         float64                            `json:"total"`
+	Currency      string                             `json:"currency"`
 	Status        repository.OrderStatus             `json:"status"`
 	CustomerName  string                             `json:"customerName"`
 	CustomerEmail string                             `json:"customerEmail"`
@@ -39,13 +40,61 @@ type OrderDetail struct {
 	ShippingInfo  repository.ShippingAddressSnapshot `json:"shippingInfo"`
 	LineItems     []LineItem                         `json:"lineItems"`
 	Discounts     []OrderDiscount                    `json:"discounts"`
+	Shipments     []Shipment                         `json:"shipments"`
 	CreatedAt     time.Time                          `json:"createdAt"`
 	UpdatedAt     time.Time                          `json:"updatedAt"`
 }
 
+type ShipmentItem struct {
+	OrderItemID string `json:"orderItemId"`
+	Name        string `json:"name"`
+	Sku         string `json:"sku"`
+	Quantity    int32  `json:"quantity"`
+}
+
+type Shipment struct {
+	ID               string         `json:"id"`
+	Status           string         `json:"status"`
+	TrackingNumber   *string        `json:"trackingNumber"`
+	TrackingUrl      *string        `json:"trackingUrl"`
+	ShippingProvider *string        `json:"shippingProvider"`
+	ShippedAt        *time.Time     `json:"shippedAt"`
+	DeliveredAt      *time.Time     `json:"deliveredAt"`
+	Items            []ShipmentItem `json:"items"`
+	CreatedAt        time.Time      `json:"createdAt"`
+}
+
+type RefundItem struct {
+	OrderItemID uuid.UUID `json:"orderItemId"`
+	Quantity    int32     `json:"quantity"`
+	Amount      float64   `json:"amount"`
+}
+
+type Refund struct {
+	ID              uuid.UUID    `json:"id"`
+	Amount          float64      `json:"amount"`
+	Reason          *string      `json:"reason,omitempty"`
+	Status          string       `json:"status"`
+	GatewayRefundID *string      `json:"gatewayRefundId,omitempty"`
+	Restocked       bool         `json:"restocked"`
+	Items           []RefundItem `json:"items"`
+	CreatedAt       time.Time    `json:"createdAt"`
+}
+
+type OrderStatusHistory struct {
+	ID         uuid.UUID               `json:"id"`
+	FromStatus *repository.OrderStatus `json:"fromStatus"`
+	ToStatus   repository.OrderStatus  `json:"toStatus"`
+	Actor      string                  `json:"actor"`
+	ActorID    *uuid.UUID              `json:"actorId,omitempty"`
+	Reason     *string                 `json:"reason,omitempty"`
+	CreatedAt  time.Time               `json:"createdAt"`
+}
+
 type OrderListItem struct {
 	ID            uuid.UUID                `json:"id"`
 	Total         float64                  `json:"total"`
+	Currency      string                   `json:"currency"`
 	TotalItems    int32                    `json:"totalItems"`
 	Status        repository.OrderStatus   `json:"status"`
 	PaymentStatus repository.PaymentStatus `json:"paymentStatus"`
@@ -55,6 +104,26 @@ type OrderListItem struct {
 	UpdatedAt     time.Time                `json:"updatedAt"`
 }
 
+func MapToOrderListItem(row repository.GetOrdersRow) OrderListItem {
+	paymentStatus := repository.PaymentStatusPending
+	if row.PaymentStatus.Valid {
+		paymentStatus = row.PaymentStatus.PaymentStatus
+	}
+	total, _ := row.TotalPrice.Float64Value()
+	return OrderListItem{
+		ID:            row.ID,
+		Total:         total.Float64,
+		Currency:      row.Currency,
+		TotalItems:    int32(row.TotalItems),
+		Status:        row.Status,
+		CustomerName:  row.CustomerName,
+		CustomerEmail: row.CustomerEmail,
+		PaymentStatus: paymentStatus,
+		CreatedAt:     row.CreatedAt.UTC(),
+		UpdatedAt:     row.UpdatedAt.UTC(),
+	}
+}
+
 type OrderItemAttribute struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
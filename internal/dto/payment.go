@@ -1,6 +1,12 @@
 package dto
 
-import "github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+import (
+	"time"
+
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
 
 type PaymentIntentSecret struct {
 	PaymentID    string  `json:"paymentId"`
@@ -25,6 +31,8 @@ type PaymentInfo struct {
 	GateWay      *string `json:"gateway"`
 	Method       string  `json:"method"`
 	Status       string  `json:"status"`
+	// Reference is what the customer quotes on a bank transfer
+	Reference *string `json:"reference,omitempty"`
 }
 
 type PaymentMethodResponse struct {
@@ -37,4 +45,69 @@ type PaymentMethodResponse struct {
 	IsActive           bool      `json:"isActive"`
 	CurrencySupported  *[]string `json:"currencySupported,omitempty"`
 	CountriesSupported *[]string `json:"countriesSupported,omitempty"`
+	// ProcessingFee is what the method charges for the payment
+	ProcessingFee float64 `json:"processingFee"`
+}
+
+// PaymentSetupIntent is confirmed by the client with the card details, which never reach us
+type PaymentSetupIntent struct {
+	SetupIntentID string `json:"setupIntentId"`
+	ClientSecret  string `json:"clientSecret"`
+	Gateway       string `json:"gateway"`
+}
+
+type SavedPaymentMethod struct {
+	ID        string    `json:"id"`
+	Gateway   string    `json:"gateway"`
+	Brand     *string   `json:"brand,omitempty"`
+	Last4     string    `json:"last4"`
+	ExpMonth  int       `json:"expMonth"`
+	ExpYear   int       `json:"expYear"`
+	Default   bool      `json:"default"`
+	Expired   bool      `json:"expired"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func MapSavedPaymentMethod(info repository.UserPaymentInfo) SavedPaymentMethod {
+	return SavedPaymentMethod{
+		ID:        info.ID.String(),
+		Gateway:   info.Gateway,
+		Brand:     info.CardBrand,
+		Last4:     info.CardLast4,
+		ExpMonth:  int(info.ExpirationDate.Time.Month()),
+		ExpYear:   info.ExpirationDate.Time.Year(),
+		Default:   info.IsDefault,
+		Expired:   repository.IsPaymentInfoExpired(info, time.Now()),
+		CreatedAt: info.CreatedAt.Time,
+	}
+}
+
+// ExpiringAuthorization is money held at a gateway that is released unless captured before ExpiresAt
+type ExpiringAuthorization struct {
+	PaymentID       string                 `json:"paymentId"`
+	OrderID         string                 `json:"orderId"`
+	OrderStatus     repository.OrderStatus `json:"orderStatus"`
+	CustomerEmail   string                 `json:"customerEmail"`
+	Gateway         string                 `json:"gateway"`
+	PaymentIntentID *string                `json:"paymentIntentId,omitempty"`
+	Amount          float64                `json:"amount"`
+	Currency        string                 `json:"currency"`
+	AuthorizedAt    time.Time              `json:"authorizedAt"`
+	ExpiresAt       time.Time              `json:"expiresAt"`
+}
+
+func MapExpiringAuthorization(row repository.ListExpiringPaymentAuthorizationsRow) ExpiringAuthorization {
+	currency := payment.NormalizeCurrency(row.Currency)
+	return ExpiringAuthorization{
+		PaymentID:       row.PaymentID.String(),
+		OrderID:         row.OrderID.String(),
+		OrderStatus:     row.OrderStatus,
+		CustomerEmail:   row.CustomerEmail,
+		Gateway:         row.PaymentMethodCode,
+		PaymentIntentID: row.PaymentIntentID,
+		Amount:          utils.GetMoneyFromPgNumeric(row.Amount, currency).Float64(),
+		Currency:        string(currency),
+		AuthorizedAt:    row.AuthorizedAt,
+		ExpiresAt:       row.ExpiresAt,
+	}
 }
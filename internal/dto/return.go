@@ -0,0 +1,33 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReturnRequestItem struct {
+	OrderItemID uuid.UUID `json:"orderItemId"`
+	Name        string    `json:"name"`
+	Sku         string    `json:"sku"`
+	Quantity    int32     `json:"quantity"`
+}
+
+type ReturnRequestImage struct {
+	ID  uuid.UUID `json:"id"`
+	Url string    `json:"url"`
+}
+
+type ReturnRequest struct {
+	ID         uuid.UUID            `json:"id"`
+	OrderID    uuid.UUID            `json:"orderId"`
+	UserID     uuid.UUID            `json:"userId"`
+	Reason     string               `json:"reason"`
+	Status     string               `json:"status"`
+	AdminNote  *string              `json:"adminNote,omitempty"`
+	RefundID   *uuid.UUID           `json:"refundId,omitempty"`
+	ReviewedAt *time.Time           `json:"reviewedAt,omitempty"`
+	Items      []ReturnRequestItem  `json:"items"`
+	Images     []ReturnRequestImage `json:"images"`
+	CreatedAt  time.Time            `json:"createdAt"`
+}
@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -34,11 +35,123 @@ type LoginResponse struct {
 	AccessTokenExpiresAt  time.Time `json:"accessTokenExpiresIn"`
 	RefreshToken          string    `json:"refreshToken"`
 	RefreshTokenExpiresAt time.Time `json:"refreshTokenExpiresAt"`
+	// MfaRequired means no tokens were issued yet, the login is finished with MfaToken and a code
+	MfaRequired       bool       `json:"mfaRequired,omitempty"`
+	MfaToken          string     `json:"mfaToken,omitempty"`
+	MfaTokenExpiresAt *time.Time `json:"mfaTokenExpiresAt,omitempty"`
 }
 
 type RefreshToken struct {
-	AccessToken          string    `json:"accessToken"`
-	AccessTokenExpiresAt time.Time `json:"accessTokenExpiresAt"`
+	AccessToken           string    `json:"accessToken"`
+	AccessTokenExpiresAt  time.Time `json:"accessTokenExpiresAt"`
+	RefreshToken          string    `json:"refreshToken"`
+	RefreshTokenExpiresAt time.Time `json:"refreshTokenExpiresAt"`
+}
+
+// SessionDetail is a signed in device, its ID stays the same while its refresh token rotates
+type SessionDetail struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	ClientIP   string    `json:"clientIp"`
+	SignedInAt time.Time `json:"signedInAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
+// MfaStatus tells whether the user signs in with a second factor and whether the role demands it
+type MfaStatus struct {
+	Enabled           bool       `json:"enabled"`
+	EnabledAt         *time.Time `json:"enabledAt,omitempty"`
+	Required          bool       `json:"required"`
+	RecoveryCodesLeft int64      `json:"recoveryCodesLeft"`
+}
+
+// MfaEnrollment is what an authenticator app is set up with, ProvisioningURI is meant for a QR code
+type MfaEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+// MfaRecoveryCodes are shown once, only their hashes are kept
+type MfaRecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// UserSupportView is everything support needs to help a customer, in one place
+type UserSupportView struct {
+	User      UserDetail      `json:"user"`
+	Orders    []OrderListItem `json:"orders"`
+	Addresses []AddressDetail `json:"addresses"`
+	Ratings   []RatingDetail  `json:"ratings"`
+	Sessions  []SessionDetail `json:"sessions"`
+}
+
+type UserAuditLog struct {
+	ID        uuid.UUID       `json:"id"`
+	Action    string          `json:"action"`
+	ActorID   *uuid.UUID      `json:"actorId,omitempty"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+func MapToUserAuditLog(log repository.UserAuditLog) UserAuditLog {
+	resp := UserAuditLog{
+		ID:        log.ID,
+		Action:    log.Action,
+		Details:   log.Details,
+		CreatedAt: log.CreatedAt,
+	}
+	if log.ActorID.Valid {
+		actorID := uuid.UUID(log.ActorID.Bytes)
+		resp.ActorID = &actorID
+	}
+	return resp
+}
+
+type RoleDetail struct {
+	ID          uuid.UUID          `json:"id"`
+	Code        string             `json:"code"`
+	Name        string             `json:"name"`
+	Description *string            `json:"description,omitempty"`
+	IsActive    bool               `json:"isActive"`
+	RequireMfa  bool               `json:"requireMfa"`
+	Permissions []PermissionDetail `json:"permissions,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt"`
+	UpdatedAt   time.Time          `json:"updatedAt"`
+}
+
+type PermissionDetail struct {
+	Module  string `json:"module"`
+	Read    bool   `json:"read"`
+	Write   bool   `json:"write"`
+	Execute bool   `json:"execute"`
+}
+
+func MapToRoleDetail(role repository.UserRole, permissions []repository.Permission) RoleDetail {
+	resp := RoleDetail{
+		ID:          role.ID,
+		Code:        role.Code,
+		Name:        role.Name,
+		Description: role.Description,
+		IsActive:    role.IsActive,
+		RequireMfa:  role.RequireMfa,
+		CreatedAt:   role.CreatedAt,
+		UpdatedAt:   role.UpdatedAt,
+	}
+	for _, perm := range permissions {
+		resp.Permissions = append(resp.Permissions, MapToPermissionDetail(perm))
+	}
+	return resp
+}
+
+func MapToPermissionDetail(perm repository.Permission) PermissionDetail {
+	return PermissionDetail{
+		Module:  perm.Module,
+		Read:    perm.R,
+		Write:   perm.W,
+		Execute: perm.X,
+	}
 }
 
 func MapToUserResponse(user repository.User, roleCode string) UserDetail {
@@ -54,8 +167,21 @@ func MapToUserResponse(user repository.User, roleCode string) UserDetail {
 		Username:          user.Username,
 		VerifiedEmail:     user.VerifiedEmail,
 		VerifiedPhone:     user.VerifiedPhone,
+		Locked:            user.Locked,
 		CreatedAt:         user.CreatedAt.String(),
 		UpdatedAt:         user.UpdatedAt.String(),
 		PasswordChangedAt: user.PasswordChangedAt.String(),
 	}
 }
+
+func MapToSessionDetail(session repository.ListActiveUserSessionsRow, currentID uuid.UUID) SessionDetail {
+	return SessionDetail{
+		ID:         session.FamilyID,
+		UserAgent:  session.UserAgent,
+		ClientIP:   session.ClientIp.String(),
+		SignedInAt: session.SignedInAt,
+		LastUsedAt: session.CreatedAt,
+		ExpiresAt:  session.ExpiredAt,
+		Current:    session.FamilyID == currentID,
+	}
+}
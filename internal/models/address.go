@@ -6,6 +6,9 @@ type CreateAddress struct {
 	District  string  `json:"district" validate:"required"`
 	City      string  `json:"city" validate:"required"`
 	Ward      *string `json:"ward,omitempty" validate:"omitempty,max=100"`
+	Country   *string `json:"country,omitempty" validate:"omitempty,iso3166_1_alpha2"`
+	State     *string `json:"state,omitempty" validate:"omitempty,max=100"`
+	ZipCode   *string `json:"zipCode,omitempty" validate:"omitempty,max=20"`
 	IsDefault bool    `json:"isDefault,omitempty" validate:"omitempty"`
 }
 
@@ -15,5 +18,8 @@ type UpdateAddress struct {
 	Ward      *string `json:"ward" validate:"omitempty"`
 	District  *string `json:"district" validate:"omitempty"`
 	City      *string `json:"city" validate:"omitempty"`
+	Country   *string `json:"country" validate:"omitempty,iso3166_1_alpha2"`
+	State     *string `json:"state" validate:"omitempty,max=100"`
+	ZipCode   *string `json:"zipCode" validate:"omitempty,max=20"`
 	IsDefault *bool   `json:"isDefault" validate:"omitempty"`
 }
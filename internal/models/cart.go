@@ -3,8 +3,23 @@ package models
 type CheckoutModel struct {
 	PaymentMethodId string   `json:"paymentMethodId" validate:"required,uuid"`
 	DiscountCodes   []string `json:"discountCodes" validate:"omitempty"`
+	ShippingRateId  *string  `json:"shippingRateId" validate:"omitempty,uuid"`
+	// Currency is the ISO 4217 code to pay in, the store currency when empty
+	Currency string `json:"currency" validate:"omitempty,iso4217"`
+	// SavedPaymentMethodId charges a saved card right away, it must belong to the gateway of the payment method
+	SavedPaymentMethodId *string `json:"savedPaymentMethodId" validate:"omitempty,uuid"`
 }
 
 type UpdateCartItemQtyModel struct {
 	Quantity int16 `json:"quantity" validate:"required,gt=0"`
 }
+
+type GuestCheckoutModel struct {
+	Email           string        `json:"email" validate:"required,email,max=255"`
+	FullName        string        `json:"fullName" validate:"required,min=2,max=255"`
+	Phone           string        `json:"phone" validate:"required,min=10,max=15"`
+	Address         CreateAddress `json:"address" validate:"required"`
+	PaymentMethodId string        `json:"paymentMethodId" validate:"required,uuid"`
+	ShippingRateId  *string       `json:"shippingRateId" validate:"omitempty,uuid"`
+	Currency        string        `json:"currency" validate:"omitempty,iso4217"`
+}
@@ -1,7 +1,7 @@
 package models
 
 type OrderStatusModel struct {
-	Status string `json:"status" validate:"required,oneof=pending confirmed delivering delivered completed"`
+	Status string `json:"status" validate:"required,oneof=confirmed delivering delivered completed"`
 	Reason string `json:"reason,omitempty"`
 }
 
@@ -9,6 +9,21 @@ type RefundOrderModel struct {
 	Reason string `json:"reason" validate:"required"`
 }
 
+type RefundItemModel struct {
+	OrderItemID string `json:"orderItemId" validate:"required,uuid"`
+	Quantity    int32  `json:"quantity" validate:"required,gt=0"`
+}
+
+// CreateRefundModel refunds either order items or an arbitrary amount. Leaving both
+// out refunds what is left of the payment. The amount is a decimal string, e.g. "19.99",
+// so it reaches the gateway exactly as it was typed.
+type CreateRefundModel struct {
+	Items   []RefundItemModel `json:"items" validate:"omitempty,unique=OrderItemID,dive"`
+	Amount  *string           `json:"amount" validate:"omitempty,numeric,excludes=-"`
+	Reason  string            `json:"reason" validate:"required,max=500"`
+	Restock bool              `json:"restock"`
+}
+
 type CancelOrderModel struct {
 	Reason string `json:"reason" validate:"required"`
 }
@@ -17,3 +32,24 @@ type OrderListQuery struct {
 	Status        *string `form:"status,omitempty" validate:"omitempty,oneof=pending confirmed delivering delivered completed cancelled refunded"`
 	PaymentStatus *string `form:"paymentStatus,omitempty" validate:"omitempty,oneof=pending succeeded failed cancelled refunded"`
 }
+
+type ShipmentItemModel struct {
+	OrderItemID string `json:"orderItemId" validate:"required,uuid"`
+	Quantity    int32  `json:"quantity" validate:"required,gt=0"`
+}
+
+type CreateShipmentModel struct {
+	Items            []ShipmentItemModel `json:"items" validate:"required,min=1,dive"`
+	TrackingNumber   *string             `json:"trackingNumber" validate:"omitempty,max=100"`
+	TrackingUrl      *string             `json:"trackingUrl" validate:"omitempty,url"`
+	ShippingProvider *string             `json:"shippingProvider" validate:"omitempty,max=100"`
+	ShippingNotes    *string             `json:"shippingNotes" validate:"omitempty,max=1000"`
+}
+
+type UpdateShipmentModel struct {
+	Status           *string `json:"status" validate:"omitempty,oneof=shipped delivered"`
+	TrackingNumber   *string `json:"trackingNumber" validate:"omitempty,max=100"`
+	TrackingUrl      *string `json:"trackingUrl" validate:"omitempty,url"`
+	ShippingProvider *string `json:"shippingProvider" validate:"omitempty,max=100"`
+	ShippingNotes    *string `json:"shippingNotes" validate:"omitempty,max=1000"`
+}
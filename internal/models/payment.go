@@ -12,3 +12,40 @@ type PaymentModel struct {
 type UpdatePaymentStatusModel struct {
 	Status repository.PaymentStatus `json:"status" validate:"required"`
 }
+
+// RecordOfflinePaymentModel records money received for a cash on delivery or bank
+// transfer order. Leaving the amount out settles the outstanding balance.
+type RecordOfflinePaymentModel struct {
+	Amount    *float64 `json:"amount" validate:"omitempty,gt=0"`
+	Reference *string  `json:"reference" validate:"omitempty,max=255"`
+	Note      *string  `json:"note" validate:"omitempty,max=500"`
+}
+
+type ExpireOfflinePaymentModel struct {
+	Reason *string `json:"reason" validate:"omitempty,max=500"`
+}
+
+// CapturePaymentModel takes the money of an authorized payment. Leaving the amount out
+// captures everything authorized, a smaller amount releases the rest.
+type CapturePaymentModel struct {
+	Amount *float64 `json:"amount" validate:"omitempty,gt=0"`
+}
+
+type VoidPaymentModel struct {
+	Reason *string `json:"reason" validate:"omitempty,max=500"`
+}
+
+// UpsertCurrencyRateModel sets the exchange rate from the store currency
+type UpsertCurrencyRateModel struct {
+	Rate              float64  `json:"rate" validate:"required,gt=0"`
+	RoundingIncrement *float64 `json:"roundingIncrement" validate:"omitempty,gt=0"`
+	IsActive          *bool    `json:"isActive" validate:"omitempty"`
+}
+
+// SavePaymentMethodModel keeps the card saved by a setup intent the client confirmed
+type SavePaymentMethodModel struct {
+	SetupIntentID string `json:"setupIntentId" validate:"required,max=255"`
+	// Gateway the setup intent was created with, stripe when empty
+	Gateway   string `json:"gateway" validate:"omitempty,max=50"`
+	IsDefault bool   `json:"isDefault"`
+}
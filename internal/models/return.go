@@ -0,0 +1,29 @@
+package models
+
+import (
+	"mime/multipart"
+)
+
+type ReturnItemModel struct {
+	OrderItemID string `json:"orderItemId" validate:"required,uuid"`
+	Quantity    int32  `json:"quantity" validate:"required,gt=0"`
+}
+
+type CreateReturnRequestModel struct {
+	Items  []ReturnItemModel `json:"items" validate:"required,min=1,dive"`
+	Reason string            `json:"reason" validate:"required,max=1000"`
+}
+
+type ReturnRequestImageModel struct {
+	Image *multipart.FileHeader `form:"image" validate:"required"`
+}
+
+type ApproveReturnRequestModel struct {
+	Note *string `json:"note" validate:"omitempty,max=1000"`
+	// Restock puts the returned items back on sale
+	Restock bool `json:"restock"`
+}
+
+type RejectReturnRequestModel struct {
+	Note string `json:"note" validate:"required,max=1000"`
+}
@@ -0,0 +1,57 @@
+package models
+
+type CreateShippingMethodModel struct {
+	Name                  string  `json:"name" validate:"required,min=2,max=100"`
+	Description           *string `json:"description" validate:"omitempty,max=1000"`
+	IsActive              *bool   `json:"isActive" validate:"omitempty"`
+	RequiresAddress       *bool   `json:"requiresAddress" validate:"omitempty"`
+	EstimatedDeliveryTime *string `json:"estimatedDeliveryTime" validate:"omitempty,max=50"`
+	IconUrl               *string `json:"iconUrl" validate:"omitempty,url"`
+}
+
+type UpdateShippingMethodModel struct {
+	Name                  *string `json:"name" validate:"omitempty,min=2,max=100"`
+	Description           *string `json:"description" validate:"omitempty,max=1000"`
+	IsActive              *bool   `json:"isActive" validate:"omitempty"`
+	RequiresAddress       *bool   `json:"requiresAddress" validate:"omitempty"`
+	EstimatedDeliveryTime *string `json:"estimatedDeliveryTime" validate:"omitempty,max=50"`
+	IconUrl               *string `json:"iconUrl" validate:"omitempty,url"`
+}
+
+type CreateShippingZoneModel struct {
+	Name        string   `json:"name" validate:"required,min=2,max=100"`
+	Description *string  `json:"description" validate:"omitempty,max=1000"`
+	Countries   []string `json:"countries" validate:"required,min=1,dive,iso3166_1_alpha2"`
+	States      []string `json:"states" validate:"omitempty,dive,required,max=100"`
+	ZipCodes    []string `json:"zipCodes" validate:"omitempty,dive,required,max=20"`
+	IsActive    *bool    `json:"isActive" validate:"omitempty"`
+}
+
+type UpdateShippingZoneModel struct {
+	Name        *string  `json:"name" validate:"omitempty,min=2,max=100"`
+	Description *string  `json:"description" validate:"omitempty,max=1000"`
+	Countries   []string `json:"countries" validate:"omitempty,min=1,dive,iso3166_1_alpha2"`
+	States      []string `json:"states" validate:"omitempty,dive,required,max=100"`
+	ZipCodes    []string `json:"zipCodes" validate:"omitempty,dive,required,max=20"`
+	IsActive    *bool    `json:"isActive" validate:"omitempty"`
+}
+
+type CreateShippingRateModel struct {
+	ShippingMethodID      string   `json:"shippingMethodId" validate:"required,uuid"`
+	ShippingZoneID        string   `json:"shippingZoneId" validate:"required,uuid"`
+	Name                  string   `json:"name" validate:"required,min=2,max=100"`
+	BaseRate              float64  `json:"baseRate" validate:"gte=0"`
+	MinOrderAmount        *float64 `json:"minOrderAmount" validate:"omitempty,gte=0"`
+	MaxOrderAmount        *float64 `json:"maxOrderAmount" validate:"omitempty,gt=0"`
+	FreeShippingThreshold *float64 `json:"freeShippingThreshold" validate:"omitempty,gt=0"`
+	IsActive              *bool    `json:"isActive" validate:"omitempty"`
+}
+
+type UpdateShippingRateModel struct {
+	Name                  *string  `json:"name" validate:"omitempty,min=2,max=100"`
+	BaseRate              *float64 `json:"baseRate" validate:"omitempty,gte=0"`
+	MinOrderAmount        *float64 `json:"minOrderAmount" validate:"omitempty,gte=0"`
+	MaxOrderAmount        *float64 `json:"maxOrderAmount" validate:"omitempty,gt=0"`
+	FreeShippingThreshold *float64 `json:"freeShippingThreshold" validate:"omitempty,gt=0"`
+	IsActive              *bool    `json:"isActive" validate:"omitempty"`
+}
@@ -27,3 +27,66 @@ type LoginModel struct {
 type VerifyEmailQuery struct {
 	VerifyCode string `form:"verifyCode" validate:"required,min=1"`
 }
+
+type ForgotPasswordModel struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// ResetPasswordModel sets a new password with the token mailed by forgot-password
+type ResetPasswordModel struct {
+	Token       string `json:"token" validate:"required,max=255"`
+	NewPassword string `json:"newPassword" validate:"required,min=6,max=32"`
+}
+
+type ChangePasswordModel struct {
+	CurrentPassword string `json:"currentPassword" validate:"required,min=6,max=32"`
+	NewPassword     string `json:"newPassword" validate:"required,min=6,max=32,nefield=CurrentPassword"`
+}
+
+type CreateRoleModel struct {
+	Code        string  `json:"code" validate:"required,min=2,max=50,lowercase"`
+	Name        string  `json:"name" validate:"required,min=2,max=100"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=500"`
+	IsActive    *bool   `json:"isActive,omitempty"`
+}
+
+type UpdateRoleModel struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,min=2,max=100"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=500"`
+	IsActive    *bool   `json:"isActive,omitempty"`
+	// RequireMfa keeps users of the role out of the back office until they sign in with a second factor
+	RequireMfa *bool `json:"requireMfa,omitempty"`
+}
+
+// RolePermissionModel replaces the flags a role has on a module
+type RolePermissionModel struct {
+	Read    bool `json:"read"`
+	Write   bool `json:"write"`
+	Execute bool `json:"execute"`
+}
+
+// AdminUserActionModel is the reason staff give for acting on an account, kept in its audit trail
+type AdminUserActionModel struct {
+	Reason string `json:"reason" validate:"required,min=3,max=500"`
+}
+
+type ChangeUserRoleModel struct {
+	RoleID string  `json:"roleId" validate:"required,uuid"`
+	Reason *string `json:"reason,omitempty" validate:"omitempty,max=500"`
+}
+
+// LoginMfaModel finishes a login waiting for its second factor
+type LoginMfaModel struct {
+	MfaToken string `json:"mfaToken" validate:"required"`
+	// Code is one of the authenticator app or a recovery code
+	Code string `json:"code" validate:"required,min=6,max=20"`
+}
+
+type MfaCodeModel struct {
+	Code string `json:"code" validate:"required,min=6,max=20"`
+}
+
+type DisableMfaModel struct {
+	Password string `json:"password" validate:"required,min=6,max=32"`
+	Code     string `json:"code" validate:"required,min=6,max=20"`
+}
@@ -0,0 +1,158 @@
+package orderstate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrInvalidTransition = errors.New("invalid order status transition")
+	ErrActorNotAllowed   = errors.New("actor is not allowed to make this order status transition")
+)
+
+// Status mirrors the order_status enum of the database
+type Status string
+
+const (
+	StatusPending    Status = "pending"
+	StatusConfirmed  Status = "confirmed"
+	StatusDelivering Status = "delivering"
+	StatusDelivered  Status = "delivered"
+	StatusCompleted  Status = "completed"
+	StatusCancelled  Status = "cancelled"
+	StatusRefunded   Status = "refunded"
+)
+
+// Actor is who moves an order from one status to another
+type Actor string
+
+const (
+	ActorCustomer Actor = "customer"
+	ActorAdmin    Actor = "admin"
+	ActorSystem   Actor = "system"
+)
+
+// Effects are the side effects to apply together with a transition
+type Effects struct {
+	StampConfirmedAt bool
+	StampDeliveredAt bool
+	StampCancelledAt bool
+	StampRefundedAt  bool
+	// CommitReservations keeps the reserved stock sold for good
+	CommitReservations bool
+	// ReleaseReservations puts the reserved stock back on sale
+	ReleaseReservations bool
+	// SettleCashOnDelivery marks a cash on delivery payment as collected
+	SettleCashOnDelivery bool
+}
+
+// Transition is a legal move between two statuses
+type Transition struct {
+	From    Status
+	To      Status
+	Actors  []Actor
+	Effects Effects
+}
+
+var transitions = []Transition{
+	{
+		From:    StatusPending,
+		To:      StatusConfirmed,
+		Actors:  []Actor{ActorAdmin, ActorSystem},
+		Effects: Effects{StampConfirmedAt: true, CommitReservations: true},
+	},
+	{
+		From:    StatusPending,
+		To:      StatusCancelled,
+		Actors:  []Actor{ActorCustomer, ActorAdmin, ActorSystem},
+		Effects: Effects{StampCancelledAt: true, ReleaseReservations: true},
+	},
+	{
+		// until it ships the store can still call off a confirmed order, a payment that
+		// already went through has to be refunded first
+		From:    StatusConfirmed,
+		To:      StatusCancelled,
+		Actors:  []Actor{ActorAdmin, ActorSystem},
+		Effects: Effects{StampCancelledAt: true, ReleaseReservations: true},
+	},
+	{
+		From:   StatusConfirmed,
+		To:     StatusDelivering,
+		Actors: []Actor{ActorAdmin, ActorSystem},
+	},
+	{
+		From:    StatusConfirmed,
+		To:      StatusDelivered,
+		Actors:  []Actor{ActorAdmin, ActorSystem},
+		Effects: Effects{StampDeliveredAt: true, SettleCashOnDelivery: true},
+	},
+	{
+		From:    StatusConfirmed,
+		To:      StatusRefunded,
+		Actors:  []Actor{ActorAdmin, ActorSystem},
+		Effects: Effects{StampRefundedAt: true},
+	},
+	{
+		From:    StatusDelivering,
+		To:      StatusDelivered,
+		Actors:  []Actor{ActorAdmin, ActorSystem},
+		Effects: Effects{StampDeliveredAt: true, SettleCashOnDelivery: true},
+	},
+	{
+		From:   StatusDelivered,
+		To:     StatusCompleted,
+		Actors: []Actor{ActorCustomer, ActorAdmin, ActorSystem},
+	},
+	{
+		From:    StatusDelivered,
+		To:      StatusRefunded,
+		Actors:  []Actor{ActorAdmin, ActorSystem},
+		Effects: Effects{StampRefundedAt: true},
+	},
+	{
+		From:    StatusCompleted,
+		To:      StatusRefunded,
+		Actors:  []Actor{ActorAdmin, ActorSystem},
+		Effects: Effects{StampRefundedAt: true},
+	},
+}
+
+// Get returns the transition between two statuses, or ErrInvalidTransition if the
+// order can't make that move.
+func Get(from, to Status) (Transition, error) {
+	for _, t := range transitions {
+		if t.From == from && t.To == to {
+			return t, nil
+		}
+	}
+	return Transition{}, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, from, to)
+}
+
+// Authorize returns the transition between two statuses if the actor is allowed to make it.
+func Authorize(from, to Status, actor Actor) (Transition, error) {
+	t, err := Get(from, to)
+	if err != nil {
+		return t, err
+	}
+	if !slices.Contains(t.Actors, actor) {
+		return t, fmt.Errorf("%w: %s can't move an order from %s to %s", ErrActorNotAllowed, actor, from, to)
+	}
+	return t, nil
+}
+
+// Next lists the statuses an order can move to from its current status.
+func Next(from Status) []Status {
+	next := make([]Status, 0)
+	for _, t := range transitions {
+		if t.From == from {
+			next = append(next, t.To)
+		}
+	}
+	return next
+}
+
+// IsFinal reports whether no transition leaves the status.
+func IsFinal(status Status) bool {
+	return len(Next(status)) == 0
+}
@@ -0,0 +1,114 @@
+package orderstate_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/thanhphuocnguyen/go-eshop/internal/orderstate"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    orderstate.Status
+		to      orderstate.Status
+		wantErr error
+		want    orderstate.Effects
+	}{
+		{"confirm", orderstate.StatusPending, orderstate.StatusConfirmed, nil, orderstate.Effects{StampConfirmedAt: true, CommitReservations: true}},
+		{"cancel pending", orderstate.StatusPending, orderstate.StatusCancelled, nil, orderstate.Effects{StampCancelledAt: true, ReleaseReservations: true}},
+		{"cancel confirmed", orderstate.StatusConfirmed, orderstate.StatusCancelled, nil, orderstate.Effects{StampCancelledAt: true, ReleaseReservations: true}},
+		{"ship", orderstate.StatusConfirmed, orderstate.StatusDelivering, nil, orderstate.Effects{}},
+		{"deliver confirmed", orderstate.StatusConfirmed, orderstate.StatusDelivered, nil, orderstate.Effects{StampDeliveredAt: true, SettleCashOnDelivery: true}},
+		{"deliver shipped", orderstate.StatusDelivering, orderstate.StatusDelivered, nil, orderstate.Effects{StampDeliveredAt: true, SettleCashOnDelivery: true}},
+		{"complete", orderstate.StatusDelivered, orderstate.StatusCompleted, nil, orderstate.Effects{}},
+		{"refund confirmed", orderstate.StatusConfirmed, orderstate.StatusRefunded, nil, orderstate.Effects{StampRefundedAt: true}},
+		{"refund delivered", orderstate.StatusDelivered, orderstate.StatusRefunded, nil, orderstate.Effects{StampRefundedAt: true}},
+		{"refund completed", orderstate.StatusCompleted, orderstate.StatusRefunded, nil, orderstate.Effects{StampRefundedAt: true}},
+		{"deliver unpaid", orderstate.StatusPending, orderstate.StatusDelivered, orderstate.ErrInvalidTransition, orderstate.Effects{}},
+		{"refund unpaid", orderstate.StatusPending, orderstate.StatusRefunded, orderstate.ErrInvalidTransition, orderstate.Effects{}},
+		{"cancel shipped", orderstate.StatusDelivering, orderstate.StatusCancelled, orderstate.ErrInvalidTransition, orderstate.Effects{}},
+		{"cancel delivered", orderstate.StatusDelivered, orderstate.StatusCancelled, orderstate.ErrInvalidTransition, orderstate.Effects{}},
+		{"reopen cancelled", orderstate.StatusCancelled, orderstate.StatusPending, orderstate.ErrInvalidTransition, orderstate.Effects{}},
+		{"reopen refunded", orderstate.StatusRefunded, orderstate.StatusConfirmed, orderstate.ErrInvalidTransition, orderstate.Effects{}},
+		{"back to confirmed", orderstate.StatusDelivering, orderstate.StatusConfirmed, orderstate.ErrInvalidTransition, orderstate.Effects{}},
+		{"same status", orderstate.StatusConfirmed, orderstate.StatusConfirmed, orderstate.ErrInvalidTransition, orderstate.Effects{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transition, err := orderstate.Get(tt.from, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get(%s, %s) err = %v, want %v", tt.from, tt.to, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if transition.From != tt.from || transition.To != tt.to {
+				t.Errorf("transition = %s to %s, want %s to %s", transition.From, transition.To, tt.from, tt.to)
+			}
+			if transition.Effects != tt.want {
+				t.Errorf("effects = %+v, want %+v", transition.Effects, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    orderstate.Status
+		to      orderstate.Status
+		actor   orderstate.Actor
+		wantErr error
+	}{
+		{"customer cancels pending", orderstate.StatusPending, orderstate.StatusCancelled, orderstate.ActorCustomer, nil},
+		{"system cancels pending", orderstate.StatusPending, orderstate.StatusCancelled, orderstate.ActorSystem, nil},
+		{"customer confirms", orderstate.StatusPending, orderstate.StatusConfirmed, orderstate.ActorCustomer, orderstate.ErrActorNotAllowed},
+		{"system confirms", orderstate.StatusPending, orderstate.StatusConfirmed, orderstate.ActorSystem, nil},
+		{"admin cancels confirmed", orderstate.StatusConfirmed, orderstate.StatusCancelled, orderstate.ActorAdmin, nil},
+		{"system cancels confirmed", orderstate.StatusConfirmed, orderstate.StatusCancelled, orderstate.ActorSystem, nil},
+		{"customer cancels confirmed", orderstate.StatusConfirmed, orderstate.StatusCancelled, orderstate.ActorCustomer, orderstate.ErrActorNotAllowed},
+		{"customer ships", orderstate.StatusConfirmed, orderstate.StatusDelivering, orderstate.ActorCustomer, orderstate.ErrActorNotAllowed},
+		{"admin ships", orderstate.StatusConfirmed, orderstate.StatusDelivering, orderstate.ActorAdmin, nil},
+		{"customer completes", orderstate.StatusDelivered, orderstate.StatusCompleted, orderstate.ActorCustomer, nil},
+		{"customer refunds", orderstate.StatusDelivered, orderstate.StatusRefunded, orderstate.ActorCustomer, orderstate.ErrActorNotAllowed},
+		{"admin refunds", orderstate.StatusCompleted, orderstate.StatusRefunded, orderstate.ActorAdmin, nil},
+		{"unknown actor", orderstate.StatusPending, orderstate.StatusCancelled, orderstate.Actor("guest"), orderstate.ErrActorNotAllowed},
+		{"illegal move checked first", orderstate.StatusCancelled, orderstate.StatusConfirmed, orderstate.ActorAdmin, orderstate.ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := orderstate.Authorize(tt.from, tt.to, tt.actor)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authorize(%s, %s, %s) err = %v, want %v", tt.from, tt.to, tt.actor, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		from      orderstate.Status
+		want      []orderstate.Status
+		wantFinal bool
+	}{
+		{orderstate.StatusPending, []orderstate.Status{orderstate.StatusConfirmed, orderstate.StatusCancelled}, false},
+		{orderstate.StatusConfirmed, []orderstate.Status{orderstate.StatusCancelled, orderstate.StatusDelivering, orderstate.StatusDelivered, orderstate.StatusRefunded}, false},
+		{orderstate.StatusDelivering, []orderstate.Status{orderstate.StatusDelivered}, false},
+		{orderstate.StatusDelivered, []orderstate.Status{orderstate.StatusCompleted, orderstate.StatusRefunded}, false},
+		{orderstate.StatusCompleted, []orderstate.Status{orderstate.StatusRefunded}, false},
+		{orderstate.StatusCancelled, []orderstate.Status{}, true},
+		{orderstate.StatusRefunded, []orderstate.Status{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from), func(t *testing.T) {
+			if got := orderstate.Next(tt.from); !slices.Equal(got, tt.want) {
+				t.Errorf("Next(%s) = %v, want %v", tt.from, got, tt.want)
+			}
+			if got := orderstate.IsFinal(tt.from); got != tt.wantFinal {
+				t.Errorf("IsFinal(%s) = %v, want %v", tt.from, got, tt.wantFinal)
+			}
+		})
+	}
+}
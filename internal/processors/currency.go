@@ -0,0 +1,159 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+var ErrCurrencyNotSupported = errors.New("currency is not supported")
+
+// MaxCurrencyDecimals is the scale of the money columns, DECIMAL(10, 2). Currencies with
+// finer minor units like KWD or BHD would be rounded by the database, so they are refused.
+const MaxCurrencyDecimals = 2
+
+// CheckCurrencyPrecision returns ErrCurrencyNotSupported for a currency whose amounts the
+// database can't keep exactly
+func CheckCurrencyPrecision(currency payment.Currency) error {
+	if decimals := currency.Decimals(); decimals > MaxCurrencyDecimals {
+		return fmt.Errorf("%w: %s has %d decimals, amounts are stored with %d", ErrCurrencyNotSupported, currency, decimals, MaxCurrencyDecimals)
+	}
+	return nil
+}
+
+// CurrencyProcessor converts prices kept in the store currency into the currencies customers pay in
+type CurrencyProcessor struct {
+	repo          repository.Store
+	storeCurrency payment.Currency
+}
+
+func NewCurrencyProcessor(repo repository.Store, storeCurrency string) (*CurrencyProcessor, error) {
+	currency := payment.NormalizeCurrency(storeCurrency)
+	if err := CheckCurrencyPrecision(currency); err != nil {
+		return nil, fmt.Errorf("store currency: %w", err)
+	}
+	return &CurrencyProcessor{
+		repo:          repo,
+		storeCurrency: currency,
+	}, nil
+}
+
+// CurrencyConverter converts store currency amounts with the exchange rate of a currency
+type CurrencyConverter struct {
+	Currency payment.Currency `json:"currency"`
+	// Rate is how many units of Currency one unit of the store currency buys
+	Rate float64 `json:"rate"`
+	// Increment is what converted amounts are rounded to, never finer than the minor unit
+	Increment float64 `json:"increment"`
+	store     payment.Currency
+	rate      *big.Rat
+	// increment in minor units of Currency
+	step int64
+}
+
+// ------------------------------ Currency Processing Methods ------------------------------
+
+// StoreCurrency returns the currency product prices are kept in
+func (cp *CurrencyProcessor) StoreCurrency() payment.Currency {
+	return cp.storeCurrency
+}
+
+// Converter returns the converter of a currency, the store currency when it is empty.
+// Currencies without an active exchange rate, or with more decimals than the database
+// keeps, return ErrCurrencyNotSupported.
+func (cp *CurrencyProcessor) Converter(c context.Context, currency string) (CurrencyConverter, error) {
+	code := payment.NormalizeCurrency(currency)
+	if code == "" || code == cp.storeCurrency {
+		return cp.identity(), nil
+	}
+	if err := CheckCurrencyPrecision(code); err != nil {
+		return CurrencyConverter{}, err
+	}
+
+	rate, err := cp.repo.GetCurrencyRate(c, string(code))
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return CurrencyConverter{}, ErrCurrencyNotSupported
+		}
+		return CurrencyConverter{}, err
+	}
+	if !rate.IsActive {
+		return CurrencyConverter{}, ErrCurrencyNotSupported
+	}
+	return cp.fromRate(rate), nil
+}
+
+// ListConverters returns the store currency followed by every currency with an active rate
+func (cp *CurrencyProcessor) ListConverters(c context.Context) ([]CurrencyConverter, error) {
+	rates, err := cp.repo.ListCurrencyRates(c, &[]bool{true}[0])
+	if err != nil {
+		return nil, err
+	}
+	converters := make([]CurrencyConverter, 0, len(rates)+1)
+	converters = append(converters, cp.identity())
+	for _, rate := range rates {
+		code := payment.NormalizeCurrency(rate.Currency)
+		// rates set before finer currencies were refused can't be paid in
+		if code == cp.storeCurrency || CheckCurrencyPrecision(code) != nil {
+			continue
+		}
+		converters = append(converters, cp.fromRate(rate))
+	}
+	return converters, nil
+}
+
+func (cp *CurrencyProcessor) identity() CurrencyConverter {
+	return CurrencyConverter{
+		Currency:  cp.storeCurrency,
+		Rate:      1,
+		Increment: cp.storeCurrency.FromMinorUnits(1),
+		store:     cp.storeCurrency,
+		rate:      big.NewRat(1, 1),
+		step:      1,
+	}
+}
+
+func (cp *CurrencyProcessor) fromRate(rate repository.CurrencyRate) CurrencyConverter {
+	code := payment.NormalizeCurrency(rate.Currency)
+	value, ok := utils.GetRatFromPgNumeric(rate.Rate)
+	if !ok || value.Sign() <= 0 {
+		value = big.NewRat(1, 1)
+	}
+	step := int64(1)
+	if increment, ok := utils.GetRatFromPgNumeric(rate.RoundingIncrement); ok {
+		step = max(payment.MoneyFromRat(increment, code, payment.RoundHalfUp).Amount, 1)
+	}
+	converter := CurrencyConverter{
+		Currency:  code,
+		Increment: code.FromMinorUnits(step),
+		store:     cp.storeCurrency,
+		rate:      value,
+		step:      step,
+	}
+	converter.Rate, _ = value.Float64()
+	return converter
+}
+
+// Convert converts a store currency amount and rounds it half up to the increment of the currency
+func (cc CurrencyConverter) Convert(amount payment.Money) payment.Money {
+	if amount.Currency != cc.store {
+		panic(fmt.Sprintf("processors: converting %s with a converter from %s", amount.Currency, cc.store))
+	}
+	// count the increments in the converted amount so it is rounded only once
+	steps := new(big.Rat).Mul(amount.Rat(), cc.rate)
+	steps.Quo(steps, big.NewRat(cc.step, 1))
+	return payment.MoneyFromRat(steps, cc.Currency, payment.RoundHalfUp).Mul(cc.step)
+}
+
+// ToStore converts an amount in the currency back to the store currency
+func (cc CurrencyConverter) ToStore(amount payment.Money) payment.Money {
+	if amount.Currency == cc.store {
+		return amount
+	}
+	return payment.MoneyFromRat(new(big.Rat).Quo(amount.Rat(), cc.rate), cc.store, payment.RoundHalfUp)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,6 +12,8 @@ import (
 	"github.com/thanhphuocnguyen/go-eshop/internal/constants"
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
 	"github.com/thanhphuocnguyen/go-eshop/internal/models"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
 )
 
 // DiscountProcessor handles discount validation and calculation
@@ -28,19 +31,21 @@ func NewDiscountProcessor(repo repository.Store) *DiscountProcessor {
 type DiscountContext struct {
 	User      repository.GetUserDetailsByIDRow `json:"user"`
 	CartItems []repository.GetCartItemsRow     `json:"cartItems"`
+	// Currency is the store currency the cart prices are in
+	Currency payment.Currency `json:"currency"`
 }
 
 // ItemDiscount represents discount applied to a specific item
 type ItemDiscount struct {
-	ItemIndex      int       `json:"itemIndex"`
-	DiscountAmount float64   `json:"discountAmount"`
-	DiscountID     uuid.UUID `json:"discountId"`
+	ItemIndex      int           `json:"itemIndex"`
+	DiscountAmount payment.Money `json:"discountAmount"`
+	DiscountID     uuid.UUID     `json:"discountId"`
 }
 
 // DiscountResult contains the final discount calculation results
 type DiscountResult struct {
 	ItemDiscounts    []ItemDiscount `json:"itemDiscounts"`
-	TotalDiscount    float64        `json:"totalDiscount"`
+	TotalDiscount    payment.Money  `json:"totalDiscount"`
 	AppliedDiscounts []uuid.UUID    `json:"appliedDiscounts"`
 }
 
@@ -50,7 +55,7 @@ type DiscountResult struct {
 func (dp *DiscountProcessor) ProcessDiscounts(c context.Context, ctx DiscountContext, discountCodes []string) (*DiscountResult, error) {
 	result := &DiscountResult{
 		ItemDiscounts:    []ItemDiscount{},
-		TotalDiscount:    0,
+		TotalDiscount:    payment.NewMoney(0, ctx.Currency),
 		AppliedDiscounts: []uuid.UUID{},
 	}
 
@@ -87,7 +92,7 @@ func (dp *DiscountProcessor) ProcessDiscounts(c context.Context, ctx DiscountCon
 
 	// Calculate total discount
 	for _, itemDiscount := range result.ItemDiscounts {
-		result.TotalDiscount += itemDiscount.DiscountAmount
+		result.TotalDiscount = result.TotalDiscount.Add(itemDiscount.DiscountAmount)
 	}
 
 	return result, nil
@@ -153,18 +158,28 @@ func (dp *DiscountProcessor) processDiscountForItems(c context.Context, ctx Disc
 	}
 
 	var itemDiscounts []ItemDiscount
-	discountVal, _ := discount.DiscountValue.Float64Value()
+	discountVal, ok := utils.GetRatFromPgNumeric(discount.DiscountValue)
+	if !ok {
+		return itemDiscounts, nil
+	}
 
+	var indexes []int
+	var lineTotals []payment.Money
 	for i, item := range ctx.CartItems {
 		if dp.isDiscountApplicableToItem(item, ctx.User, ruleRows) {
-			discountAmount := dp.calculateItemDiscount(item, discount.DiscountType, discountVal.Float64)
-			if discountAmount > 0 {
-				itemDiscounts = append(itemDiscounts, ItemDiscount{
-					ItemIndex:      i,
-					DiscountAmount: discountAmount,
-					DiscountID:     discount.ID,
-				})
-			}
+			price := utils.GetMoneyFromPgNumeric(item.VariantPrice, ctx.Currency)
+			indexes = append(indexes, i)
+			lineTotals = append(lineTotals, price.Mul(int64(item.CartItem.Quantity)))
+		}
+	}
+
+	for j, discountAmount := range dp.calculateItemDiscounts(lineTotals, discount.DiscountType, discountVal, ctx.Currency) {
+		if discountAmount.IsPositive() {
+			itemDiscounts = append(itemDiscounts, ItemDiscount{
+				ItemIndex:      indexes[j],
+				DiscountAmount: discountAmount,
+				DiscountID:     discount.ID,
+			})
 		}
 	}
 
@@ -363,17 +378,29 @@ func (dp *DiscountProcessor) determineCustomerType(user repository.GetUserDetail
 	}
 }
 
-// calculateItemDiscount calculates the discount amount for a specific item
-func (dp *DiscountProcessor) calculateItemDiscount(item repository.GetCartItemsRow, discountType repository.DiscountType, discountValue float64) float64 {
-	itemPrice, _ := item.VariantPrice.Float64Value()
-	lineTotal := float64(item.CartItem.Quantity) * itemPrice.Float64
+// calculateItemDiscounts calculates the discount of every eligible line. A fixed amount is an
+// order level discount, it is spread over the lines in proportion to their totals.
+func (dp *DiscountProcessor) calculateItemDiscounts(lineTotals []payment.Money, discountType repository.DiscountType, discountValue *big.Rat, currency payment.Currency) []payment.Money {
+	amounts := make([]payment.Money, len(lineTotals))
+	for i := range amounts {
+		amounts[i] = payment.NewMoney(0, currency)
+	}
 
 	switch discountType {
 	case repository.DiscountTypeFixedAmount:
-		return discountValue
+		subtotal := payment.NewMoney(0, currency)
+		weights := make([]int64, len(lineTotals))
+		for i, lineTotal := range lineTotals {
+			subtotal = subtotal.Add(lineTotal)
+			weights[i] = lineTotal.Amount
+		}
+		amount := payment.MoneyFromRat(discountValue, currency, payment.RoundHalfUp).Min(subtotal)
+		return amount.Allocate(weights)
 	case repository.DiscountTypePercentage:
-		return lineTotal * (discountValue / 100)
-	default:
-		return 0
+		percentage := new(big.Rat).Quo(discountValue, big.NewRat(100, 1))
+		for i, lineTotal := range lineTotals {
+			amounts[i] = lineTotal.MulRat(percentage, payment.RoundHalfUp)
+		}
 	}
+	return amounts
 }
@@ -0,0 +1,271 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/thanhphuocnguyen/go-eshop/internal/constants"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+var (
+	ErrShippingZoneNotFound = errors.New("no shipping zone covers this address")
+	ErrShippingRateNotFound = errors.New("shipping rate is not available for this cart")
+)
+
+// ShippingProcessor resolves shipping zones and prices the rates available for a cart
+type ShippingProcessor struct {
+	repo repository.Store
+}
+
+func NewShippingProcessor(repo repository.Store) *ShippingProcessor {
+	return &ShippingProcessor{
+		repo: repo,
+	}
+}
+
+// ShippingContext contains the data needed to quote shipping for a cart
+type ShippingContext struct {
+	Address   repository.UserAddress       `json:"address"`
+	CartItems []repository.GetCartItemsRow `json:"cartItems"`
+	// Currency is the store currency the cart prices and shipping rates are in
+	Currency payment.Currency `json:"currency"`
+}
+
+// ShippingQuote is a priced shipping option for a cart
+type ShippingQuote struct {
+	RateID                uuid.UUID `json:"rateId"`
+	RateName              string    `json:"rateName"`
+	MethodID              uuid.UUID `json:"methodId"`
+	MethodName            string    `json:"methodName"`
+	ZoneID                uuid.UUID `json:"zoneId"`
+	ZoneName              string    `json:"zoneName"`
+	EstimatedDeliveryTime *string   `json:"estimatedDeliveryTime,omitempty"`
+	BaseRate              float64   `json:"baseRate"`
+	AdditionalFee         float64   `json:"additionalFee"`
+	Price                 float64   `json:"price"`
+	FreeShipping          bool      `json:"freeShipping"`
+	// Fee is the exact amount of Price, the one checkout charges
+	Fee payment.Money `json:"-"`
+}
+
+// ------------------------------ Shipping Processing Methods ------------------------------
+
+// QuoteRates returns every rate of the address zone that the cart qualifies for, cheapest first
+func (sp *ShippingProcessor) QuoteRates(c context.Context, ctx ShippingContext) ([]ShippingQuote, error) {
+	zone, err := sp.resolveZone(c, ctx.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := sp.repo.GetShippingRatesByZone(c, zone.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipping rates: %w", err)
+	}
+	if len(rates) == 0 {
+		return []ShippingQuote{}, nil
+	}
+
+	methods, err := sp.repo.GetShippingMethods(c, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipping methods: %w", err)
+	}
+	methodByID := make(map[uuid.UUID]repository.ShippingMethod, len(methods))
+	for _, method := range methods {
+		methodByID[method.ID] = method
+	}
+
+	rateIDs := make([]uuid.UUID, len(rates))
+	for i, rate := range rates {
+		rateIDs[i] = rate.ID
+	}
+	conditions, err := sp.repo.GetShippingRateConditionsByRateIDs(c, rateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipping rate conditions: %w", err)
+	}
+	conditionsByRate := make(map[uuid.UUID][]repository.ShippingRateCondition)
+	for _, condition := range conditions {
+		conditionsByRate[condition.ShippingRateID] = append(conditionsByRate[condition.ShippingRateID], condition)
+	}
+
+	orderValue, weight := sp.cartTotals(ctx.CartItems, ctx.Currency)
+	value := orderValue.Rat()
+
+	quotes := make([]ShippingQuote, 0, len(rates))
+	for _, rate := range rates {
+		method, ok := methodByID[rate.ShippingMethodID]
+		if !ok {
+			// inactive method
+			continue
+		}
+		if !inRange(value, rate.MinOrderAmount, rate.MaxOrderAmount) {
+			continue
+		}
+
+		baseRate := utils.GetMoneyFromPgNumeric(rate.BaseRate, ctx.Currency)
+		additionalFee := payment.NewMoney(0, ctx.Currency)
+		for _, condition := range conditionsByRate[rate.ID] {
+			if sp.isConditionMet(condition, ctx.CartItems, value, weight) {
+				additionalFee = additionalFee.Add(utils.GetMoneyFromPgNumeric(condition.AdditionalFee, ctx.Currency))
+			}
+		}
+
+		quote := ShippingQuote{
+			RateID:                rate.ID,
+			RateName:              rate.Name,
+			MethodID:              rate.ShippingMethodID,
+			MethodName:            rate.MethodName,
+			ZoneID:                zone.ID,
+			ZoneName:              zone.Name,
+			EstimatedDeliveryTime: method.EstimatedDeliveryTime,
+			BaseRate:              baseRate.Float64(),
+			AdditionalFee:         additionalFee.Float64(),
+			Fee:                   payment.NewMoney(0, ctx.Currency),
+		}
+
+		if threshold, ok := utils.GetRatFromPgNumeric(rate.FreeShippingThreshold); ok && value.Cmp(threshold) >= 0 {
+			quote.FreeShipping = true
+		} else {
+			quote.Fee = baseRate.Add(additionalFee)
+		}
+		quote.Price = quote.Fee.Float64()
+
+		quotes = append(quotes, quote)
+	}
+
+	slices.SortStableFunc(quotes, func(a, b ShippingQuote) int {
+		return a.Fee.Cmp(b.Fee)
+	})
+
+	return quotes, nil
+}
+
+// QuoteRate prices a single rate, failing if the cart doesn't qualify for it
+func (sp *ShippingProcessor) QuoteRate(c context.Context, ctx ShippingContext, rateID uuid.UUID) (*ShippingQuote, error) {
+	quotes, err := sp.QuoteRates(c, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, quote := range quotes {
+		if quote.RateID == rateID {
+			return &quote, nil
+		}
+	}
+
+	return nil, ErrShippingRateNotFound
+}
+
+// resolveZone picks the most specific active zone matching the address
+func (sp *ShippingProcessor) resolveZone(c context.Context, address repository.UserAddress) (*repository.ShippingZone, error) {
+	if address.Country == nil || *address.Country == "" {
+		return nil, ErrShippingZoneNotFound
+	}
+
+	zones, err := sp.repo.GetShippingZones(c, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shipping zones: %w", err)
+	}
+
+	var matched *repository.ShippingZone
+	bestScore := -1
+	for i, zone := range zones {
+		score, ok := zoneMatchScore(zone, address)
+		if ok && score > bestScore {
+			matched = &zones[i]
+			bestScore = score
+		}
+	}
+
+	if matched == nil {
+		return nil, ErrShippingZoneNotFound
+	}
+
+	return matched, nil
+}
+
+// zoneMatchScore tells whether the zone covers the address and how specific the match is
+func zoneMatchScore(zone repository.ShippingZone, address repository.UserAddress) (int, bool) {
+	if !containsFold(zone.Countries, *address.Country) {
+		return 0, false
+	}
+
+	score := 0
+	if len(zone.States) > 0 {
+		if address.State == nil || !containsFold(zone.States, *address.State) {
+			return 0, false
+		}
+		score++
+	}
+	if len(zone.ZipCodes) > 0 {
+		if address.ZipCode == nil || !containsFold(zone.ZipCodes, *address.ZipCode) {
+			return 0, false
+		}
+		score += 2
+	}
+
+	return score, true
+}
+
+// isConditionMet checks a rate condition against the cart
+func (sp *ShippingProcessor) isConditionMet(condition repository.ShippingRateCondition, items []repository.GetCartItemsRow, orderValue, weight *big.Rat) bool {
+	switch constants.ShippingCondition(condition.ConditionType) {
+	case constants.WeightCondition:
+		return inRange(weight, condition.MinValue, condition.MaxValue)
+	case constants.OrderValueCondition:
+		return inRange(orderValue, condition.MinValue, condition.MaxValue)
+	case constants.CategoryCondition:
+		for _, item := range items {
+			for _, categoryID := range item.CategoryIds {
+				if slices.Contains(condition.CategoryIds, categoryID) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// cartTotals returns the order value and the exact total weight of the cart
+func (sp *ShippingProcessor) cartTotals(items []repository.GetCartItemsRow, currency payment.Currency) (payment.Money, *big.Rat) {
+	orderValue := payment.NewMoney(0, currency)
+	weight := new(big.Rat)
+	for _, item := range items {
+		price := utils.GetMoneyFromPgNumeric(item.VariantPrice, currency)
+		orderValue = orderValue.Add(price.Mul(int64(item.CartItem.Quantity)))
+
+		if variantWeight, ok := utils.GetRatFromPgNumeric(item.VariantWeight); ok {
+			quantity := new(big.Rat).SetInt64(int64(item.CartItem.Quantity))
+			weight.Add(weight, new(big.Rat).Mul(variantWeight, quantity))
+		}
+	}
+
+	return orderValue, weight
+}
+
+// inRange tells whether the value lies in the half-open range [lower, upper), so adjacent
+// tiers like [0, 50) and [50, 100) never both match. A NULL bound is open.
+func inRange(value *big.Rat, lower, upper pgtype.Numeric) bool {
+	if minValue, ok := utils.GetRatFromPgNumeric(lower); ok && value.Cmp(minValue) < 0 {
+		return false
+	}
+	if maxValue, ok := utils.GetRatFromPgNumeric(upper); ok && value.Cmp(maxValue) >= 0 {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, target string) bool {
+	return slices.ContainsFunc(values, func(v string) bool {
+		return strings.EqualFold(v, target)
+	})
+}
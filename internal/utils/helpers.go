@@ -29,6 +29,12 @@ func Int32Ptr(value int32) *int32 {
 func Int64Ptr(value int64) *int64 {
 	return &value
 }
+func StringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
 
 func CalculateTotalPages(total int64, pageSize int64) int64 {
 	if total == 0 {
@@ -1,11 +1,14 @@
 package utils
 
 import (
+	"math"
 	"math/big"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
 )
 
 const (
@@ -42,8 +45,53 @@ func GetPgTypeUUID(value [16]byte) pgtype.UUID {
 
 func GetPgNumericFromFloat(value float64) pgtype.Numeric {
 	return pgtype.Numeric{
-		Int:   big.NewInt(int64(value * MUL)),
+		Int:   big.NewInt(int64(math.Round(value * MUL))),
 		Exp:   -EXP,
 		Valid: true,
 	}
 }
+
+// GetPgNumericFromDecimal keeps every digit of the shortest decimal that represents the
+// float, for values like exchange rates that need more than two decimal places
+func GetPgNumericFromDecimal(value float64) pgtype.Numeric {
+	var num pgtype.Numeric
+	if err := num.Scan(strconv.FormatFloat(value, 'f', -1, 64)); err != nil {
+		return pgtype.Numeric{}
+	}
+	return num
+}
+
+// GetPgNumericFromMoney stores an amount with exactly the decimal places of its currency
+func GetPgNumericFromMoney(value payment.Money) pgtype.Numeric {
+	return pgtype.Numeric{
+		Int:   big.NewInt(value.Amount),
+		Exp:   -int32(value.Currency.Decimals()),
+		Valid: true,
+	}
+}
+
+// GetMoneyFromPgNumeric reads a numeric amount in the currency, NULL reads as zero
+func GetMoneyFromPgNumeric(value pgtype.Numeric, currency payment.Currency) payment.Money {
+	amount, ok := GetRatFromPgNumeric(value)
+	if !ok {
+		return payment.NewMoney(0, currency)
+	}
+	return payment.MoneyFromRat(amount, currency, payment.RoundHalfUp)
+}
+
+// GetRatFromPgNumeric returns the exact value of a numeric, false when it is NULL or not finite
+func GetRatFromPgNumeric(value pgtype.Numeric) (*big.Rat, bool) {
+	if !value.Valid || value.NaN || value.InfinityModifier != pgtype.Finite || value.Int == nil {
+		return nil, false
+	}
+	rat := new(big.Rat).SetInt(value.Int)
+	exp := value.Exp
+	if exp < 0 {
+		exp = -exp
+	}
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exp)), nil))
+	if value.Exp < 0 {
+		return rat.Quo(rat, scale), true
+	}
+	return rat.Mul(rat, scale), true
+}
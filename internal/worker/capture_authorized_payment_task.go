@@ -0,0 +1,68 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+func (d *RedisTaskDistributor) SendCaptureAuthorizedPayment(ctx context.Context, payload *PayloadCaptureAuthorizedPayment, options ...asynq.Option) error {
+	marshaled, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not marshal payload: %w", err)
+	}
+	task := asynq.NewTask(CaptureAuthorizedPaymentTaskType, marshaled, options...)
+	info, err := d.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("could not enqueue task: %w", err)
+	}
+	log.Info().
+		Str("type", task.Type()).
+		RawJSON("payload", task.Payload()).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("task enqueued")
+	return nil
+}
+
+// ProcessCaptureAuthorizedPayment captures the whole authorization of an order that shipped.
+// Orders paid without an authorization, or already captured, are left alone.
+func (p *RedisTaskProcessor) ProcessCaptureAuthorizedPayment(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadCaptureAuthorizedPayment
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("could not unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	_, err := p.repo.CapturePaymentTx(ctx, repository.CapturePaymentTxArgs{
+		OrderID: payload.OrderID,
+		CapturePaymentFn: func(ctx context.Context, paymentIntentID, method string, amount payment.Money) (string, error) {
+			result, err := p.paymentSrv.CapturePayment(ctx, payment.CaptureRequest{
+				TransactionID: paymentIntentID,
+				Amount:        amount.Amount,
+			}, method)
+			if err != nil {
+				return "", err
+			}
+			if !result.Success {
+				return "", fmt.Errorf("capture was not completed: %s", result.Status)
+			}
+			return result.Metadata["charge_id"], nil
+		},
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidPayment) || errors.Is(err, repository.ErrRecordNotFound) {
+			log.Info().Err(err).Str("order_id", payload.OrderID.String()).Msg("nothing to capture")
+			return nil
+		}
+		return fmt.Errorf("could not capture payment: %w", err)
+	}
+
+	log.Info().Str("order_id", payload.OrderID.String()).Msg("captured authorized payment")
+	return nil
+}
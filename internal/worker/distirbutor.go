@@ -10,6 +10,11 @@ import (
 type TaskDistributor interface {
 	SendOrderCreatedEmailTask(ctx context.Context, payload *PayloadSendOrderCreatedEmailTask, options ...asynq.Option) error
 	SendVerifyAccountEmail(ctx context.Context, payload *PayloadVerifyEmail, options ...asynq.Option) error
+	SendGuestOrderLookupEmail(ctx context.Context, payload *PayloadSendGuestOrderLookupEmail, options ...asynq.Option) error
+	SendReturnRequestStatusEmail(ctx context.Context, payload *PayloadSendReturnRequestStatusEmail, options ...asynq.Option) error
+	SendPasswordResetEmail(ctx context.Context, payload *PayloadSendPasswordResetEmail, options ...asynq.Option) error
+	SendProcessWebhookEvent(ctx context.Context, payload *PayloadProcessWebhookEvent, options ...asynq.Option) error
+	SendCaptureAuthorizedPayment(ctx context.Context, payload *PayloadCaptureAuthorizedPayment, options ...asynq.Option) error
 	Shutdown() error
 }
 
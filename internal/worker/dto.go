@@ -13,6 +13,17 @@ type VerifyEmailData struct {
 	VerifyLink string
 }
 
+type PayloadSendPasswordResetEmail struct {
+	UserID uuid.UUID `json:"userId"`
+}
+
+type PasswordResetEmailData struct {
+	Email     string
+	FullName  string
+	ResetLink string
+	ExpiresIn string
+}
+
 type PayloadSendOrderCreatedEmailTask struct {
 	PaymentID uuid.UUID `json:"paymentId"`
 }
@@ -25,6 +36,44 @@ type OrderCreatedItems struct {
 type OrderCreatedEmailData struct {
 	OrderID  uuid.UUID           `json:"orderId"`
 	Total    float64             `json:"total"`
+	Currency string              `json:"currency"`
 	FullName string              `json:"fullName"`
 	Items    []OrderCreatedItems `json:"items"`
+	// PaymentReference is set for bank transfers that are still to be paid
+	PaymentReference *string `json:"paymentReference,omitempty"`
+	PaymentDueAt     string  `json:"paymentDueAt,omitempty"`
+}
+
+type PayloadSendGuestOrderLookupEmail struct {
+	OrderID uuid.UUID `json:"orderId"`
+}
+
+type GuestOrderLookupEmailData struct {
+	OrderID    uuid.UUID
+	Email      string
+	FullName   string
+	LookupLink string
+}
+
+type PayloadSendReturnRequestStatusEmail struct {
+	ReturnRequestID uuid.UUID `json:"returnRequestId"`
+}
+
+type ReturnRequestStatusEmailData struct {
+	ReturnRequestID uuid.UUID
+	OrderID         uuid.UUID
+	Email           string
+	FullName        string
+	Status          string
+	Note            string
+	RefundAmount    float64
+	Currency        string
+}
+
+type PayloadProcessWebhookEvent struct {
+	WebhookEventID uuid.UUID `json:"webhookEventId"`
+}
+
+type PayloadCaptureAuthorizedPayment struct {
+	OrderID uuid.UUID `json:"orderId"`
 }
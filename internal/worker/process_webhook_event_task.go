@@ -0,0 +1,112 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+func (d *RedisTaskDistributor) SendProcessWebhookEvent(ctx context.Context, payload *PayloadProcessWebhookEvent, options ...asynq.Option) error {
+	marshaled, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not marshal payload: %w", err)
+	}
+	task := asynq.NewTask(ProcessWebhookEventTaskType, marshaled, options...)
+	info, err := d.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("could not enqueue task: %w", err)
+	}
+	log.Info().
+		Str("type", task.Type()).
+		RawJSON("payload", task.Payload()).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("task enqueued")
+	return nil
+}
+
+// ProcessWebhookEvent applies a stored webhook event. Events that were already processed
+// are skipped, so redelivered or replayed events change nothing.
+func (p *RedisTaskProcessor) ProcessWebhookEvent(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadProcessWebhookEvent
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("could not unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	event, err := p.repo.ClaimWebhookEvent(ctx, payload.WebhookEventID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			log.Info().Str("webhook_event_id", payload.WebhookEventID.String()).Msg("webhook event already processed")
+			return nil
+		}
+		return fmt.Errorf("could not claim webhook event: %w", err)
+	}
+
+	// gateways normalize their own payloads, the provider is the gateway the webhook was sent by
+	evt, err := p.paymentSrv.ParseWebhook(event.Payload, event.Provider)
+	if err != nil {
+		err = fmt.Errorf("could not parse webhook event: %v: %w", err, asynq.SkipRetry)
+	} else {
+		err = p.handlePaymentWebhook(ctx, evt, "")
+	}
+	if err != nil {
+		lastError := err.Error()
+		markErr := p.repo.MarkWebhookEventFailed(ctx, repository.MarkWebhookEventFailedParams{
+			ID:        event.ID,
+			LastError: &lastError,
+		})
+		if markErr != nil {
+			log.Error().Err(markErr).Str("webhook_event_id", event.ID.String()).Msg("MarkWebhookEventFailed")
+		}
+		return err
+	}
+
+	if err := p.repo.MarkWebhookEventProcessed(ctx, event.ID); err != nil {
+		return fmt.Errorf("could not mark webhook event processed: %w", err)
+	}
+	return nil
+}
+
+// handlePaymentWebhook applies a normalized gateway event to payments and payment_transactions.
+// The note is kept on the payment transaction when the gateway sent no message of its own.
+func (p *RedisTaskProcessor) handlePaymentWebhook(ctx context.Context, evt *payment.WebhookEvent, note string) error {
+	log.Info().Str("type", string(evt.Type)).Str("gateway_type", evt.GatewayType).Msg("Processing webhook event")
+	if evt.Type == payment.WebhookIgnored {
+		return nil
+	}
+
+	rs, err := p.repo.ApplyPaymentWebhookTx(ctx, repository.ApplyPaymentWebhookTxArgs{
+		Event:            evt,
+		Note:             note,
+		AuthorizationTTL: p.cfg.PaymentAuthorizationTTL,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			// If the payment is not found, we can ignore the event
+			log.Info().Str("transaction_id", evt.TransactionID).Str("charge_id", evt.ChargeID).Msg("Payment not found for webhook event")
+			return nil
+		}
+		return fmt.Errorf("could not apply payment webhook: %w", err)
+	}
+
+	if rs.Confirmed {
+		err := p.distributor.SendOrderCreatedEmailTask(ctx,
+			&PayloadSendOrderCreatedEmailTask{
+				PaymentID: rs.Payment.ID,
+			},
+			asynq.MaxRetry(10),
+			asynq.ProcessIn(time.Second*3),
+			asynq.Queue(QueueDefault))
+		if err != nil {
+			log.Error().Err(err).Msg("SendOrderCreatedEmailTask")
+		}
+	}
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
 	app_logger "github.com/thanhphuocnguyen/go-eshop/pkg/logger"
 	"github.com/thanhphuocnguyen/go-eshop/pkg/mailer"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
 )
 
 const (
@@ -25,14 +26,18 @@ type TaskProcessor interface {
 type RedisTaskProcessor struct {
 	asynqServer *asynq.Server
 	repo        repository.Store
+	distributor TaskDistributor
 	mailer      mailer.EmailSender
+	paymentSrv  *payment.PaymentManager
 	cfg         config.Config
 }
 
 func NewRedisTaskProcessor(
 	redisOtp asynq.RedisClientOpt,
 	postgres repository.Store,
+	distributor TaskDistributor,
 	mailer mailer.EmailSender,
+	paymentSrv *payment.PaymentManager,
 	cfg config.Config,
 ) TaskProcessor {
 	logger := app_logger.NewLogger(nil)
@@ -57,7 +62,7 @@ func NewRedisTaskProcessor(
 				Msg("error processing task")
 		}),
 	})
-	return &RedisTaskProcessor{server, postgres, mailer, cfg}
+	return &RedisTaskProcessor{server, postgres, distributor, mailer, paymentSrv, cfg}
 }
 
 func (p *RedisTaskProcessor) Start() error {
@@ -65,6 +70,14 @@ func (p *RedisTaskProcessor) Start() error {
 	// register task handlers
 	mux.HandleFunc(OrderCreatedEmailTaskType, p.ProcessSendOrderCreatedEmail)
 	mux.HandleFunc(VerifyEmailTaskType, p.ProcessSendVerifyEmail)
+	mux.HandleFunc(GuestOrderLookupEmailTaskType, p.ProcessSendGuestOrderLookupEmail)
+	mux.HandleFunc(ReturnRequestStatusEmailTaskType, p.ProcessSendReturnRequestStatusEmail)
+	mux.HandleFunc(PasswordResetEmailTaskType, p.ProcessSendPasswordResetEmail)
+	mux.HandleFunc(ReleaseExpiredReservationsTaskType, p.ProcessReleaseExpiredReservations)
+	mux.HandleFunc(PurgeGuestCartsTaskType, p.ProcessPurgeGuestCarts)
+	mux.HandleFunc(ReconcilePaymentsTaskType, p.ProcessReconcilePayments)
+	mux.HandleFunc(ProcessWebhookEventTaskType, p.ProcessWebhookEvent)
+	mux.HandleFunc(CaptureAuthorizedPaymentTaskType, p.ProcessCaptureAuthorizedPayment)
 
 	return p.asynqServer.Start(mux)
 }
@@ -0,0 +1,22 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+)
+
+// ProcessPurgeGuestCarts removes guest carts that haven't been touched within GuestCartTTL.
+func (p *RedisTaskProcessor) ProcessPurgeGuestCarts(ctx context.Context, task *asynq.Task) error {
+	cutoff := time.Now().Add(-p.cfg.GuestCartTTL)
+	deleted, err := p.repo.DeleteStaleGuestCarts(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("could not purge guest carts: %w", err)
+	}
+
+	log.Info().Int64("deleted", deleted).Time("cutoff", cutoff).Msg("purged stale guest carts")
+	return nil
+}
@@ -0,0 +1,195 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/orderstate"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+const (
+	ReconciliationUnchanged = "unchanged"
+	ReconciliationRepaired  = "repaired"
+	ReconciliationFailed    = "failed"
+
+	// reconcilePaymentsBatchSize caps the gateway calls of a run, the rest wait for the next one
+	reconcilePaymentsBatchSize = 200
+)
+
+// ProcessReconcilePayments asks the gateways about payments that have been waiting on a
+// webhook for longer than PaymentReconciliationThreshold, and applies what the lost webhooks
+// would have. Every payment checked goes in the run's report. Refunds the gateway never
+// answered for are handed to it again.
+func (p *RedisTaskProcessor) ProcessReconcilePayments(ctx context.Context, task *asynq.Task) error {
+	cutoff := time.Now().Add(-p.cfg.PaymentReconciliationThreshold)
+	p.reconcileRefunds(ctx, cutoff)
+
+	payments, err := p.repo.ListPaymentsToReconcile(ctx, repository.ListPaymentsToReconcileParams{
+		UpdatedBefore: cutoff,
+		Limit:         reconcilePaymentsBatchSize,
+	})
+	if err != nil {
+		return fmt.Errorf("could not list payments to reconcile: %w", err)
+	}
+	if len(payments) == 0 {
+		return nil
+	}
+
+	run, err := p.repo.CreatePaymentReconciliation(ctx)
+	if err != nil {
+		return fmt.Errorf("could not create payment reconciliation: %w", err)
+	}
+
+	var repaired, failed int32
+	for _, pm := range payments {
+		item := p.reconcilePayment(ctx, pm)
+		item.ReconciliationID = run.ID
+		switch item.Action {
+		case ReconciliationRepaired:
+			repaired++
+		case ReconciliationFailed:
+			failed++
+		}
+		if err := p.repo.CreatePaymentReconciliationItem(ctx, item); err != nil {
+			log.Error().Err(err).Str("payment_id", pm.ID.String()).Msg("CreatePaymentReconciliationItem")
+		}
+	}
+
+	_, err = p.repo.FinishPaymentReconciliation(ctx, repository.FinishPaymentReconciliationParams{
+		ID:       run.ID,
+		Checked:  int32(len(payments)),
+		Repaired: repaired,
+		Failed:   failed,
+	})
+	if err != nil {
+		return fmt.Errorf("could not finish payment reconciliation: %w", err)
+	}
+
+	log.Info().
+		Str("reconciliation_id", run.ID.String()).
+		Int("checked", len(payments)).
+		Int32("repaired", repaired).
+		Int32("failed", failed).
+		Msg("reconciled payments")
+	return nil
+}
+
+// reconcilePayment compares a payment with the gateway's record of it. A gateway that settled
+// the payment one way or another is applied as if its webhook had arrived.
+func (p *RedisTaskProcessor) reconcilePayment(ctx context.Context, pm repository.ListPaymentsToReconcileRow) repository.CreatePaymentReconciliationItemParams {
+	item := repository.CreatePaymentReconciliationItemParams{
+		PaymentID:       pm.ID,
+		OrderID:         pm.OrderID,
+		Gateway:         pm.PaymentMethodCode,
+		PaymentIntentID: pm.PaymentIntentID,
+		LocalStatus:     pm.Status,
+		Action:          ReconciliationUnchanged,
+	}
+
+	intent, err := p.paymentSrv.GetPayment(ctx, *pm.PaymentIntentID, pm.PaymentMethodCode)
+	if err != nil {
+		item.Action = ReconciliationFailed
+		item.Message = utils.StringPtr(err.Error())
+		return item
+	}
+	item.GatewayStatus = utils.StringPtr(string(intent.Status))
+
+	var eventType payment.WebhookEventType
+	switch intent.Status {
+	case payment.StatusCompleted:
+		eventType = payment.WebhookPaymentSucceeded
+	case payment.StatusAuthorized:
+		eventType = payment.WebhookPaymentAuthorized
+	case payment.StatusFailed:
+		eventType = payment.WebhookPaymentFailed
+	case payment.StatusCancelled:
+		eventType = payment.WebhookPaymentCancelled
+	default:
+		item.Message = utils.StringPtr("still waiting on the customer at the gateway")
+		return item
+	}
+
+	evt := &payment.WebhookEvent{
+		Type:          eventType,
+		GatewayType:   "reconciliation",
+		TransactionID: *pm.PaymentIntentID,
+		Amount:        intent.Amount,
+		Currency:      intent.Currency,
+		CreatedAt:     time.Now(),
+	}
+	if err := p.handlePaymentWebhook(ctx, evt, "reconciled against the gateway"); err != nil {
+		item.Action = ReconciliationFailed
+		item.Message = utils.StringPtr(err.Error())
+		return item
+	}
+	item.Action = ReconciliationRepaired
+	item.Message = utils.StringPtr(fmt.Sprintf("applied missing %s event", eventType))
+	return item
+}
+
+// reconcileRefunds settles refunds left pending without a gateway refund ID, e.g. when the
+// process stopped between committing a refund and hearing back from the gateway. The refund
+// ID is the idempotency key, so a gateway that already took the refund returns it again
+// instead of refunding twice.
+func (p *RedisTaskProcessor) reconcileRefunds(ctx context.Context, cutoff time.Time) {
+	refunds, err := p.repo.ListRefundsToReconcile(ctx, repository.ListRefundsToReconcileParams{
+		UpdatedBefore: cutoff,
+		Limit:         reconcilePaymentsBatchSize,
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("ListRefundsToReconcile")
+		return
+	}
+
+	note := "reconciled against the gateway"
+	for _, rf := range refunds {
+		// the same reason as the first call, stripe rejects a reused key with other parameters
+		reason := ""
+		if rf.Reason != nil {
+			reason = *rf.Reason
+		}
+		if rf.FromReturnRequest {
+			reason = "requested_by_customer"
+		}
+		amount := utils.GetMoneyFromPgNumeric(rf.Amount, payment.NormalizeCurrency(rf.Currency))
+
+		settle := repository.SettleRefundTxArgs{
+			RefundID: rf.ID,
+			Status:   repository.RefundStatusSucceeded,
+			OrderActorArgs: repository.OrderActorArgs{
+				Actor:  orderstate.ActorSystem,
+				Reason: &note,
+			},
+		}
+		rs, err := p.paymentSrv.RefundPayment(ctx, payment.RefundRequest{
+			TransactionID:  *rf.PaymentIntentID,
+			Amount:         amount.Amount,
+			Reason:         reason,
+			IdempotencyKey: rf.ID.String(),
+		}, rf.PaymentMethodCode)
+		switch {
+		case err != nil:
+			log.Error().Err(err).Str("refund_id", rf.ID.String()).Msg("RefundPayment")
+			settle.Status = repository.RefundStatusFailed
+		case rs.Status == payment.StatusFailed || rs.Status == payment.StatusCancelled:
+			settle.Status = repository.RefundStatusFailed
+		case rs.Status != payment.StatusCompleted:
+			settle.Status = repository.RefundStatusPending
+		}
+		if err == nil && rs.RefundID != "" {
+			settle.GatewayRefundID = &rs.RefundID
+		}
+
+		if _, err := p.repo.SettleRefundTx(ctx, settle); err != nil {
+			log.Error().Err(err).Str("refund_id", rf.ID.String()).Msg("SettleRefundTx")
+			continue
+		}
+		log.Info().Str("refund_id", rf.ID.String()).Str("status", settle.Status).Msg("reconciled refund")
+	}
+}
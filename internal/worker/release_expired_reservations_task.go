@@ -0,0 +1,93 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/orderstate"
+)
+
+const releaseExpiredReservationsBatchSize = 100
+
+func (p *RedisTaskProcessor) ProcessReleaseExpiredReservations(ctx context.Context, task *asynq.Task) error {
+	orderIDs, err := p.repo.GetExpiredReservationOrderIDs(ctx, releaseExpiredReservationsBatchSize)
+	if err != nil {
+		return fmt.Errorf("could not get expired reservations: %w", err)
+	}
+
+	for _, orderID := range orderIDs {
+		if err := p.releaseExpiredOrder(ctx, orderID); err != nil {
+			log.Error().Err(err).Str("order_id", orderID.String()).Msg("could not release expired reservations")
+		}
+	}
+
+	return nil
+}
+
+func (p *RedisTaskProcessor) releaseExpiredOrder(ctx context.Context, orderID uuid.UUID) error {
+	order, err := p.repo.GetOrder(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("could not get order: %w", err)
+	}
+
+	// the order moved on while the reservation was waiting, keep the stock
+	if order.Status != repository.OrderStatusPending {
+		return p.repo.CommitOrderReservations(ctx, orderID)
+	}
+
+	payment, err := p.repo.GetPaymentByOrderID(ctx, orderID)
+	if err != nil && !errors.Is(err, repository.ErrRecordNotFound) {
+		return fmt.Errorf("could not get payment: %w", err)
+	}
+	// keep the stock of a payment that is being settled, e.g. a bank transfer that arrived in part,
+	// or whose money is held until the order ships
+	if err == nil && (payment.Status == repository.PaymentStatusSuccess ||
+		payment.Status == repository.PaymentStatusProcessing ||
+		payment.Status == repository.PaymentStatusAuthorized) {
+		return p.repo.CommitOrderReservations(ctx, orderID)
+	}
+
+	reason := "inventory reservation expired before payment"
+	if err == nil {
+		method, err := p.repo.GetPaymentMethodByID(ctx, payment.PaymentMethodID)
+		if err != nil {
+			return fmt.Errorf("could not get payment method: %w", err)
+		}
+		if repository.IsOfflinePaymentMethod(method) {
+			_, err = p.repo.ExpireOfflinePaymentTx(ctx, repository.ExpireOfflinePaymentTxArgs{
+				OrderID: orderID,
+				OrderActorArgs: repository.OrderActorArgs{
+					Actor:  orderstate.ActorSystem,
+					Reason: &reason,
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("could not expire offline payment: %w", err)
+			}
+			log.Info().Str("order_id", orderID.String()).Msg("expired offline payment")
+			return nil
+		}
+	}
+
+	_, err = p.repo.CancelOrderTx(ctx, repository.CancelOrderTxArgs{
+		OrderID: orderID,
+		OrderActorArgs: repository.OrderActorArgs{
+			Actor:  orderstate.ActorSystem,
+			Reason: &reason,
+		},
+		CancelPaymentFromMethod: func(intentID string, method string) error {
+			return p.paymentSrv.CancelPayment(ctx, intentID, method)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("could not cancel order: %w", err)
+	}
+
+	log.Info().Str("order_id", orderID.String()).Msg("released expired reservations")
+	return nil
+}
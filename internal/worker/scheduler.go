@@ -1,38 +1,38 @@
 package worker
 
 import (
+	"fmt"
 	"time"
+	// named time zones work on hosts without a zoneinfo database
+	_ "time/tzdata"
 
 	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
 	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
 	app_logger "github.com/thanhphuocnguyen/go-eshop/pkg/logger"
 )
 
+const (
+	ReleaseExpiredReservationsInterval = "@every 1m"
+	PurgeGuestCartsInterval            = "@every 1h"
+	ReconcilePaymentsInterval          = "@every 15m"
+)
+
 type RedisTaskScheduler struct {
 	scheduler *asynq.Scheduler
 	repo      repository.Store
 }
 
-// SendEmailConfirmation implements TaskScheduler.
-func (r *RedisTaskScheduler) SendEmailConfirmation() error {
-	panic("unimplemented")
-}
-
-// SendOrderCreatedEmail implements TaskScheduler.
-func (r *RedisTaskScheduler) SendOrderCreatedEmail() error {
-	panic("unimplemented")
-}
-
 type TaskScheduler interface {
-	SendEmailConfirmation() error
-	SendOrderCreatedEmail() error
+	Start() error
+	Shutdown()
 }
 
-func NewRedisTaskScheduler(redisOtp asynq.RedisClientOpt, postgres repository.Store) TaskScheduler {
-	// Example of using America/Los_Angeles timezone instead of the default UTC timezone.
-	loc, err := time.LoadLocation("Vietnam/Ho_Chi_Minh")
+// NewRedisTaskScheduler schedules the periodic tasks in the given IANA time zone.
+func NewRedisTaskScheduler(redisOtp asynq.RedisClientOpt, postgres repository.Store, timezone string) (TaskScheduler, error) {
+	loc, err := time.LoadLocation(timezone)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("invalid scheduler time zone %q: %w", timezone, err)
 	}
 	scheduler := asynq.NewScheduler(
 		redisOtp,
@@ -42,5 +42,50 @@ func NewRedisTaskScheduler(redisOtp asynq.RedisClientOpt, postgres repository.St
 		},
 	)
 
-	return &RedisTaskScheduler{scheduler, postgres}
+	return &RedisTaskScheduler{scheduler, postgres}, nil
+}
+
+// Start registers the periodic tasks and starts the scheduler.
+func (s *RedisTaskScheduler) Start() error {
+	entryID, err := s.scheduler.Register(
+		ReleaseExpiredReservationsInterval,
+		asynq.NewTask(ReleaseExpiredReservationsTaskType, nil),
+		asynq.Queue(QueueCritical),
+		asynq.MaxRetry(0),
+		asynq.Unique(time.Minute),
+	)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("entry_id", entryID).Msg("registered release expired reservations task")
+
+	entryID, err = s.scheduler.Register(
+		PurgeGuestCartsInterval,
+		asynq.NewTask(PurgeGuestCartsTaskType, nil),
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(0),
+		asynq.Unique(time.Hour),
+	)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("entry_id", entryID).Msg("registered purge guest carts task")
+
+	entryID, err = s.scheduler.Register(
+		ReconcilePaymentsInterval,
+		asynq.NewTask(ReconcilePaymentsTaskType, nil),
+		asynq.Queue(QueueLow),
+		asynq.MaxRetry(0),
+		asynq.Unique(15*time.Minute),
+	)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("entry_id", entryID).Msg("registered reconcile payments task")
+
+	return s.scheduler.Start()
+}
+
+func (s *RedisTaskScheduler) Shutdown() {
+	s.scheduler.Shutdown()
 }
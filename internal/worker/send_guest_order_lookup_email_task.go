@@ -0,0 +1,74 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/auth"
+)
+
+func (d *RedisTaskDistributor) SendGuestOrderLookupEmail(ctx context.Context, payload *PayloadSendGuestOrderLookupEmail, options ...asynq.Option) error {
+	marshaled, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not marshal payload: %w", err)
+	}
+	task := asynq.NewTask(GuestOrderLookupEmailTaskType, marshaled, options...)
+	info, err := d.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("could not enqueue task: %w", err)
+	}
+	log.Info().
+		Str("type", task.Type()).
+		RawJSON("payload", task.Payload()).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("task enqueued")
+	return nil
+}
+
+// ProcessSendGuestOrderLookupEmail mails a guest buyer the link to look up their order.
+func (p *RedisTaskProcessor) ProcessSendGuestOrderLookupEmail(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendGuestOrderLookupEmail
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("could not unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	order, err := p.repo.GetOrder(ctx, payload.OrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return fmt.Errorf("could not find order: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("could not get order: %w", err)
+	}
+
+	token := auth.GenerateOrderLookupToken(p.cfg.SymmetricKey, order.ID, time.Now().Add(p.cfg.OrderLookupTokenTTL))
+	lookupLink := fmt.Sprintf("http://%s:%s/api/v1/order-lookup?token=%s", p.cfg.Domain, p.cfg.Port, token)
+	emailData := GuestOrderLookupEmailData{
+		OrderID:    order.ID,
+		Email:      order.CustomerEmail,
+		FullName:   order.CustomerName,
+		LookupLink: lookupLink,
+	}
+
+	body, err := utils.ParseHtmlTemplate("./static/templates/guest-order-lookup.html", emailData)
+	if err != nil {
+		log.Err(err).Msg("could not parse html template")
+	}
+
+	err = p.mailer.Send("Your Order #"+order.ID.String(), body, []string{order.CustomerEmail}, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("could not send email: %w", err)
+	}
+
+	if p.cfg.Env == "development" {
+		log.Info().Str("email", order.CustomerEmail).Msgf("sent order lookup email with link: %s", lookupLink)
+	}
+	return nil
+}
@@ -69,21 +69,21 @@ func (p *RedisTaskProcessor) ProcessSendOrderCreatedEmail(ctx context.Context, t
 		}
 	}
 
-	user, err := p.repo.GetUserByID(ctx, order.UserID)
-	if err != nil {
-		if errors.Is(err, repository.ErrRecordNotFound) {
-			return fmt.Errorf("could not find user: %w", asynq.SkipRetry)
-		}
-		return fmt.Errorf("could not get user: %w", asynq.SkipRetry)
-	}
-
 	price, _ := payment.Amount.Float64Value()
 	emailData := OrderCreatedEmailData{
 		OrderID:  order.ID,
 		Total:    price.Float64,
-		FullName: user.FirstName + " " + user.LastName,
+		Currency: order.Currency,
+		FullName: order.CustomerName,
 		Items:    items,
 	}
+	if payment.Status == repository.PaymentStatusPending && payment.GatewayReference != nil {
+		emailData.PaymentReference = payment.GatewayReference
+		// the order is cancelled once its reservation runs out
+		if expiresAt, err := p.repo.GetOrderReservationExpiry(ctx, order.ID); err == nil && expiresAt.Valid {
+			emailData.PaymentDueAt = expiresAt.Time.UTC().Format("January 2, 2006 15:04 MST")
+		}
+	}
 
 	body, err := utils.ParseHtmlTemplate("./static/templates/order-created.html", emailData)
 
@@ -91,7 +91,7 @@ func (p *RedisTaskProcessor) ProcessSendOrderCreatedEmail(ctx context.Context, t
 		log.Err(err).Msg("could not parse html template")
 	}
 
-	err = p.mailer.Send("Order Confirmation - #"+order.ID.String(), body, []string{user.Email}, nil, nil, nil)
+	err = p.mailer.Send("Order Confirmation - #"+order.ID.String(), body, []string{order.CustomerEmail}, nil, nil, nil)
 	if err != nil {
 		return fmt.Errorf("could not send email: %w", err)
 	}
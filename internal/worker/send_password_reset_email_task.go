@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/auth"
+)
+
+func (d *RedisTaskDistributor) SendPasswordResetEmail(ctx context.Context, payload *PayloadSendPasswordResetEmail, options ...asynq.Option) error {
+	marshaled, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not marshal payload: %w", err)
+	}
+	task := asynq.NewTask(PasswordResetEmailTaskType, marshaled, options...)
+	info, err := d.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("could not enqueue task: %w", err)
+	}
+	log.Info().
+		Str("type", task.Type()).
+		RawJSON("payload", task.Payload()).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("task enqueued")
+	return nil
+}
+
+// ProcessSendPasswordResetEmail mails a user a single use link to choose a new password.
+// Links mailed before stop working, only the latest one can be used.
+func (p *RedisTaskProcessor) ProcessSendPasswordResetEmail(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendPasswordResetEmail
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("could not unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	user, err := p.repo.GetUserByID(ctx, payload.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return fmt.Errorf("could not find user: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("could not get user: %w", err)
+	}
+
+	token, tokenHash, err := auth.GeneratePasswordResetToken()
+	if err != nil {
+		return fmt.Errorf("could not generate password reset token: %w", err)
+	}
+	if err := p.repo.ExpireUserPasswordResets(ctx, user.ID); err != nil {
+		return fmt.Errorf("could not expire password resets: %w", err)
+	}
+	_, err = p.repo.CreatePasswordReset(ctx, repository.CreatePasswordResetParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(p.cfg.PasswordResetTokenTTL),
+	})
+	if err != nil {
+		return fmt.Errorf("could not create password reset: %w", err)
+	}
+
+	resetLink := p.cfg.PasswordResetURL + "?token=" + url.QueryEscape(token)
+	emailData := PasswordResetEmailData{
+		Email:     user.Email,
+		FullName:  user.FirstName + " " + user.LastName,
+		ResetLink: resetLink,
+		ExpiresIn: humanizeDuration(p.cfg.PasswordResetTokenTTL),
+	}
+
+	body, err := utils.ParseHtmlTemplate("./static/templates/reset-password.html", emailData)
+	if err != nil {
+		log.Err(err).Msg("could not parse html template")
+	}
+
+	err = p.mailer.Send("Reset Your Password", body, []string{user.Email}, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("could not send email: %w", err)
+	}
+
+	if p.cfg.Env == "development" {
+		log.Info().Str("email", user.Email).Msgf("sent password reset email with link: %s", resetLink)
+	}
+	return nil
+}
+
+// humanizeDuration writes a duration the way an email reads, "1 hour" rather than "1h0m0s"
+func humanizeDuration(d time.Duration) string {
+	value, unit := int64(d/time.Minute), "minute"
+	if d >= time.Hour && d%time.Hour == 0 {
+		value, unit = int64(d/time.Hour), "hour"
+	}
+	if value == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", value, unit)
+}
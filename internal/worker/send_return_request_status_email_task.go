@@ -0,0 +1,92 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hibiken/asynq"
+	"github.com/rs/zerolog/log"
+	"github.com/thanhphuocnguyen/go-eshop/internal/db/repository"
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+)
+
+func (d *RedisTaskDistributor) SendReturnRequestStatusEmail(ctx context.Context, payload *PayloadSendReturnRequestStatusEmail, options ...asynq.Option) error {
+	marshaled, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("could not marshal payload: %w", err)
+	}
+	task := asynq.NewTask(ReturnRequestStatusEmailTaskType, marshaled, options...)
+	info, err := d.client.EnqueueContext(ctx, task)
+	if err != nil {
+		return fmt.Errorf("could not enqueue task: %w", err)
+	}
+	log.Info().
+		Str("type", task.Type()).
+		RawJSON("payload", task.Payload()).
+		Str("queue", info.Queue).
+		Int("max_retry", info.MaxRetry).
+		Msg("task enqueued")
+	return nil
+}
+
+// ProcessSendReturnRequestStatusEmail tells the customer a return request was received, approved or rejected.
+func (p *RedisTaskProcessor) ProcessSendReturnRequestStatusEmail(ctx context.Context, task *asynq.Task) error {
+	var payload PayloadSendReturnRequestStatusEmail
+	if err := json.Unmarshal(task.Payload(), &payload); err != nil {
+		return fmt.Errorf("could not unmarshal payload: %w", asynq.SkipRetry)
+	}
+
+	returnRequest, err := p.repo.GetReturnRequestByID(ctx, payload.ReturnRequestID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return fmt.Errorf("could not find return request: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("could not get return request: %w", err)
+	}
+
+	order, err := p.repo.GetOrder(ctx, returnRequest.OrderID)
+	if err != nil {
+		if errors.Is(err, repository.ErrRecordNotFound) {
+			return fmt.Errorf("could not find order: %w", asynq.SkipRetry)
+		}
+		return fmt.Errorf("could not get order: %w", err)
+	}
+
+	emailData := ReturnRequestStatusEmailData{
+		ReturnRequestID: returnRequest.ID,
+		OrderID:         order.ID,
+		Email:           order.CustomerEmail,
+		FullName:        order.CustomerName,
+		Status:          returnRequest.Status,
+		Currency:        order.Currency,
+	}
+	if returnRequest.AdminNote != nil {
+		emailData.Note = *returnRequest.AdminNote
+	}
+	if returnRequest.RefundID.Valid {
+		refunds, err := p.repo.GetRefundsByOrderID(ctx, order.ID)
+		if err != nil {
+			return fmt.Errorf("could not get refunds: %w", err)
+		}
+		for _, refund := range refunds {
+			if refund.ID == returnRequest.RefundID.Bytes {
+				amount, _ := refund.Amount.Float64Value()
+				emailData.RefundAmount = amount.Float64
+			}
+		}
+	}
+
+	body, err := utils.ParseHtmlTemplate("./static/templates/return-request-status.html", emailData)
+	if err != nil {
+		log.Err(err).Msg("could not parse html template")
+	}
+
+	subject := fmt.Sprintf("Your return request for order #%s is %s", order.ID.String(), returnRequest.Status)
+	err = p.mailer.Send(subject, body, []string{order.CustomerEmail}, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("could not send email: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,16 @@
 package worker
 
 const (
-	OrderCreatedEmailTaskType = "order_created_email"
-	VerifyEmailTaskType       = "send_verify_email"
+	OrderCreatedEmailTaskType        = "order_created_email"
+	VerifyEmailTaskType              = "send_verify_email"
+	GuestOrderLookupEmailTaskType    = "send_guest_order_lookup_email"
+	ReturnRequestStatusEmailTaskType = "send_return_request_status_email"
+	PasswordResetEmailTaskType       = "send_password_reset_email"
+
+	ReleaseExpiredReservationsTaskType = "release_expired_reservations"
+	PurgeGuestCartsTaskType            = "purge_guest_carts"
+	ReconcilePaymentsTaskType          = "reconcile_payments"
+
+	ProcessWebhookEventTaskType      = "process_webhook_event"
+	CaptureAuthorizedPaymentTaskType = "capture_authorized_payment"
 )
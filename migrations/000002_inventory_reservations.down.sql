@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_inventory_reservations_status_expires_at;
+DROP TABLE IF EXISTS inventory_reservations;
@@ -0,0 +1,14 @@
+-- Stock held by orders that are waiting for payment
+CREATE TABLE IF NOT EXISTS inventory_reservations (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(), 
+  order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE, 
+  variant_id UUID NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE, 
+  quantity INT NOT NULL CHECK (quantity > 0), 
+  status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'committed', 'released')), 
+  -- 'active' holds stock until expires_at, 'committed' once paid, 'released' once stock is restored
+  expires_at TIMESTAMPTZ NOT NULL, 
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), 
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(), 
+  UNIQUE (order_id, variant_id)
+);
+CREATE INDEX idx_inventory_reservations_status_expires_at ON inventory_reservations(status, expires_at);
@@ -0,0 +1,3 @@
+ALTER TABLE user_addresses DROP COLUMN IF EXISTS zip_code;
+ALTER TABLE user_addresses DROP COLUMN IF EXISTS state;
+ALTER TABLE user_addresses DROP COLUMN IF EXISTS country;
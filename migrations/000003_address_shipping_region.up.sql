@@ -0,0 +1,4 @@
+-- Region fields used to resolve shipping zones
+ALTER TABLE user_addresses ADD COLUMN IF NOT EXISTS country VARCHAR(2);
+ALTER TABLE user_addresses ADD COLUMN IF NOT EXISTS state VARCHAR(100);
+ALTER TABLE user_addresses ADD COLUMN IF NOT EXISTS zip_code VARCHAR(20);
@@ -0,0 +1 @@
+ALTER TABLE shipping_rates ADD CONSTRAINT shipping_rates_shipping_method_id_shipping_zone_id_key UNIQUE (shipping_method_id, shipping_zone_id);
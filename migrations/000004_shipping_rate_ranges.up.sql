@@ -0,0 +1,2 @@
+-- Allow several rates per method and zone, split by order amount range
+ALTER TABLE shipping_rates DROP CONSTRAINT IF EXISTS shipping_rates_shipping_method_id_shipping_zone_id_key;
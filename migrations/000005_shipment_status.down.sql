@@ -0,0 +1 @@
+ALTER TABLE shipments DROP CONSTRAINT IF EXISTS shipments_status_check;
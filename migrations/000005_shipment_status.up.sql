@@ -0,0 +1,2 @@
+-- Shipments move pending -> shipped -> delivered
+ALTER TABLE shipments ADD CONSTRAINT shipments_status_check CHECK (status IN ('pending', 'shipped', 'delivered'));
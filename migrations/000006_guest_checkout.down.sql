@@ -0,0 +1,3 @@
+-- Unclaimed guest orders can't satisfy the constraint anymore
+DELETE FROM orders WHERE user_id IS NULL;
+ALTER TABLE orders ALTER COLUMN user_id SET NOT NULL;
@@ -0,0 +1,2 @@
+-- Guest orders have no user until a registration with the same email claims them
+ALTER TABLE orders ALTER COLUMN user_id DROP NOT NULL;
@@ -0,0 +1,13 @@
+-- Audit trail of every order status transition
+CREATE TABLE order_status_history (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  order_id UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+  from_status order_status,
+  to_status order_status NOT NULL,
+  actor VARCHAR(20) NOT NULL CHECK (actor IN ('customer', 'admin', 'system')),
+  actor_id UUID REFERENCES users (id) ON DELETE SET NULL,
+  reason TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_order_status_history_order_id ON order_status_history (order_id, created_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_refund_items_refund_id;
+DROP INDEX IF EXISTS idx_refunds_order_id;
+DROP INDEX IF EXISTS idx_refunds_payment_id;
+DROP TABLE IF EXISTS refund_items;
+DROP TABLE IF EXISTS refunds;
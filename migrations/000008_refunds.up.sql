@@ -0,0 +1,26 @@
+-- Each refund returns part (or the rest) of a payment, optionally for specific order items
+CREATE TABLE refunds (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  payment_id UUID NOT NULL REFERENCES payments (id) ON DELETE CASCADE,
+  order_id UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+  amount DECIMAL(10, 2) NOT NULL CHECK (amount > 0),
+  reason TEXT,
+  status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+  gateway_refund_id VARCHAR(255),
+  restocked BOOLEAN NOT NULL DEFAULT FALSE,
+  created_by UUID REFERENCES users (id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE refund_items (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  refund_id UUID NOT NULL REFERENCES refunds (id) ON DELETE CASCADE,
+  order_item_id UUID NOT NULL REFERENCES order_items (id) ON DELETE CASCADE,
+  quantity INT NOT NULL CHECK (quantity > 0),
+  amount DECIMAL(10, 2) NOT NULL CHECK (amount >= 0)
+);
+
+CREATE INDEX idx_refunds_payment_id ON refunds (payment_id);
+CREATE INDEX idx_refunds_order_id ON refunds (order_id);
+CREATE INDEX idx_refund_items_refund_id ON refund_items (refund_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS return_request_images;
+DROP TABLE IF EXISTS return_request_items;
+DROP TABLE IF EXISTS return_requests;
@@ -0,0 +1,35 @@
+-- Customers ask to return delivered order items, an admin approves or rejects the request
+CREATE TABLE return_requests (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  order_id UUID NOT NULL REFERENCES orders (id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  reason TEXT NOT NULL,
+  status VARCHAR(20) NOT NULL DEFAULT 'requested' CHECK (status IN ('requested', 'approved', 'rejected')),
+  admin_note TEXT,
+  refund_id UUID REFERENCES refunds (id) ON DELETE SET NULL,
+  reviewed_by UUID REFERENCES users (id) ON DELETE SET NULL,
+  reviewed_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE return_request_items (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  return_request_id UUID NOT NULL REFERENCES return_requests (id) ON DELETE CASCADE,
+  order_item_id UUID NOT NULL REFERENCES order_items (id) ON DELETE CASCADE,
+  quantity INT NOT NULL CHECK (quantity > 0)
+);
+
+CREATE TABLE return_request_images (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  return_request_id UUID NOT NULL REFERENCES return_requests (id) ON DELETE CASCADE,
+  image_id VARCHAR(255) NOT NULL,
+  image_url TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_return_requests_order_id ON return_requests (order_id);
+CREATE INDEX idx_return_requests_user_id ON return_requests (user_id);
+CREATE INDEX idx_return_requests_status ON return_requests (status);
+CREATE INDEX idx_return_request_items_return_request_id ON return_request_items (return_request_id);
+CREATE INDEX idx_return_request_images_return_request_id ON return_request_images (return_request_id);
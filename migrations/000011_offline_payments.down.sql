@@ -0,0 +1 @@
+DROP INDEX IF EXISTS payments_gateway_reference_idx;
@@ -0,0 +1,2 @@
+-- offline payments are looked up by the reference the customer quotes on the transfer
+CREATE UNIQUE INDEX IF NOT EXISTS payments_gateway_reference_idx ON payments (gateway_reference) WHERE gateway_reference IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Exchange rates from the store currency, prices are converted at checkout
+CREATE TABLE currency_rates (
+  currency VARCHAR(3) PRIMARY KEY,
+  -- units of this currency for one unit of the store currency
+  rate DECIMAL(18, 8) NOT NULL CHECK (rate > 0),
+  -- converted prices are rounded to a multiple of this, e.g. 0.05 or 1
+  rounding_increment DECIMAL(10, 4) NOT NULL DEFAULT 0.01 CHECK (rounding_increment > 0),
+  is_active BOOLEAN NOT NULL DEFAULT TRUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- orders and payments keep the currency their amounts are in
+ALTER TABLE orders ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE payments ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD';
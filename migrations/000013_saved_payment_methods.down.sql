@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS payment_customers;
+DROP INDEX IF EXISTS user_payment_infos_default_idx;
+ALTER TABLE user_payment_infos DROP COLUMN IF EXISTS card_brand;
+ALTER TABLE user_payment_infos DROP COLUMN IF EXISTS gateway;
+ALTER TABLE user_payment_infos ALTER COLUMN is_default DROP NOT NULL;
+UPDATE user_payment_infos SET billing_address = '' WHERE billing_address IS NULL;
+ALTER TABLE user_payment_infos ALTER COLUMN billing_address SET NOT NULL;
+ALTER TABLE user_payment_infos ADD COLUMN card_number VARCHAR(19);
@@ -0,0 +1,19 @@
+-- cards are saved with the gateway, only the token and what the user sees are kept here
+ALTER TABLE user_payment_infos DROP COLUMN card_number;
+ALTER TABLE user_payment_infos ALTER COLUMN billing_address DROP NOT NULL;
+UPDATE user_payment_infos SET is_default = FALSE WHERE is_default IS NULL;
+ALTER TABLE user_payment_infos ALTER COLUMN is_default SET NOT NULL;
+ALTER TABLE user_payment_infos ADD COLUMN gateway VARCHAR(50) NOT NULL DEFAULT 'stripe';
+ALTER TABLE user_payment_infos ADD COLUMN card_brand VARCHAR(50);
+
+-- a user has at most one default card
+CREATE UNIQUE INDEX user_payment_infos_default_idx ON user_payment_infos (user_id) WHERE is_default;
+
+-- the customer a user is registered as on each gateway, saved cards are attached to it
+CREATE TABLE payment_customers (
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  gateway VARCHAR(50) NOT NULL,
+  customer_id VARCHAR(255) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (user_id, gateway)
+);
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS payment_authorizations;
+
+-- enum values can't be dropped, the type is rebuilt without it
+UPDATE payments SET status = 'pending' WHERE status = 'authorized';
+UPDATE payment_transactions SET status = 'pending' WHERE status = 'authorized';
+ALTER TYPE payment_status RENAME TO payment_status_old;
+CREATE TYPE payment_status AS ENUM (
+  'pending', 'success', 'failed', 'cancelled', 
+  'refunded', 'processing'
+);
+ALTER TABLE payments ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE payments ALTER COLUMN status TYPE payment_status USING status::TEXT::payment_status;
+ALTER TABLE payments ALTER COLUMN status SET DEFAULT 'pending';
+ALTER TABLE payment_transactions ALTER COLUMN status DROP DEFAULT;
+ALTER TABLE payment_transactions ALTER COLUMN status TYPE payment_status USING status::TEXT::payment_status;
+ALTER TABLE payment_transactions ALTER COLUMN status SET DEFAULT 'pending';
+DROP TYPE payment_status_old;
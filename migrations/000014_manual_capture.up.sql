@@ -0,0 +1,16 @@
+-- card payments can be authorized at checkout and captured when the order ships
+ALTER TYPE payment_status ADD VALUE IF NOT EXISTS 'authorized';
+
+-- an authorization holds the money on the card until it is captured or voided
+CREATE TABLE payment_authorizations (
+  payment_id UUID PRIMARY KEY REFERENCES payments (id) ON DELETE CASCADE,
+  amount DECIMAL(10, 2) NOT NULL,
+  captured_amount DECIMAL(10, 2),
+  authorized_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  -- the gateway releases the money after this
+  expires_at TIMESTAMPTZ NOT NULL,
+  captured_at TIMESTAMPTZ,
+  voided_at TIMESTAMPTZ
+);
+
+CREATE INDEX ON payment_authorizations (expires_at) WHERE captured_at IS NULL AND voided_at IS NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS payments_updated_at_idx;
+DROP TABLE IF EXISTS payment_reconciliation_items;
+DROP TABLE IF EXISTS payment_reconciliations;
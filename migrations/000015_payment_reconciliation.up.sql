@@ -0,0 +1,28 @@
+-- a run of the job checking payments still waiting on a webhook against the gateway
+CREATE TABLE payment_reconciliations (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  finished_at TIMESTAMPTZ,
+  checked INT NOT NULL DEFAULT 0,
+  repaired INT NOT NULL DEFAULT 0,
+  failed INT NOT NULL DEFAULT 0
+);
+
+-- what a run found for each payment, the lines of the report
+CREATE TABLE payment_reconciliation_items (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  reconciliation_id UUID NOT NULL REFERENCES payment_reconciliations (id) ON DELETE CASCADE,
+  payment_id UUID NOT NULL REFERENCES payments (id) ON DELETE CASCADE,
+  order_id UUID NOT NULL,
+  gateway VARCHAR(50) NOT NULL,
+  payment_intent_id VARCHAR(255),
+  local_status payment_status NOT NULL,
+  gateway_status VARCHAR(50),
+  -- unchanged, repaired or failed
+  action VARCHAR(20) NOT NULL,
+  message TEXT,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON payment_reconciliation_items (reconciliation_id);
+CREATE INDEX ON payments (updated_at) WHERE status IN ('pending', 'processing');
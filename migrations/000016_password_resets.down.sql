@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS user_sessions_user_id_idx;
+DROP TABLE IF EXISTS password_resets;
@@ -0,0 +1,12 @@
+-- single use tokens mailed to users who forgot their password, only their hash is kept
+CREATE TABLE password_resets (
+  id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  token_hash VARCHAR(64) NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX ON password_resets (user_id) WHERE used_at IS NULL;
+CREATE INDEX ON user_sessions (user_id) WHERE NOT blocked;
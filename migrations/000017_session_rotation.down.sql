@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS user_sessions_family_id_idx;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS rotated_at;
+ALTER TABLE user_sessions DROP COLUMN IF EXISTS family_id;
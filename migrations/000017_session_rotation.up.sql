@@ -0,0 +1,8 @@
+-- every refresh replaces the session with a new one of the same family, the family is the
+-- device and is revoked as a whole when a replaced refresh token is used again
+ALTER TABLE user_sessions ADD COLUMN family_id UUID;
+UPDATE user_sessions SET family_id = id;
+ALTER TABLE user_sessions ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE user_sessions ADD COLUMN rotated_at TIMESTAMPTZ;
+
+CREATE INDEX ON user_sessions (family_id);
@@ -0,0 +1 @@
+DELETE FROM permissions WHERE module IN ('admin', 'roles');
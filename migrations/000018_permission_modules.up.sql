@@ -0,0 +1,12 @@
+-- "admin" opens the back office, each route then checks the permission of its own module.
+-- "roles" guards editing roles and their permissions.
+INSERT INTO permissions (role_id, module, r, w, x)
+SELECT r.id, perm.module, perm.read_perm, perm.write_perm, perm.execute_perm
+FROM user_roles r
+JOIN (
+    VALUES
+        ('admin', 'admin', true, true, true),
+        ('admin', 'roles', true, true, true),
+        ('moderator', 'admin', true, false, false)
+) AS perm(role_code, module, read_perm, write_perm, execute_perm) ON r.code = perm.role_code
+ON CONFLICT (role_id, module) DO NOTHING;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
+ALTER TABLE user_roles DROP COLUMN IF EXISTS require_mfa;
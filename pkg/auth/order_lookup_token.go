@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// orderLookupPrefix keeps lookup signatures apart from other tokens signed with the same secret
+const orderLookupPrefix = "order_lookup:"
+
+var ErrInvalidOrderLookupToken = errors.New("invalid order lookup token")
+
+// GenerateOrderLookupToken creates a token of the form "<orderID>.<expiresAt>.<signature>" that
+// grants read access to a single order without an account until it expires.
+func GenerateOrderLookupToken(secret string, orderID uuid.UUID, expiresAt time.Time) string {
+	payload := orderID.String() + "." + strconv.FormatInt(expiresAt.Unix(), 10)
+	return payload + "." + sign(secret, orderLookupPrefix+payload)
+}
+
+// VerifyOrderLookupToken checks the signature and expiry of a token created by
+// GenerateOrderLookupToken and returns its order ID.
+func VerifyOrderLookupToken(secret, token string) (uuid.UUID, error) {
+	idx := strings.LastIndex(token, ".")
+	if idx < 0 {
+		return uuid.Nil, ErrInvalidOrderLookupToken
+	}
+	payload, signature := token[:idx], token[idx+1:]
+	if !hmac.Equal([]byte(signature), []byte(sign(secret, orderLookupPrefix+payload))) {
+		return uuid.Nil, ErrInvalidOrderLookupToken
+	}
+	rawID, rawExp, ok := strings.Cut(payload, ".")
+	if !ok {
+		return uuid.Nil, ErrInvalidOrderLookupToken
+	}
+	orderID, err := uuid.Parse(rawID)
+	if err != nil {
+		return uuid.Nil, ErrInvalidOrderLookupToken
+	}
+	exp, err := strconv.ParseInt(rawExp, 10, 64)
+	if err != nil || time.Now().Unix() >= exp {
+		return uuid.Nil, ErrInvalidOrderLookupToken
+	}
+	return orderID, nil
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// GeneratePasswordResetToken creates a random token to mail to the user and the hash of it
+// to keep. Only the hash is stored, so a leaked database can't be used to reset passwords.
+func GeneratePasswordResetToken() (token string, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err = rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, HashPasswordResetToken(token), nil
+}
+
+// HashPasswordResetToken returns the hash a token created by GeneratePasswordResetToken is stored as
+func HashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
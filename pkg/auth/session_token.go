@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidSessionToken = errors.New("invalid session token")
+
+// GenerateSessionToken creates a random session ID and a token of the form
+// "<sessionID>.<signature>" signed with the given secret.
+func GenerateSessionToken(secret string) (sessionID string, token string) {
+	sessionID = uuid.NewString()
+	return sessionID, sessionID + "." + sign(secret, sessionID)
+}
+
+// VerifySessionToken checks the signature of a token created by GenerateSessionToken
+// and returns its session ID.
+func VerifySessionToken(secret, token string) (string, error) {
+	sessionID, signature, ok := strings.Cut(token, ".")
+	if !ok || sessionID == "" {
+		return "", ErrInvalidSessionToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(sign(secret, sessionID))) {
+		return "", ErrInvalidSessionToken
+	}
+	return sessionID, nil
+}
+
+func sign(secret, value string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 seed of RFC 6238 Appendix B, "12345678901234567890" in base32
+var rfc6238Secret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateTOTPRFC6238Vectors(t *testing.T) {
+	// the appendix lists 8 digit codes, authenticator apps show their last 6
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			at := time.Unix(tt.unix, 0)
+			step, ok := ValidateTOTP(rfc6238Secret, tt.code, at, 0)
+			if !ok {
+				t.Fatalf("ValidateTOTP(%s at %d) rejected", tt.code, tt.unix)
+			}
+			if want := tt.unix / 30; step != want {
+				t.Errorf("step = %d, want %d", step, want)
+			}
+			if lower, ok := ValidateTOTP(strings.ToLower(rfc6238Secret), tt.code, at, 0); !ok || lower != step {
+				t.Errorf("lower case secret = %d %v, want %d true", lower, ok, step)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPSkew(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Unix(1234567890, 0)
+	current := at.Unix() / 30
+
+	tests := []struct {
+		name   string
+		offset int64
+		want   bool
+	}{
+		{"two periods early", -2, false},
+		{"one period early", -1, true},
+		{"current period", 0, true},
+		{"one period late", 1, true},
+		{"two periods late", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfc6238Secret, totpCode(key, current+tt.offset), at, 0)
+			if ok != tt.want {
+				t.Fatalf("accepted = %v, want %v", ok, tt.want)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPReplay(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Unix(1234567890, 0)
+	current := at.Unix() / 30
+
+	tests := []struct {
+		name     string
+		offset   int64
+		lastStep int64
+		want     bool
+	}{
+		{"never used", 0, 0, true},
+		{"earlier step used", 0, current - 1, true},
+		{"same step used", 0, current, false},
+		{"later step used", 0, current + 1, false},
+		{"early code after the current one", -1, current, false},
+		{"late code after the current one", 1, current, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ok := ValidateTOTP(rfc6238Secret, totpCode(key, current+tt.offset), at, tt.lastStep)
+			if ok != tt.want {
+				t.Errorf("accepted = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTOTPMalformed(t *testing.T) {
+	at := time.Unix(59, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+	}{
+		{"wrong code", rfc6238Secret, "287083"},
+		{"eight digits", rfc6238Secret, "94287082"},
+		{"short code", rfc6238Secret, "28708"},
+		{"empty code", rfc6238Secret, ""},
+		{"secret not base32", "not base32!", "287082"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := ValidateTOTP(tt.secret, tt.code, at, 0); ok {
+				t.Errorf("ValidateTOTP(%q, %q) accepted", tt.secret, tt.code)
+			}
+		})
+	}
+}
+
+func TestTOTPSecretEncryption(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := EncryptTOTPSecret("symmetric-key", secret)
+	if err != nil {
+		t.Fatalf("EncryptTOTPSecret: %v", err)
+	}
+	if strings.Contains(encrypted, secret) {
+		t.Fatal("encrypted secret contains the secret")
+	}
+	again, err := EncryptTOTPSecret("symmetric-key", secret)
+	if err != nil {
+		t.Fatalf("EncryptTOTPSecret: %v", err)
+	}
+	if again == encrypted {
+		t.Error("encrypting twice gave the same ciphertext, the nonce isn't random")
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 0x01
+	tampered := base64.RawStdEncoding.EncodeToString(sealed)
+
+	tests := []struct {
+		name      string
+		key       string
+		encrypted string
+		wantErr   error
+	}{
+		{"round trip", "symmetric-key", encrypted, nil},
+		{"second ciphertext", "symmetric-key", again, nil},
+		{"wrong key", "other-key", encrypted, ErrInvalidTOTPSecret},
+		{"tampered", "symmetric-key", tampered, ErrInvalidTOTPSecret},
+		{"truncated", "symmetric-key", encrypted[:8], ErrInvalidTOTPSecret},
+		{"not base64", "symmetric-key", "!!!", ErrInvalidTOTPSecret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecryptTOTPSecret(tt.key, tt.encrypted)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got != secret {
+				t.Errorf("decrypted %q, want %q", got, secret)
+			}
+		})
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	want := HashRecoveryCode("abcde-fghij")
+	for _, typed := range []string{"abcdefghij", "ABCDE-FGHIJ", "abcde fghij", " abcde-fghij "} {
+		if got := HashRecoveryCode(typed); got != want {
+			t.Errorf("HashRecoveryCode(%q) differs from the stored hash", typed)
+		}
+	}
+	if HashRecoveryCode("abcde-fghik") == want {
+		t.Error("different codes hash the same")
+	}
+}
@@ -13,6 +13,9 @@ type CacheContainer interface {
 	Set(c context.Context, key string, value interface{}, expireIn *time.Duration) error
 	Get(c context.Context, key string, value interface{}) error
 	Delete(c context.Context, key string) error
+	// Incr atomically increments the counter at key and returns its new value. A new counter
+	// expires after expireIn, incrementing it doesn't extend it.
+	Incr(c context.Context, key string, expireIn *time.Duration) (int64, error)
 }
 
 var DEFAULT_EXPIRATION time.Duration = 5 * time.Minute
@@ -23,4 +26,7 @@ const (
 	ORDER_KEY_PREFIX            = "order:"
 	ORDER_ITEM_KEY_PREFIX       = "order_item:"
 	PRODUCT_CATEGORY_KEY_PREFIX = "product_category:"
+	SESSION_KEY_PREFIX          = "session:"
+	ROLE_KEY_PREFIX             = "role:"
+	MFA_KEY_PREFIX              = "mfa:"
 )
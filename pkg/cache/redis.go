@@ -12,6 +12,8 @@ import (
 
 type RedisContainer struct {
 	Storage *cache.Cache
+	// Client reaches redis directly for what the cache can't do, such as counters
+	Client redis.Cmdable
 }
 
 // Delete implements Cache.
@@ -45,6 +47,23 @@ func (r *RedisContainer) Set(c context.Context, key string, value interface{}, e
 	})
 }
 
+// Incr implements Cache. Counters skip the local cache so every instance sees the same value.
+func (r *RedisContainer) Incr(c context.Context, key string, expireIn *time.Duration) (int64, error) {
+	if expireIn == nil {
+		expireIn = &DEFAULT_EXPIRATION
+	}
+	value, err := r.Client.Incr(c, key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if value == 1 {
+		if err := r.Client.Expire(c, key, *expireIn).Err(); err != nil {
+			return value, err
+		}
+	}
+	return value, nil
+}
+
 func NewRedisCache(cfg config.Config) CacheContainer {
 	ring := redis.NewRing(&redis.RingOptions{
 		Addrs: map[string]string{
@@ -58,6 +77,7 @@ func NewRedisCache(cfg config.Config) CacheContainer {
 	})
 
 	return &RedisContainer{
-		storage,
+		Storage: storage,
+		Client:  ring,
 	}
 }
@@ -1,100 +1,714 @@
 package gateways
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
 )
 
+const (
+	paypalSandboxURL    = "https://api-m.sandbox.paypal.com"
+	paypalProductionURL = "https://api-m.paypal.com"
+)
+
+// PaypalGateway talks to the PayPal Orders v2 REST API. A PayPal order plays the role of
+// the payment intent: it is created at checkout, approved by the buyer on PayPal and
+// captured on confirmation. Manual capture orders are authorized on confirmation instead
+// and their authorization is captured or voided later.
 type PaypalGateway struct {
-	apiKey        string
-	webhookSecret string
-	environment   string
+	clientID     string
+	clientSecret string
+	webhookID    string
+	environment  string
+	baseURL      string
+	httpClient   *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
 }
 
+// NewPaypalGateway expects the client ID as APIKey, the client secret as SecretKey and the
+// webhook ID as WebhookSecret. Extra["base_url"] overrides the API host, e.g. for a stand-in server.
 func NewPaypalGateway(config payment.GatewayConfig) (payment.PaymentGateway, error) {
-	if config.APIKey == "" {
-		return nil, fmt.Errorf("Paypal API key is required")
+	if config.APIKey == "" || config.SecretKey == "" {
+		return nil, fmt.Errorf("paypal client ID and secret are required")
+	}
+
+	baseURL := paypalSandboxURL
+	if config.Environment == "production" || config.Environment == "live" {
+		baseURL = paypalProductionURL
+	}
+	if override := config.Extra["base_url"]; override != "" {
+		baseURL = override
 	}
 
 	return &PaypalGateway{
-		apiKey:        config.APIKey,
-		webhookSecret: config.WebhookSecret,
-		environment:   config.Environment,
+		clientID:     config.APIKey,
+		clientSecret: config.SecretKey,
+		webhookID:    config.WebhookSecret,
+		environment:  config.Environment,
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		httpClient:   &http.Client{Timeout: 30 * time.Second},
 	}, nil
 }
 
 func (s *PaypalGateway) Name() string {
-	return "Paypal"
+	return "paypal"
+}
+
+type paypalAmount struct {
+	CurrencyCode string `json:"currency_code"`
+	Value        string `json:"value"`
+}
+
+type paypalLink struct {
+	Href string `json:"href"`
+	Rel  string `json:"rel"`
+}
+
+type paypalCapture struct {
+	ID     string       `json:"id"`
+	Status string       `json:"status"`
+	Amount paypalAmount `json:"amount"`
+}
+
+type paypalAuthorization struct {
+	ID             string       `json:"id"`
+	Status         string       `json:"status"`
+	Amount         paypalAmount `json:"amount"`
+	ExpirationTime string       `json:"expiration_time"`
+}
+
+type paypalOrder struct {
+	ID            string `json:"id"`
+	Intent        string `json:"intent"`
+	Status        string `json:"status"`
+	CreateTime    string `json:"create_time"`
+	UpdateTime    string `json:"update_time"`
+	PurchaseUnits []struct {
+		Amount      paypalAmount `json:"amount"`
+		Description string       `json:"description"`
+		CustomID    string       `json:"custom_id"`
+		Payments    struct {
+			Captures       []paypalCapture       `json:"captures"`
+			Authorizations []paypalAuthorization `json:"authorizations"`
+		} `json:"payments"`
+	} `json:"purchase_units"`
+	Payer struct {
+		EmailAddress string `json:"email_address"`
+	} `json:"payer"`
+	Links []paypalLink `json:"links"`
+}
+
+type paypalRefund struct {
+	ID     string       `json:"id"`
+	Status string       `json:"status"`
+	Amount paypalAmount `json:"amount"`
+}
+
+type paypalError struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+	DebugID string `json:"debug_id"`
 }
 
 func (s *PaypalGateway) CreatePaymentIntent(ctx context.Context, req payment.PaymentRequest) (*payment.PaymentIntent, error) {
-	// Implement Paypal PaymentIntent creation
-	// This would use the actual Paypal SDK
+	if req.PaymentMethodID != "" {
+		return nil, payment.ErrSavedMethodsNotSupported
+	}
+	// checkout and the payments API don't agree on the metadata key
+	customID := req.Metadata["order_id"]
+	if customID == "" {
+		customID = req.Metadata["OrderID"]
+	}
+	orderIntent := "CAPTURE"
+	if req.ManualCapture {
+		orderIntent = "AUTHORIZE"
+	}
+	body := map[string]any{
+		"intent": orderIntent,
+		"purchase_units": []map[string]any{{
+			"amount":      paypalAmountFromMinor(req.Amount, req.Currency),
+			"description": req.Description,
+			"custom_id":   customID,
+		}},
+	}
 
-	intent := &payment.PaymentIntent{
-		ID:           fmt.Sprintf("pi_%d", time.Now().Unix()),
-		Amount:       req.Amount,
-		Currency:     req.Currency,
-		Status:       payment.StatusPending,
-		ClientSecret: fmt.Sprintf("pi_%d_secret_xyz", time.Now().Unix()),
-		Email:        req.Email,
-		Description:  req.Description,
-		Metadata:     req.Metadata,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+	var order paypalOrder
+	if err := s.doIdempotent(ctx, http.MethodPost, "/v2/checkout/orders", req.IdempotencyKey, body, &order); err != nil {
+		return nil, fmt.Errorf("failed to create Paypal order: %w", err)
 	}
 
+	intent := s.toPaymentIntent(&order)
+	intent.Email = req.Email
+	intent.Metadata = req.Metadata
 	return intent, nil
 }
 
 func (s *PaypalGateway) ConfirmPayment(ctx context.Context, intentID string) (*payment.PaymentResult, error) {
-	// Implement Paypal payment confirmation
+	current, err := s.getOrder(ctx, intentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Paypal order: %w", err)
+	}
+	if current.Intent == "AUTHORIZE" {
+		return s.authorizeOrder(ctx, intentID)
+	}
 
+	// an order is captured once, its ID keeps a retried capture from taking the money twice
+	var order paypalOrder
+	path := "/v2/checkout/orders/" + url.PathEscape(intentID) + "/capture"
+	if err := s.doIdempotent(ctx, http.MethodPost, path, "capture-"+intentID, nil, &order); err != nil {
+		return nil, fmt.Errorf("failed to capture Paypal order: %w", err)
+	}
+
+	status := paypalOrderStatus(order.Status)
 	result := &payment.PaymentResult{
-		Success:       true,
-		TransactionID: fmt.Sprintf("txn_%s", intentID),
-		Status:        payment.StatusCompleted,
-		Message:       "Payment completed successfully",
+		Success:       status == payment.StatusCompleted,
+		TransactionID: order.ID,
+		Status:        status,
+		Message:       "Payment captured",
+		Metadata:      map[string]string{},
 		ProcessedAt:   time.Now(),
 	}
-
+	if capture := order.capture(); capture != nil {
+		result.Metadata["capture_id"] = capture.ID
+		// the order completes even when the capture itself is declined or held for review
+		switch capture.Status {
+		case "DECLINED", "FAILED":
+			result.Success = false
+			result.Status = payment.StatusFailed
+		case "PENDING":
+			result.Success = false
+			result.Status = payment.StatusPending
+		}
+	}
 	return result, nil
 }
 
 func (s *PaypalGateway) GetPayment(ctx context.Context, transactionID string) (*payment.PaymentIntent, error) {
-	// Implement payment retrieval
-	return nil, nil
+	order, err := s.getOrder(ctx, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Paypal order: %w", err)
+	}
+	return s.toPaymentIntent(order), nil
 }
 
 func (s *PaypalGateway) RefundPayment(ctx context.Context, req payment.RefundRequest) (*payment.RefundResult, error) {
-	// Implement refund logic
+	// payments keep the PayPal order ID, refunds are made against its capture
+	order, err := s.getOrder(ctx, req.TransactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Paypal order: %w", err)
+	}
+	capture := order.capture()
+	if capture == nil {
+		return nil, fmt.Errorf("paypal order %s has no capture to refund", req.TransactionID)
+	}
+
+	body := map[string]any{}
+	// leaving the amount out refunds whatever is left on the capture
+	if req.Amount > 0 {
+		body["amount"] = paypalAmountFromMinor(req.Amount, payment.Currency(capture.Amount.CurrencyCode))
+	}
+	if req.Reason != "" {
+		body["note_to_payer"] = req.Reason
+	}
+
+	var refund paypalRefund
+	path := "/v2/payments/captures/" + url.PathEscape(capture.ID) + "/refund"
+	if err := s.doIdempotent(ctx, http.MethodPost, path, req.IdempotencyKey, body, &refund); err != nil {
+		return nil, fmt.Errorf("failed to create Paypal refund: %w", err)
+	}
 
-	result := &payment.RefundResult{
-		RefundID:      fmt.Sprintf("rf_%d", time.Now().Unix()),
+	status := payment.StatusCompleted
+	switch refund.Status {
+	case "PENDING":
+		status = payment.StatusPending
+	case "FAILED":
+		status = payment.StatusFailed
+	case "CANCELLED":
+		status = payment.StatusCancelled
+	}
+	amount, err := paypalAmountToMinor(refund.Amount)
+	if err != nil {
+		return nil, err
+	}
+	return &payment.RefundResult{
+		RefundID:      refund.ID,
 		TransactionID: req.TransactionID,
-		Amount:        req.Amount,
-		Status:        payment.StatusCompleted,
+		Amount:        amount,
+		Status:        status,
 		Reason:        req.Reason,
 		CreatedAt:     time.Now(),
+	}, nil
+}
+
+func (s *PaypalGateway) CancelPayment(ctx context.Context, intentID string) error {
+	// PayPal has no call to void an uncaptured CAPTURE order, it expires on its own.
+	// Refuse only when money was already taken so the caller refunds instead.
+	order, err := s.getOrder(ctx, intentID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve Paypal order: %w", err)
+	}
+	if auth := order.authorization(); auth != nil && order.capture() == nil {
+		return s.voidAuthorization(ctx, auth)
 	}
+	if order.Status == "COMPLETED" {
+		return fmt.Errorf("paypal order %s is already captured", intentID)
+	}
+	return nil
+}
 
+func (s *PaypalGateway) CapturePayment(ctx context.Context, req payment.CaptureRequest) (*payment.PaymentResult, error) {
+	order, err := s.getOrder(ctx, req.TransactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Paypal order: %w", err)
+	}
+	auth := order.authorization()
+	if auth == nil {
+		return nil, fmt.Errorf("paypal order %s has no authorization to capture", req.TransactionID)
+	}
+
+	// a final capture releases whatever is left of the authorization
+	body := map[string]any{"final_capture": true}
+	if req.Amount > 0 {
+		body["amount"] = paypalAmountFromMinor(req.Amount, payment.Currency(auth.Amount.CurrencyCode))
+	}
+	// the capture is final, so the authorization is only ever captured once
+	var capture paypalCapture
+	path := "/v2/payments/authorizations/" + url.PathEscape(auth.ID) + "/capture"
+	if err := s.doIdempotent(ctx, http.MethodPost, path, "capture-"+auth.ID, body, &capture); err != nil {
+		return nil, fmt.Errorf("failed to capture Paypal authorization: %w", err)
+	}
+
+	result := &payment.PaymentResult{
+		Success:       capture.Status == "COMPLETED",
+		TransactionID: req.TransactionID,
+		Status:        payment.StatusCompleted,
+		Message:       "Payment captured",
+		Metadata:      map[string]string{"charge_id": capture.ID},
+		ProcessedAt:   time.Now(),
+	}
+	switch capture.Status {
+	case "DECLINED", "FAILED":
+		result.Status = payment.StatusFailed
+	case "PENDING":
+		result.Status = payment.StatusPending
+	}
 	return result, nil
 }
 
-func (s *PaypalGateway) CancelPayment(ctx context.Context, intentID string) error {
-	// Implement payment cancellation
-	return nil
+func (s *PaypalGateway) VoidPayment(ctx context.Context, intentID string) error {
+	order, err := s.getOrder(ctx, intentID)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve Paypal order: %w", err)
+	}
+	auth := order.authorization()
+	if auth == nil {
+		return fmt.Errorf("paypal order %s has no authorization to void", intentID)
+	}
+	return s.voidAuthorization(ctx, auth)
 }
 
-func (s *PaypalGateway) VerifyWebhook(payload []byte, signature string) error {
-	// Implement webhook signature verification
+func (s *PaypalGateway) VerifyWebhook(ctx context.Context, payload []byte, headers http.Header) error {
+	if s.webhookID == "" {
+		return fmt.Errorf("paypal webhook ID is not configured")
+	}
+	body := map[string]any{
+		"auth_algo":         headers.Get("PAYPAL-AUTH-ALGO"),
+		"cert_url":          headers.Get("PAYPAL-CERT-URL"),
+		"transmission_id":   headers.Get("PAYPAL-TRANSMISSION-ID"),
+		"transmission_sig":  headers.Get("PAYPAL-TRANSMISSION-SIG"),
+		"transmission_time": headers.Get("PAYPAL-TRANSMISSION-TIME"),
+		"webhook_id":        s.webhookID,
+		"webhook_event":     json.RawMessage(payload),
+	}
+	for key, value := range body {
+		if value == "" {
+			return fmt.Errorf("invalid Paypal webhook: missing %s", key)
+		}
+	}
+
+	var rs struct {
+		VerificationStatus string `json:"verification_status"`
+	}
+	if err := s.do(ctx, http.MethodPost, "/v1/notifications/verify-webhook-signature", body, &rs); err != nil {
+		return fmt.Errorf("failed to verify Paypal webhook: %w", err)
+	}
+	if rs.VerificationStatus != "SUCCESS" {
+		return fmt.Errorf("invalid Paypal webhook signature: %s", rs.VerificationStatus)
+	}
 	return nil
 }
 
+type paypalWebhookEvent struct {
+	ID         string    `json:"id"`
+	EventType  string    `json:"event_type"`
+	CreateTime time.Time `json:"create_time"`
+	Resource   struct {
+		ID             string       `json:"id"`
+		Status         string       `json:"status"`
+		Amount         paypalAmount `json:"amount"`
+		ExpirationTime string       `json:"expiration_time"`
+		// disputes name the captures they are about and carry their own amount
+		DisputedTransactions []struct {
+			SellerTransactionID string `json:"seller_transaction_id"`
+		} `json:"disputed_transactions"`
+		DisputeAmount     paypalAmount `json:"dispute_amount"`
+		Reason            string       `json:"reason"`
+		SupplementaryData struct {
+			RelatedIDs struct {
+				OrderID string `json:"order_id"`
+			} `json:"related_ids"`
+		} `json:"supplementary_data"`
+	} `json:"resource"`
+}
+
+func (s *PaypalGateway) ParseWebhook(payload []byte) (*payment.WebhookEvent, error) {
+	var evt paypalWebhookEvent
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		return nil, fmt.Errorf("failed to parse Paypal event: %w", err)
+	}
+	rs := &payment.WebhookEvent{
+		ID:            evt.ID,
+		GatewayType:   evt.EventType,
+		Type:          payment.WebhookIgnored,
+		TransactionID: evt.Resource.SupplementaryData.RelatedIDs.OrderID,
+		Currency:      payment.NormalizeCurrency(evt.Resource.Amount.CurrencyCode),
+		CreatedAt:     evt.CreateTime,
+	}
+	amount, err := paypalAmountToMinor(evt.Resource.Amount)
+	if err != nil {
+		return nil, err
+	}
+	rs.Amount = amount
+
+	switch evt.EventType {
+	case "PAYMENT.CAPTURE.COMPLETED":
+		rs.Type = payment.WebhookPaymentSucceeded
+		rs.ChargeID = evt.Resource.ID
+	case "PAYMENT.CAPTURE.DENIED", "PAYMENT.CAPTURE.DECLINED":
+		rs.Type = payment.WebhookPaymentFailed
+		rs.ChargeID = evt.Resource.ID
+		rs.FailureCode = evt.Resource.Status
+	case "PAYMENT.AUTHORIZATION.CREATED":
+		rs.Type = payment.WebhookPaymentAuthorized
+		rs.AuthorizationExpiresAt, _ = time.Parse(time.RFC3339, evt.Resource.ExpirationTime)
+	case "PAYMENT.AUTHORIZATION.VOIDED":
+		rs.Type = payment.WebhookPaymentCancelled
+	case "CHECKOUT.ORDER.VOIDED":
+		rs.Type = payment.WebhookPaymentCancelled
+		rs.TransactionID = evt.Resource.ID
+	case "PAYMENT.CAPTURE.REFUNDED":
+		rs.Type = payment.WebhookRefundCompleted
+		rs.RefundID = evt.Resource.ID
+	case "CUSTOMER.DISPUTE.CREATED":
+		rs.Type = payment.WebhookDisputeOpened
+		rs.DisputeID = evt.Resource.ID
+		rs.FailureCode = evt.Resource.Reason
+		// a dispute resource has no order, the payment is found by the capture it disputes
+		if len(evt.Resource.DisputedTransactions) > 0 {
+			rs.ChargeID = evt.Resource.DisputedTransactions[0].SellerTransactionID
+		}
+		rs.Currency = payment.NormalizeCurrency(evt.Resource.DisputeAmount.CurrencyCode)
+		if rs.Amount, err = paypalAmountToMinor(evt.Resource.DisputeAmount); err != nil {
+			return nil, err
+		}
+	}
+	return rs, nil
+}
+
+// Cards can't be saved through the Orders API, the buyer approves every order on Paypal
+
+func (s *PaypalGateway) CreateCustomer(ctx context.Context, req payment.CustomerRequest) (string, error) {
+	return "", payment.ErrSavedMethodsNotSupported
+}
+
+func (s *PaypalGateway) CreateSetupIntent(ctx context.Context, customerID string) (*payment.SetupIntent, error) {
+	return nil, payment.ErrSavedMethodsNotSupported
+}
+
+func (s *PaypalGateway) GetSetupIntent(ctx context.Context, intentID string) (*payment.SetupIntent, error) {
+	return nil, payment.ErrSavedMethodsNotSupported
+}
+
+func (s *PaypalGateway) GetSavedMethod(ctx context.Context, methodID string) (*payment.SavedMethod, error) {
+	return nil, payment.ErrSavedMethodsNotSupported
+}
+
+func (s *PaypalGateway) DetachSavedMethod(ctx context.Context, methodID string) error {
+	return payment.ErrSavedMethodsNotSupported
+}
+
 func (s *PaypalGateway) Health(ctx context.Context) error {
-	// Implement health check
+	if _, err := s.token(ctx); err != nil {
+		return fmt.Errorf("paypal health check failed: %w", err)
+	}
+	return nil
+}
+
+func (s *PaypalGateway) getOrder(ctx context.Context, orderID string) (*paypalOrder, error) {
+	var order paypalOrder
+	if err := s.do(ctx, http.MethodGet, "/v2/checkout/orders/"+url.PathEscape(orderID), nil, &order); err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (s *PaypalGateway) toPaymentIntent(order *paypalOrder) *payment.PaymentIntent {
+	intent := &payment.PaymentIntent{
+		ID:     order.ID,
+		Status: paypalOrderStatus(order.Status),
+		Email:  order.Payer.EmailAddress,
+	}
+	// an authorized order completes without money being taken
+	if auth := order.authorization(); auth != nil && auth.Status == "CREATED" && order.capture() == nil {
+		intent.Status = payment.StatusAuthorized
+	}
+	if len(order.PurchaseUnits) > 0 {
+		unit := order.PurchaseUnits[0]
+		intent.Amount, _ = paypalAmountToMinor(unit.Amount)
+		intent.Currency = payment.NormalizeCurrency(unit.Amount.CurrencyCode)
+		intent.Description = unit.Description
+	}
+	// the buyer approves the order on PayPal, the client redirects to this link
+	for _, link := range order.Links {
+		if link.Rel == "approve" || link.Rel == "payer-action" {
+			intent.ClientSecret = link.Href
+			break
+		}
+	}
+	intent.CreatedAt, _ = time.Parse(time.RFC3339, order.CreateTime)
+	intent.UpdatedAt, _ = time.Parse(time.RFC3339, order.UpdateTime)
+	if intent.UpdatedAt.IsZero() {
+		intent.UpdatedAt = intent.CreatedAt
+	}
+	return intent
+}
+
+// capture returns the first capture of the order, nil until the order is captured.
+func (o *paypalOrder) capture() *paypalCapture {
+	for _, unit := range o.PurchaseUnits {
+		if len(unit.Payments.Captures) > 0 {
+			return &unit.Payments.Captures[0]
+		}
+	}
+	return nil
+}
+
+// authorization returns the first authorization of a manual capture order
+func (o *paypalOrder) authorization() *paypalAuthorization {
+	for _, unit := range o.PurchaseUnits {
+		if len(unit.Payments.Authorizations) > 0 {
+			return &unit.Payments.Authorizations[0]
+		}
+	}
+	return nil
+}
+
+// authorizeOrder authorizes an approved manual capture order
+func (s *PaypalGateway) authorizeOrder(ctx context.Context, orderID string) (*payment.PaymentResult, error) {
+	var order paypalOrder
+	path := "/v2/checkout/orders/" + url.PathEscape(orderID) + "/authorize"
+	if err := s.doIdempotent(ctx, http.MethodPost, path, "authorize-"+orderID, nil, &order); err != nil {
+		return nil, fmt.Errorf("failed to authorize Paypal order: %w", err)
+	}
+
+	result := &payment.PaymentResult{
+		TransactionID: order.ID,
+		Status:        payment.StatusPending,
+		Message:       "Payment authorized",
+		Metadata:      map[string]string{},
+		ProcessedAt:   time.Now(),
+	}
+	if auth := order.authorization(); auth != nil {
+		result.Metadata["authorization_id"] = auth.ID
+		result.Metadata["expiration_time"] = auth.ExpirationTime
+		switch auth.Status {
+		case "CREATED", "PENDING":
+			result.Success = auth.Status == "CREATED"
+			if result.Success {
+				result.Status = payment.StatusAuthorized
+			}
+		default:
+			result.Status = payment.StatusFailed
+		}
+	}
+	return result, nil
+}
+
+func (s *PaypalGateway) voidAuthorization(ctx context.Context, auth *paypalAuthorization) error {
+	if auth.Status == "VOIDED" {
+		return nil
+	}
+	if err := s.do(ctx, http.MethodPost, "/v2/payments/authorizations/"+url.PathEscape(auth.ID)+"/void", nil, nil); err != nil {
+		return fmt.Errorf("failed to void Paypal authorization: %w", err)
+	}
 	return nil
 }
+
+func paypalOrderStatus(status string) payment.PaymentStatus {
+	switch status {
+	case "COMPLETED":
+		return payment.StatusCompleted
+	case "VOIDED":
+		return payment.StatusCancelled
+	default:
+		// CREATED, SAVED, APPROVED and PAYER_ACTION_REQUIRED still wait for a capture
+		return payment.StatusPending
+	}
+}
+
+func paypalAmountFromMinor(amount int64, currency payment.Currency) paypalAmount {
+	currency = payment.NormalizeCurrency(string(currency))
+	return paypalAmount{
+		CurrencyCode: string(currency),
+		Value:        payment.NewMoney(amount, currency).Rat().FloatString(currency.Decimals()),
+	}
+}
+
+func paypalAmountToMinor(amount paypalAmount) (int64, error) {
+	if amount.Value == "" {
+		return 0, nil
+	}
+	// parsed as a decimal, a float can land a minor unit off
+	money, err := payment.ParseMoney(amount.Value, payment.Currency(amount.CurrencyCode))
+	if err != nil {
+		return 0, fmt.Errorf("invalid Paypal amount %s: %w", amount.Value, err)
+	}
+	return money.Amount, nil
+}
+
+// token returns a cached OAuth access token, fetching a new one shortly before it expires.
+func (s *PaypalGateway) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accessToken != "" && time.Now().Before(s.tokenExpiry) {
+		return s.accessToken, nil
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/v1/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(s.clientID, s.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var rs struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := s.send(req, &rs); err != nil {
+		return "", fmt.Errorf("failed to get Paypal access token: %w", err)
+	}
+	if rs.AccessToken == "" {
+		return "", errors.New("paypal returned an empty access token")
+	}
+
+	// refresh a minute early so a token never expires mid request
+	lifetime := time.Duration(rs.ExpiresIn)*time.Second - time.Minute
+	s.accessToken = rs.AccessToken
+	s.tokenExpiry = time.Now().Add(max(lifetime, 0))
+	return s.accessToken, nil
+}
+
+// do sends an authenticated JSON request and decodes the response into out. An expired
+// token is refreshed and the request retried once.
+func (s *PaypalGateway) do(ctx context.Context, method, path string, body, out any) error {
+	return s.doIdempotent(ctx, method, path, "", body, out)
+}
+
+// doIdempotent is do with a PayPal-Request-Id, PayPal answers a repeated request ID with the
+// result of the first request instead of running it again.
+func (s *PaypalGateway) doIdempotent(ctx context.Context, method, path, requestID string, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		token, err := s.token(ctx)
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, s.baseURL+path, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if requestID != "" {
+			req.Header.Set("PayPal-Request-Id", requestID)
+		}
+
+		err = s.send(req, out)
+		var apiErr *paypalAPIError
+		if attempt == 0 && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			s.mu.Lock()
+			s.accessToken = ""
+			s.mu.Unlock()
+			continue
+		}
+		return err
+	}
+}
+
+type paypalAPIError struct {
+	StatusCode int
+	paypalError
+}
+
+func (e *paypalAPIError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("paypal responded with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("paypal %s: %s (debug id %s)", e.Name, e.Message, e.DebugID)
+}
+
+func (s *PaypalGateway) send(req *http.Request, out any) error {
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		apiErr := &paypalAPIError{StatusCode: resp.StatusCode}
+		// the OAuth endpoint answers with error/error_description instead
+		var oauthErr struct {
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		if json.Unmarshal(data, &apiErr.paypalError) != nil || apiErr.Name == "" {
+			if json.Unmarshal(data, &oauthErr) == nil && oauthErr.Error != "" {
+				apiErr.Name = oauthErr.Error
+				apiErr.Message = oauthErr.ErrorDescription
+			}
+		}
+		return apiErr
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,592 @@
+package gateways
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+// paypalStandIn is an httptest PayPal that hands out tokens and routes API calls to handlers
+type paypalStandIn struct {
+	server      *httptest.Server
+	tokenCalls  atomic.Int32
+	tokenTTL    int64
+	tokenStatus int
+	routes      map[string]http.HandlerFunc
+}
+
+func newPaypalStandIn(t *testing.T) (*paypalStandIn, *PaypalGateway) {
+	t.Helper()
+	s := &paypalStandIn{tokenTTL: 3600, routes: map[string]http.HandlerFunc{}}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.server.Close)
+
+	gateway, err := NewPaypalGateway(payment.GatewayConfig{
+		APIKey:        "client-id",
+		SecretKey:     "client-secret",
+		WebhookSecret: "webhook-id",
+		Extra:         map[string]string{"base_url": s.server.URL + "/"},
+	})
+	if err != nil {
+		t.Fatalf("NewPaypalGateway: %v", err)
+	}
+	return s, gateway.(*PaypalGateway)
+}
+
+func (s *paypalStandIn) handle(method, path string, handler http.HandlerFunc) {
+	s.routes[method+" "+path] = handler
+}
+
+func (s *paypalStandIn) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v1/oauth2/token" {
+		n := s.tokenCalls.Add(1)
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "client-id" || secret != "client-secret" || r.FormValue("grant_type") != "client_credentials" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client", "error_description": "Client Authentication failed"})
+			return
+		}
+		if s.tokenStatus != 0 {
+			writeJSON(w, s.tokenStatus, map[string]string{"error": "invalid_client", "error_description": "Client Authentication failed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"access_token": "token-" + string(rune('0'+n)), "expires_in": s.tokenTTL})
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer token-") {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"name": "AUTHENTICATION_FAILURE", "message": "invalid token"})
+		return
+	}
+	handler, ok := s.routes[r.Method+" "+r.URL.Path]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]string{"name": "RESOURCE_NOT_FOUND", "message": r.URL.Path, "debug_id": "dbg-404"})
+		return
+	}
+	handler(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func decodeBody(t *testing.T, r *http.Request) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Errorf("decode %s body: %v", r.URL.Path, err)
+	}
+	return body
+}
+
+// orderJSON is a PayPal order with one purchase unit, captures and authorizations are optional
+func orderJSON(id, intent, status string, captures, authorizations []map[string]any) map[string]any {
+	payments := map[string]any{}
+	if captures != nil {
+		payments["captures"] = captures
+	}
+	if authorizations != nil {
+		payments["authorizations"] = authorizations
+	}
+	return map[string]any{
+		"id":          id,
+		"intent":      intent,
+		"status":      status,
+		"create_time": "2026-01-02T03:04:05Z",
+		"purchase_units": []map[string]any{{
+			"amount":      map[string]string{"currency_code": "USD", "value": "19.99"},
+			"description": "Order",
+			"payments":    payments,
+		}},
+		"links": []map[string]string{
+			{"rel": "self", "href": "https://paypal.test/v2/checkout/orders/" + id},
+			{"rel": "approve", "href": "https://paypal.test/checkoutnow?token=" + id},
+		},
+	}
+}
+
+func TestPaypalTokenCaching(t *testing.T) {
+	standIn, gateway := newPaypalStandIn(t)
+	standIn.handle(http.MethodGet, "/v2/checkout/orders/ORDER-1", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, orderJSON("ORDER-1", "CAPTURE", "CREATED", nil, nil))
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, err := gateway.GetPayment(context.Background(), "ORDER-1"); err != nil {
+			t.Fatalf("GetPayment: %v", err)
+		}
+	}
+	if got := standIn.tokenCalls.Load(); got != 1 {
+		t.Errorf("token fetched %d times, want 1", got)
+	}
+}
+
+func TestPaypalTokenRefresh(t *testing.T) {
+	t.Run("token about to expire", func(t *testing.T) {
+		standIn, gateway := newPaypalStandIn(t)
+		// tokens expiring within the minute are never reused
+		standIn.tokenTTL = 30
+		standIn.handle(http.MethodGet, "/v2/checkout/orders/ORDER-1", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, orderJSON("ORDER-1", "CAPTURE", "CREATED", nil, nil))
+		})
+		for i := 0; i < 2; i++ {
+			if _, err := gateway.GetPayment(context.Background(), "ORDER-1"); err != nil {
+				t.Fatalf("GetPayment: %v", err)
+			}
+		}
+		if got := standIn.tokenCalls.Load(); got != 2 {
+			t.Errorf("token fetched %d times, want 2", got)
+		}
+	})
+
+	t.Run("token revoked by paypal", func(t *testing.T) {
+		standIn, gateway := newPaypalStandIn(t)
+		var calls atomic.Int32
+		standIn.handle(http.MethodGet, "/v2/checkout/orders/ORDER-1", func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"name": "AUTHENTICATION_FAILURE", "message": "token expired"})
+				return
+			}
+			if got := r.Header.Get("Authorization"); got != "Bearer token-2" {
+				t.Errorf("retried with %q, want a new token", got)
+			}
+			writeJSON(w, http.StatusOK, orderJSON("ORDER-1", "CAPTURE", "CREATED", nil, nil))
+		})
+		if _, err := gateway.GetPayment(context.Background(), "ORDER-1"); err != nil {
+			t.Fatalf("GetPayment: %v", err)
+		}
+		if got := standIn.tokenCalls.Load(); got != 2 {
+			t.Errorf("token fetched %d times, want 2", got)
+		}
+	})
+
+	t.Run("retried once", func(t *testing.T) {
+		standIn, gateway := newPaypalStandIn(t)
+		var calls atomic.Int32
+		standIn.handle(http.MethodGet, "/v2/checkout/orders/ORDER-1", func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"name": "AUTHENTICATION_FAILURE", "message": "token expired"})
+		})
+		if _, err := gateway.GetPayment(context.Background(), "ORDER-1"); err == nil {
+			t.Fatal("GetPayment succeeded, want an error")
+		}
+		if got := calls.Load(); got != 2 {
+			t.Errorf("order requested %d times, want 2", got)
+		}
+	})
+}
+
+func TestPaypalCreatePaymentIntent(t *testing.T) {
+	tests := []struct {
+		name         string
+		req          payment.PaymentRequest
+		wantIntent   string
+		wantValue    string
+		wantCustomID string
+		wantCurrency string
+		wantErr      error
+	}{
+		{
+			name:         "capture",
+			req:          payment.PaymentRequest{Amount: 1999, Currency: "usd", Metadata: map[string]string{"order_id": "order-1"}, IdempotencyKey: "order-1"},
+			wantIntent:   "CAPTURE",
+			wantValue:    "19.99",
+			wantCustomID: "order-1",
+			wantCurrency: "USD",
+		},
+		{
+			name:         "manual capture",
+			req:          payment.PaymentRequest{Amount: 1999, Currency: "USD", ManualCapture: true, Metadata: map[string]string{"OrderID": "order-2"}},
+			wantIntent:   "AUTHORIZE",
+			wantValue:    "19.99",
+			wantCustomID: "order-2",
+			wantCurrency: "USD",
+		},
+		{
+			name:         "zero decimal currency",
+			req:          payment.PaymentRequest{Amount: 1500, Currency: "JPY"},
+			wantIntent:   "CAPTURE",
+			wantValue:    "1500",
+			wantCurrency: "JPY",
+		},
+		{
+			name:    "saved method",
+			req:     payment.PaymentRequest{Amount: 1999, Currency: "USD", PaymentMethodID: "pm_1"},
+			wantErr: payment.ErrSavedMethodsNotSupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn, gateway := newPaypalStandIn(t)
+			var requested bool
+			standIn.handle(http.MethodPost, "/v2/checkout/orders", func(w http.ResponseWriter, r *http.Request) {
+				requested = true
+				body := decodeBody(t, r)
+				if body["intent"] != tt.wantIntent {
+					t.Errorf("intent = %v, want %s", body["intent"], tt.wantIntent)
+				}
+				unit := body["purchase_units"].([]any)[0].(map[string]any)
+				amount := unit["amount"].(map[string]any)
+				if amount["value"] != tt.wantValue || amount["currency_code"] != tt.wantCurrency {
+					t.Errorf("amount = %v, want %s %s", amount, tt.wantValue, tt.wantCurrency)
+				}
+				if unit["custom_id"] != tt.wantCustomID {
+					t.Errorf("custom_id = %v, want %s", unit["custom_id"], tt.wantCustomID)
+				}
+				if got := r.Header.Get("PayPal-Request-Id"); got != tt.req.IdempotencyKey {
+					t.Errorf("PayPal-Request-Id = %q, want %q", got, tt.req.IdempotencyKey)
+				}
+				writeJSON(w, http.StatusCreated, orderJSON("ORDER-1", tt.wantIntent, "CREATED", nil, nil))
+			})
+
+			intent, err := gateway.CreatePaymentIntent(context.Background(), tt.req)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if requested {
+					t.Error("order created for a saved method")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreatePaymentIntent: %v", err)
+			}
+			if intent.ID != "ORDER-1" || intent.Status != payment.StatusPending {
+				t.Errorf("intent = %s %s, want ORDER-1 %s", intent.ID, intent.Status, payment.StatusPending)
+			}
+			if intent.ClientSecret != "https://paypal.test/checkoutnow?token=ORDER-1" {
+				t.Errorf("client secret = %s, want the approve link", intent.ClientSecret)
+			}
+		})
+	}
+}
+
+func TestPaypalConfirmPayment(t *testing.T) {
+	tests := []struct {
+		name          string
+		orderIntent   string
+		captureStatus string
+		authStatus    string
+		wantSuccess   bool
+		wantStatus    payment.PaymentStatus
+	}{
+		{"captured", "CAPTURE", "COMPLETED", "", true, payment.StatusCompleted},
+		{"capture declined", "CAPTURE", "DECLINED", "", false, payment.StatusFailed},
+		{"capture held for review", "CAPTURE", "PENDING", "", false, payment.StatusPending},
+		{"authorized", "AUTHORIZE", "", "CREATED", true, payment.StatusAuthorized},
+		{"authorization pending", "AUTHORIZE", "", "PENDING", false, payment.StatusPending},
+		{"authorization denied", "AUTHORIZE", "", "DENIED", false, payment.StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn, gateway := newPaypalStandIn(t)
+			standIn.handle(http.MethodGet, "/v2/checkout/orders/ORDER-1", func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, http.StatusOK, orderJSON("ORDER-1", tt.orderIntent, "APPROVED", nil, nil))
+			})
+			standIn.handle(http.MethodPost, "/v2/checkout/orders/ORDER-1/capture", func(w http.ResponseWriter, r *http.Request) {
+				if tt.orderIntent != "CAPTURE" {
+					t.Error("captured an AUTHORIZE order")
+				}
+				if got := r.Header.Get("PayPal-Request-Id"); got != "capture-ORDER-1" {
+					t.Errorf("PayPal-Request-Id = %q, want capture-ORDER-1", got)
+				}
+				captures := []map[string]any{{"id": "CAPTURE-1", "status": tt.captureStatus}}
+				writeJSON(w, http.StatusCreated, orderJSON("ORDER-1", "CAPTURE", "COMPLETED", captures, nil))
+			})
+			standIn.handle(http.MethodPost, "/v2/checkout/orders/ORDER-1/authorize", func(w http.ResponseWriter, r *http.Request) {
+				if tt.orderIntent != "AUTHORIZE" {
+					t.Error("authorized a CAPTURE order")
+				}
+				if got := r.Header.Get("PayPal-Request-Id"); got != "authorize-ORDER-1" {
+					t.Errorf("PayPal-Request-Id = %q, want authorize-ORDER-1", got)
+				}
+				auths := []map[string]any{{"id": "AUTH-1", "status": tt.authStatus, "expiration_time": "2026-02-01T00:00:00Z"}}
+				writeJSON(w, http.StatusCreated, orderJSON("ORDER-1", "AUTHORIZE", "COMPLETED", nil, auths))
+			})
+
+			rs, err := gateway.ConfirmPayment(context.Background(), "ORDER-1")
+			if err != nil {
+				t.Fatalf("ConfirmPayment: %v", err)
+			}
+			if rs.Success != tt.wantSuccess || rs.Status != tt.wantStatus {
+				t.Errorf("result = %v %s, want %v %s", rs.Success, rs.Status, tt.wantSuccess, tt.wantStatus)
+			}
+			if tt.captureStatus != "" && rs.Metadata["capture_id"] != "CAPTURE-1" {
+				t.Errorf("capture_id = %s, want CAPTURE-1", rs.Metadata["capture_id"])
+			}
+			if tt.authStatus != "" && rs.Metadata["authorization_id"] != "AUTH-1" {
+				t.Errorf("authorization_id = %s, want AUTH-1", rs.Metadata["authorization_id"])
+			}
+		})
+	}
+}
+
+func TestPaypalRefundPayment(t *testing.T) {
+	tests := []struct {
+		name         string
+		amount       int64
+		refundStatus string
+		wantAmount   any
+		wantStatus   payment.PaymentStatus
+	}{
+		{"partial", 500, "COMPLETED", "5.00", payment.StatusCompleted},
+		{"full", 0, "COMPLETED", nil, payment.StatusCompleted},
+		{"pending", 500, "PENDING", "5.00", payment.StatusPending},
+		{"failed", 500, "FAILED", "5.00", payment.StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn, gateway := newPaypalStandIn(t)
+			standIn.handle(http.MethodGet, "/v2/checkout/orders/ORDER-1", func(w http.ResponseWriter, r *http.Request) {
+				captures := []map[string]any{{
+					"id":     "CAPTURE-1",
+					"status": "COMPLETED",
+					"amount": map[string]string{"currency_code": "USD", "value": "19.99"},
+				}}
+				writeJSON(w, http.StatusOK, orderJSON("ORDER-1", "CAPTURE", "COMPLETED", captures, nil))
+			})
+			standIn.handle(http.MethodPost, "/v2/payments/captures/CAPTURE-1/refund", func(w http.ResponseWriter, r *http.Request) {
+				body := decodeBody(t, r)
+				var value any
+				if amount, ok := body["amount"].(map[string]any); ok {
+					value = amount["value"]
+				}
+				if value != tt.wantAmount {
+					t.Errorf("refund amount = %v, want %v", value, tt.wantAmount)
+				}
+				if body["note_to_payer"] != "damaged" {
+					t.Errorf("note_to_payer = %v, want damaged", body["note_to_payer"])
+				}
+				if got := r.Header.Get("PayPal-Request-Id"); got != "refund-1" {
+					t.Errorf("PayPal-Request-Id = %q, want refund-1", got)
+				}
+				refunded := "5.00"
+				if tt.amount == 0 {
+					refunded = "19.99"
+				}
+				writeJSON(w, http.StatusCreated, map[string]any{
+					"id":     "REFUND-1",
+					"status": tt.refundStatus,
+					"amount": map[string]string{"currency_code": "USD", "value": refunded},
+				})
+			})
+
+			rs, err := gateway.RefundPayment(context.Background(), payment.RefundRequest{
+				TransactionID:  "ORDER-1",
+				Amount:         tt.amount,
+				Reason:         "damaged",
+				IdempotencyKey: "refund-1",
+			})
+			if err != nil {
+				t.Fatalf("RefundPayment: %v", err)
+			}
+			wantAmount := tt.amount
+			if wantAmount == 0 {
+				wantAmount = 1999
+			}
+			if rs.RefundID != "REFUND-1" || rs.TransactionID != "ORDER-1" || rs.Amount != wantAmount || rs.Status != tt.wantStatus {
+				t.Errorf("refund = %s %s %d %s, want REFUND-1 ORDER-1 %d %s", rs.RefundID, rs.TransactionID, rs.Amount, rs.Status, wantAmount, tt.wantStatus)
+			}
+		})
+	}
+
+	t.Run("not captured", func(t *testing.T) {
+		standIn, gateway := newPaypalStandIn(t)
+		standIn.handle(http.MethodGet, "/v2/checkout/orders/ORDER-1", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, orderJSON("ORDER-1", "CAPTURE", "APPROVED", nil, nil))
+		})
+		if _, err := gateway.RefundPayment(context.Background(), payment.RefundRequest{TransactionID: "ORDER-1"}); err == nil {
+			t.Fatal("RefundPayment succeeded, want an error")
+		}
+	})
+}
+
+func TestPaypalAmountToMinor(t *testing.T) {
+	tests := []struct {
+		amount  paypalAmount
+		want    int64
+		wantErr bool
+	}{
+		{paypalAmount{CurrencyCode: "USD", Value: "19.99"}, 1999, false},
+		{paypalAmount{CurrencyCode: "USD", Value: "0.29"}, 29, false},
+		{paypalAmount{CurrencyCode: "USD", Value: "12345678901.23"}, 1234567890123, false},
+		{paypalAmount{CurrencyCode: "JPY", Value: "1500"}, 1500, false},
+		{paypalAmount{CurrencyCode: "USD", Value: ""}, 0, false},
+		{paypalAmount{CurrencyCode: "USD", Value: "1.005"}, 0, true},
+		{paypalAmount{CurrencyCode: "USD", Value: "1e3"}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount.Value+" "+tt.amount.CurrencyCode, func(t *testing.T) {
+			got, err := paypalAmountToMinor(tt.amount)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("paypalAmountToMinor(%v) err = %v, wantErr %v", tt.amount, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("paypalAmountToMinor(%v) = %d, want %d", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaypalErrorMapping(t *testing.T) {
+	tests := []struct {
+		name        string
+		tokenStatus int
+		status      int
+		body        string
+		wantStatus  int
+		wantName    string
+		wantMessage string
+		wantText    string
+	}{
+		{
+			name:        "api error",
+			status:      http.StatusUnprocessableEntity,
+			body:        `{"name":"UNPROCESSABLE_ENTITY","message":"The requested action could not be performed","debug_id":"dbg-1"}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantName:    "UNPROCESSABLE_ENTITY",
+			wantMessage: "The requested action could not be performed",
+			wantText:    "paypal UNPROCESSABLE_ENTITY: The requested action could not be performed (debug id dbg-1)",
+		},
+		{
+			name:       "empty body",
+			status:     http.StatusInternalServerError,
+			wantStatus: http.StatusInternalServerError,
+			wantText:   "paypal responded with status 500",
+		},
+		{
+			name:       "body that isn't json",
+			status:     http.StatusBadGateway,
+			body:       "<html>bad gateway</html>",
+			wantStatus: http.StatusBadGateway,
+			wantText:   "paypal responded with status 502",
+		},
+		{
+			name:        "oauth error",
+			tokenStatus: http.StatusUnauthorized,
+			wantStatus:  http.StatusUnauthorized,
+			wantName:    "invalid_client",
+			wantMessage: "Client Authentication failed",
+			wantText:    "paypal invalid_client: Client Authentication failed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn, gateway := newPaypalStandIn(t)
+			standIn.tokenStatus = tt.tokenStatus
+			standIn.handle(http.MethodGet, "/v2/checkout/orders/ORDER-1", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			})
+
+			_, err := gateway.GetPayment(context.Background(), "ORDER-1")
+			var apiErr *paypalAPIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v, want a paypal API error", err)
+			}
+			if apiErr.StatusCode != tt.wantStatus || apiErr.Name != tt.wantName || apiErr.Message != tt.wantMessage {
+				t.Errorf("api error = %d %q %q, want %d %q %q", apiErr.StatusCode, apiErr.Name, apiErr.Message, tt.wantStatus, tt.wantName, tt.wantMessage)
+			}
+			if !strings.Contains(err.Error(), tt.wantText) {
+				t.Errorf("err = %q, want it to contain %q", err, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestPaypalVerifyWebhook(t *testing.T) {
+	signed := http.Header{}
+	signed.Set("PAYPAL-AUTH-ALGO", "SHA256withRSA")
+	signed.Set("PAYPAL-CERT-URL", "https://api.paypal.com/v1/notifications/certs/CERT-1")
+	signed.Set("PAYPAL-TRANSMISSION-ID", "transmission-1")
+	signed.Set("PAYPAL-TRANSMISSION-SIG", "signature")
+	signed.Set("PAYPAL-TRANSMISSION-TIME", "2026-01-02T03:04:05Z")
+	unsigned := signed.Clone()
+	unsigned.Del("PAYPAL-TRANSMISSION-SIG")
+	payload := []byte(`{"id":"WH-1","event_type":"PAYMENT.CAPTURE.COMPLETED"}`)
+
+	tests := []struct {
+		name          string
+		headers       http.Header
+		status        string
+		wantErr       bool
+		wantVerifyHit bool
+	}{
+		{"valid signature", signed, "SUCCESS", false, true},
+		{"invalid signature", signed, "FAILURE", true, true},
+		{"missing header", unsigned, "SUCCESS", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			standIn, gateway := newPaypalStandIn(t)
+			var verifyHit bool
+			standIn.handle(http.MethodPost, "/v1/notifications/verify-webhook-signature", func(w http.ResponseWriter, r *http.Request) {
+				verifyHit = true
+				body := decodeBody(t, r)
+				if body["webhook_id"] != "webhook-id" || body["transmission_sig"] != "signature" || body["auth_algo"] != "SHA256withRSA" {
+					t.Errorf("verify body = %v", body)
+				}
+				if event, ok := body["webhook_event"].(map[string]any); !ok || event["id"] != "WH-1" {
+					t.Errorf("webhook_event = %v, want the payload", body["webhook_event"])
+				}
+				writeJSON(w, http.StatusOK, map[string]string{"verification_status": tt.status})
+			})
+
+			err := gateway.VerifyWebhook(context.Background(), payload, tt.headers)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyWebhook err = %v, want error %v", err, tt.wantErr)
+			}
+			if verifyHit != tt.wantVerifyHit {
+				t.Errorf("verify endpoint called = %v, want %v", verifyHit, tt.wantVerifyHit)
+			}
+		})
+	}
+
+	t.Run("no webhook ID", func(t *testing.T) {
+		_, gateway := newPaypalStandIn(t)
+		gateway.webhookID = ""
+		if err := gateway.VerifyWebhook(context.Background(), payload, signed); err == nil {
+			t.Fatal("VerifyWebhook succeeded without a webhook ID")
+		}
+	})
+}
+
+func TestPaypalParseWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    payment.WebhookEvent
+	}{
+		{
+			"capture completed",
+			`{"id":"WH-1","event_type":"PAYMENT.CAPTURE.COMPLETED","resource":{"id":"CAP-1","amount":{"currency_code":"USD","value":"19.99"},"supplementary_data":{"related_ids":{"order_id":"ORDER-1"}}}}`,
+			payment.WebhookEvent{ID: "WH-1", Type: payment.WebhookPaymentSucceeded, TransactionID: "ORDER-1", ChargeID: "CAP-1", Amount: 1999, Currency: "USD"},
+		},
+		{
+			"dispute created",
+			`{"id":"WH-2","event_type":"CUSTOMER.DISPUTE.CREATED","resource":{"dispute_id":"PP-D-1","id":"PP-D-1","reason":"MERCHANDISE_OR_SERVICE_NOT_RECEIVED","dispute_amount":{"currency_code":"USD","value":"5.00"},"disputed_transactions":[{"seller_transaction_id":"CAP-1"}]}}`,
+			payment.WebhookEvent{ID: "WH-2", Type: payment.WebhookDisputeOpened, ChargeID: "CAP-1", DisputeID: "PP-D-1", Amount: 500, Currency: "USD", FailureCode: "MERCHANDISE_OR_SERVICE_NOT_RECEIVED"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, gateway := newPaypalStandIn(t)
+			got, err := gateway.ParseWebhook([]byte(tt.payload))
+			if err != nil {
+				t.Fatalf("ParseWebhook: %v", err)
+			}
+			if got.ID != tt.want.ID || got.Type != tt.want.Type || got.TransactionID != tt.want.TransactionID || got.ChargeID != tt.want.ChargeID ||
+				got.DisputeID != tt.want.DisputeID || got.Amount != tt.want.Amount || got.Currency != tt.want.Currency || got.FailureCode != tt.want.FailureCode {
+				t.Errorf("ParseWebhook = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -2,10 +2,14 @@ package gateways
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/stripe/stripe-go/v84"
+	"github.com/stripe/stripe-go/v84/webhook"
 	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
 )
 
@@ -35,15 +39,32 @@ func (s *StripeGateway) Name() string {
 }
 
 func (s *StripeGateway) CreatePaymentIntent(ctx context.Context, req payment.PaymentRequest) (*payment.PaymentIntent, error) {
-	// Implement Stripe PaymentIntent creation
-	// This would use the actual Stripe SDK
-	intent, err := s.client.V1PaymentIntents.Create(ctx, &stripe.PaymentIntentCreateParams{
+	// Stripe expects lower case ISO codes and amounts in the currency's minor unit
+	currency := strings.ToLower(string(req.Currency))
+	params := &stripe.PaymentIntentCreateParams{
 		Amount:       &req.Amount,
-		Currency:     (*string)(&req.Currency),
+		Currency:     &currency,
 		Description:  &req.Description,
 		ReceiptEmail: &req.Email,
 		Metadata:     req.Metadata,
-	})
+	}
+	if req.CustomerID != "" {
+		params.Customer = &req.CustomerID
+	}
+	if req.PaymentMethodID != "" {
+		// a saved card is charged right away, the client secret is only needed for 3D Secure
+		params.PaymentMethod = &req.PaymentMethodID
+		params.PaymentMethodTypes = []*string{stripe.String("card")}
+		params.Confirm = stripe.Bool(true)
+	}
+	if req.ManualCapture {
+		// the card is only authorized, the money is captured once the order ships
+		params.CaptureMethod = stripe.String(string(stripe.PaymentIntentCaptureMethodManual))
+	}
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+	intent, err := s.client.V1PaymentIntents.Create(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Stripe payment intent: %w", err)
 	}
@@ -51,8 +72,8 @@ func (s *StripeGateway) CreatePaymentIntent(ctx context.Context, req payment.Pay
 	return &payment.PaymentIntent{
 		ID:           intent.ID,
 		Amount:       intent.Amount,
-		Currency:     payment.Currency(intent.Currency),
-		Status:       payment.PaymentStatus(intent.Status),
+		Currency:     payment.NormalizeCurrency(string(intent.Currency)),
+		Status:       stripePaymentStatus(intent.Status),
 		Email:        intent.ReceiptEmail,
 		Description:  intent.Description,
 		ClientSecret: intent.ClientSecret,
@@ -72,7 +93,7 @@ func (s *StripeGateway) ConfirmPayment(ctx context.Context, intentID string) (*p
 	result := &payment.PaymentResult{
 		Success:       rs.Status == stripe.PaymentIntentStatusSucceeded,
 		TransactionID: rs.ID,
-		Status:        payment.PaymentStatus(rs.Status),
+		Status:        stripePaymentStatus(rs.Status),
 		Message:       "Payment confirmed",
 		Metadata:      rs.Metadata,
 		ProcessedAt:   time.Now(),
@@ -89,8 +110,8 @@ func (s *StripeGateway) GetPayment(ctx context.Context, transactionID string) (*
 	return &payment.PaymentIntent{
 		ID:           intent.ID,
 		Amount:       intent.Amount,
-		Currency:     payment.Currency(intent.Currency),
-		Status:       payment.PaymentStatus(intent.Status),
+		Currency:     payment.NormalizeCurrency(string(intent.Currency)),
+		Status:       stripePaymentStatus(intent.Status),
 		Email:        intent.ReceiptEmail,
 		Description:  intent.Description,
 		ClientSecret: intent.ClientSecret,
@@ -100,20 +121,42 @@ func (s *StripeGateway) GetPayment(ctx context.Context, transactionID string) (*
 }
 
 func (s *StripeGateway) RefundPayment(ctx context.Context, req payment.RefundRequest) (*payment.RefundResult, error) {
-	// Implement refund logic
-	refundRs, err := s.client.V1Refunds.Create(ctx, &stripe.RefundCreateParams{
+	params := &stripe.RefundCreateParams{
 		PaymentIntent: &req.TransactionID,
-		Amount:        &req.Amount,
-		Reason:        &req.Reason,
-	})
+	}
+	// leaving the amount out refunds whatever is left on the payment intent
+	if req.Amount > 0 {
+		params.Amount = &req.Amount
+	}
+	// stripe only accepts a few reasons, anything else is kept as metadata
+	switch req.Reason {
+	case string(stripe.RefundReasonDuplicate), string(stripe.RefundReasonFraudulent), string(stripe.RefundReasonRequestedByCustomer):
+		params.Reason = &req.Reason
+	case "":
+	default:
+		params.AddMetadata("reason", req.Reason)
+	}
+	if req.IdempotencyKey != "" {
+		params.SetIdempotencyKey(req.IdempotencyKey)
+	}
+	refundRs, err := s.client.V1Refunds.Create(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Stripe refund: %w", err)
 	}
+	status := payment.StatusCompleted
+	switch refundRs.Status {
+	case stripe.RefundStatusPending, stripe.RefundStatusRequiresAction:
+		status = payment.StatusPending
+	case stripe.RefundStatusFailed:
+		status = payment.StatusFailed
+	case stripe.RefundStatusCanceled:
+		status = payment.StatusCancelled
+	}
 	return &payment.RefundResult{
 		RefundID:      refundRs.ID,
 		TransactionID: req.TransactionID,
-		Amount:        req.Amount,
-		Status:        payment.StatusCompleted,
+		Amount:        refundRs.Amount,
+		Status:        status,
 		Reason:        req.Reason,
 		CreatedAt:     time.Now(),
 	}, nil
@@ -128,8 +171,214 @@ func (s *StripeGateway) CancelPayment(ctx context.Context, intentID string) erro
 	return nil
 }
 
-func (s *StripeGateway) VerifyWebhook(payload []byte, signature string) error {
-	// Implement webhook verification
+func (s *StripeGateway) CapturePayment(ctx context.Context, req payment.CaptureRequest) (*payment.PaymentResult, error) {
+	params := &stripe.PaymentIntentCaptureParams{}
+	// leaving the amount out captures everything authorized, the rest is released
+	if req.Amount > 0 {
+		params.AmountToCapture = &req.Amount
+	}
+	rs, err := s.client.V1PaymentIntents.Capture(ctx, req.TransactionID, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture Stripe payment intent: %w", err)
+	}
+
+	result := &payment.PaymentResult{
+		Success:       rs.Status == stripe.PaymentIntentStatusSucceeded,
+		TransactionID: rs.ID,
+		Status:        payment.StatusPending,
+		Message:       "Payment captured",
+		Metadata:      rs.Metadata,
+		ProcessedAt:   time.Now(),
+	}
+	if result.Success {
+		result.Status = payment.StatusCompleted
+	}
+	if rs.LatestCharge != nil {
+		if result.Metadata == nil {
+			result.Metadata = map[string]string{}
+		}
+		result.Metadata["charge_id"] = rs.LatestCharge.ID
+	}
+	return result, nil
+}
+
+func (s *StripeGateway) VoidPayment(ctx context.Context, intentID string) error {
+	// cancelling an uncaptured payment intent releases the authorization
+	_, err := s.client.V1PaymentIntents.Cancel(ctx, intentID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to void Stripe payment intent: %w", err)
+	}
+	return nil
+}
+
+func (s *StripeGateway) VerifyWebhook(ctx context.Context, payload []byte, headers http.Header) error {
+	if s.webhookSecret == "" {
+		return fmt.Errorf("stripe webhook secret is not configured")
+	}
+	// checks the Stripe-Signature header and rejects deliveries older than the default tolerance
+	if err := webhook.ValidatePayload(payload, headers.Get("Stripe-Signature"), s.webhookSecret); err != nil {
+		return fmt.Errorf("invalid Stripe webhook signature: %w", err)
+	}
+	return nil
+}
+
+func (s *StripeGateway) ParseWebhook(payload []byte) (*payment.WebhookEvent, error) {
+	var evt stripe.Event
+	if err := json.Unmarshal(payload, &evt); err != nil {
+		return nil, fmt.Errorf("failed to parse Stripe event: %w", err)
+	}
+	rs := &payment.WebhookEvent{
+		ID:          evt.ID,
+		GatewayType: string(evt.Type),
+		Type:        payment.WebhookIgnored,
+		CreatedAt:   time.Unix(evt.Created, 0),
+	}
+
+	switch evt.Type {
+	case stripe.EventTypePaymentIntentSucceeded, stripe.EventTypePaymentIntentPaymentFailed, stripe.EventTypePaymentIntentCanceled,
+		stripe.EventTypePaymentIntentAmountCapturableUpdated:
+		var pi stripe.PaymentIntent
+		if err := json.Unmarshal(evt.Data.Raw, &pi); err != nil {
+			return nil, fmt.Errorf("failed to parse Stripe payment intent: %w", err)
+		}
+		rs.TransactionID = pi.ID
+		rs.Amount = pi.Amount
+		rs.Currency = payment.NormalizeCurrency(string(pi.Currency))
+		if pi.LatestCharge != nil {
+			rs.ChargeID = pi.LatestCharge.ID
+		}
+		if pi.PaymentMethod != nil {
+			rs.PaymentMethod = string(pi.PaymentMethod.Type)
+		}
+		switch evt.Type {
+		case stripe.EventTypePaymentIntentSucceeded:
+			rs.Type = payment.WebhookPaymentSucceeded
+			rs.Amount = pi.AmountReceived
+		case stripe.EventTypePaymentIntentAmountCapturableUpdated:
+			// card authorizations are held for 7 days, the event doesn't carry the exact deadline
+			rs.Type = payment.WebhookPaymentAuthorized
+			rs.Amount = pi.AmountCapturable
+		case stripe.EventTypePaymentIntentPaymentFailed:
+			rs.Type = payment.WebhookPaymentFailed
+			if pi.LastPaymentError != nil {
+				rs.FailureCode = string(pi.LastPaymentError.Code)
+				rs.FailureMessage = pi.LastPaymentError.Msg
+			}
+		default:
+			rs.Type = payment.WebhookPaymentCancelled
+			rs.FailureMessage = string(pi.CancellationReason)
+		}
+	case stripe.EventTypeRefundCreated, stripe.EventTypeRefundUpdated, stripe.EventTypeChargeRefundUpdated:
+		var refund stripe.Refund
+		if err := json.Unmarshal(evt.Data.Raw, &refund); err != nil {
+			return nil, fmt.Errorf("failed to parse Stripe refund: %w", err)
+		}
+		// refunds are created pending, only act once the money is actually returned
+		if refund.Status != stripe.RefundStatusSucceeded {
+			break
+		}
+		rs.Type = payment.WebhookRefundCompleted
+		rs.RefundID = refund.ID
+		rs.Amount = refund.Amount
+		rs.Currency = payment.NormalizeCurrency(string(refund.Currency))
+		if refund.PaymentIntent != nil {
+			rs.TransactionID = refund.PaymentIntent.ID
+		}
+		if refund.Charge != nil {
+			rs.ChargeID = refund.Charge.ID
+		}
+	case stripe.EventTypeChargeDisputeCreated:
+		var dispute stripe.Dispute
+		if err := json.Unmarshal(evt.Data.Raw, &dispute); err != nil {
+			return nil, fmt.Errorf("failed to parse Stripe dispute: %w", err)
+		}
+		rs.Type = payment.WebhookDisputeOpened
+		rs.DisputeID = dispute.ID
+		rs.Amount = dispute.Amount
+		rs.Currency = payment.NormalizeCurrency(string(dispute.Currency))
+		rs.FailureCode = string(dispute.Reason)
+		if dispute.PaymentIntent != nil {
+			rs.TransactionID = dispute.PaymentIntent.ID
+		}
+		if dispute.Charge != nil {
+			rs.ChargeID = dispute.Charge.ID
+		}
+	}
+	return rs, nil
+}
+
+func (s *StripeGateway) CreateCustomer(ctx context.Context, req payment.CustomerRequest) (string, error) {
+	params := &stripe.CustomerCreateParams{
+		Email:    &req.Email,
+		Metadata: req.Metadata,
+	}
+	if req.Name != "" {
+		params.Name = &req.Name
+	}
+	customer, err := s.client.V1Customers.Create(ctx, params)
+	if err != nil {
+		return "", fmt.Errorf("failed to create Stripe customer: %w", err)
+	}
+	return customer.ID, nil
+}
+
+func (s *StripeGateway) CreateSetupIntent(ctx context.Context, customerID string) (*payment.SetupIntent, error) {
+	intent, err := s.client.V1SetupIntents.Create(ctx, &stripe.SetupIntentCreateParams{
+		Customer:           &customerID,
+		PaymentMethodTypes: []*string{stripe.String("card")},
+		// cards are charged again while the customer is checking out
+		Usage: stripe.String(string(stripe.SetupIntentUsageOnSession)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Stripe setup intent: %w", err)
+	}
+	return toSetupIntent(intent), nil
+}
+
+func (s *StripeGateway) GetSetupIntent(ctx context.Context, intentID string) (*payment.SetupIntent, error) {
+	intent, err := s.client.V1SetupIntents.Retrieve(ctx, intentID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Stripe setup intent: %w", err)
+	}
+	return toSetupIntent(intent), nil
+}
+
+func (s *StripeGateway) GetSavedMethod(ctx context.Context, methodID string) (*payment.SavedMethod, error) {
+	pm, err := s.client.V1PaymentMethods.Retrieve(ctx, methodID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Stripe payment method: %w", err)
+	}
+	if pm.Card == nil {
+		return nil, fmt.Errorf("stripe payment method %s is not a card", methodID)
+	}
+	rs := &payment.SavedMethod{
+		ID:       pm.ID,
+		Brand:    string(pm.Card.Brand),
+		Last4:    pm.Card.Last4,
+		ExpMonth: int(pm.Card.ExpMonth),
+		ExpYear:  int(pm.Card.ExpYear),
+	}
+	if pm.Customer != nil {
+		rs.CustomerID = pm.Customer.ID
+	}
+	if pm.BillingDetails != nil && pm.BillingDetails.Address != nil {
+		addr := pm.BillingDetails.Address
+		parts := make([]string, 0, 6)
+		for _, part := range []string{addr.Line1, addr.Line2, addr.City, addr.State, addr.PostalCode, addr.Country} {
+			if part != "" {
+				parts = append(parts, part)
+			}
+		}
+		rs.BillingAddress = strings.Join(parts, ", ")
+	}
+	return rs, nil
+}
+
+func (s *StripeGateway) DetachSavedMethod(ctx context.Context, methodID string) error {
+	_, err := s.client.V1PaymentMethods.Detach(ctx, methodID, nil)
+	if err != nil {
+		return fmt.Errorf("failed to detach Stripe payment method: %w", err)
+	}
 	return nil
 }
 
@@ -141,3 +390,40 @@ func (s *StripeGateway) Health(ctx context.Context) error {
 	}
 	return nil
 }
+
+// stripePaymentStatus maps a payment intent status onto the gateway neutral ones
+func stripePaymentStatus(status stripe.PaymentIntentStatus) payment.PaymentStatus {
+	switch status {
+	case stripe.PaymentIntentStatusSucceeded:
+		return payment.StatusCompleted
+	case stripe.PaymentIntentStatusRequiresCapture:
+		return payment.StatusAuthorized
+	case stripe.PaymentIntentStatusCanceled:
+		return payment.StatusCancelled
+	default:
+		// processing and the requires_* statuses still wait on the customer or the bank,
+		// a declined card goes back to requires_payment_method so it can be retried
+		return payment.StatusPending
+	}
+}
+
+func toSetupIntent(intent *stripe.SetupIntent) *payment.SetupIntent {
+	rs := &payment.SetupIntent{
+		ID:           intent.ID,
+		ClientSecret: intent.ClientSecret,
+		Status:       payment.StatusPending,
+	}
+	switch intent.Status {
+	case stripe.SetupIntentStatusSucceeded:
+		rs.Status = payment.StatusCompleted
+	case stripe.SetupIntentStatusCanceled:
+		rs.Status = payment.StatusCancelled
+	}
+	if intent.Customer != nil {
+		rs.CustomerID = intent.Customer.ID
+	}
+	if intent.PaymentMethod != nil {
+		rs.PaymentMethodID = intent.PaymentMethod.ID
+	}
+	return rs
+}
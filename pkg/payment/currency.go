@@ -0,0 +1,46 @@
+package payment
+
+import (
+	"math"
+	"strings"
+)
+
+// zeroDecimalCurrencies have no minor unit, an amount of 500 JPY is sent as 500
+var zeroDecimalCurrencies = map[Currency]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "JPY": true, "KMF": true,
+	"KRW": true, "MGA": true, "PYG": true, "RWF": true, "UGX": true, "VND": true,
+	"VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
+// threeDecimalCurrencies are divided in thousandths instead of hundredths
+var threeDecimalCurrencies = map[Currency]bool{
+	"BHD": true, "JOD": true, "KWD": true, "OMR": true, "TND": true,
+}
+
+// NormalizeCurrency returns the ISO 4217 code of a currency in upper case
+func NormalizeCurrency(code string) Currency {
+	return Currency(strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// Decimals returns the number of digits after the decimal point of the currency
+func (c Currency) Decimals() int {
+	code := NormalizeCurrency(string(c))
+	switch {
+	case zeroDecimalCurrencies[code]:
+		return 0
+	case threeDecimalCurrencies[code]:
+		return 3
+	default:
+		return 2
+	}
+}
+
+// ToMinorUnits converts an amount to the smallest unit of the currency, e.g. cents
+func (c Currency) ToMinorUnits(amount float64) int64 {
+	return int64(math.Round(amount * math.Pow10(c.Decimals())))
+}
+
+// FromMinorUnits converts an amount in the smallest unit of the currency back to a decimal amount
+func (c Currency) FromMinorUnits(amount int64) float64 {
+	return float64(amount) / math.Pow10(c.Decimals())
+}
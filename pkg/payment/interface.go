@@ -1,6 +1,9 @@
 package payment
 
-import "context"
+import (
+	"context"
+	"net/http"
+)
 
 // PaymentGateway defines the contract for all payment providers
 type PaymentGateway interface {
@@ -22,8 +25,32 @@ type PaymentGateway interface {
 	// CancelPayment cancels a pending payment
 	CancelPayment(ctx context.Context, intentID string) error
 
-	// VerifyWebhook verifies incoming webhook signatures
-	VerifyWebhook(payload []byte, signature string) error
+	// CapturePayment takes the money of an authorized payment, at most what was authorized
+	CapturePayment(ctx context.Context, req CaptureRequest) (*PaymentResult, error)
+
+	// VoidPayment releases an authorized payment without taking any money
+	VoidPayment(ctx context.Context, intentID string) error
+
+	// VerifyWebhook verifies incoming webhook signatures from the request headers
+	VerifyWebhook(ctx context.Context, payload []byte, headers http.Header) error
+
+	// ParseWebhook turns a verified webhook payload into a normalized event
+	ParseWebhook(payload []byte) (*WebhookEvent, error)
+
+	// CreateCustomer registers a customer payment methods can be saved for and returns its ID
+	CreateCustomer(ctx context.Context, req CustomerRequest) (string, error)
+
+	// CreateSetupIntent starts saving a payment method for a customer
+	CreateSetupIntent(ctx context.Context, customerID string) (*SetupIntent, error)
+
+	// GetSetupIntent retrieves a setup intent and the payment method it saved
+	GetSetupIntent(ctx context.Context, intentID string) (*SetupIntent, error)
+
+	// GetSavedMethod retrieves the display details of a saved payment method
+	GetSavedMethod(ctx context.Context, methodID string) (*SavedMethod, error)
+
+	// DetachSavedMethod removes a saved payment method from its customer
+	DetachSavedMethod(ctx context.Context, methodID string) error
 
 	// Health checks if the gateway is operational
 	Health(ctx context.Context) error
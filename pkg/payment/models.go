@@ -8,11 +8,13 @@ import (
 type PaymentStatus string
 
 const (
-	StatusPending   PaymentStatus = "pending"
-	StatusCompleted PaymentStatus = "completed"
-	StatusFailed    PaymentStatus = "failed"
-	StatusRefunded  PaymentStatus = "refunded"
-	StatusCancelled PaymentStatus = "cancelled"
+	StatusPending PaymentStatus = "pending"
+	// StatusAuthorized holds the money on the card until it is captured or voided
+	StatusAuthorized PaymentStatus = "authorized"
+	StatusCompleted  PaymentStatus = "completed"
+	StatusFailed     PaymentStatus = "failed"
+	StatusRefunded   PaymentStatus = "refunded"
+	StatusCancelled  PaymentStatus = "cancelled"
 )
 
 // Currency represents supported currencies
@@ -31,6 +33,14 @@ type PaymentRequest struct {
 	Email       string            `json:"email"`
 	Description string            `json:"description"`
 	Metadata    map[string]string `json:"metadata"`
+	// CustomerID and PaymentMethodID charge a payment method saved for the customer
+	// straight away instead of waiting for the client to confirm the payment
+	CustomerID      string `json:"customer_id,omitempty"`
+	PaymentMethodID string `json:"payment_method_id,omitempty"`
+	// ManualCapture only authorizes the amount, the money is taken later with CapturePayment
+	ManualCapture bool `json:"manual_capture,omitempty"`
+	// IdempotencyKey makes a retried request return the first payment instead of creating another
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 // PaymentIntent represents a payment intention
@@ -57,11 +67,19 @@ type PaymentResult struct {
 	ProcessedAt   time.Time         `json:"processed_at"`
 }
 
+// CaptureRequest takes the money of an authorized payment
+type CaptureRequest struct {
+	TransactionID string `json:"transaction_id"`
+	Amount        int64  `json:"amount"` // Amount to capture, 0 captures everything authorized
+}
+
 // RefundRequest encapsulates refund data
 type RefundRequest struct {
 	TransactionID string `json:"transaction_id"`
 	Amount        int64  `json:"amount"` // Amount to refund, nil for full refund
 	Reason        string `json:"reason"`
+	// IdempotencyKey makes a retried refund return the first one instead of refunding again
+	IdempotencyKey string `json:"idempotency_key"`
 }
 
 // RefundResult represents the outcome of a refund
@@ -73,3 +91,32 @@ type RefundResult struct {
 	Reason        string        `json:"reason"`
 	CreatedAt     time.Time     `json:"created_at"`
 }
+
+// CustomerRequest registers the customer saved payment methods belong to
+type CustomerRequest struct {
+	Email    string            `json:"email"`
+	Name     string            `json:"name"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// SetupIntent saves a payment method for later payments without charging it
+type SetupIntent struct {
+	ID           string `json:"id"`
+	CustomerID   string `json:"customer_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	// PaymentMethodID is set once the client has confirmed the setup
+	PaymentMethodID string        `json:"payment_method_id,omitempty"`
+	Status          PaymentStatus `json:"status"`
+}
+
+// SavedMethod is a payment method kept by the gateway. Only its token and what is shown
+// to the customer ever leave the gateway.
+type SavedMethod struct {
+	ID             string `json:"id"`
+	CustomerID     string `json:"customer_id"`
+	Brand          string `json:"brand"`
+	Last4          string `json:"last4"`
+	ExpMonth       int    `json:"exp_month"`
+	ExpYear        int    `json:"exp_year"`
+	BillingAddress string `json:"billing_address,omitempty"`
+}
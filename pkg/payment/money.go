@@ -0,0 +1,254 @@
+package payment
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// RoundingMode decides what happens to fractions of a minor unit
+type RoundingMode int
+
+const (
+	// RoundHalfUp rounds halves away from zero, 0.125 becomes 0.13
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds halves to the even neighbour, 0.125 becomes 0.12
+	RoundHalfEven
+	// RoundDown drops the fraction, rounding towards zero
+	RoundDown
+	// RoundUp rounds any fraction away from zero
+	RoundUp
+)
+
+// Money is an exact amount in the smallest unit of a currency, e.g. 1999 USD is $19.99.
+// Arithmetic between amounts of different currencies panics as it is always a bug.
+type Money struct {
+	Amount   int64    `json:"amount"`
+	Currency Currency `json:"currency"`
+}
+
+// NewMoney returns an amount already in minor units
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: NormalizeCurrency(string(currency))}
+}
+
+// MoneyFromRat converts an exact decimal amount in major units, rounding it to the minor unit
+func MoneyFromRat(amount *big.Rat, currency Currency, mode RoundingMode) Money {
+	currency = NormalizeCurrency(string(currency))
+	minor := new(big.Rat).Mul(amount, new(big.Rat).SetInt(pow10(currency.Decimals())))
+	return Money{Amount: roundRat(minor, mode), Currency: currency}
+}
+
+// MoneyFromFloat converts a decimal amount in major units. The float is read as the shortest
+// decimal that represents it, so 0.1 is 10 cents rather than 0.1000000000000000055 dollars.
+func MoneyFromFloat(amount float64, currency Currency, mode RoundingMode) Money {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(amount, 'f', -1, 64))
+	if !ok {
+		return NewMoney(0, currency)
+	}
+	return MoneyFromRat(r, currency, mode)
+}
+
+// ParseMoney reads a decimal amount in major units, e.g. "19.99". Unlike the float
+// conversions it never rounds, an amount with more digits than the currency has is an error.
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	currency = NormalizeCurrency(string(currency))
+	whole, frac, _ := strings.Cut(amount, ".")
+	digits := strings.TrimPrefix(whole, "-")
+	if (digits == "" && frac == "") || !isDigits(digits) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("payment: invalid amount %q", amount)
+	}
+	if len(frac) > currency.Decimals() {
+		return Money{}, fmt.Errorf("payment: amount %q has more than %d decimals for %s", amount, currency.Decimals(), currency)
+	}
+	minor, err := strconv.ParseInt(whole+frac+strings.Repeat("0", currency.Decimals()-len(frac)), 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("payment: invalid amount %q: %w", amount, err)
+	}
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Rat returns the exact amount in major units
+func (m Money) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(big.NewInt(m.Amount), pow10(m.Currency.Decimals()))
+}
+
+// Float64 returns the amount in major units, for display and legacy float fields only
+func (m Money) Float64() float64 {
+	return m.Currency.FromMinorUnits(m.Amount)
+}
+
+func (m Money) IsZero() bool     { return m.Amount == 0 }
+func (m Money) IsPositive() bool { return m.Amount > 0 }
+func (m Money) IsNegative() bool { return m.Amount < 0 }
+
+// Add returns m + o
+func (m Money) Add(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount + o.Amount, Currency: m.Currency}
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) Money {
+	m.mustMatch(o)
+	return Money{Amount: m.Amount - o.Amount, Currency: m.Currency}
+}
+
+// Mul returns m multiplied by a whole number, e.g. a unit price by a quantity
+func (m Money) Mul(n int64) Money {
+	return Money{Amount: m.Amount * n, Currency: m.Currency}
+}
+
+// MulRat returns m multiplied by an exact factor and rounded to the minor unit
+func (m Money) MulRat(factor *big.Rat, mode RoundingMode) Money {
+	minor := new(big.Rat).Mul(new(big.Rat).SetInt64(m.Amount), factor)
+	return Money{Amount: roundRat(minor, mode), Currency: m.Currency}
+}
+
+// Percent returns pct percent of m, Percent(15, ...) of $10.00 is $1.50
+func (m Money) Percent(pct float64, mode RoundingMode) Money {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(pct, 'f', -1, 64))
+	if !ok {
+		return Money{Currency: m.Currency}
+	}
+	return m.MulRat(r.Quo(r, big.NewRat(100, 1)), mode)
+}
+
+// Cmp compares m and o and returns -1, 0 or +1
+func (m Money) Cmp(o Money) int {
+	m.mustMatch(o)
+	switch {
+	case m.Amount < o.Amount:
+		return -1
+	case m.Amount > o.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Min returns the smaller of m and o
+func (m Money) Min(o Money) Money {
+	if m.Cmp(o) > 0 {
+		return o
+	}
+	return m
+}
+
+// Max returns the larger of m and o
+func (m Money) Max(o Money) Money {
+	if m.Cmp(o) < 0 {
+		return o
+	}
+	return m
+}
+
+// Allocate splits m proportionally to the weights without losing or inventing a minor unit.
+// Shares are rounded down and the units left over go to the largest remainders, earlier
+// weights first on a tie. With no positive weight m is split evenly.
+func (m Money) Allocate(weights []int64) []Money {
+	shares := make([]Money, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+	var total int64
+	for _, w := range weights {
+		if w > 0 {
+			total += w
+		}
+	}
+	if total == 0 {
+		weights = make([]int64, len(weights))
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = int64(len(weights))
+	}
+
+	sign := int64(1)
+	amount := m.Amount
+	if amount < 0 {
+		sign, amount = -1, -amount
+	}
+	remainders := make([]*big.Int, len(weights))
+	allocated := int64(0)
+	for i, w := range weights {
+		w = max(w, 0)
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(amount), big.NewInt(w)), big.NewInt(total), new(big.Int))
+		shares[i] = Money{Amount: q.Int64(), Currency: m.Currency}
+		remainders[i] = r
+		allocated += q.Int64()
+	}
+	for left := amount - allocated; left > 0; left-- {
+		best := -1
+		for i, r := range remainders {
+			if weights[i] > 0 && (best < 0 || r.Cmp(remainders[best]) > 0) {
+				best = i
+			}
+		}
+		shares[best].Amount++
+		remainders[best] = big.NewInt(-1)
+	}
+	for i := range shares {
+		shares[i].Amount *= sign
+	}
+	return shares
+}
+
+// String formats the amount with the digits of its currency, e.g. "19.99 USD" or "500 JPY"
+func (m Money) String() string {
+	return strconv.FormatFloat(m.Float64(), 'f', m.Currency.Decimals(), 64) + " " + string(m.Currency)
+}
+
+func (m Money) mustMatch(o Money) {
+	if m.Currency != o.Currency {
+		panic(fmt.Sprintf("payment: money currency mismatch %s and %s", m.Currency, o.Currency))
+	}
+}
+
+// roundRat rounds an amount of minor units to a whole number
+func roundRat(r *big.Rat, mode RoundingMode) int64 {
+	num, denom := r.Num(), r.Denom()
+	q, rem := new(big.Int).QuoRem(num, denom, new(big.Int))
+	if rem.Sign() == 0 {
+		return q.Int64()
+	}
+
+	away := false
+	switch mode {
+	case RoundDown:
+	case RoundUp:
+		away = true
+	default:
+		// compare twice the remainder with the denominator to find out which side of the half it is on
+		cmp := new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(denom)
+		switch {
+		case cmp > 0:
+			away = true
+		case cmp == 0:
+			away = mode == RoundHalfUp || q.Bit(0) == 1
+		}
+	}
+	if away {
+		if num.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q.Int64()
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,209 @@
+package payment_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/thanhphuocnguyen/go-eshop/internal/utils"
+	"github.com/thanhphuocnguyen/go-eshop/pkg/payment"
+)
+
+func rat(t *testing.T, s string) *big.Rat {
+	t.Helper()
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		t.Fatalf("invalid decimal %q", s)
+	}
+	return r
+}
+
+func TestMoneyFromRatRounding(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		currency payment.Currency
+		mode     payment.RoundingMode
+		want     int64
+	}{
+		{"half up on half", "0.125", "USD", payment.RoundHalfUp, 13},
+		{"half even on half to even", "0.125", "USD", payment.RoundHalfEven, 12},
+		{"half even on half to odd", "0.135", "USD", payment.RoundHalfEven, 14},
+		{"down on half", "0.125", "USD", payment.RoundDown, 12},
+		{"up on half", "0.125", "USD", payment.RoundUp, 13},
+		{"half up on negative half", "-0.125", "USD", payment.RoundHalfUp, -13},
+		{"half even on negative half to even", "-0.125", "USD", payment.RoundHalfEven, -12},
+		{"half even on negative half to odd", "-0.135", "USD", payment.RoundHalfEven, -14},
+		{"down on negative half", "-0.125", "USD", payment.RoundDown, -12},
+		{"up on negative half", "-0.125", "USD", payment.RoundUp, -13},
+		{"half up below half", "0.124", "USD", payment.RoundHalfUp, 12},
+		{"half even above half", "0.1251", "USD", payment.RoundHalfEven, 13},
+		{"up on any fraction", "0.121", "USD", payment.RoundUp, 13},
+		{"down on negative fraction", "-0.129", "USD", payment.RoundDown, -12},
+		{"exact amount", "19.99", "USD", payment.RoundUp, 1999},
+		{"lower case currency", "19.99", "usd", payment.RoundHalfUp, 1999},
+		{"zero decimal half up", "2.5", "JPY", payment.RoundHalfUp, 3},
+		{"zero decimal half even", "2.5", "JPY", payment.RoundHalfEven, 2},
+		{"zero decimal half even to odd", "3.5", "JPY", payment.RoundHalfEven, 4},
+		{"zero decimal negative half up", "-2.5", "JPY", payment.RoundHalfUp, -3},
+		{"zero decimal negative half even", "-2.5", "JPY", payment.RoundHalfEven, -2},
+		{"zero decimal down", "1999.99", "JPY", payment.RoundDown, 1999},
+		{"three decimal half up", "1.2345", "KWD", payment.RoundHalfUp, 1235},
+		{"three decimal half even", "1.2345", "KWD", payment.RoundHalfEven, 1234},
+		{"three decimal half even to odd", "1.2355", "KWD", payment.RoundHalfEven, 1236},
+		{"three decimal negative half up", "-1.2345", "BHD", payment.RoundHalfUp, -1235},
+		{"three decimal up", "0.0001", "OMR", payment.RoundUp, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := payment.MoneyFromRat(rat(t, tt.amount), tt.currency, tt.mode)
+			if got.Amount != tt.want {
+				t.Errorf("MoneyFromRat(%s %s) = %d, want %d", tt.amount, tt.currency, got.Amount, tt.want)
+			}
+			if got.Currency != payment.NormalizeCurrency(string(tt.currency)) {
+				t.Errorf("currency = %s, want %s", got.Currency, payment.NormalizeCurrency(string(tt.currency)))
+			}
+		})
+	}
+}
+
+func TestMoneyFromFloat(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		currency payment.Currency
+		mode     payment.RoundingMode
+		want     int64
+	}{
+		{"sum with float artifact", 0.1 + 0.2, "USD", payment.RoundHalfUp, 30},
+		{"sum with float artifact half even", 0.1 + 0.2, "USD", payment.RoundHalfEven, 30},
+		{"sum with float artifact down", 0.1 + 0.2, "USD", payment.RoundDown, 30},
+		// 1.005 is stored as 1.00499999999999989..., which math.Round(x*100) takes down to 100
+		{"half stored below half", 1.005, "USD", payment.RoundHalfUp, 101},
+		{"half stored below half even", 1.005, "USD", payment.RoundHalfEven, 100},
+		{"tenth", 0.1, "USD", payment.RoundHalfUp, 10},
+		{"price", 19.99, "USD", payment.RoundHalfUp, 1999},
+		{"negative half", -1.005, "USD", payment.RoundHalfUp, -101},
+		{"large amount", 1234567.89, "USD", payment.RoundHalfUp, 123456789},
+		{"zero decimal", 1999.5, "JPY", payment.RoundHalfUp, 2000},
+		{"zero decimal half even", 1998.5, "JPY", payment.RoundHalfEven, 1998},
+		{"three decimal", 1.0005, "KWD", payment.RoundHalfUp, 1001},
+		{"three decimal exact", 0.001, "TND", payment.RoundHalfUp, 1},
+		{"zero", 0, "USD", payment.RoundUp, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := payment.MoneyFromFloat(tt.amount, tt.currency, tt.mode)
+			if got.Amount != tt.want {
+				t.Errorf("MoneyFromFloat(%v %s) = %d, want %d", tt.amount, tt.currency, got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency payment.Currency
+		want     int64
+		wantErr  bool
+	}{
+		{"19.99", "USD", 1999, false},
+		{"0.1", "USD", 10, false},
+		{"1.005", "USD", 0, true},
+		{"12", "USD", 1200, false},
+		{".5", "USD", 50, false},
+		{"-1.25", "USD", -125, false},
+		{"500", "JPY", 500, false},
+		{"500.5", "JPY", 0, true},
+		{"1.234", "KWD", 1234, false},
+		{"1e2", "USD", 0, true},
+		{"1/3", "USD", 0, true},
+		{"+1", "USD", 0, true},
+		{"", "USD", 0, true},
+		{".", "USD", 0, true},
+		{"-", "USD", 0, true},
+		{"1.2.3", "USD", 0, true},
+		{"99999999999999999999", "USD", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+string(tt.currency), func(t *testing.T) {
+			got, err := payment.ParseMoney(tt.amount, tt.currency)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMoney(%q, %s) err = %v, wantErr %v", tt.amount, tt.currency, err, tt.wantErr)
+			}
+			if err == nil && got.Amount != tt.want {
+				t.Errorf("ParseMoney(%q, %s) = %d, want %d", tt.amount, tt.currency, got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		weights []int64
+		want    []int64
+	}{
+		{"even thirds", 100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{"largest remainder", 100, []int64{1, 2}, []int64{33, 67}},
+		{"tie goes to earlier weight", 1, []int64{1, 1, 1}, []int64{1, 0, 0}},
+		{"zero weight gets nothing", 1, []int64{1, 0, 1}, []int64{1, 0, 0}},
+		{"exact split", 7, []int64{3, 0, 4}, []int64{3, 0, 4}},
+		{"negative amount", -100, []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		{"no positive weight splits evenly", 5, []int64{0, 0}, []int64{3, 2}},
+		{"negative weight counts as zero", 10, []int64{-5, 1}, []int64{0, 10}},
+		{"line totals", 1000, []int64{1999, 2999, 4999}, []int64{200, 300, 500}},
+		{"zero amount", 0, []int64{1, 2}, []int64{0, 0}},
+		{"no weights", 100, nil, []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total := payment.NewMoney(tt.amount, "USD")
+			shares := total.Allocate(tt.weights)
+			if len(shares) != len(tt.want) {
+				t.Fatalf("got %d shares, want %d", len(shares), len(tt.want))
+			}
+			var sum int64
+			for i, share := range shares {
+				if share.Amount != tt.want[i] {
+					t.Errorf("share %d = %d, want %d", i, share.Amount, tt.want[i])
+				}
+				if share.Currency != total.Currency {
+					t.Errorf("share %d currency = %s, want %s", i, share.Currency, total.Currency)
+				}
+				sum += share.Amount
+			}
+			if len(shares) > 0 && sum != tt.amount {
+				t.Errorf("shares sum to %d, want %d", sum, tt.amount)
+			}
+		})
+	}
+}
+
+func TestPgNumericRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		money payment.Money
+		exp   int32
+	}{
+		{"two decimals", payment.NewMoney(1999, "USD"), -2},
+		{"negative", payment.NewMoney(-1, "EUR"), -2},
+		{"zero", payment.NewMoney(0, "USD"), -2},
+		{"zero decimals", payment.NewMoney(500, "JPY"), 0},
+		{"three decimals", payment.NewMoney(1234, "KWD"), -3},
+		{"large", payment.NewMoney(922337203685477, "USD"), -2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			num := utils.GetPgNumericFromMoney(tt.money)
+			if !num.Valid || num.Exp != tt.exp || num.Int.Int64() != tt.money.Amount {
+				t.Errorf("numeric = %v e%d, want %d e%d", num.Int, num.Exp, tt.money.Amount, tt.exp)
+			}
+			got := utils.GetMoneyFromPgNumeric(num, tt.money.Currency)
+			if got != tt.money {
+				t.Errorf("round trip = %v, want %v", got, tt.money)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 )
 
@@ -13,6 +14,8 @@ var (
 	ErrInvalidAmount     = errors.New("invalid payment amount")
 	ErrInvalidCurrency   = errors.New("invalid currency")
 	ErrInvalidEmail      = errors.New("invalid email address")
+	// ErrSavedMethodsNotSupported is returned by gateways that can't keep payment methods
+	ErrSavedMethodsNotSupported = errors.New("payment gateway does not support saved payment methods")
 )
 
 // GatewayFactory creates payment gateway instances
@@ -110,6 +113,39 @@ func (ps *PaymentManager) RefundPayment(ctx context.Context, req RefundRequest,
 	return gateway.RefundPayment(ctx, req)
 }
 
+// CancelPayment cancels a pending payment intent
+func (ps *PaymentManager) CancelPayment(ctx context.Context, intentID string, gatewayName string) error {
+	gateway, err := ps.getGateway(gatewayName)
+	if err != nil {
+		return err
+	}
+
+	return gateway.CancelPayment(ctx, intentID)
+}
+
+// CapturePayment takes the money of an authorized payment
+func (ps *PaymentManager) CapturePayment(ctx context.Context, req CaptureRequest, gatewayName string) (*PaymentResult, error) {
+	if req.Amount < 0 {
+		return nil, ErrInvalidAmount
+	}
+	gateway, err := ps.getGateway(gatewayName)
+	if err != nil {
+		return nil, err
+	}
+
+	return gateway.CapturePayment(ctx, req)
+}
+
+// VoidPayment releases an authorized payment
+func (ps *PaymentManager) VoidPayment(ctx context.Context, intentID string, gatewayName string) error {
+	gateway, err := ps.getGateway(gatewayName)
+	if err != nil {
+		return err
+	}
+
+	return gateway.VoidPayment(ctx, intentID)
+}
+
 // GetPayment retrieves payment details
 func (ps *PaymentManager) GetPayment(ctx context.Context, transactionID string, gatewayName string) (*PaymentIntent, error) {
 	gateway, err := ps.getGateway(gatewayName)
@@ -120,6 +156,79 @@ func (ps *PaymentManager) GetPayment(ctx context.Context, transactionID string,
 	return gateway.GetPayment(ctx, transactionID)
 }
 
+// VerifyWebhook verifies the signature of a webhook sent by a gateway
+func (ps *PaymentManager) VerifyWebhook(ctx context.Context, payload []byte, headers http.Header, gatewayName string) error {
+	gateway, err := ps.getGateway(gatewayName)
+	if err != nil {
+		return err
+	}
+
+	return gateway.VerifyWebhook(ctx, payload, headers)
+}
+
+// ParseWebhook normalizes a webhook payload sent by a gateway
+func (ps *PaymentManager) ParseWebhook(payload []byte, gatewayName string) (*WebhookEvent, error) {
+	gateway, err := ps.getGateway(gatewayName)
+	if err != nil {
+		return nil, err
+	}
+
+	return gateway.ParseWebhook(payload)
+}
+
+// CreateCustomer registers a customer to save payment methods for
+func (ps *PaymentManager) CreateCustomer(ctx context.Context, req CustomerRequest, gatewayName string) (string, error) {
+	if req.Email == "" {
+		return "", ErrInvalidEmail
+	}
+	gateway, err := ps.getGateway(gatewayName)
+	if err != nil {
+		return "", err
+	}
+
+	return gateway.CreateCustomer(ctx, req)
+}
+
+// CreateSetupIntent starts saving a payment method for a customer
+func (ps *PaymentManager) CreateSetupIntent(ctx context.Context, customerID string, gatewayName string) (*SetupIntent, error) {
+	gateway, err := ps.getGateway(gatewayName)
+	if err != nil {
+		return nil, err
+	}
+
+	return gateway.CreateSetupIntent(ctx, customerID)
+}
+
+// GetSetupIntent retrieves a setup intent
+func (ps *PaymentManager) GetSetupIntent(ctx context.Context, intentID string, gatewayName string) (*SetupIntent, error) {
+	gateway, err := ps.getGateway(gatewayName)
+	if err != nil {
+		return nil, err
+	}
+
+	return gateway.GetSetupIntent(ctx, intentID)
+}
+
+// GetSavedMethod retrieves a saved payment method
+func (ps *PaymentManager) GetSavedMethod(ctx context.Context, methodID string, gatewayName string) (*SavedMethod, error) {
+	gateway, err := ps.getGateway(gatewayName)
+	if err != nil {
+		return nil, err
+	}
+
+	return gateway.GetSavedMethod(ctx, methodID)
+}
+
+// DetachSavedMethod removes a saved payment method from its customer
+func (ps *PaymentManager) DetachSavedMethod(ctx context.Context, methodID string, gatewayName string) error {
+	gateway, err := ps.getGateway(gatewayName)
+	if err != nil {
+		return err
+	}
+
+	return gateway.DetachSavedMethod(ctx, methodID)
+}
+
 // Helper methods
 
 func (ps *PaymentManager) validatePaymentRequest(req PaymentRequest) error {
@@ -0,0 +1,42 @@
+package payment
+
+import "time"
+
+// WebhookEventType is what a gateway webhook means for our payments, whatever the
+// gateway calls it
+type WebhookEventType string
+
+const (
+	WebhookPaymentSucceeded WebhookEventType = "payment.succeeded"
+	// WebhookPaymentAuthorized is sent for manual capture payments, the money is held but not taken
+	WebhookPaymentAuthorized WebhookEventType = "payment.authorized"
+	WebhookPaymentFailed     WebhookEventType = "payment.failed"
+	WebhookPaymentCancelled  WebhookEventType = "payment.cancelled"
+	WebhookRefundCompleted   WebhookEventType = "refund.completed"
+	WebhookDisputeOpened     WebhookEventType = "dispute.opened"
+	// WebhookIgnored marks events we receive but don't act on
+	WebhookIgnored WebhookEventType = "ignored"
+)
+
+// WebhookEvent is a gateway webhook normalized into what the shop cares about
+type WebhookEvent struct {
+	// ID is the gateway's event ID, deliveries of the same event share it
+	ID string `json:"id"`
+	// GatewayType is the event type as the gateway named it
+	GatewayType string           `json:"gateway_type"`
+	Type        WebhookEventType `json:"type"`
+	// TransactionID is the payment intent (or order) the event is about
+	TransactionID string `json:"transaction_id"`
+	ChargeID      string `json:"charge_id,omitempty"`
+	RefundID      string `json:"refund_id,omitempty"`
+	DisputeID     string `json:"dispute_id,omitempty"`
+	// Amount in smallest currency unit
+	Amount         int64    `json:"amount"`
+	Currency       Currency `json:"currency"`
+	PaymentMethod  string   `json:"payment_method,omitempty"`
+	FailureCode    string   `json:"failure_code,omitempty"`
+	FailureMessage string   `json:"failure_message,omitempty"`
+	// AuthorizationExpiresAt is when the gateway releases an authorization, zero when it doesn't say
+	AuthorizationExpiresAt time.Time `json:"authorization_expires_at,omitempty"`
+	CreatedAt              time.Time `json:"created_at"`
+}